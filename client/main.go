@@ -2,10 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net"
-	"os"
 	"strings"
 	"time"
 
@@ -15,6 +15,8 @@ import (
 )
 
 func main() {
+	timeout := flag.Duration("timeout", redisclientlib.DefaultTimeout, "how long a command may take to reply, 0 to wait forever")
+	flag.Parse()
 	slog.SetDefault(slog.New(logger.NewHandler(slog.LevelDebug)))
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -30,9 +32,9 @@ func main() {
 	}
 
 	defer conn.Close()
-	redis := redisclientlib.NewRedisClient(conn)
-	if len(os.Args) > 1 {
-		request := strings.Join(os.Args[1:], " ")
+	redis := redisclientlib.NewRedisClient(conn, redisclientlib.WithTimeout(*timeout))
+	if flag.NArg() > 0 {
+		request := strings.Join(flag.Args(), " ")
 		slog.DebugContext(ctx, "One shot mode", "request", request)
 		redis.ExecuteCommand(ctx, request)
 		return
@@ -0,0 +1,116 @@
+// Package dedupe is an example extension adding a deduplicating queue type to the server, with
+// the DEDUPE.PUSH, DEDUPE.POP and DEDUPE.LEN commands. Elements pushed while an equal element is
+// still queued are ignored.
+//
+// Extensions are added when creating the processor:
+//
+//	cp := redisserverlib.NewRedisCommandProcessor(append(opts, dedupe.Options()...)...)
+package dedupe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// queue is the value of a key holding a deduplicating queue.
+	queue struct {
+		elements []string
+		queued   map[string]struct{}
+	}
+)
+
+var (
+	// QueueType is the type of the keys holding a deduplicating queue, as reported by TYPE.
+	QueueType = &redistypes.CustomType{
+		Name:            "dedupe-qu",
+		EncodingVersion: 0,
+		Encode:          encode,
+		Decode:          decode,
+	}
+
+	errWrongType = resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
+	errCorrupted = errors.New("corrupted queue")
+)
+
+// Options returns the options adding the queue type and its commands to a processor.
+func Options() []redisserverlib.Option {
+	return []redisserverlib.Option{
+		redisserverlib.WithValueType(QueueType),
+		redisserverlib.WithCommand(push{}),
+		redisserverlib.WithCommand(pop{}),
+		redisserverlib.WithCommand(length{}),
+	}
+}
+
+func newQueue() redistypes.StoreValue {
+	return redistypes.NewCustomValue(QueueType, &queue{queued: make(map[string]struct{})})
+}
+
+// queueOf returns the queue held by v, or false if v holds another type.
+func queueOf(v redistypes.StoreValue) (*queue, bool) {
+	q, ok := v.Custom.(*queue)
+	return q, ok
+}
+
+// push appends the elements that are not queued yet, and returns how many were appended.
+func (q *queue) push(elements ...string) int {
+	pushed := 0
+	for _, element := range elements {
+		if _, exists := q.queued[element]; !exists {
+			q.queued[element] = struct{}{}
+			q.elements = append(q.elements, element)
+			pushed++
+		}
+	}
+
+	return pushed
+}
+
+func (q *queue) pop() (string, bool) {
+	if len(q.elements) == 0 {
+		return "", false
+	}
+
+	element := q.elements[0]
+	q.elements = q.elements[1:]
+	delete(q.queued, element)
+	return element, true
+}
+
+func (q *queue) Clone() redistypes.CustomValue {
+	clone := &queue{queued: make(map[string]struct{})}
+	clone.push(q.elements...)
+	return clone
+}
+
+// encode writes the elements in order, each prefixed with its length.
+func encode(v redistypes.CustomValue) []byte {
+	buf := []byte{}
+	for _, element := range v.(*queue).elements {
+		buf = binary.AppendUvarint(buf, uint64(len(element)))
+		buf = append(buf, element...)
+	}
+
+	return buf
+}
+
+func decode(data []byte, encodingVersion int) (redistypes.CustomValue, error) {
+	q := &queue{queued: make(map[string]struct{})}
+	for len(data) > 0 {
+		n, size := binary.Uvarint(data)
+		if size <= 0 || uint64(len(data)-size) < n {
+			return nil, errCorrupted
+		}
+
+		q.push(string(data[size : size+int(n)]))
+		data = data[size+int(n):]
+	}
+
+	return q, nil
+}
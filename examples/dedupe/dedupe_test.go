@@ -0,0 +1,93 @@
+package dedupe_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/examples/dedupe"
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type testClient struct {
+	ctx context.Context
+	cp  redisserverlib.CommandProcessor
+}
+
+func newProcessor(t *testing.T, dir string) testClient {
+	cp := redisserverlib.NewRedisCommandProcessor(append(dedupe.Options(), redisserverlib.WithDir(dir))...)
+	t.Cleanup(func() { cp.Close() })
+	ctx, _ := cp.ConnectClient(context.Background())
+	return testClient{ctx: ctx, cp: cp}
+}
+
+func (c testClient) do(args ...string) string {
+	return c.cp.ExecuteCommand(c.ctx, resptypes.ToBulkStringArray(args).ToRespString()).ToRespString()
+}
+
+func (c testClient) expect(t *testing.T, expected string, args ...string) {
+	t.Helper()
+	if actual := c.do(args...); actual != expected {
+		t.Errorf("%v = %q; Expected: %q", args, actual, expected)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	c := newProcessor(t, t.TempDir())
+
+	c.expect(t, ":2\r\n", "DEDUPE.PUSH", "q", "a", "b")
+	c.expect(t, ":1\r\n", "dedupe.push", "q", "a", "c", "b")
+	c.expect(t, ":3\r\n", "DEDUPE.LEN", "q")
+	c.expect(t, "+dedupe-qu\r\n", "TYPE", "q")
+	c.expect(t, "$1\r\na\r\n", "DEDUPE.POP", "q")
+	c.expect(t, ":1\r\n", "DEDUPE.PUSH", "q", "a")
+	for _, element := range []string{"b", "c", "a"} {
+		c.expect(t, "$1\r\n"+element+"\r\n", "DEDUPE.POP", "q")
+	}
+
+	c.expect(t, "$-1\r\n", "DEDUPE.POP", "q")
+	c.expect(t, ":0\r\n", "DEDUPE.LEN", "q")
+	c.expect(t, "+none\r\n", "TYPE", "q")
+
+	c.expect(t, "+OK\r\n", "SET", "s", "v")
+	c.expect(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "DEDUPE.PUSH", "s", "a")
+	c.expect(t, ":1\r\n", "DEDUPE.PUSH", "q", "a")
+	c.expect(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "GET", "q")
+	c.expect(t, "-ERR wrong number of arguments for 'dedupe.push' command\r\n", "DEDUPE.PUSH", "q")
+}
+
+func TestIntrospection(t *testing.T) {
+	c := newProcessor(t, t.TempDir())
+
+	c.expect(t, "*1\r\n*10\r\n$11\r\ndedupe.push\r\n:-3\r\n*2\r\n+write\r\n+fast\r\n:1\r\n:1\r\n:1\r\n*2\r\n+@write\r\n+@fast\r\n*0\r\n"+
+		"*1\r\n*6\r\n$5\r\nflags\r\n*2\r\n+RW\r\n+insert\r\n$12\r\nbegin_search\r\n*4\r\n$4\r\ntype\r\n$5\r\nindex\r\n$4\r\nspec\r\n*2\r\n$5\r\nindex\r\n:1\r\n"+
+		"$9\r\nfind_keys\r\n*4\r\n$4\r\ntype\r\n$5\r\nrange\r\n$4\r\nspec\r\n*6\r\n$7\r\nlastkey\r\n:0\r\n$7\r\nkeystep\r\n:1\r\n$5\r\nlimit\r\n:0\r\n*0\r\n",
+		"COMMAND", "INFO", "dedupe.push")
+	c.expect(t, "*1\r\n$1\r\nq\r\n", "COMMAND", "GETKEYS", "DEDUPE.POP", "q")
+	if help := c.do("HELP", "DEDUPE.PUSH"); !strings.Contains(help, "DEDUPE.PUSH key element [element ...]") {
+		t.Errorf("HELP DEDUPE.PUSH = %q", help)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+	c := newProcessor(t, dir)
+	c.expect(t, ":3\r\n", "DEDUPE.PUSH", "q", "a", "b", "c")
+	c.expect(t, "$1\r\na\r\n", "DEDUPE.POP", "q")
+	c.expect(t, "+OK\r\n", "SAVE")
+
+	restarted := newProcessor(t, dir)
+	restarted.expect(t, ":2\r\n", "DEDUPE.LEN", "q")
+	restarted.expect(t, ":0\r\n", "DEDUPE.PUSH", "q", "b")
+
+	dump := c.cp.ExecuteCommand(c.ctx, resptypes.ToBulkStringArray([]string{"DUMP", "q"}).ToRespString())
+	payload, ok := dump.(*resptypes.BulkString)
+	if !ok {
+		t.Fatalf("DUMP q = %q", dump.ToRespString())
+	}
+
+	c.expect(t, "+OK\r\n", "RESTORE", "copy", "0", payload.Val)
+	c.expect(t, "$1\r\nb\r\n", "DEDUPE.POP", "copy")
+	c.expect(t, ":2\r\n", "DEDUPE.LEN", "q")
+}
@@ -0,0 +1,50 @@
+package dedupe
+
+import (
+	"context"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	length struct{}
+)
+
+func (c length) Name() string {
+	return "DEDUPE.LEN"
+}
+
+func (c length) Spec() redisserverlib.CommandSpec {
+	return redisserverlib.CommandSpec{
+		Arity:      2,
+		Flags:      []string{"readonly", "fast"},
+		FirstKey:   1,
+		Summary:    "Returns the number of elements in a deduplicating queue.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}
+}
+
+func (c length) Usage() string {
+	return `
+usage:
+	DEDUPE.LEN key
+summary:
+	Returns the number of elements queued in the deduplicating queue stored at key, or 0 when key does not exist.
+`
+}
+
+func (c length) Execute(ctx context.Context, keyspace redisserverlib.Keyspace, args []string) resptypes.RespSerializable {
+	v, exists := keyspace.Get(ctx, args[1])
+	if !exists {
+		return resptypes.Integer{Val: 0}
+	}
+
+	q, ok := queueOf(v)
+	if !ok {
+		return errWrongType
+	}
+
+	return resptypes.Integer{Val: int64(len(q.elements))}
+}
@@ -0,0 +1,60 @@
+package dedupe
+
+import (
+	"context"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	pop struct{}
+)
+
+func (c pop) Name() string {
+	return "DEDUPE.POP"
+}
+
+func (c pop) Spec() redisserverlib.CommandSpec {
+	return redisserverlib.CommandSpec{
+		Arity:      2,
+		Flags:      []string{"write", "fast"},
+		FirstKey:   1,
+		KeyFlags:   []string{"RW", "access", "delete"},
+		Summary:    "Removes and returns the first element of a deduplicating queue. Deletes the queue if the last element was popped.",
+		Since:      "1.0.0",
+		Complexity: "O(1)",
+	}
+}
+
+func (c pop) Usage() string {
+	return `
+usage:
+	DEDUPE.POP key
+summary:
+	Removes and returns the element at the head of the deduplicating queue stored at key, which may then be pushed again.
+	Returns nil when key does not exist.
+`
+}
+
+func (c pop) Execute(ctx context.Context, keyspace redisserverlib.Keyspace, args []string) resptypes.RespSerializable {
+	key := args[1]
+	v, exists := keyspace.Get(ctx, key)
+	if !exists {
+		return resptypes.NullBulkString
+	}
+
+	q, ok := queueOf(v)
+	if !ok {
+		return errWrongType
+	}
+
+	element, _ := q.pop()
+	if len(q.elements) == 0 {
+		keyspace.Delete(ctx, key)
+	} else {
+		keyspace.Modified(ctx, key, "dedupe.pop")
+	}
+
+	return resptypes.NewBulkString(element)
+}
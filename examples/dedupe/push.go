@@ -0,0 +1,54 @@
+package dedupe
+
+import (
+	"context"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	push struct{}
+)
+
+func (c push) Name() string {
+	return "DEDUPE.PUSH"
+}
+
+func (c push) Spec() redisserverlib.CommandSpec {
+	return redisserverlib.CommandSpec{
+		Arity:      -3,
+		Flags:      []string{"write", "fast"},
+		FirstKey:   1,
+		KeyFlags:   []string{"RW", "insert"},
+		Summary:    "Appends the elements that are not queued yet to a deduplicating queue. Creates the key if it doesn't exist.",
+		Since:      "1.0.0",
+		Complexity: "O(1) for each element pushed",
+	}
+}
+
+func (c push) Usage() string {
+	return `
+usage:
+	DEDUPE.PUSH key element [element ...]
+summary:
+	Appends the elements to the tail of the deduplicating queue stored at key, except those already queued.
+	If key does not exist, it is created as an empty queue first.
+	Returns the number of elements appended.
+`
+}
+
+func (c push) Execute(ctx context.Context, keyspace redisserverlib.Keyspace, args []string) resptypes.RespSerializable {
+	key := args[1]
+	q, ok := queueOf(keyspace.GetOrCreate(ctx, key, newQueue))
+	if !ok {
+		return errWrongType
+	}
+
+	pushed := q.push(args[2:]...)
+	if pushed > 0 {
+		keyspace.Modified(ctx, key, "dedupe.push")
+	}
+
+	return resptypes.Integer{Val: int64(pushed)}
+}
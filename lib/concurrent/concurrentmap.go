@@ -14,13 +14,20 @@ type (
 
 	ConcurrentMap[K any, V any] interface {
 		Get(key K) (value V, exists bool)
+		GetWithTTL(key K) (value V, ttl time.Duration, exists bool)
 		Delete(key K)
 		GetOrCreate(key K, newFunc func() V, expiryDuration time.Duration) V
+		Set(key K, value V, expiryDuration time.Duration) (replaced bool)
+		Len() int
+		Clear(async bool)
+		OnExpire(f func(key K))
+		ForEach(f func(key K, value V, expiresAt time.Time))
 	}
 
 	concurrentMap[Key comparable, Value any] struct {
-		entries map[Key]mapEntry[Value]
-		mu      sync.RWMutex
+		entries  map[Key]mapEntry[Value]
+		mu       sync.RWMutex
+		onExpire func(key Key)
 	}
 )
 
@@ -46,6 +53,26 @@ func (m *concurrentMap[K, V]) Get(key K) (value V, exists bool) {
 	return entry.data, exists
 }
 
+// GetWithTTL behaves like Get, additionally returning the time left before the entry expires.
+// A ttl of 0 means the entry has no expiry.
+func (m *concurrentMap[K, V]) GetWithTTL(key K) (value V, ttl time.Duration, exists bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, exists := m.entries[key]
+	if !exists {
+		return value, 0, false
+	}
+
+	if !entry.expiresAt.IsZero() {
+		ttl = time.Until(entry.expiresAt)
+		if ttl <= 0 {
+			return value, 0, false
+		}
+	}
+
+	return entry.data, ttl, true
+}
+
 func (m *concurrentMap[K, V]) Delete(key K) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -86,24 +113,110 @@ func (m *concurrentMap[K, V]) GetOrCreate(key K, newFunc func() V, expiryDuratio
 	// If it existed but was expired, we overwrite it now.
 	// If it didn't exist, we create it now.
 	newValue := newFunc()
-	newEntry := mapEntry[V]{data: newValue}
-
-	// 5. Handle expiration logic
-	if expiryDuration > 0 {
-		newEntry.expiresAt = time.Now().Add(expiryDuration)
-
-		// Use AfterFunc to avoid manual goroutine management with a timer and a channel
-		newEntry.timer = time.AfterFunc(expiryDuration, func() {
-			// Double-check: only delete if this is still the same timer
-			// (Prevents the "new value deleted by old timer" race)
-			m.mu.Lock()
-			defer m.mu.Unlock()
-			if current, exists := m.entries[key]; exists && current.timer == newEntry.timer {
-				delete(m.entries, key)
+	m.entries[key] = m.newEntryNoLock(key, newValue, expiryDuration)
+	return newValue
+}
+
+// Set stores value under key, replacing any existing entry and its expiry.
+// It reports whether a live entry was replaced.
+func (m *concurrentMap[K, V]) Set(key K, value V, expiryDuration time.Duration) (replaced bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, exists := m.entries[key]
+	if exists {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+
+		replaced = entry.expiresAt.IsZero() || time.Now().Before(entry.expiresAt)
+	}
+
+	m.entries[key] = m.newEntryNoLock(key, value, expiryDuration)
+	return replaced
+}
+
+func (m *concurrentMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.entries)
+}
+
+// Clear removes every entry. The map is swapped out under the lock so that callers only
+// wait for the swap; when async is set, stopping the old timers and dropping the old
+// entries happens on a background goroutine.
+func (m *concurrentMap[K, V]) Clear(async bool) {
+	m.mu.Lock()
+	old := m.entries
+	m.entries = make(map[K]mapEntry[V])
+	m.mu.Unlock()
+
+	free := func() {
+		for key, entry := range old {
+			if entry.timer != nil {
+				entry.timer.Stop()
 			}
-		})
+			delete(old, key)
+		}
 	}
 
-	m.entries[key] = newEntry
-	return newValue
+	if async {
+		go free()
+		return
+	}
+
+	free()
+}
+
+// ForEach calls f for every live entry while holding the read lock, so f sees a consistent
+// view of the map and must not call back into it. expiresAt is zero for entries without expiry.
+func (m *concurrentMap[K, V]) ForEach(f func(key K, value V, expiresAt time.Time)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := time.Now()
+	for key, entry := range m.entries {
+		if !entry.expiresAt.IsZero() && now.After(entry.expiresAt) {
+			continue
+		}
+
+		f(key, entry.data, entry.expiresAt)
+	}
+}
+
+// OnExpire registers a callback invoked after an entry has been removed because its expiry elapsed.
+// It must be called before any entry with an expiry is created.
+func (m *concurrentMap[K, V]) OnExpire(f func(key K)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExpire = f
+}
+
+// Must be called with the write lock held
+func (m *concurrentMap[K, V]) newEntryNoLock(key K, value V, expiryDuration time.Duration) mapEntry[V] {
+	newEntry := mapEntry[V]{data: value}
+	if expiryDuration <= 0 {
+		return newEntry
+	}
+
+	newEntry.expiresAt = time.Now().Add(expiryDuration)
+	onExpire := m.onExpire
+
+	// Use AfterFunc to avoid manual goroutine management with a timer and a channel
+	newEntry.timer = time.AfterFunc(expiryDuration, func() {
+		// Double-check: only delete if this is still the same timer
+		// (Prevents the "new value deleted by old timer" race)
+		m.mu.Lock()
+		current, exists := m.entries[key]
+		expired := exists && current.timer == newEntry.timer
+		if expired {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+
+		if expired && onExpire != nil {
+			onExpire(key)
+		}
+	})
+
+	return newEntry
 }
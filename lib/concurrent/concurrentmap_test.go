@@ -31,3 +31,30 @@ func TestTimerRaceCondition(t *testing.T) {
 	}
 	fmt.Println("Could not reproduce. Your logic might be safe or the CPU was too fast.")
 }
+
+func TestClearAndTTL(t *testing.T) {
+	m := concurrent.NewConcurrentMap[string, string]()
+	m.GetOrCreate("persistent", func() string { return "a" }, 0)
+	m.GetOrCreate("volatile", func() string { return "b" }, time.Hour)
+
+	if _, ttl, ok := m.GetWithTTL("persistent"); !ok || ttl != 0 {
+		t.Errorf("GetWithTTL(persistent) = %v, %v; Expected: 0, true", ttl, ok)
+	}
+
+	if _, ttl, ok := m.GetWithTTL("volatile"); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("GetWithTTL(volatile) = %v, %v; Expected: (0, 1h], true", ttl, ok)
+	}
+
+	if n := m.Len(); n != 2 {
+		t.Errorf("Len() = %d; Expected: 2", n)
+	}
+
+	m.Clear(true)
+	if n := m.Len(); n != 0 {
+		t.Errorf("Len() after Clear = %d; Expected: 0", n)
+	}
+
+	if _, ok := m.Get("volatile"); ok {
+		t.Errorf("Get(volatile) after Clear found a value")
+	}
+}
@@ -19,7 +19,7 @@ const (
 	RequestIdKey = "request_id"
 )
 
-func NewHandler(logLevel slog.Level) *MyHandler {
+func NewHandler(logLevel slog.Leveler) *MyHandler {
 	// Configure colored logging with tint
 	return &MyHandler{
 		Handler: tint.NewHandler(os.Stderr, &tint.Options{
@@ -0,0 +1,883 @@
+// Package raft implements the Raft consensus algorithm: leader election, log replication,
+// log compaction with snapshots and linearizable reads through read indexes.
+//
+// A Node does not do any I/O besides calling its Storage. Messages are sent through a
+// Transport, and the transport of each member delivers the messages addressed to it by
+// calling Step, so that nodes can run over any network, including a simulated one.
+package raft
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+type (
+	MessageType int
+
+	// Message is exchanged between the members of a cluster.
+	Message struct {
+		Type MessageType `json:"type"`
+		From string      `json:"from"`
+		To   string      `json:"to"`
+		Term uint64      `json:"term"`
+		// Vote requests: the index and term of the candidate's last entry.
+		// Appends: the index and term of the entry preceding Entries.
+		// Append responses: the last index known to match the leader's log, or when
+		// rejected, a hint where the logs may start to match.
+		Index   uint64  `json:"index"`
+		LogTerm uint64  `json:"logTerm"`
+		Entries []Entry `json:"entries,omitempty"`
+		Commit  uint64  `json:"commit"`
+		Reject  bool    `json:"reject"`
+		// The leader's heartbeat round, echoed by responses. Reads are linearizable once
+		// a majority responded to a round started after the read.
+		Seq      uint64    `json:"seq"`
+		Snapshot *Snapshot `json:"snapshot,omitempty"`
+	}
+
+	Entry struct {
+		Term  uint64 `json:"term"`
+		Index uint64 `json:"index"`
+		// Nil for the entry a new leader appends to commit the entries of previous terms
+		Data []byte `json:"data"`
+	}
+
+	// Snapshot is the state of the state machine after applying the entries up to Index.
+	Snapshot struct {
+		Index uint64 `json:"index"`
+		Term  uint64 `json:"term"`
+		Data  []byte `json:"data"`
+	}
+
+	// HardState is the state a node must persist before it sends any message.
+	HardState struct {
+		Term uint64 `json:"term"`
+		Vote string `json:"vote"`
+	}
+
+	StateMachine interface {
+		// Apply applies the data of a committed entry. The result is returned by Propose
+		// on the node that proposed the entry.
+		Apply(data []byte) any
+		// Snapshot serializes the state reached by the entries applied so far.
+		Snapshot() ([]byte, error)
+		// Restore replaces the state with a snapshot.
+		Restore(data []byte) error
+	}
+
+	Transport interface {
+		// Send delivers msg to the member msg.To on a best-effort basis. It must not block:
+		// lost messages are sent again.
+		Send(msg Message)
+	}
+
+	Config struct {
+		// ID identifies the node among Members, which lists every member of the cluster.
+		ID      string
+		Members []string
+
+		StateMachine StateMachine
+		Transport    Transport
+		// Defaults to a MemoryStorage
+		Storage Storage
+
+		// Defaults to 100ms
+		TickInterval time.Duration
+		// Followers start an election after between ElectionTicks and twice as many ticks
+		// without hearing from a leader. Defaults to 10.
+		ElectionTicks int
+		// Leaders send heartbeats every HeartbeatTicks ticks. Defaults to 1.
+		HeartbeatTicks int
+		// The log is compacted once that many entries were applied since the last snapshot.
+		// Defaults to 10000.
+		SnapshotEntries uint64
+		// Defaults to 64
+		MaxEntriesPerMessage int
+	}
+
+	Status struct {
+		ID            string
+		Role          string
+		Term          uint64
+		Leader        string
+		Commit        uint64
+		Applied       uint64
+		LastIndex     uint64
+		SnapshotIndex uint64
+	}
+
+	Node interface {
+		// Propose appends data to the log and returns the result of applying it once it is
+		// committed. It fails with ErrNotLeader unless the node is the leader.
+		Propose(ctx context.Context, data []byte) (any, error)
+		// ReadIndex returns once the state machine reflects every entry committed before the
+		// call, so that a read that follows is linearizable. It fails with ErrNotLeader unless
+		// the node is the leader.
+		ReadIndex(ctx context.Context) error
+		// Step processes a message from another member.
+		Step(msg Message)
+		Status() Status
+		// Stop stops the node. Pending proposals and reads fail with ErrStopped.
+		Stop()
+	}
+
+	role int
+
+	proposal struct {
+		term uint64
+		done chan proposalResult
+	}
+
+	proposalResult struct {
+		value any
+		err   error
+	}
+
+	readRequest struct {
+		seq   uint64
+		index uint64
+		done  chan error
+	}
+
+	node struct {
+		id     string
+		peers  []string
+		config Config
+
+		mu     sync.Mutex
+		role   role
+		term   uint64
+		vote   string
+		leader string
+		// The entries following the snapshot
+		log      []Entry
+		snapshot Snapshot
+		commit   uint64
+		applied  uint64
+		// A snapshot received from the leader, waiting for the applier to restore it
+		restore *Snapshot
+
+		electionElapsed  int
+		electionTimeout  int
+		heartbeatElapsed int
+
+		// Candidates: the votes received
+		votes map[string]bool
+		// Leaders: the next entry to send to each peer, and the last one known to be replicated
+		next  map[string]uint64
+		match map[string]uint64
+		// Leaders: the peers that responded within the current election timeout
+		active map[string]bool
+		// Leaders: the last heartbeat round each peer responded to
+		acked     map[string]uint64
+		readSeq   uint64
+		reads     []*readRequest
+		proposals map[uint64]*proposal
+
+		// Signals the applier that entries were committed or a snapshot is to be restored
+		applyCond *sync.Cond
+		// Closed and replaced whenever entries are applied
+		appliedCh chan struct{}
+		stopped   bool
+		cancel    context.CancelFunc
+		wg        sync.WaitGroup
+	}
+)
+
+const (
+	MsgVote MessageType = iota
+	MsgVoteResp
+	MsgApp
+	MsgAppResp
+	MsgSnap
+)
+
+const (
+	follower role = iota
+	candidate
+	leader
+)
+
+var (
+	ErrNotLeader       = errors.New("raft: not the leader")
+	ErrProposalDropped = errors.New("raft: proposal was dropped by a new leader")
+	ErrStopped         = errors.New("raft: node stopped")
+)
+
+func (r role) String() string {
+	switch r {
+	case candidate:
+		return "candidate"
+	case leader:
+		return "leader"
+	default:
+		return "follower"
+	}
+}
+
+// NewNode starts a node from the state in config.Storage.
+func NewNode(config Config) (Node, error) {
+	if !slices.Contains(config.Members, config.ID) {
+		return nil, fmt.Errorf("raft: %q is not a member", config.ID)
+	}
+
+	config.TickInterval = cmp.Or(config.TickInterval, 100*time.Millisecond)
+	config.ElectionTicks = cmp.Or(config.ElectionTicks, 10)
+	config.HeartbeatTicks = cmp.Or(config.HeartbeatTicks, 1)
+	config.SnapshotEntries = cmp.Or(config.SnapshotEntries, 10000)
+	config.MaxEntriesPerMessage = cmp.Or(config.MaxEntriesPerMessage, 64)
+	if config.Storage == nil {
+		config.Storage = NewMemoryStorage()
+	}
+
+	hardState, snapshot, entries, err := config.Storage.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	n := &node{
+		id:        config.ID,
+		peers:     slices.DeleteFunc(slices.Clone(config.Members), func(id string) bool { return id == config.ID }),
+		config:    config,
+		term:      hardState.Term,
+		vote:      hardState.Vote,
+		log:       entries,
+		proposals: make(map[uint64]*proposal),
+		appliedCh: make(chan struct{}),
+	}
+	n.applyCond = sync.NewCond(&n.mu)
+	if snapshot != nil {
+		n.snapshot, n.commit = *snapshot, snapshot.Index
+		n.restore = snapshot
+	}
+
+	n.resetElectionTimeoutNoLock()
+	var ctx context.Context
+	ctx, n.cancel = context.WithCancel(context.Background())
+	n.wg.Go(func() { n.runTicker(ctx) })
+	n.wg.Go(n.runApplier)
+	return n, nil
+}
+
+func (n *node) Stop() {
+	n.mu.Lock()
+	n.stopped = true
+	n.cancel()
+	n.failReadsNoLock(ErrStopped)
+	for index, p := range n.proposals {
+		p.done <- proposalResult{err: ErrStopped}
+		delete(n.proposals, index)
+	}
+
+	n.applyCond.Broadcast()
+	n.notifyAppliedNoLock()
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+func (n *node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return Status{
+		ID:            n.id,
+		Role:          n.role.String(),
+		Term:          n.term,
+		Leader:        n.leader,
+		Commit:        n.commit,
+		Applied:       n.applied,
+		LastIndex:     n.lastIndexNoLock(),
+		SnapshotIndex: n.snapshot.Index,
+	}
+}
+
+func (n *node) Propose(ctx context.Context, data []byte) (any, error) {
+	if data == nil {
+		data = []byte{}
+	}
+
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return nil, ErrStopped
+	}
+
+	if n.role != leader {
+		n.mu.Unlock()
+		return nil, ErrNotLeader
+	}
+
+	e := Entry{Term: n.term, Index: n.lastIndexNoLock() + 1, Data: data}
+	n.appendNoLock(e)
+	p := &proposal{term: n.term, done: make(chan proposalResult, 1)}
+	n.proposals[e.Index] = p
+	n.broadcastAppendNoLock()
+	n.maybeCommitNoLock()
+	n.mu.Unlock()
+
+	select {
+	case result := <-p.done:
+		return result.value, result.err
+	case <-ctx.Done():
+		n.mu.Lock()
+		if n.proposals[e.Index] == p {
+			delete(n.proposals, e.Index)
+		}
+
+		n.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (n *node) ReadIndex(ctx context.Context) error {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return ErrStopped
+	}
+
+	if n.role != leader {
+		n.mu.Unlock()
+		return ErrNotLeader
+	}
+
+	n.readSeq++
+	req := &readRequest{seq: n.readSeq, done: make(chan error, 1)}
+	n.reads = append(n.reads, req)
+	n.broadcastAppendNoLock()
+	n.checkReadsNoLock()
+	n.mu.Unlock()
+
+	select {
+	case err := <-req.done:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return n.waitApplied(ctx, req.index)
+}
+
+// waitApplied returns once the entries up to index are applied.
+func (n *node) waitApplied(ctx context.Context, index uint64) error {
+	for {
+		n.mu.Lock()
+		applied, stopped, ch := n.applied, n.stopped, n.appliedCh
+		n.mu.Unlock()
+		switch {
+		case applied >= index:
+			return nil
+		case stopped:
+			return ErrStopped
+		}
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (n *node) Step(m Message) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		return
+	}
+
+	switch {
+	case m.Term > n.term:
+		if m.Type == MsgVote && n.leader != "" && n.electionElapsed < n.config.ElectionTicks {
+			// A leader was heard from recently, so the candidate is probably cut off from
+			// it. Ignoring it keeps the candidate from disrupting the cluster when it rejoins.
+			return
+		}
+
+		lead := ""
+		if m.Type == MsgApp || m.Type == MsgSnap {
+			lead = m.From
+		}
+
+		n.becomeFollowerNoLock(m.Term, lead)
+	case m.Term < n.term:
+		if m.Type == MsgApp || m.Type == MsgSnap {
+			// Lets a stale leader learn about the new term
+			n.sendNoLock(Message{Type: MsgAppResp, To: m.From, Reject: true})
+		}
+
+		return
+	}
+
+	switch m.Type {
+	case MsgVote:
+		n.handleVoteNoLock(m)
+	case MsgVoteResp:
+		if n.role == candidate {
+			n.votes[m.From] = !m.Reject
+			if n.countNoLock(func(granted bool) bool { return granted }) >= n.quorum() {
+				n.becomeLeaderNoLock()
+			}
+		}
+	case MsgApp:
+		n.becomeFollowerNoLock(m.Term, m.From)
+		n.handleAppendNoLock(m)
+	case MsgSnap:
+		n.becomeFollowerNoLock(m.Term, m.From)
+		n.handleSnapshotNoLock(m)
+	case MsgAppResp:
+		if n.role == leader {
+			n.handleAppendResponseNoLock(m)
+		}
+	}
+}
+
+func (n *node) countNoLock(granted func(bool) bool) int {
+	count := 1
+	for _, peer := range n.peers {
+		if granted(n.votes[peer]) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (n *node) quorum() int {
+	return (len(n.peers)+1)/2 + 1
+}
+
+func (n *node) runTicker(ctx context.Context) {
+	ticker := time.NewTicker(n.config.TickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.tick()
+		}
+	}
+}
+
+func (n *node) tick() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		return
+	}
+
+	n.electionElapsed++
+	if n.role != leader {
+		if n.electionElapsed >= n.electionTimeout {
+			n.campaignNoLock()
+		}
+
+		return
+	}
+
+	n.heartbeatElapsed++
+	if n.heartbeatElapsed >= n.config.HeartbeatTicks {
+		n.heartbeatElapsed = 0
+		n.broadcastAppendNoLock()
+	}
+
+	if n.electionElapsed >= n.config.ElectionTicks {
+		// A leader that cannot reach a majority steps down, so that clients look for the new one
+		n.electionElapsed = 0
+		active := 1
+		for _, peer := range n.peers {
+			if n.active[peer] {
+				active++
+			}
+		}
+
+		if active < n.quorum() {
+			slog.Warn("Raft leader lost contact with the majority", "id", n.id, "term", n.term)
+			n.becomeFollowerNoLock(n.term, "")
+			return
+		}
+
+		clear(n.active)
+	}
+}
+
+func (n *node) resetElectionTimeoutNoLock() {
+	n.electionElapsed = 0
+	n.electionTimeout = n.config.ElectionTicks + rand.IntN(n.config.ElectionTicks)
+}
+
+func (n *node) campaignNoLock() {
+	n.role, n.leader = candidate, ""
+	n.term++
+	n.vote = n.id
+	n.saveHardStateNoLock()
+	n.resetElectionTimeoutNoLock()
+	n.votes = make(map[string]bool)
+	slog.Debug("Raft election started", "id", n.id, "term", n.term)
+	if n.quorum() == 1 {
+		n.becomeLeaderNoLock()
+		return
+	}
+
+	lastIndex := n.lastIndexNoLock()
+	lastTerm, _ := n.termAtNoLock(lastIndex)
+	for _, peer := range n.peers {
+		n.sendNoLock(Message{Type: MsgVote, To: peer, Index: lastIndex, LogTerm: lastTerm})
+	}
+}
+
+func (n *node) becomeFollowerNoLock(term uint64, lead string) {
+	if term != n.term {
+		n.term, n.vote = term, ""
+		n.saveHardStateNoLock()
+	}
+
+	if n.role != follower || n.leader != lead {
+		n.resetElectionTimeoutNoLock()
+	}
+
+	if n.role == leader {
+		n.failReadsNoLock(ErrNotLeader)
+	}
+
+	n.role, n.leader = follower, lead
+}
+
+func (n *node) becomeLeaderNoLock() {
+	slog.Info("Raft leader elected", "id", n.id, "term", n.term)
+	n.role, n.leader = leader, n.id
+	n.electionElapsed, n.heartbeatElapsed = 0, 0
+	n.next = make(map[string]uint64)
+	n.match = make(map[string]uint64)
+	n.active = make(map[string]bool)
+	n.acked = make(map[string]uint64)
+	for _, peer := range n.peers {
+		n.next[peer] = n.lastIndexNoLock() + 1
+	}
+
+	// Entries of previous terms only count as committed once an entry of the current term is
+	n.appendNoLock(Entry{Term: n.term, Index: n.lastIndexNoLock() + 1})
+	n.broadcastAppendNoLock()
+	n.maybeCommitNoLock()
+}
+
+func (n *node) handleVoteNoLock(m Message) {
+	lastIndex := n.lastIndexNoLock()
+	lastTerm, _ := n.termAtNoLock(lastIndex)
+	canVote := n.vote == m.From || n.vote == "" && n.leader == ""
+	upToDate := m.LogTerm > lastTerm || m.LogTerm == lastTerm && m.Index >= lastIndex
+	if !canVote || !upToDate {
+		n.sendNoLock(Message{Type: MsgVoteResp, To: m.From, Reject: true})
+		return
+	}
+
+	n.vote = m.From
+	n.saveHardStateNoLock()
+	n.resetElectionTimeoutNoLock()
+	n.sendNoLock(Message{Type: MsgVoteResp, To: m.From})
+}
+
+func (n *node) handleAppendNoLock(m Message) {
+	n.electionElapsed = 0
+	resp := Message{Type: MsgAppResp, To: m.From, Seq: m.Seq}
+	if m.Index < n.commit {
+		resp.Index = n.commit
+		n.sendNoLock(resp)
+		return
+	}
+
+	if term, ok := n.termAtNoLock(m.Index); !ok || term != m.LogTerm {
+		resp.Reject, resp.Index = true, min(m.Index-1, n.lastIndexNoLock())
+		n.sendNoLock(resp)
+		return
+	}
+
+	for i, e := range m.Entries {
+		if term, ok := n.termAtNoLock(e.Index); ok && term == e.Term {
+			continue
+		}
+
+		// Entries from the first conflicting one on are replaced, they cannot be committed
+		n.log = n.log[:e.Index-n.snapshot.Index-1]
+		n.appendNoLock(m.Entries[i:]...)
+		break
+	}
+
+	last := m.Index + uint64(len(m.Entries))
+	if commit := min(m.Commit, last); commit > n.commit {
+		n.commit = commit
+		n.applyCond.Broadcast()
+	}
+
+	resp.Index = last
+	n.sendNoLock(resp)
+}
+
+func (n *node) handleSnapshotNoLock(m Message) {
+	n.electionElapsed = 0
+	s := m.Snapshot
+	if s == nil || s.Index <= n.commit {
+		n.sendNoLock(Message{Type: MsgAppResp, To: m.From, Seq: m.Seq, Index: n.commit})
+		return
+	}
+
+	if term, ok := n.termAtNoLock(s.Index); ok && term == s.Term {
+		n.log = slices.Clone(n.log[s.Index-n.snapshot.Index:])
+	} else {
+		n.log = nil
+	}
+
+	slog.Info("Raft snapshot received", "id", n.id, "index", s.Index, "term", s.Term)
+	n.snapshot, n.commit, n.restore = *s, s.Index, s
+	if err := n.config.Storage.SaveSnapshot(*s, n.log); err != nil {
+		slog.Error("Failed to persist the Raft snapshot", "id", n.id, "error", err)
+	}
+
+	n.applyCond.Broadcast()
+	n.sendNoLock(Message{Type: MsgAppResp, To: m.From, Seq: m.Seq, Index: s.Index})
+}
+
+func (n *node) handleAppendResponseNoLock(m Message) {
+	n.active[m.From] = true
+	n.acked[m.From] = max(n.acked[m.From], m.Seq)
+	if m.Reject {
+		n.next[m.From] = max(1, min(n.next[m.From]-1, m.Index+1))
+		n.sendAppendNoLock(m.From)
+	} else if m.Index > n.match[m.From] {
+		n.match[m.From] = m.Index
+		n.next[m.From] = max(n.next[m.From], m.Index+1)
+		n.maybeCommitNoLock()
+		if n.next[m.From] <= n.lastIndexNoLock() {
+			n.sendAppendNoLock(m.From)
+		}
+	}
+
+	n.checkReadsNoLock()
+}
+
+// maybeCommitNoLock commits the entries replicated on a majority.
+func (n *node) maybeCommitNoLock() {
+	matches := []uint64{n.lastIndexNoLock()}
+	for _, peer := range n.peers {
+		matches = append(matches, n.match[peer])
+	}
+
+	slices.Sort(matches)
+	index := matches[len(matches)-n.quorum()]
+	if term, _ := n.termAtNoLock(index); index > n.commit && term == n.term {
+		n.commit = index
+		n.applyCond.Broadcast()
+		n.checkReadsNoLock()
+	}
+}
+
+// checkReadsNoLock completes the reads confirmed by a majority. Until an entry of the
+// current term is committed, the leader does not know the latest commit index.
+func (n *node) checkReadsNoLock() {
+	if term, _ := n.termAtNoLock(n.commit); len(n.reads) == 0 || term != n.term {
+		return
+	}
+
+	seqs := []uint64{n.readSeq}
+	for _, peer := range n.peers {
+		seqs = append(seqs, n.acked[peer])
+	}
+
+	slices.Sort(seqs)
+	confirmed := seqs[len(seqs)-n.quorum()]
+	n.reads = slices.DeleteFunc(n.reads, func(req *readRequest) bool {
+		if req.seq > confirmed {
+			return false
+		}
+
+		req.index = n.commit
+		req.done <- nil
+		return true
+	})
+}
+
+func (n *node) failReadsNoLock(err error) {
+	for _, req := range n.reads {
+		req.done <- err
+	}
+
+	n.reads = nil
+}
+
+func (n *node) broadcastAppendNoLock() {
+	for _, peer := range n.peers {
+		n.sendAppendNoLock(peer)
+	}
+}
+
+// sendAppendNoLock sends the entries a peer is missing, or the snapshot when they were compacted.
+func (n *node) sendAppendNoLock(peer string) {
+	next := n.next[peer]
+	if next <= n.snapshot.Index {
+		snapshot := n.snapshot
+		n.sendNoLock(Message{Type: MsgSnap, To: peer, Seq: n.readSeq, Snapshot: &snapshot})
+		return
+	}
+
+	prevTerm, _ := n.termAtNoLock(next - 1)
+	first := next - n.snapshot.Index - 1
+	last := min(uint64(len(n.log)), first+uint64(n.config.MaxEntriesPerMessage))
+	n.sendNoLock(Message{
+		Type:    MsgApp,
+		To:      peer,
+		Index:   next - 1,
+		LogTerm: prevTerm,
+		Entries: slices.Clone(n.log[first:last]),
+		Commit:  n.commit,
+		Seq:     n.readSeq,
+	})
+}
+
+func (n *node) sendNoLock(m Message) {
+	m.From, m.Term = n.id, n.term
+	n.config.Transport.Send(m)
+}
+
+func (n *node) lastIndexNoLock() uint64 {
+	return n.snapshot.Index + uint64(len(n.log))
+}
+
+// termAtNoLock returns the term of the entry at index, unless it was compacted.
+func (n *node) termAtNoLock(index uint64) (uint64, bool) {
+	switch {
+	case index == n.snapshot.Index:
+		return n.snapshot.Term, true
+	case index < n.snapshot.Index || index > n.lastIndexNoLock():
+		return 0, false
+	default:
+		return n.log[index-n.snapshot.Index-1].Term, true
+	}
+}
+
+func (n *node) appendNoLock(entries ...Entry) {
+	n.log = append(n.log, entries...)
+	if err := n.config.Storage.Append(entries); err != nil {
+		slog.Error("Failed to persist Raft log entries", "id", n.id, "error", err)
+	}
+}
+
+func (n *node) saveHardStateNoLock() {
+	if err := n.config.Storage.SaveHardState(HardState{Term: n.term, Vote: n.vote}); err != nil {
+		slog.Error("Failed to persist the Raft state", "id", n.id, "error", err)
+	}
+}
+
+func (n *node) notifyAppliedNoLock() {
+	close(n.appliedCh)
+	n.appliedCh = make(chan struct{})
+}
+
+// runApplier applies the committed entries in order, restores snapshots received from the
+// leader and compacts the log.
+func (n *node) runApplier() {
+	for {
+		n.mu.Lock()
+		for !n.stopped && n.restore == nil && n.applied >= n.commit {
+			n.applyCond.Wait()
+		}
+
+		if n.stopped {
+			n.mu.Unlock()
+			return
+		}
+
+		if snapshot := n.restore; snapshot != nil {
+			n.restore = nil
+			n.mu.Unlock()
+			if err := n.config.StateMachine.Restore(snapshot.Data); err != nil {
+				slog.Error("Failed to restore the Raft snapshot", "id", n.id, "index", snapshot.Index, "error", err)
+			}
+
+			n.mu.Lock()
+			n.applied = snapshot.Index
+			for index, p := range n.proposals {
+				if index <= snapshot.Index {
+					p.done <- proposalResult{err: ErrProposalDropped}
+					delete(n.proposals, index)
+				}
+			}
+
+			n.notifyAppliedNoLock()
+			n.mu.Unlock()
+			continue
+		}
+
+		entries := slices.Clone(n.log[n.applied-n.snapshot.Index : n.commit-n.snapshot.Index])
+		n.mu.Unlock()
+		for _, e := range entries {
+			var result any
+			if e.Data != nil {
+				result = n.config.StateMachine.Apply(e.Data)
+			}
+
+			n.mu.Lock()
+			if n.restore != nil {
+				// The remaining entries are part of the snapshot
+				n.mu.Unlock()
+				break
+			}
+
+			n.applied = e.Index
+			if p, exists := n.proposals[e.Index]; exists {
+				delete(n.proposals, e.Index)
+				if p.term == e.Term {
+					p.done <- proposalResult{value: result}
+				} else {
+					p.done <- proposalResult{err: ErrProposalDropped}
+				}
+			}
+
+			n.notifyAppliedNoLock()
+			n.mu.Unlock()
+		}
+
+		n.maybeCompact()
+	}
+}
+
+// maybeCompact replaces the applied entries with a snapshot once there are enough of them.
+// It runs on the applier, so the state machine does not change while it is serialized.
+func (n *node) maybeCompact() {
+	n.mu.Lock()
+	index := n.applied
+	term, _ := n.termAtNoLock(index)
+	due := n.restore == nil && index-n.snapshot.Index >= n.config.SnapshotEntries
+	n.mu.Unlock()
+	if !due {
+		return
+	}
+
+	data, err := n.config.StateMachine.Snapshot()
+	if err != nil {
+		slog.Error("Failed to snapshot the Raft state machine", "id", n.id, "error", err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.restore != nil || index <= n.snapshot.Index {
+		return
+	}
+
+	n.log = slices.Clone(n.log[index-n.snapshot.Index:])
+	n.snapshot = Snapshot{Index: index, Term: term, Data: data}
+	if err := n.config.Storage.SaveSnapshot(n.snapshot, n.log); err != nil {
+		slog.Error("Failed to persist the Raft snapshot", "id", n.id, "error", err)
+	}
+
+	slog.Debug("Raft log compacted", "id", n.id, "index", index)
+}
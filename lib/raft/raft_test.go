@@ -0,0 +1,435 @@
+package raft_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/raft"
+)
+
+type (
+	// network delivers messages between nodes of the same process after a random delay,
+	// which reorders them, and can drop them or cut nodes off from each other.
+	network struct {
+		mu       sync.Mutex
+		nodes    map[string]raft.Node
+		groups   map[string]int
+		dropRate float64
+	}
+
+	endpoint struct {
+		*network
+		id string
+	}
+
+	// appendLog is a state machine recording the applied entries.
+	appendLog struct {
+		mu      sync.Mutex
+		entries []string
+	}
+
+	testCluster struct {
+		t        *testing.T
+		net      *network
+		ids      []string
+		logs     map[string]*appendLog
+		storages map[string]raft.Storage
+		config   func(*raft.Config)
+	}
+)
+
+func (e endpoint) Send(msg raft.Message) {
+	e.mu.Lock()
+	to, exists := e.nodes[msg.To]
+	deliver := exists && e.groups[msg.From] == e.groups[msg.To] && rand.Float64() >= e.dropRate
+	e.mu.Unlock()
+	if deliver {
+		time.AfterFunc(time.Duration(rand.IntN(1000))*time.Microsecond, func() { to.Step(msg) })
+	}
+}
+
+// partition cuts the nodes of each group off from the nodes outside of it.
+func (n *network) partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i, group := range groups {
+		for _, id := range group {
+			n.groups[id] = i + 1
+		}
+	}
+}
+
+func (n *network) heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	clear(n.groups)
+}
+
+func (n *network) setDropRate(rate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dropRate = rate
+}
+
+func (l *appendLog) Apply(data []byte) any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, string(data))
+	return len(l.entries)
+}
+
+func (l *appendLog) Snapshot() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return json.Marshal(l.entries)
+}
+
+func (l *appendLog) Restore(data []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return json.Unmarshal(data, &l.entries)
+}
+
+func (l *appendLog) get() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.entries)
+}
+
+func newTestCluster(t *testing.T, size int, config func(*raft.Config)) *testCluster {
+	c := &testCluster{
+		t:        t,
+		net:      &network{nodes: make(map[string]raft.Node), groups: make(map[string]int)},
+		logs:     make(map[string]*appendLog),
+		storages: make(map[string]raft.Storage),
+		config:   config,
+	}
+	for i := range size {
+		id := fmt.Sprintf("n%d", i+1)
+		c.ids = append(c.ids, id)
+		c.storages[id] = raft.NewMemoryStorage()
+	}
+
+	for _, id := range c.ids {
+		c.start(id)
+	}
+
+	t.Cleanup(func() {
+		for _, id := range c.ids {
+			c.stop(id)
+		}
+	})
+	return c
+}
+
+// start starts a node from its storage with a fresh state machine.
+func (c *testCluster) start(id string) {
+	config := raft.Config{
+		ID:             id,
+		Members:        c.ids,
+		StateMachine:   &appendLog{},
+		Transport:      endpoint{c.net, id},
+		Storage:        c.storages[id],
+		TickInterval:   5 * time.Millisecond,
+		ElectionTicks:  10,
+		HeartbeatTicks: 1,
+	}
+	if c.config != nil {
+		c.config(&config)
+	}
+
+	node, err := raft.NewNode(config)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	c.net.mu.Lock()
+	c.net.nodes[id] = node
+	c.net.mu.Unlock()
+	c.logs[id] = config.StateMachine.(*appendLog)
+}
+
+func (c *testCluster) stop(id string) {
+	c.net.mu.Lock()
+	node, exists := c.net.nodes[id]
+	delete(c.net.nodes, id)
+	c.net.mu.Unlock()
+	if exists {
+		node.Stop()
+	}
+}
+
+func (c *testCluster) node(id string) raft.Node {
+	c.net.mu.Lock()
+	defer c.net.mu.Unlock()
+	return c.net.nodes[id]
+}
+
+// leader waits until exactly one of ids is the leader, and every other one follows it.
+func (c *testCluster) leader(ids ...string) string {
+	c.t.Helper()
+	if len(ids) == 0 {
+		ids = c.ids
+	}
+
+	var found string
+	waitFor(c.t, "a leader", func() bool {
+		found = ""
+		for _, id := range ids {
+			if status := c.node(id).Status(); status.Role == "leader" {
+				found = id
+			}
+		}
+
+		for _, id := range ids {
+			if found == "" || c.node(id).Status().Leader != found {
+				return false
+			}
+		}
+
+		return true
+	})
+	return found
+}
+
+func (c *testCluster) propose(id string, data string) (any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return c.node(id).Propose(ctx, []byte(data))
+}
+
+// converged waits until every node in ids applied the same entries as the first one.
+func (c *testCluster) converged(expectedLen int, ids ...string) []string {
+	c.t.Helper()
+	if len(ids) == 0 {
+		ids = c.ids
+	}
+
+	waitFor(c.t, "the nodes to apply the same entries", func() bool {
+		first := c.logs[ids[0]].get()
+		if len(first) != expectedLen {
+			return false
+		}
+
+		for _, id := range ids[1:] {
+			if !slices.Equal(c.logs[id].get(), first) {
+				return false
+			}
+		}
+
+		return true
+	})
+	return c.logs[ids[0]].get()
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func others(ids []string, id string) []string {
+	return slices.DeleteFunc(slices.Clone(ids), func(other string) bool { return other == id })
+}
+
+func TestReplication(t *testing.T) {
+	c := newTestCluster(t, 3, nil)
+	leader := c.leader()
+
+	for i := range 20 {
+		result, err := c.propose(leader, fmt.Sprint(i))
+		if err != nil || result != i+1 {
+			t.Fatalf("Propose(%d) = %v, %v; Expected: %d", i, result, err, i+1)
+		}
+	}
+
+	follower := others(c.ids, leader)[0]
+	if _, err := c.propose(follower, "x"); !errors.Is(err, raft.ErrNotLeader) {
+		t.Errorf("Propose on a follower = %v; Expected: %v", err, raft.ErrNotLeader)
+	}
+
+	if err := c.node(follower).ReadIndex(context.Background()); !errors.Is(err, raft.ErrNotLeader) {
+		t.Errorf("ReadIndex on a follower = %v; Expected: %v", err, raft.ErrNotLeader)
+	}
+
+	if err := c.node(leader).ReadIndex(context.Background()); err != nil {
+		t.Errorf("ReadIndex on the leader = %v", err)
+	}
+
+	entries := c.converged(20)
+	if entries[19] != "19" {
+		t.Errorf("Entries = %v", entries)
+	}
+}
+
+func TestSingleNode(t *testing.T) {
+	c := newTestCluster(t, 1, nil)
+	leader := c.leader()
+	if result, err := c.propose(leader, "a"); err != nil || result != 1 {
+		t.Fatalf("Propose = %v, %v", result, err)
+	}
+
+	if err := c.node(leader).ReadIndex(context.Background()); err != nil {
+		t.Errorf("ReadIndex = %v", err)
+	}
+}
+
+func TestLeaderPartition(t *testing.T) {
+	c := newTestCluster(t, 5, nil)
+	oldLeader := c.leader()
+	if _, err := c.propose(oldLeader, "before"); err != nil {
+		t.Fatal(err)
+	}
+
+	majority := others(c.ids, oldLeader)
+	c.net.partition([]string{oldLeader, majority[0]}, majority[1:])
+
+	// The minority cannot commit, so the entry is lost
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.node(oldLeader).Propose(ctx, []byte("lost")); err == nil {
+		t.Errorf("Propose in the minority succeeded")
+	}
+
+	if err := c.node(oldLeader).ReadIndex(ctx); err == nil {
+		t.Errorf("ReadIndex in the minority succeeded")
+	}
+
+	newLeader := c.leader(majority[1:]...)
+	if _, err := c.propose(newLeader, "after"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.converged(2, majority[1:]...)
+	c.net.heal()
+	c.leader()
+	if entries := c.converged(2); !slices.Equal(entries, []string{"before", "after"}) {
+		t.Errorf("Entries = %v", entries)
+	}
+}
+
+func TestMessageLoss(t *testing.T) {
+	c := newTestCluster(t, 3, nil)
+	c.net.setDropRate(0.2)
+	committed := 0
+	for i := range 30 {
+		// Leadership may change while messages are lost
+		for attempt := 0; ; attempt++ {
+			leader := c.leader()
+			if _, err := c.propose(leader, fmt.Sprint(i)); err == nil {
+				committed++
+				break
+			} else if attempt == 10 {
+				t.Fatalf("Propose(%d) = %v", i, err)
+			}
+		}
+	}
+
+	c.net.setDropRate(0)
+	// Retried proposals may have been committed more than once
+	entries := c.converged(len(c.logs[c.leader()].get()))
+	if len(entries) < committed {
+		t.Errorf("%d entries applied; Expected at least %d", len(entries), committed)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	c := newTestCluster(t, 3, func(config *raft.Config) {
+		config.SnapshotEntries = 10
+		config.MaxEntriesPerMessage = 4
+	})
+	leader := c.leader()
+	lagging := others(c.ids, leader)[0]
+	c.net.partition([]string{lagging}, others(c.ids, lagging))
+	for i := range 50 {
+		if _, err := c.propose(leader, fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if status := c.node(leader).Status(); status.SnapshotIndex == 0 {
+		t.Errorf("The leader did not compact its log: %+v", status)
+	}
+
+	c.net.heal()
+	c.converged(50)
+	if status := c.node(lagging).Status(); status.SnapshotIndex == 0 {
+		t.Errorf("The lagging node did not receive a snapshot: %+v", status)
+	}
+
+	// A restarted node restores its snapshot and replays the entries following it
+	c.stop(lagging)
+	c.start(lagging)
+	if _, err := c.propose(c.leader(), "after restart"); err != nil {
+		t.Fatal(err)
+	}
+
+	c.converged(51)
+}
+
+func TestFileStorage(t *testing.T) {
+	dirs := map[string]string{}
+	c := newTestCluster(t, 3, func(config *raft.Config) {
+		config.SnapshotEntries = 5
+	})
+	for _, id := range c.ids {
+		dirs[id] = t.TempDir()
+		storage, err := raft.NewFileStorage(dirs[id])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.stop(id)
+		c.storages[id] = storage
+		c.start(id)
+	}
+
+	for i := range 12 {
+		if _, err := c.propose(c.leader(), fmt.Sprint(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.converged(12)
+	for _, id := range c.ids {
+		c.stop(id)
+		c.storages[id].(*raft.FileStorage).Close()
+	}
+
+	// Every node restarts from its files
+	for _, id := range c.ids {
+		storage, err := raft.NewFileStorage(dirs[id])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.storages[id] = storage
+		c.start(id)
+	}
+
+	if _, err := c.propose(c.leader(), "12"); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries := c.converged(13); entries[0] != "0" || entries[12] != "12" {
+		t.Errorf("Entries = %v", entries)
+	}
+
+	for _, id := range c.ids {
+		c.stop(id)
+		c.storages[id].(*raft.FileStorage).Close()
+	}
+}
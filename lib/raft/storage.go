@@ -0,0 +1,296 @@
+package raft
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+type (
+	// Storage persists the state of a node. A node calls it with its lock held, so calls
+	// never overlap, and it must not return before the data is durable.
+	Storage interface {
+		// Load returns the persisted state, the latest snapshot if any and the entries following it.
+		Load() (HardState, *Snapshot, []Entry, error)
+		SaveHardState(hs HardState) error
+		// Append persists entries, replacing any persisted entries from the index of the first one on.
+		Append(entries []Entry) error
+		// SaveSnapshot persists a snapshot together with the entries following it, replacing the log.
+		SaveSnapshot(snapshot Snapshot, entries []Entry) error
+	}
+
+	// MemoryStorage keeps the state in memory, so that a node can be restarted in the same process.
+	MemoryStorage struct {
+		mu        sync.Mutex
+		hardState HardState
+		snapshot  *Snapshot
+		entries   []Entry
+	}
+
+	// FileStorage keeps the state in a directory: the term and vote in the state file, the latest
+	// snapshot in the snapshot file and the entries following it in the log file, one JSON
+	// document per line.
+	FileStorage struct {
+		dir string
+		log *os.File
+		// The index of the first entry in the log file, and the file offset of each entry
+		first   uint64
+		offsets []int64
+		size    int64
+	}
+)
+
+const (
+	stateFileName    = "state.json"
+	snapshotFileName = "snapshot.json"
+	logFileName      = "log.jsonl"
+)
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+func (s *MemoryStorage) Load() (HardState, *Snapshot, []Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hardState, s.snapshot, slices.Clone(s.entries), nil
+}
+
+func (s *MemoryStorage) SaveHardState(hs HardState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hardState = hs
+	return nil
+}
+
+func (s *MemoryStorage) Append(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(truncateEntries(s.entries, entries[0].Index), entries...)
+	return nil
+}
+
+func (s *MemoryStorage) SaveSnapshot(snapshot Snapshot, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot, s.entries = &snapshot, slices.Clone(entries)
+	return nil
+}
+
+// truncateEntries drops the entries from index on.
+func truncateEntries(entries []Entry, index uint64) []Entry {
+	if len(entries) == 0 || index <= entries[0].Index {
+		return entries[:0]
+	}
+
+	return entries[:min(uint64(len(entries)), index-entries[0].Index)]
+}
+
+// NewFileStorage opens the storage in dir, creating the directory if needed.
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &FileStorage{dir: dir}, nil
+}
+
+func (s *FileStorage) Close() error {
+	if s.log == nil {
+		return nil
+	}
+
+	return s.log.Close()
+}
+
+// Load reads the state. A partially written entry at the end of the log, as left by a crash,
+// is discarded.
+func (s *FileStorage) Load() (HardState, *Snapshot, []Entry, error) {
+	var hs HardState
+	if err := readJSONFile(filepath.Join(s.dir, stateFileName), &hs); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return hs, nil, nil, err
+	}
+
+	var snapshot *Snapshot
+	if err := readJSONFile(filepath.Join(s.dir, snapshotFileName), &snapshot); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return hs, nil, nil, err
+	}
+
+	entries, err := s.readLog()
+	if err != nil {
+		return hs, nil, nil, err
+	}
+
+	// A crash between writing the snapshot and the log leaves entries the snapshot covers
+	if snapshot != nil {
+		entries = slices.DeleteFunc(entries, func(e Entry) bool { return e.Index <= snapshot.Index })
+	}
+
+	if err := s.rewriteLog(entries); err != nil {
+		return hs, nil, nil, err
+	}
+
+	return hs, snapshot, entries, nil
+}
+
+func (s *FileStorage) readLog() ([]Entry, error) {
+	f, err := os.Open(filepath.Join(s.dir, logFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+	entries := []Entry{}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("raft: corrupt log entry after index %d: %w", len(entries), err)
+		}
+
+		entries = append(truncateEntries(entries, e.Index), e)
+	}
+}
+
+func (s *FileStorage) SaveHardState(hs HardState) error {
+	return writeJSONFile(filepath.Join(s.dir, stateFileName), hs)
+}
+
+func (s *FileStorage) Append(entries []Entry) error {
+	if index := entries[0].Index; len(s.offsets) > 0 && index < s.first+uint64(len(s.offsets)) {
+		if index <= s.first {
+			s.offsets, s.size = nil, 0
+		} else {
+			s.offsets, s.size = s.offsets[:index-s.first], s.offsets[index-s.first]
+		}
+
+		if err := s.log.Truncate(s.size); err != nil {
+			return err
+		}
+	}
+
+	if len(s.offsets) == 0 {
+		s.first = entries[0].Index
+	}
+
+	data, offsets, err := encodeEntries(entries, s.size)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.log.WriteAt(data, s.size); err != nil {
+		return err
+	}
+
+	s.offsets = append(s.offsets, offsets...)
+	s.size += int64(len(data))
+	return s.log.Sync()
+}
+
+// encodeEntries encodes entries as lines of the log file starting at offset base,
+// and returns the offset of each.
+func encodeEntries(entries []Entry, base int64) ([]byte, []int64, error) {
+	var buf bytes.Buffer
+	offsets := make([]int64, len(entries))
+	for i, e := range entries {
+		offsets[i] = base + int64(buf.Len())
+		line, err := json.Marshal(e)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		buf.Write(append(line, '\n'))
+	}
+
+	return buf.Bytes(), offsets, nil
+}
+
+func (s *FileStorage) SaveSnapshot(snapshot Snapshot, entries []Entry) error {
+	if err := writeJSONFile(filepath.Join(s.dir, snapshotFileName), snapshot); err != nil {
+		return err
+	}
+
+	return s.rewriteLog(entries)
+}
+
+// rewriteLog replaces the log file with one holding entries.
+func (s *FileStorage) rewriteLog(entries []Entry) error {
+	data, offsets, err := encodeEntries(entries, 0)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, logFileName)
+	if err := writeFile(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if s.log != nil {
+		s.log.Close()
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		s.log = nil
+		return err
+	}
+
+	s.log, s.offsets, s.size = f, offsets, int64(len(data))
+	if len(entries) > 0 {
+		s.first = entries[0].Index
+	}
+
+	return nil
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func writeJSONFile(path string, v any) error {
+	return writeFile(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+}
+
+// writeFile writes to a temporary file which replaces path once it is synced, so that
+// a crash never leaves a partial file behind.
+func writeFile(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := errors.Join(write(f), f.Sync(), f.Close()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -21,19 +21,40 @@ type (
 		writer  io.Writer
 		scanner *bufio.Scanner
 		mu      sync.Mutex
+		// How long a command may take, sending it and reading its reply included. 0 waits forever.
+		timeout time.Duration
 	}
 
 	RedisClient interface {
 		ExecuteCommand(ctx context.Context, cmd string) CommandResult
 	}
+
+	// Option configures a client created by NewRedisClient.
+	Option func(*redisClient)
+)
+
+const (
+	DefaultTimeout = 30 * time.Second
 )
 
-func NewRedisClient(readerWriter io.ReadWriter) RedisClient {
+// WithTimeout sets how long a command may take, sending it and reading its reply included.
+// 0 waits forever. It defaults to DefaultTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(redis *redisClient) {
+		redis.timeout = d
+	}
+}
+
+func NewRedisClient(readerWriter io.ReadWriter, opts ...Option) RedisClient {
 	redis := &redisClient{
 		writer:  readerWriter,
 		scanner: bufio.NewScanner(readerWriter),
+		timeout: DefaultTimeout,
 	}
 	redis.scanner.Split(redislib.ScanResp)
+	for _, opt := range opts {
+		opt(redis)
+	}
 
 	if conn, ok := redis.writer.(net.Conn); ok {
 		addr := conn.RemoteAddr()
@@ -52,8 +73,11 @@ func (redis *redisClient) ExecuteCommand(ctx context.Context, command string) Co
 		return newCommandResult(nil, err)
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
-	defer cancel()
+	if redis.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, redis.timeout)
+		defer cancel()
+	}
 
 	if dl, ok := ctx.Deadline(); ok {
 		if conn, ok := redis.writer.(net.Conn); ok {
@@ -122,6 +146,18 @@ func (redis *redisClient) executeCommand(ctx context.Context, command string) Co
 	text := redis.scanner.Text()
 	slog.DebugContext(ctx, "Scanner read successful", "text", text)
 	response, bytesCount := resptypes.ParseRespString(text)
+
+	// Out-of-band pushes (e.g. tracking invalidations) can arrive ahead of the reply
+	for _, isPush := response.(resptypes.Push); isPush; _, isPush = response.(resptypes.Push) {
+		slog.InfoContext(ctx, "Push message received", "push", text)
+		if !redis.scanner.Scan() {
+			redis.close()
+			return newCommandResult(nil, redis.scanner.Err())
+		}
+
+		text = redis.scanner.Text()
+		response, bytesCount = resptypes.ParseRespString(text)
+	}
 	if bytesCount <= 0 {
 		if err, ok := response.(resptypes.SimpleError); ok {
 			return newCommandResult(err, err.Val)
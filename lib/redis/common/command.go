@@ -0,0 +1,182 @@
+package redislib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+// ReadCommand reads one command sent as a RESP array of bulk strings, the form commands take on
+// the wire, in the AOF and in the replication stream. Unlike resptypes.ParseRespString it is
+// binary safe and distinguishes input that ends early, reported as io.ErrUnexpectedEOF
+// (or io.EOF when nothing was read), from malformed input.
+// It returns the number of bytes consumed.
+func ReadCommand(r *bufio.Reader) (args []string, n int, err error) {
+	header, err := readLine(r)
+	n += len(header)
+	if err != nil {
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, n, err
+	}
+
+	if header[0] != '*' {
+		return nil, n, fmt.Errorf("expected a RESP array, got %q", strings.TrimSpace(header))
+	}
+
+	count, err := strconv.Atoi(header[1 : len(header)-2])
+	if err != nil || count < 1 {
+		return nil, n, fmt.Errorf("invalid array length %q", strings.TrimSpace(header))
+	}
+
+	args = make([]string, count)
+	for i := range args {
+		if args[i], err = ReadBulkString(r, &n); err != nil {
+			return nil, n, err
+		}
+	}
+
+	return args, n, nil
+}
+
+// ReadBulkString reads a RESP bulk string, adding the number of bytes consumed to n.
+func ReadBulkString(r *bufio.Reader, n *int) (string, error) {
+	header, err := readLine(r)
+	*n += len(header)
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+
+	if header[0] != '$' {
+		return "", fmt.Errorf("expected a RESP bulk string, got %q", strings.TrimSpace(header))
+	}
+
+	return readBulkStringBody(r, header, n)
+}
+
+// readBulkStringBody reads the contents of a bulk string whose header was already read.
+func readBulkStringBody(r *bufio.Reader, header string, n *int) (string, error) {
+	length, err := strconv.Atoi(header[1 : len(header)-2])
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid bulk string length %q", strings.TrimSpace(header))
+	}
+
+	buf := make([]byte, length+2)
+	read, err := io.ReadFull(r, buf)
+	*n += read
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+
+	if buf[length] != '\r' || buf[length+1] != '\n' {
+		return "", fmt.Errorf("bulk string is not terminated by CRLF")
+	}
+
+	return string(buf[:length]), nil
+}
+
+// ReadReply reads one reply of any RESP2 type, or a RESP3 push. Unlike resptypes.ParseRespString
+// it is binary safe, so that bulk strings may contain CRLF (e.g. the INFO report).
+// Error replies are returned as resptypes.SimpleError values, not as err.
+func ReadReply(r *bufio.Reader) (resptypes.RespSerializable, error) {
+	n := 0
+	header, err := readLine(r)
+	if err != nil {
+		if err == io.EOF && header != "" {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	value := header[1 : len(header)-2]
+	switch header[0] {
+	case '+':
+		return resptypes.SimpleString{Val: value}, nil
+	case '-':
+		return resptypes.SimpleError{Val: errors.New(value)}, nil
+	case ':':
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", value)
+		}
+
+		return resptypes.Integer{Val: i}, nil
+	case '$':
+		if value == "-1" {
+			return resptypes.NullBulkString, nil
+		}
+
+		s, err := readBulkStringBody(r, header, &n)
+		if err != nil {
+			return nil, err
+		}
+
+		return *resptypes.NewBulkString(s), nil
+	case '*', '>':
+		count, err := strconv.Atoi(value)
+		if err != nil || count < -1 {
+			return nil, fmt.Errorf("invalid array length %q", value)
+		}
+
+		if count == -1 {
+			return resptypes.NullArray, nil
+		}
+
+		elements := make([]resptypes.RespSerializable, count)
+		for i := range elements {
+			if elements[i], err = ReadReply(r); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+		}
+
+		if header[0] == '>' {
+			return resptypes.Push(elements), nil
+		}
+
+		return resptypes.Array[resptypes.RespSerializable](elements), nil
+	default:
+		return nil, fmt.Errorf("unexpected RESP type %q", strings.TrimSpace(header))
+	}
+}
+
+// readLine reads up to and including the next CRLF.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return line, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return line, fmt.Errorf("malformed RESP line %q", strings.TrimSpace(line))
+	}
+
+	return line, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// AppendCommand serializes args as a RESP array of bulk strings.
+func AppendCommand(buf []byte, args ...string) []byte {
+	buf = fmt.Appendf(buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n", len(arg))
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	return buf
+}
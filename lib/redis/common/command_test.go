@@ -0,0 +1,107 @@
+package redislib
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadCommand(t *testing.T) {
+	tcs := []struct {
+		input    string
+		expected []string
+		err      error
+	}{
+		{"*2\r\n$3\r\nGET\r\n$1\r\nk\r\n", []string{"GET", "k"}, nil},
+		{"*2\r\n$3\r\nSET\r\n$4\r\na\r\nb\r\n", []string{"SET", "a\r\nb"}, nil},
+		{"", nil, io.EOF},
+		{"*2\r\n$3\r\nGET\r\n$1\r", nil, io.ErrUnexpectedEOF},
+		{"*2\r\n$3\r\nGET\r\n", nil, io.ErrUnexpectedEOF},
+		{"*2", nil, io.ErrUnexpectedEOF},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.input, func(t *testing.T) {
+			args, n, err := ReadCommand(bufio.NewReader(strings.NewReader(tc.input)))
+			if err != tc.err {
+				t.Fatalf("ReadCommand(%q) error = %v; Expected: %v", tc.input, err, tc.err)
+			}
+
+			if err == nil && n != len(tc.input) {
+				t.Errorf("ReadCommand(%q) consumed %d bytes; Expected: %d", tc.input, n, len(tc.input))
+			}
+
+			if strings.Join(args, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("ReadCommand(%q) = %q; Expected: %q", tc.input, args, tc.expected)
+			}
+
+			if err == nil && string(AppendCommand(nil, args...)) != tc.input {
+				t.Errorf("AppendCommand(%q) = %q; Expected: %q", args, AppendCommand(nil, args...), tc.input)
+			}
+		})
+	}
+
+	if _, _, err := ReadCommand(bufio.NewReader(strings.NewReader("PING\r\n"))); err == nil || err == io.ErrUnexpectedEOF {
+		t.Errorf("ReadCommand(inline) error = %v; Expected a protocol error", err)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tcs := []string{
+		"+OK\r\n",
+		"-ERR wrong\r\n",
+		":-42\r\n",
+		"$-1\r\n",
+		"$10\r\na:1\r\nb:2\r\n\r\n",
+		"*-1\r\n",
+		"*3\r\n$7\r\nmessage\r\n:1\r\n*1\r\n$0\r\n\r\n",
+		">2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\nk\r\n",
+	}
+
+	for _, input := range tcs {
+		t.Run(input, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(input + "+next\r\n"))
+			reply, err := ReadReply(reader)
+			if err != nil {
+				t.Fatalf("ReadReply(%q) error = %v", input, err)
+			}
+
+			if reply.ToRespString() != input {
+				t.Errorf("ReadReply(%q) = %q", input, reply.ToRespString())
+			}
+
+			if next, err := ReadReply(reader); err != nil || next.ToRespString() != "+next\r\n" {
+				t.Errorf("ReadReply(%q) did not stop at the end of the reply, next = %v, %v", input, next, err)
+			}
+		})
+	}
+
+	if _, err := ReadReply(bufio.NewReader(strings.NewReader("*2\r\n:1\r\n"))); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadReply(truncated) error = %v; Expected: %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestScanCommand(t *testing.T) {
+	input := "*2\r\n$4\r\nECHO\r\n$4\r\na\r\nb\r\n*1\r\n$4\r\nPING\r\n"
+	// One byte at a time, so that every command is first seen incomplete
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(input)))
+	scanner.Split(ScanCommand)
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil || strings.Join(tokens, "") != input || len(tokens) != 2 {
+		t.Errorf("ScanCommand tokens = %q, error = %v", tokens, err)
+	}
+
+	for _, input := range []string{"PING\r\n", "*1\r\n$4\r\nPING"} {
+		scanner := bufio.NewScanner(strings.NewReader(input))
+		scanner.Split(ScanCommand)
+		if scanner.Scan() || scanner.Err() == nil {
+			t.Errorf("ScanCommand(%q) = %q, error = %v; Expected an error", input, scanner.Text(), scanner.Err())
+		}
+	}
+}
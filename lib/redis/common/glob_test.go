@@ -0,0 +1,38 @@
+package redislib
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tcs := []struct {
+		pattern  string
+		str      string
+		expected bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"news.*", "news.tech", true},
+		{"news.*", "news", false},
+		{"news.*", "sports.tech", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"__keyspace@*__:*", "__keyspace@0__:foo", true},
+		{"a**b", "axxb", true},
+		{"*b", "abc", false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.pattern+" "+tc.str, func(t *testing.T) {
+			if actual := GlobMatch(tc.pattern, tc.str); actual != tc.expected {
+				t.Errorf("GlobMatch(%q, %q) = %v; Expected: %v", tc.pattern, tc.str, actual, tc.expected)
+			}
+		})
+	}
+}
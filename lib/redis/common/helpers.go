@@ -2,7 +2,9 @@ package redislib
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
@@ -27,6 +29,30 @@ func ScanResp(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	return 0, nil, nil
 }
 
+// ScanCommand is a bufio.SplitFunc returning one command sent as a RESP array of bulk strings
+// per token. Unlike ScanResp it reads the arguments by their length, so that they may hold any
+// byte. Malformed input stops the scanner with the error.
+func ScanCommand(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	_, n, err := ReadCommand(bufio.NewReader(bytes.NewReader(data)))
+	switch {
+	case err == nil:
+		return n, data[:n], nil
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+
+		// An incomplete command, request more data
+		return 0, nil, nil
+	default:
+		return 0, nil, err
+	}
+}
+
 func CreateScannerChannel(ctx context.Context, cancel context.CancelFunc, reader io.Reader, splitFunc bufio.SplitFunc) <-chan string {
 	out := make(chan string)
 	go func() {
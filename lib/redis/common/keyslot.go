@@ -0,0 +1,46 @@
+package redislib
+
+import "strings"
+
+// ClusterSlots is the number of hash slots the keyspace of a cluster is divided into.
+const ClusterSlots = 16384
+
+var crc16Table = func() (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}()
+
+// CRC16 computes the CRC-16/XMODEM checksum Redis Cluster hashes keys with.
+func CRC16(s string) uint16 {
+	crc := uint16(0)
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+
+	return crc
+}
+
+// KeyHashSlot returns the cluster slot of key. When the key contains a non-empty hash tag,
+// the part between the first { and the following }, only the tag is hashed, so that related
+// keys can be placed in the same slot.
+func KeyHashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return int(CRC16(key) % ClusterSlots)
+}
@@ -0,0 +1,28 @@
+package redislib
+
+import "testing"
+
+func TestKeyHashSlot(t *testing.T) {
+	if crc := CRC16("123456789"); crc != 0x31C3 {
+		t.Errorf("CRC16(123456789) = %#x; Expected: 0x31c3", crc)
+	}
+
+	tcs := []struct {
+		key  string
+		slot int
+	}{
+		{key: "foo", slot: 12182},
+		{key: "bar", slot: 5061},
+		{key: "{foo}bar", slot: 12182},
+		{key: "x{foo}y{bar}", slot: 12182},
+		// An empty tag is not a tag, the whole key is hashed
+		{key: "foo{}{bar}", slot: 8363},
+		{key: "", slot: 0},
+	}
+
+	for _, tc := range tcs {
+		if slot := KeyHashSlot(tc.key); slot != tc.slot {
+			t.Errorf("KeyHashSlot(%q) = %d; Expected: %d", tc.key, slot, tc.slot)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package redisrdblib
+
+import "hash/crc64"
+
+var (
+	// Redis checksums with the reflected Jones polynomial, starting from 0 and without a final XOR
+	crcTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+)
+
+// crcUpdate extends a Redis CRC-64. hash/crc64 inverts the register before and after each
+// update, so the inversions are undone here.
+func crcUpdate(crc uint64, p []byte) uint64 {
+	return ^crc64.Update(^crc, crcTable, p)
+}
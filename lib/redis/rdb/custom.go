@@ -0,0 +1,78 @@
+package redisrdblib
+
+import (
+	"fmt"
+	"strings"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+)
+
+// custom writes a value of a custom type the way Redis saves the values of module types: the
+// module type ID packing the type name and encoding version, then the opcodes of the saved
+// values. Custom types save their encoded value as a single string.
+func (e *encoder) custom(v redistypes.StoreValue) {
+	t, _ := redistypes.CustomTypeOf(v.Type)
+	e.length(moduleTypeId(t.Name, t.EncodingVersion))
+	e.length(moduleOpString)
+	e.string(string(t.Encode(v.Custom)))
+	e.length(moduleOpEOF)
+}
+
+func (d *decoder) custom() (redistypes.StoreValue, error) {
+	id, err := d.length()
+	if err != nil {
+		return redistypes.StoreValue{}, err
+	}
+
+	name, encodingVersion := moduleTypeName(id)
+	t, exists := redistypes.CustomTypeNamed(name)
+	if !exists {
+		return redistypes.StoreValue{}, fmt.Errorf("unknown module type %s", name)
+	}
+
+	if op, err := d.length(); err != nil {
+		return redistypes.StoreValue{}, err
+	} else if op != moduleOpString {
+		return redistypes.StoreValue{}, fmt.Errorf("unexpected opcode %d in a value of module type %s", op, name)
+	}
+
+	data, err := d.string()
+	if err != nil {
+		return redistypes.StoreValue{}, err
+	}
+
+	if op, err := d.length(); err != nil {
+		return redistypes.StoreValue{}, err
+	} else if op != moduleOpEOF {
+		return redistypes.StoreValue{}, fmt.Errorf("unexpected opcode %d in a value of module type %s", op, name)
+	}
+
+	v, err := t.Decode([]byte(data), encodingVersion)
+	if err != nil {
+		return redistypes.StoreValue{}, fmt.Errorf("cannot decode a value of module type %s: %w", name, err)
+	}
+
+	return redistypes.NewCustomValue(t, v), nil
+}
+
+// moduleTypeId packs a type name of 9 characters, 6 bits each, followed by 10 bits of encoding version.
+func moduleTypeId(name string, encodingVersion int) uint64 {
+	id := uint64(0)
+	for i := range name {
+		id = id<<6 | uint64(strings.IndexByte(redistypes.CustomTypeNameChars, name[i]))
+	}
+
+	return id<<10 | uint64(encodingVersion)
+}
+
+func moduleTypeName(id uint64) (string, int) {
+	encodingVersion := int(id & redistypes.MaxEncodingVersion)
+	name := make([]byte, redistypes.CustomTypeNameLen)
+	id >>= 10
+	for i := len(name) - 1; i >= 0; i-- {
+		name[i] = redistypes.CustomTypeNameChars[id&63]
+		id >>= 6
+	}
+
+	return string(name), encodingVersion
+}
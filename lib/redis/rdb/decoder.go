@@ -0,0 +1,608 @@
+package redisrdblib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	decoder struct {
+		buf []byte
+		pos int
+	}
+)
+
+// Read parses an RDB file and calls load for every key in it, including keys whose expiry
+// already elapsed; callers decide what to do with those.
+func Read(r io.Reader, load func(db int, entry Entry) error) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	d := &decoder{buf: buf}
+	header, err := d.bytes(len(magic) + 4)
+	if err != nil {
+		return err
+	}
+
+	if string(header[:len(magic)]) != magic {
+		return fmt.Errorf("not an RDB file")
+	}
+
+	fileVersion, err := strconv.Atoi(string(header[len(magic):]))
+	if err != nil || fileVersion < 1 || fileVersion > maxVersion {
+		return fmt.Errorf("unsupported RDB version %q", header[len(magic):])
+	}
+
+	db := 0
+	expiresAt := time.Time{}
+	for {
+		op, err := d.byte()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case opEOF:
+			return d.verifyChecksum(fileVersion)
+		case opSelectDB:
+			index, err := d.length()
+			if err != nil {
+				return err
+			}
+
+			db = int(index)
+		case opResizeDB:
+			if _, err := d.length(); err != nil {
+				return err
+			}
+
+			if _, err := d.length(); err != nil {
+				return err
+			}
+		case opSlotInfo:
+			for range 3 {
+				if _, err := d.length(); err != nil {
+					return err
+				}
+			}
+		case opAux:
+			if _, err := d.string(); err != nil {
+				return err
+			}
+
+			if _, err := d.string(); err != nil {
+				return err
+			}
+		case opFunction2:
+			if _, err := d.string(); err != nil {
+				return err
+			}
+		case opExpireTime:
+			b, err := d.bytes(4)
+			if err != nil {
+				return err
+			}
+
+			expiresAt = time.Unix(int64(binary.LittleEndian.Uint32(b)), 0)
+		case opExpireTimeMs:
+			b, err := d.bytes(8)
+			if err != nil {
+				return err
+			}
+
+			expiresAt = time.UnixMilli(int64(binary.LittleEndian.Uint64(b)))
+		case opIdle:
+			if _, err := d.length(); err != nil {
+				return err
+			}
+		case opFreq:
+			if _, err := d.byte(); err != nil {
+				return err
+			}
+		case opModuleAux, opFunctionPreGA:
+			return fmt.Errorf("unsupported RDB opcode 0x%02x", op)
+		default:
+			key, err := d.string()
+			if err != nil {
+				return err
+			}
+
+			value, err := d.value(op)
+			if err != nil {
+				return fmt.Errorf("loading key %q: %w", key, err)
+			}
+
+			if err := load(db, Entry{Key: key, Value: value, ExpiresAt: expiresAt}); err != nil {
+				return err
+			}
+
+			expiresAt = time.Time{}
+		}
+	}
+}
+
+func (d *decoder) verifyChecksum(fileVersion int) error {
+	// Checksums were introduced with version 5
+	if fileVersion < 5 {
+		return nil
+	}
+
+	end := d.pos
+	b, err := d.bytes(8)
+	if err != nil {
+		return err
+	}
+
+	expected := binary.LittleEndian.Uint64(b)
+	if expected == 0 {
+		// Written with rdbchecksum no
+		return nil
+	}
+
+	if actual := crcUpdate(0, d.buf[:end]); actual != expected {
+		return fmt.Errorf("RDB checksum mismatch, expected %016x but computed %016x", expected, actual)
+	}
+
+	return nil
+}
+
+func (d *decoder) bytes(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) byte() (byte, error) {
+	b, err := d.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+// lengthOrEncoding reads a length prefix. When special is set, n is one of the enc* string encodings instead.
+func (d *decoder) lengthOrEncoding() (n uint64, special bool, err error) {
+	b, err := d.byte()
+	if err != nil {
+		return 0, false, err
+	}
+
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		next, err := d.byte()
+		if err != nil {
+			return 0, false, err
+		}
+
+		return uint64(b&0x3f)<<8 | uint64(next), false, nil
+	case 2:
+		switch b {
+		case 0x80:
+			v, err := d.bytes(4)
+			if err != nil {
+				return 0, false, err
+			}
+
+			return uint64(binary.BigEndian.Uint32(v)), false, nil
+		case 0x81:
+			v, err := d.bytes(8)
+			if err != nil {
+				return 0, false, err
+			}
+
+			return binary.BigEndian.Uint64(v), false, nil
+		default:
+			return 0, false, fmt.Errorf("invalid length encoding 0x%02x", b)
+		}
+	default:
+		return uint64(b & 0x3f), true, nil
+	}
+}
+
+func (d *decoder) length() (uint64, error) {
+	n, special, err := d.lengthOrEncoding()
+	if err == nil && special {
+		err = fmt.Errorf("unexpected string encoding where a length was expected")
+	}
+
+	return n, err
+}
+
+func (d *decoder) string() (string, error) {
+	n, special, err := d.lengthOrEncoding()
+	if err != nil {
+		return "", err
+	}
+
+	if !special {
+		b, err := d.bytes(int(n))
+		return string(b), err
+	}
+
+	switch n {
+	case encInt8:
+		b, err := d.bytes(1)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.Itoa(int(int8(b[0]))), nil
+	case encInt16:
+		b, err := d.bytes(2)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b)))), nil
+	case encInt32:
+		b, err := d.bytes(4)
+		if err != nil {
+			return "", err
+		}
+
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b)))), nil
+	case encLZF:
+		compressedLen, err := d.length()
+		if err != nil {
+			return "", err
+		}
+
+		uncompressedLen, err := d.length()
+		if err != nil {
+			return "", err
+		}
+
+		compressed, err := d.bytes(int(compressedLen))
+		if err != nil {
+			return "", err
+		}
+
+		out, err := lzfDecompress(compressed, int(uncompressedLen))
+		return string(out), err
+	default:
+		return "", fmt.Errorf("invalid string encoding %d", n)
+	}
+}
+
+func (d *decoder) value(valueType byte) (redistypes.StoreValue, error) {
+	switch valueType {
+	case typeString:
+		s, err := d.string()
+		return redistypes.NewString(s)(), err
+	case typeList:
+		n, err := d.length()
+		if err != nil {
+			return redistypes.StoreValue{}, err
+		}
+
+		items := make([]string, 0, min(n, 1024))
+		for range n {
+			item, err := d.string()
+			if err != nil {
+				return redistypes.StoreValue{}, err
+			}
+
+			items = append(items, item)
+		}
+
+		return newList(items), nil
+	case typeListZiplist:
+		blob, err := d.string()
+		if err != nil {
+			return redistypes.StoreValue{}, err
+		}
+
+		items, err := decodeZiplist([]byte(blob))
+		return newList(items), err
+	case typeListQuicklist, typeListQuicklist2:
+		return d.quicklist(valueType)
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		return d.stream(valueType)
+	case typeModule2:
+		return d.custom()
+	default:
+		return redistypes.StoreValue{}, fmt.Errorf("unsupported value type %d", valueType)
+	}
+}
+
+func newList(items []string) redistypes.StoreValue {
+	list := redistypes.NewList()
+	for _, item := range items {
+		list.List.PushBack(*resptypes.NewBulkString(item))
+	}
+
+	return list
+}
+
+func (d *decoder) quicklist(valueType byte) (redistypes.StoreValue, error) {
+	nodes, err := d.length()
+	if err != nil {
+		return redistypes.StoreValue{}, err
+	}
+
+	items := []string{}
+	for range nodes {
+		container := uint64(quicklistNodePacked)
+		if valueType == typeListQuicklist2 {
+			if container, err = d.length(); err != nil {
+				return redistypes.StoreValue{}, err
+			}
+		}
+
+		blob, err := d.string()
+		if err != nil {
+			return redistypes.StoreValue{}, err
+		}
+
+		switch {
+		case container == quicklistNodePlain:
+			items = append(items, blob)
+		case valueType == typeListQuicklist:
+			nodeItems, err := decodeZiplist([]byte(blob))
+			if err != nil {
+				return redistypes.StoreValue{}, err
+			}
+
+			items = append(items, nodeItems...)
+		default:
+			nodeItems, err := decodeListpack([]byte(blob))
+			if err != nil {
+				return redistypes.StoreValue{}, err
+			}
+
+			items = append(items, nodeItems...)
+		}
+	}
+
+	return newList(items), nil
+}
+
+func (d *decoder) stream(valueType byte) (redistypes.StoreValue, error) {
+	value := redistypes.NewStream()
+	nodes, err := d.length()
+	if err != nil {
+		return value, err
+	}
+
+	for range nodes {
+		key, err := d.string()
+		if err != nil {
+			return value, err
+		}
+
+		if len(key) != 16 {
+			return value, fmt.Errorf("invalid stream node key")
+		}
+
+		master := redistypes.StreamEntryId{
+			Ms:  binary.BigEndian.Uint64([]byte(key[:8])),
+			Seq: binary.BigEndian.Uint64([]byte(key[8:])),
+		}
+
+		blob, err := d.string()
+		if err != nil {
+			return value, err
+		}
+
+		elements, err := decodeListpack([]byte(blob))
+		if err != nil {
+			return value, err
+		}
+
+		if err := loadStreamNode(value.Stream, master, elements); err != nil {
+			return value, err
+		}
+	}
+
+	// Length and last ID, then first ID, max deleted ID and entries added in newer versions
+	skip := 3
+	if valueType >= typeStreamListpacks2 {
+		skip += 5
+	}
+
+	for range skip {
+		if _, err := d.length(); err != nil {
+			return value, err
+		}
+	}
+
+	// Consumer groups are not supported and dropped
+	groups, err := d.length()
+	if err != nil {
+		return value, err
+	}
+
+	for range groups {
+		if err := d.skipConsumerGroup(valueType); err != nil {
+			return value, err
+		}
+	}
+
+	return value, nil
+}
+
+// loadStreamNode adds the live entries of one listpack node to s.
+func loadStreamNode(s redistypes.ConcurrentStream, master redistypes.StreamEntryId, elements []string) error {
+	pos := 0
+	next := func() (int64, error) {
+		if pos >= len(elements) {
+			return 0, fmt.Errorf("stream listpack ends early")
+		}
+
+		pos++
+		return strconv.ParseInt(elements[pos-1], 10, 64)
+	}
+
+	count, err := next()
+	if err != nil {
+		return err
+	}
+
+	deleted, err := next()
+	if err != nil {
+		return err
+	}
+
+	masterFieldCount, err := next()
+	if err != nil {
+		return err
+	}
+
+	if masterFieldCount < 0 || pos+int(masterFieldCount) > len(elements) {
+		return fmt.Errorf("stream listpack ends early")
+	}
+
+	masterFields := elements[pos : pos+int(masterFieldCount)]
+	pos += int(masterFieldCount)
+
+	// Master entry terminator
+	if _, err := next(); err != nil {
+		return err
+	}
+
+	for range count + deleted {
+		flags, err := next()
+		if err != nil {
+			return err
+		}
+
+		msDiff, err := next()
+		if err != nil {
+			return err
+		}
+
+		seqDiff, err := next()
+		if err != nil {
+			return err
+		}
+
+		fields := resptypes.Array[resptypes.BulkString]{}
+		if flags&streamItemSameFields != 0 {
+			if pos+len(masterFields) > len(elements) {
+				return fmt.Errorf("stream listpack ends early")
+			}
+
+			for i, field := range masterFields {
+				fields = append(fields, *resptypes.NewBulkString(field), *resptypes.NewBulkString(elements[pos+i]))
+			}
+
+			pos += len(masterFields)
+		} else {
+			fieldCount, err := next()
+			if err != nil {
+				return err
+			}
+
+			if fieldCount < 0 || pos+2*int(fieldCount) > len(elements) {
+				return fmt.Errorf("stream listpack ends early")
+			}
+
+			for i := 0; i < 2*int(fieldCount); i++ {
+				fields = append(fields, *resptypes.NewBulkString(elements[pos+i]))
+			}
+
+			pos += 2 * int(fieldCount)
+		}
+
+		// lp-count
+		if _, err := next(); err != nil {
+			return err
+		}
+
+		if flags&streamItemDeleted != 0 {
+			continue
+		}
+
+		id := redistypes.AddStreamEntryId{StreamEntryId: redistypes.StreamEntryId{
+			Ms:  master.Ms + uint64(msDiff),
+			Seq: master.Seq + uint64(seqDiff),
+		}}
+		if err, ok := s.AddEntry(id, fields).(resptypes.SimpleError); ok {
+			return err.Val
+		}
+	}
+
+	return nil
+}
+
+func (d *decoder) skipConsumerGroup(valueType byte) error {
+	if _, err := d.string(); err != nil {
+		return err
+	}
+
+	// Last delivered ID, and the entries read counter in newer versions
+	lengths := 2
+	if valueType >= typeStreamListpacks2 {
+		lengths++
+	}
+
+	for range lengths {
+		if _, err := d.length(); err != nil {
+			return err
+		}
+	}
+
+	// Pending entries: raw ID, delivery time and delivery count
+	pending, err := d.length()
+	if err != nil {
+		return err
+	}
+
+	for range pending {
+		if _, err := d.bytes(16 + 8); err != nil {
+			return err
+		}
+
+		if _, err := d.length(); err != nil {
+			return err
+		}
+	}
+
+	consumers, err := d.length()
+	if err != nil {
+		return err
+	}
+
+	for range consumers {
+		if _, err := d.string(); err != nil {
+			return err
+		}
+
+		// Seen time, and active time in newer versions
+		times := 8
+		if valueType >= typeStreamListpacks3 {
+			times += 8
+		}
+
+		if _, err := d.bytes(times); err != nil {
+			return err
+		}
+
+		// Consumer's pending entries, as raw IDs
+		consumerPending, err := d.length()
+		if err != nil {
+			return err
+		}
+
+		if _, err := d.bytes(16 * int(consumerPending)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
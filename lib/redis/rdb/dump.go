@@ -0,0 +1,69 @@
+package redisrdblib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+)
+
+// Dump serializes a single value in the format of the DUMP and RESTORE commands: the value as
+// it is stored in an RDB file, followed by the RDB version and a CRC64 checksum.
+func Dump(v redistypes.StoreValue) ([]byte, error) {
+	valueType, err := typeOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	e := &encoder{w: bufio.NewWriter(&buf)}
+	e.byte(valueType)
+	e.value(v)
+	e.write(binary.LittleEndian.AppendUint16(nil, version))
+	e.write(binary.LittleEndian.AppendUint64(nil, e.crc))
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	if err := e.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Restore parses a payload created by Dump, or by DUMP on a Redis server of the same or an older version.
+func Restore(payload []byte) (redistypes.StoreValue, error) {
+	errInvalid := fmt.Errorf("DUMP payload version or checksum are wrong")
+	if len(payload) < 10 {
+		return redistypes.StoreValue{}, errInvalid
+	}
+
+	footer := len(payload) - 10
+	if binary.LittleEndian.Uint16(payload[footer:]) > maxVersion {
+		return redistypes.StoreValue{}, errInvalid
+	}
+
+	if binary.LittleEndian.Uint64(payload[footer+2:]) != crcUpdate(0, payload[:footer+2]) {
+		return redistypes.StoreValue{}, errInvalid
+	}
+
+	d := &decoder{buf: payload[:footer]}
+	valueType, err := d.byte()
+	if err != nil {
+		return redistypes.StoreValue{}, err
+	}
+
+	v, err := d.value(valueType)
+	if err != nil {
+		return redistypes.StoreValue{}, err
+	}
+
+	if d.pos != len(d.buf) {
+		return redistypes.StoreValue{}, errInvalid
+	}
+
+	return v, nil
+}
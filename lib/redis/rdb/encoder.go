@@ -0,0 +1,233 @@
+package redisrdblib
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	encoder struct {
+		w   *bufio.Writer
+		crc uint64
+		err error
+	}
+)
+
+// Write serializes dbs as an RDB file. Empty databases are omitted.
+func Write(w io.Writer, dbs []Database) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+	e.write([]byte(fmt.Sprintf("%s%04d", magic, version)))
+	e.aux("redis-bits", strconv.Itoa(strconv.IntSize))
+	e.aux("ctime", strconv.FormatInt(time.Now().Unix(), 10))
+
+	for _, db := range dbs {
+		if len(db.Entries) == 0 {
+			continue
+		}
+
+		expires := 0
+		for _, entry := range db.Entries {
+			if !entry.ExpiresAt.IsZero() {
+				expires++
+			}
+		}
+
+		e.byte(opSelectDB)
+		e.length(uint64(db.Index))
+		e.byte(opResizeDB)
+		e.length(uint64(len(db.Entries)))
+		e.length(uint64(expires))
+
+		for _, entry := range db.Entries {
+			e.entry(entry)
+		}
+	}
+
+	e.byte(opEOF)
+	checksum := binary.LittleEndian.AppendUint64(nil, e.crc)
+	e.write(checksum)
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+// write is the single place bytes go out, so it keeps the checksum and remembers the first error.
+func (e *encoder) write(p []byte) {
+	if e.err != nil {
+		return
+	}
+
+	e.crc = crcUpdate(e.crc, p)
+	_, e.err = e.w.Write(p)
+}
+
+func (e *encoder) byte(b byte) {
+	e.write([]byte{b})
+}
+
+func (e *encoder) length(n uint64) {
+	switch {
+	case n < 1<<6:
+		e.byte(byte(n))
+	case n < 1<<14:
+		e.write([]byte{0x40 | byte(n>>8), byte(n)})
+	case n <= math.MaxUint32:
+		e.write(binary.BigEndian.AppendUint32([]byte{0x80}, uint32(n)))
+	default:
+		e.write(binary.BigEndian.AppendUint64([]byte{0x81}, n))
+	}
+}
+
+// string writes s, using the compact integer encodings when s is the canonical form of a small integer.
+func (e *encoder) string(s string) {
+	if len(s) <= 11 {
+		if v, err := strconv.ParseInt(s, 10, 32); err == nil && strconv.FormatInt(v, 10) == s {
+			switch {
+			case v >= math.MinInt8 && v <= math.MaxInt8:
+				e.write([]byte{0xC0 | encInt8, byte(v)})
+			case v >= math.MinInt16 && v <= math.MaxInt16:
+				e.write(binary.LittleEndian.AppendUint16([]byte{0xC0 | encInt16}, uint16(v)))
+			default:
+				e.write(binary.LittleEndian.AppendUint32([]byte{0xC0 | encInt32}, uint32(v)))
+			}
+
+			return
+		}
+	}
+
+	e.length(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *encoder) aux(key string, value string) {
+	e.byte(opAux)
+	e.string(key)
+	e.string(value)
+}
+
+func (e *encoder) entry(entry Entry) {
+	if !entry.ExpiresAt.IsZero() {
+		e.byte(opExpireTimeMs)
+		e.write(binary.LittleEndian.AppendUint64(nil, uint64(entry.ExpiresAt.UnixMilli())))
+	}
+
+	valueType, err := typeOf(entry.Value)
+	if err != nil {
+		if e.err == nil {
+			e.err = fmt.Errorf("cannot serialize value at key %q: %w", entry.Key, err)
+		}
+
+		return
+	}
+
+	e.byte(valueType)
+	e.string(entry.Key)
+	e.value(entry.Value)
+}
+
+// typeOf returns the value type byte v is written with.
+func typeOf(v redistypes.StoreValue) (byte, error) {
+	switch v.Type {
+	case redistypes.TypeString:
+		return typeString, nil
+	case redistypes.TypeList:
+		return typeList, nil
+	case redistypes.TypeStream:
+		return typeStreamListpacks, nil
+	default:
+		if _, exists := redistypes.CustomTypeOf(v.Type); exists && v.Custom != nil {
+			return typeModule2, nil
+		}
+
+		return 0, fmt.Errorf("unsupported value type %d", v.Type)
+	}
+}
+
+// value writes v in the encoding announced by typeOf.
+func (e *encoder) value(v redistypes.StoreValue) {
+	switch v.Type {
+	case redistypes.TypeString:
+		e.string(v.String.Val)
+	case redistypes.TypeList:
+		items := v.List.GetRange(0, -1)
+		e.length(uint64(len(items)))
+		for _, item := range items {
+			e.string(item.Val)
+		}
+	case redistypes.TypeStream:
+		e.stream(v.Stream)
+	default:
+		e.custom(v)
+	}
+}
+
+// stream writes the entries in listpack nodes keyed by their first ID, the way Redis stores them.
+func (e *encoder) stream(s redistypes.ConcurrentStream) {
+	type node struct {
+		master redistypes.StreamEntryId
+		lp     *listpack
+		count  int
+	}
+
+	nodes := []*node{}
+	length := 0
+	s.ForEach(func(id redistypes.StreamEntryId, fields resptypes.Array[resptypes.BulkString]) {
+		length++
+		if len(nodes) == 0 || nodes[len(nodes)-1].count == streamNodeMaxEntries {
+			// The master entry carries the first entry's field names, and the live and deleted entry counts
+			// which are only known once the node is complete
+			nodes = append(nodes, &node{master: id, lp: newListpack()})
+			n := nodes[len(nodes)-1]
+			n.lp.appendInt(0)
+			n.lp.appendInt(0)
+			n.lp.appendInt(int64(len(fields) / 2))
+			for i := 0; i+1 < len(fields); i += 2 {
+				n.lp.appendString(fields[i].Val)
+			}
+
+			n.lp.appendInt(0)
+		}
+
+		n := nodes[len(nodes)-1]
+		n.count++
+		n.lp.appendInt(0)
+		n.lp.appendInt(int64(id.Ms - n.master.Ms))
+		n.lp.appendInt(int64(id.Seq - n.master.Seq))
+		n.lp.appendInt(int64(len(fields) / 2))
+		for i := 0; i+1 < len(fields); i += 2 {
+			n.lp.appendString(fields[i].Val)
+			n.lp.appendString(fields[i+1].Val)
+		}
+
+		n.lp.appendInt(int64(3 + 1 + len(fields)/2*2))
+	})
+
+	e.length(uint64(len(nodes)))
+	for _, n := range nodes {
+		// Patch the live entry count, always encoded in a single byte up to streamNodeMaxEntries
+		n.lp.buf[listpackHeaderSize] = byte(n.count)
+
+		key := binary.BigEndian.AppendUint64(nil, n.master.Ms)
+		key = binary.BigEndian.AppendUint64(key, n.master.Seq)
+		e.string(string(key))
+		e.string(string(n.lp.bytes()))
+	}
+
+	lastId := s.LastId()
+	e.length(uint64(length))
+	e.length(lastId.Ms)
+	e.length(lastId.Seq)
+
+	// No consumer groups
+	e.length(0)
+}
@@ -0,0 +1,317 @@
+package redisrdblib
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type (
+	// listpack builds the serialized form of a Redis listpack.
+	listpack struct {
+		buf   []byte
+		count int
+	}
+)
+
+const (
+	listpackHeaderSize = 6
+	listpackEnd        = 0xFF
+)
+
+func newListpack() *listpack {
+	return &listpack{buf: make([]byte, listpackHeaderSize)}
+}
+
+func (lp *listpack) appendInt(v int64) {
+	start := len(lp.buf)
+	switch {
+	case v >= 0 && v <= 127:
+		lp.buf = append(lp.buf, byte(v))
+	case v >= -4096 && v <= 4095:
+		u := uint64(v) & 0x1fff
+		lp.buf = append(lp.buf, 0xC0|byte(u>>8), byte(u))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		lp.buf = binary.LittleEndian.AppendUint16(append(lp.buf, 0xF1), uint16(v))
+	case v >= -(1<<23) && v < 1<<23:
+		u := uint32(v)
+		lp.buf = append(lp.buf, 0xF2, byte(u), byte(u>>8), byte(u>>16))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		lp.buf = binary.LittleEndian.AppendUint32(append(lp.buf, 0xF3), uint32(v))
+	default:
+		lp.buf = binary.LittleEndian.AppendUint64(append(lp.buf, 0xF4), uint64(v))
+	}
+
+	lp.appendBacklen(len(lp.buf) - start)
+}
+
+func (lp *listpack) appendString(s string) {
+	start := len(lp.buf)
+	switch n := len(s); {
+	case n <= 63:
+		lp.buf = append(lp.buf, 0x80|byte(n))
+	case n <= 4095:
+		lp.buf = append(lp.buf, 0xE0|byte(n>>8), byte(n))
+	default:
+		lp.buf = binary.LittleEndian.AppendUint32(append(lp.buf, 0xF0), uint32(n))
+	}
+
+	lp.buf = append(lp.buf, s...)
+	lp.appendBacklen(len(lp.buf) - start)
+}
+
+// appendBacklen stores the element's size after it so that the listpack can be walked backwards.
+func (lp *listpack) appendBacklen(n int) {
+	size := backlenSize(n)
+	for i := size - 1; i >= 0; i-- {
+		b := byte(n>>(7*i)) & 127
+		if i != size-1 {
+			b |= 128
+		}
+
+		lp.buf = append(lp.buf, b)
+	}
+
+	lp.count++
+}
+
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// bytes finishes the listpack and returns its serialized form.
+func (lp *listpack) bytes() []byte {
+	buf := append(lp.buf, listpackEnd)
+	binary.LittleEndian.PutUint32(buf, uint32(len(buf)))
+	binary.LittleEndian.PutUint16(buf[4:], uint16(min(lp.count, math.MaxUint16)))
+	return buf
+}
+
+// decodeListpack returns the elements of a serialized listpack, with integers in decimal form.
+func decodeListpack(buf []byte) ([]string, error) {
+	if len(buf) < listpackHeaderSize+1 || int(binary.LittleEndian.Uint32(buf)) != len(buf) {
+		return nil, fmt.Errorf("invalid listpack header")
+	}
+
+	elements := make([]string, 0, binary.LittleEndian.Uint16(buf[4:]))
+	for pos := listpackHeaderSize; ; {
+		if pos >= len(buf) {
+			return nil, fmt.Errorf("listpack is missing its end marker")
+		}
+
+		b := buf[pos]
+		if b == listpackEnd {
+			return elements, nil
+		}
+
+		var (
+			element string
+			size    int
+		)
+
+		str := func(header int, n int) error {
+			if pos+header+n > len(buf) {
+				return fmt.Errorf("listpack string exceeds buffer")
+			}
+
+			element = string(buf[pos+header : pos+header+n])
+			size = header + n
+			return nil
+		}
+
+		integer := func(n int) error {
+			if pos+1+n > len(buf) {
+				return fmt.Errorf("listpack integer exceeds buffer")
+			}
+
+			var u uint64
+			for i := n - 1; i >= 0; i-- {
+				u = u<<8 | uint64(buf[pos+1+i])
+			}
+
+			// Sign extend
+			shift := 64 - 8*n
+			element = strconv.FormatInt(int64(u<<shift)>>shift, 10)
+			size = 1 + n
+			return nil
+		}
+
+		err := error(nil)
+		switch {
+		case b&0x80 == 0:
+			element, size = strconv.Itoa(int(b)), 1
+		case b&0xC0 == 0x80:
+			err = str(1, int(b&0x3f))
+		case b&0xE0 == 0xC0:
+			if pos+1 >= len(buf) {
+				return nil, fmt.Errorf("listpack integer exceeds buffer")
+			}
+
+			v := int(b&0x1f)<<8 | int(buf[pos+1])
+			if v >= 1<<12 {
+				v -= 1 << 13
+			}
+
+			element, size = strconv.Itoa(v), 2
+		case b&0xF0 == 0xE0:
+			if pos+1 >= len(buf) {
+				return nil, fmt.Errorf("listpack string exceeds buffer")
+			}
+
+			err = str(2, int(b&0x0f)<<8|int(buf[pos+1]))
+		case b == 0xF0:
+			if pos+5 > len(buf) {
+				return nil, fmt.Errorf("listpack string exceeds buffer")
+			}
+
+			err = str(5, int(binary.LittleEndian.Uint32(buf[pos+1:])))
+		case b == 0xF1:
+			err = integer(2)
+		case b == 0xF2:
+			err = integer(3)
+		case b == 0xF3:
+			err = integer(4)
+		case b == 0xF4:
+			err = integer(8)
+		default:
+			return nil, fmt.Errorf("invalid listpack encoding 0x%02x", b)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, element)
+		pos += size + backlenSize(size)
+	}
+}
+
+// decodeZiplist returns the elements of a serialized ziplist, the predecessor of listpacks
+// still found in files written by Redis before 7.0.
+func decodeZiplist(buf []byte) ([]string, error) {
+	const headerSize = 10
+	if len(buf) < headerSize+1 {
+		return nil, fmt.Errorf("invalid ziplist header")
+	}
+
+	elements := make([]string, 0, binary.LittleEndian.Uint16(buf[8:]))
+	pos := headerSize
+	need := func(n int) error {
+		if pos+n > len(buf) {
+			return fmt.Errorf("ziplist entry exceeds buffer")
+		}
+
+		return nil
+	}
+
+	for {
+		if err := need(1); err != nil {
+			return nil, err
+		}
+
+		if buf[pos] == 0xFF {
+			return elements, nil
+		}
+
+		// Skip the length of the previous entry
+		if buf[pos] < 254 {
+			pos++
+		} else {
+			pos += 5
+		}
+
+		if err := need(1); err != nil {
+			return nil, err
+		}
+
+		b := buf[pos]
+		pos++
+
+		strLen := -1
+		switch b >> 6 {
+		case 0:
+			strLen = int(b & 0x3f)
+		case 1:
+			if err := need(1); err != nil {
+				return nil, err
+			}
+
+			strLen = int(b&0x3f)<<8 | int(buf[pos])
+			pos++
+		case 2:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+
+			strLen = int(binary.BigEndian.Uint32(buf[pos:]))
+			pos += 4
+		}
+
+		if strLen >= 0 {
+			if err := need(strLen); err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, string(buf[pos:pos+strLen]))
+			pos += strLen
+			continue
+		}
+
+		var v int64
+		switch {
+		case b == 0xC0:
+			if err := need(2); err != nil {
+				return nil, err
+			}
+
+			v = int64(int16(binary.LittleEndian.Uint16(buf[pos:])))
+			pos += 2
+		case b == 0xD0:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+
+			v = int64(int32(binary.LittleEndian.Uint32(buf[pos:])))
+			pos += 4
+		case b == 0xE0:
+			if err := need(8); err != nil {
+				return nil, err
+			}
+
+			v = int64(binary.LittleEndian.Uint64(buf[pos:]))
+			pos += 8
+		case b == 0xF0:
+			if err := need(3); err != nil {
+				return nil, err
+			}
+
+			v = int64(int32(uint32(buf[pos])<<8|uint32(buf[pos+1])<<16|uint32(buf[pos+2])<<24) >> 8)
+			pos += 3
+		case b == 0xFE:
+			if err := need(1); err != nil {
+				return nil, err
+			}
+
+			v = int64(int8(buf[pos]))
+			pos++
+		case b >= 0xF1 && b <= 0xFD:
+			v = int64(b&0x0f) - 1
+		default:
+			return nil, fmt.Errorf("invalid ziplist encoding 0x%02x", b)
+		}
+
+		elements = append(elements, strconv.FormatInt(v, 10))
+	}
+}
@@ -0,0 +1,56 @@
+package redisrdblib
+
+import "fmt"
+
+// lzfDecompress expands LZF compressed data, as produced by Redis for long strings.
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	for ip := 0; ip < len(in); {
+		ctrl := int(in[ip])
+		ip++
+
+		if ctrl < 32 {
+			// Literal run of ctrl+1 bytes
+			n := ctrl + 1
+			if ip+n > len(in) {
+				return nil, fmt.Errorf("LZF literal run exceeds input")
+			}
+
+			out = append(out, in[ip:ip+n]...)
+			ip += n
+			continue
+		}
+
+		// Back reference
+		n := ctrl >> 5
+		if n == 7 {
+			if ip >= len(in) {
+				return nil, fmt.Errorf("LZF back reference truncated")
+			}
+
+			n += int(in[ip])
+			ip++
+		}
+
+		if ip >= len(in) {
+			return nil, fmt.Errorf("LZF back reference truncated")
+		}
+
+		ref := len(out) - ((ctrl & 0x1f) << 8) - int(in[ip]) - 1
+		ip++
+		if ref < 0 {
+			return nil, fmt.Errorf("LZF back reference before start of output")
+		}
+
+		// Byte by byte since the reference may overlap the bytes being written
+		for i := 0; i < n+2; i++ {
+			out = append(out, out[ref+i])
+		}
+	}
+
+	if len(out) != outLen {
+		return nil, fmt.Errorf("LZF output is %d bytes, expected %d", len(out), outLen)
+	}
+
+	return out, nil
+}
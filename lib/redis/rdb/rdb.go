@@ -0,0 +1,88 @@
+// Package redisrdblib reads and writes Redis RDB snapshot files.
+//
+// Files are written in RDB version 9 using only encodings that every Redis since 5.0 can load.
+// The reader additionally understands the compact list and stream encodings newer Redis versions
+// produce, so that dump files created by a real server can be loaded.
+package redisrdblib
+
+import (
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+)
+
+type (
+	// Entry is one key of a snapshot. The value must not be modified while it is being written.
+	Entry struct {
+		Key       redistypes.StoreKey
+		Value     redistypes.StoreValue
+		ExpiresAt time.Time
+	}
+
+	// Database is the content of one logical database.
+	Database struct {
+		Index   int
+		Entries []Entry
+	}
+)
+
+const (
+	magic   = "REDIS"
+	version = 9
+	// Newest format version the reader accepts
+	maxVersion = 12
+)
+
+// Opcodes introducing the records between the header and the EOF marker.
+const (
+	opSlotInfo      = 0xF4
+	opFunction2     = 0xF5
+	opFunctionPreGA = 0xF6
+	opModuleAux     = 0xF7
+	opIdle          = 0xF8
+	opFreq          = 0xF9
+	opAux           = 0xFA
+	opResizeDB      = 0xFB
+	opExpireTimeMs  = 0xFC
+	opExpireTime    = 0xFD
+	opSelectDB      = 0xFE
+	opEOF           = 0xFF
+)
+
+// Value types. Only the ones this server can hold are listed.
+const (
+	typeString           = 0
+	typeList             = 1
+	typeModule2          = 7
+	typeListZiplist      = 10
+	typeListQuicklist    = 14
+	typeStreamListpacks  = 15
+	typeListQuicklist2   = 18
+	typeStreamListpacks2 = 19
+	typeStreamListpacks3 = 21
+)
+
+// Special string encodings, flagged by the two high bits of the length prefix.
+const (
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+)
+
+const (
+	// Container types of quicklist 2 nodes
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+
+	// Opcodes of the values saved by module types
+	moduleOpEOF    = 0
+	moduleOpString = 5
+
+	// Flags of stream entries within a listpack
+	streamItemDeleted    = 1
+	streamItemSameFields = 2
+
+	// Same as the stream-node-max-entries default
+	streamNodeMaxEntries = 100
+)
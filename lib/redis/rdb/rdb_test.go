@@ -0,0 +1,296 @@
+package redisrdblib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+// describe renders a value in a form that is easy to compare.
+func describe(v redistypes.StoreValue) string {
+	switch v.Type {
+	case redistypes.TypeString:
+		return "string:" + v.String.Val
+	case redistypes.TypeList:
+		return fmt.Sprintf("list:%v", v.List.GetRange(0, -1))
+	case redistypes.TypeStream:
+		return "stream:" + v.Stream.GetEntries(redistypes.StreamEntryId{}, redistypes.StreamEntryId{Ms: ^uint64(0), Seq: ^uint64(0)}).ToRespString()
+	default:
+		if c, ok := v.Custom.(*testCustomValue); ok {
+			return "custom:" + c.val
+		}
+
+		return "unknown"
+	}
+}
+
+type testCustomValue struct {
+	val string
+}
+
+func (v *testCustomValue) Clone() redistypes.CustomValue {
+	return &testCustomValue{v.val}
+}
+
+func readAll(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	loaded := map[string]string{}
+	err := Read(bytes.NewReader(data), func(db int, entry Entry) error {
+		desc := describe(entry.Value)
+		if !entry.ExpiresAt.IsZero() {
+			desc += fmt.Sprintf(" expires:%d", entry.ExpiresAt.UnixMilli())
+		}
+
+		loaded[fmt.Sprintf("%d/%s", db, entry.Key)] = desc
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+
+	return loaded
+}
+
+func TestChecksum(t *testing.T) {
+	// Test vector from Redis' crc64.c
+	if actual := crcUpdate(0, []byte("123456789")); actual != 0xe9c6d914c4b8d9ca {
+		t.Errorf("crc64(123456789) = %016x; Expected: e9c6d914c4b8d9ca", actual)
+	}
+}
+
+func TestReadRedisFile(t *testing.T) {
+	// An empty dataset saved by Redis 7.2
+	data, _ := base64.StdEncoding.DecodeString("UkVESVMwMDEx+glyZWRpcy12ZXIFNy4yLjD6CnJlZGlzLWJpdHPAQPoFY3RpbWXCbQi8ZfoIdXNlZC1tZW3CsMQQAPoIYW9mLWJhc2XAAP/wbjv+wP9aog==")
+	if loaded := readAll(t, data); len(loaded) != 0 {
+		t.Errorf("Loaded %v; Expected no keys", loaded)
+	}
+
+	data[len(data)-1] ^= 1
+	if err := Read(bytes.NewReader(data), func(int, Entry) error { return nil }); err == nil {
+		t.Errorf("Read() with a corrupted checksum succeeded")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	list := redistypes.NewList()
+	list.List.PushBack(*resptypes.NewBulkString("a"), *resptypes.NewBulkString("-5"), *resptypes.NewBulkString(string(make([]byte, 300))))
+
+	stream := redistypes.NewStream()
+	for i := range 250 {
+		fields := resptypes.Array[resptypes.BulkString]{*resptypes.NewBulkString("n"), *resptypes.NewBulkString(fmt.Sprint(i * 1000))}
+		if i%2 == 1 {
+			fields = append(fields, *resptypes.NewBulkString("odd"), *resptypes.NewBulkString("yes"))
+		}
+
+		stream.Stream.AddEntry(redistypes.AddStreamEntryId{StreamEntryId: redistypes.StreamEntryId{Ms: 1700000000000 + uint64(i/3), Seq: uint64(i % 3)}}, fields)
+	}
+
+	expiresAt := time.UnixMilli(4102444800000)
+	dbs := []Database{
+		{Index: 0, Entries: []Entry{
+			{Key: "str", Value: redistypes.NewString("hello")()},
+			{Key: "int", Value: redistypes.NewString("-70000")()},
+			{Key: "notint", Value: redistypes.NewString("007")()},
+			{Key: "volatile", Value: redistypes.NewString("v")(), ExpiresAt: expiresAt},
+		}},
+		{Index: 1},
+		{Index: 15, Entries: []Entry{
+			{Key: "list", Value: list},
+			{Key: "stream", Value: stream},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, dbs); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	expected := map[string]string{}
+	for _, db := range dbs {
+		for _, entry := range db.Entries {
+			desc := describe(entry.Value)
+			if !entry.ExpiresAt.IsZero() {
+				desc += fmt.Sprintf(" expires:%d", entry.ExpiresAt.UnixMilli())
+			}
+
+			expected[fmt.Sprintf("%d/%s", db.Index, entry.Key)] = desc
+		}
+	}
+
+	loaded := readAll(t, buf.Bytes())
+	if len(loaded) != len(expected) {
+		t.Errorf("Loaded %d keys; Expected: %d", len(loaded), len(expected))
+	}
+
+	for key, desc := range expected {
+		if loaded[key] != desc {
+			t.Errorf("Key %s = %.80q; Expected: %.80q", key, loaded[key], desc)
+		}
+	}
+}
+
+func TestReadCompactEncodings(t *testing.T) {
+	var body bytes.Buffer
+	e := &encoder{w: bufio.NewWriter(&body)}
+	e.write([]byte("REDIS0012"))
+
+	// LZF compressed string of 24 'a': one literal followed by a back reference
+	e.byte(typeString)
+	e.string("lzf")
+	e.write([]byte{0xC0 | encLZF, 5, 24, 0x00, 'a', 0xE0, 14, 0x00})
+
+	// Quicklist 2 with one listpack node and one plain node
+	lp := newListpack()
+	lp.appendString("x")
+	lp.appendInt(-3000)
+	lp.appendInt(1 << 40)
+	e.byte(typeListQuicklist2)
+	e.string("ql")
+	e.length(2)
+	e.length(quicklistNodePacked)
+	e.string(string(lp.bytes()))
+	e.length(quicklistNodePlain)
+	e.string("plain")
+
+	// Stream node with a same-fields entry and a deleted entry, as Redis writes them
+	lp = newListpack()
+	lp.appendInt(1)
+	lp.appendInt(1)
+	lp.appendInt(1)
+	lp.appendString("f")
+	lp.appendInt(0)
+	for _, item := range []struct {
+		flags  int64
+		msDiff int64
+		value  string
+	}{{streamItemSameFields, 0, "v1"}, {streamItemDeleted | streamItemSameFields, 5, "gone"}} {
+		lp.appendInt(item.flags)
+		lp.appendInt(item.msDiff)
+		lp.appendInt(0)
+		lp.appendString(item.value)
+		lp.appendInt(4)
+	}
+
+	key := binary.BigEndian.AppendUint64(nil, 10)
+	key = binary.BigEndian.AppendUint64(key, 1)
+	e.byte(typeStreamListpacks3)
+	e.string("s")
+	e.length(1)
+	e.string(string(key))
+	e.string(string(lp.bytes()))
+	// Length, last ID, first ID, max deleted ID, entries added and no consumer groups
+	for _, n := range []uint64{1, 15, 0, 10, 1, 15, 0, 2, 0} {
+		e.length(n)
+	}
+
+	// A zero checksum means checksums were disabled
+	e.byte(opEOF)
+	e.write(make([]byte, 8))
+	e.w.Flush()
+
+	loaded := readAll(t, body.Bytes())
+	expected := map[string]string{
+		"0/lzf": "string:aaaaaaaaaaaaaaaaaaaaaaaa",
+		"0/ql":  "list:[{1 x} {5 -3000} {13 1099511627776} {5 plain}]",
+		"0/s":   "stream:*1\r\n*2\r\n$4\r\n10-1\r\n*2\r\n$1\r\nf\r\n$2\r\nv1\r\n",
+	}
+	for key, desc := range expected {
+		if loaded[key] != desc {
+			t.Errorf("Key %s = %q; Expected: %q", key, loaded[key], desc)
+		}
+	}
+}
+
+func TestDumpRestore(t *testing.T) {
+	// DUMP of the value 10 by Redis, as shown in the command's documentation
+	redisPayload := "\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n"
+	v, err := Restore([]byte(redisPayload))
+	if err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+
+	if desc := describe(v); desc != "string:10" {
+		t.Errorf("Restored %q; Expected: %q", desc, "string:10")
+	}
+
+	payload, err := Dump(v)
+	if err != nil {
+		t.Fatalf("Dump() failed: %v", err)
+	}
+
+	if string(payload) != redisPayload {
+		t.Errorf("Dump() = %q; Expected: %q", payload, redisPayload)
+	}
+
+	list := redistypes.NewList()
+	list.List.PushBack(*resptypes.NewBulkString("a"), *resptypes.NewBulkString("b"))
+	if payload, err = Dump(list); err != nil {
+		t.Fatalf("Dump() failed: %v", err)
+	}
+
+	if v, err = Restore(payload); err != nil || describe(v) != describe(list) {
+		t.Errorf("Restore(Dump()) = %q, %v; Expected: %q", describe(v), err, describe(list))
+	}
+
+	payload[0] ^= 1
+	if _, err := Restore(payload); err == nil {
+		t.Errorf("Restore() with a corrupted checksum succeeded")
+	}
+}
+
+func TestCustomType(t *testing.T) {
+	custom := &redistypes.CustomType{
+		Name:            "rdb-test1",
+		EncodingVersion: 3,
+		Encode:          func(v redistypes.CustomValue) []byte { return []byte(v.(*testCustomValue).val) },
+		Decode: func(data []byte, encodingVersion int) (redistypes.CustomValue, error) {
+			if encodingVersion != 3 {
+				return nil, fmt.Errorf("unexpected encoding version %d", encodingVersion)
+			}
+
+			return &testCustomValue{string(data)}, nil
+		},
+	}
+	if _, err := redistypes.RegisterType(custom); err != nil {
+		t.Fatalf("RegisterType() failed: %v", err)
+	}
+
+	if name, encodingVersion := moduleTypeName(moduleTypeId("rdb-test1", 3)); name != "rdb-test1" || encodingVersion != 3 {
+		t.Errorf("moduleTypeName() = %q, %d; Expected: %q, %d", name, encodingVersion, "rdb-test1", 3)
+	}
+
+	v := redistypes.NewCustomValue(custom, &testCustomValue{"payload"})
+	payload, err := Dump(v)
+	if err != nil {
+		t.Fatalf("Dump() failed: %v", err)
+	}
+
+	if restored, err := Restore(payload); err != nil || describe(restored) != "custom:payload" || restored.Type != v.Type {
+		t.Errorf("Restore(Dump()) = %q, %v; Expected: %q", describe(restored), err, "custom:payload")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, []Database{{Index: 0, Entries: []Entry{{Key: "k", Value: v}}}}); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	if loaded := readAll(t, buf.Bytes()); loaded["0/k"] != "custom:payload" {
+		t.Errorf("Loaded %v", loaded)
+	}
+
+	// Another type may not take the name, and names are validated
+	if _, err := redistypes.RegisterType(&redistypes.CustomType{Name: "rdb-test1", Encode: custom.Encode, Decode: custom.Decode}); err == nil {
+		t.Errorf("RegisterType() with a taken name succeeded")
+	}
+
+	if _, err := redistypes.RegisterType(&redistypes.CustomType{Name: "short", Encode: custom.Encode, Decode: custom.Decode}); err == nil {
+		t.Errorf("RegisterType() with an invalid name succeeded")
+	}
+}
@@ -0,0 +1,955 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+)
+
+type (
+	// acl holds the users that clients authenticate as, and what each of them may run and access.
+	// Users are modified in place, so that the clients authenticated as a user see its new rules
+	// from their next command.
+	acl struct {
+		commands commandMap
+		// The commands as clients call them, so that rules may also name renamed commands by their new name
+		clientCommands commandMap
+		clients        *clientList
+		// The ACL file loaded at startup and by ACL LOAD, empty if none
+		file string
+
+		mu    sync.RWMutex
+		users map[string]*aclUser
+		// The password set by requirepass, reported by CONFIG GET
+		requirePass string
+
+		protectedMode atomic.Bool
+		log           aclLog
+	}
+
+	aclUser struct {
+		name    string
+		enabled bool
+		// Set when any password authenticates the user
+		nopass bool
+		// The SHA-256 digests of the passwords, in hex
+		passwords []string
+		// Rules such as +get, -@admin or +config|get. The last one matching a command decides.
+		commands []string
+		keys     []aclKeyPattern
+		channels []string
+	}
+
+	// aclKeyPattern grants reading, writing or both to the keys matching a glob-style pattern.
+	aclKeyPattern struct {
+		pattern string
+		read    bool
+		write   bool
+	}
+
+	// aclLog keeps the latest denied commands and failed authentications, newest first.
+	aclLog struct {
+		mu      sync.Mutex
+		entries []*aclLogEntry
+		lastId  int64
+		maxLen  atomic.Int64
+	}
+
+	aclLogEntry struct {
+		id int64
+		// How many similar events happened, see aclLogMergeWindow
+		count      int
+		reason     string
+		context    string
+		object     string
+		username   string
+		clientInfo string
+		created    time.Time
+		updated    time.Time
+	}
+
+	// aclDenial tells why a user may not run a command: the command itself, or one of its keys
+	// or channels, named by object.
+	aclDenial struct {
+		reason string
+		object string
+	}
+)
+
+const (
+	defaultUsername = "default"
+	// Events similar to a logged one within this window are counted in its entry
+	aclLogMergeWindow = time.Minute
+
+	aclReasonCommand = "command"
+	aclReasonKey     = "key"
+	aclReasonChannel = "channel"
+	aclReasonAuth    = "auth"
+)
+
+var (
+	errNoAuth    = errors.New("NOAUTH Authentication required.")
+	errWrongPass = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+	errNoACLFile = errors.New("ERR This Redis instance is not configured to use an ACL file")
+
+	errProtectedMode = errors.New("DENIED Redis is running in protected mode because protected mode is enabled and no password is set for the default user. " +
+		"In this mode connections are only accepted from the loopback interface. " +
+		"If you want to connect from external computers to Redis you may adopt one of the following solutions: " +
+		"1) Just disable protected mode sending the command 'CONFIG SET protected-mode no' from the loopback interface by connecting to Redis from the same host the server is running, " +
+		"however MAKE SURE Redis is not publicly accessible from internet if you do so. Use CONFIG REWRITE to make this change permanent. " +
+		"2) Alternatively you can just disable the protected mode by editing the Redis configuration file, and setting the protected mode option to 'no', and then restarting the server. " +
+		"3) If you started the server manually just for testing, restart it with the '--protected-mode no' option. " +
+		"4) Set up an authentication password for the default user. " +
+		"NOTE: You only need to do one of the above things in order for the server to start accepting connections from the outside.")
+
+	errRuleSyntax        = errors.New("Syntax error")
+	errUnknownCommand    = errors.New("Unknown command or category name in ACL")
+	errNoSuchPassword    = errors.New("The password you are trying to remove from the user does not exist")
+	errInvalidHash       = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+	errKeyAfterAllKeys   = errors.New("Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect. Try 'resetkeys' to start with an empty list of patterns")
+	errChannelAfterAll   = errors.New("Adding a pattern after the * pattern (or the 'allchannels' flag) is not valid and does not have any effect. Try 'resetchannels' to start with an empty list of channels")
+	errUsernameWithSpace = errors.New("Usernames can't contain spaces or null characters")
+)
+
+func newACL(commands commandMap, clientCommands commandMap, clients *clientList, options processorOptions) *acl {
+	a := &acl{
+		commands:       commands,
+		clientCommands: clientCommands,
+		clients:        clients,
+		file:           options.aclFile,
+		users:          map[string]*aclUser{defaultUsername: newDefaultUser()},
+	}
+	a.protectedMode.Store(options.protectedMode)
+	a.log.maxLen.Store(int64(options.aclLogMaxLen))
+	return a
+}
+
+// newDefaultUser returns the user clients are authenticated as when it has no password.
+func newDefaultUser() *aclUser {
+	return &aclUser{name: defaultUsername, enabled: true, nopass: true, commands: []string{"+@all"},
+		keys: []aclKeyPattern{{pattern: "*", read: true, write: true}}, channels: []string{"*"}}
+}
+
+func newUser(name string) *aclUser {
+	return &aclUser{name: name, commands: []string{"-@all"}}
+}
+
+// start creates the users configured with the user directive or loaded from the ACL file, then
+// applies requirepass. It must be called once every command is registered, as rules name them.
+func (a *acl) start(options processorOptions) {
+	if a.file != "" {
+		if len(options.aclUsers) > 0 {
+			slog.Warn("Ignoring the users of the config file, as an ACL file is configured", "file", a.file)
+		}
+
+		if err := a.load(); err != nil {
+			slog.Error("Failed to load the ACL file", "error", err)
+		}
+	} else {
+		for _, rules := range options.aclUsers {
+			if err := a.setUser(rules[0], rules[1:]); err != nil {
+				slog.Error("Ignoring invalid user", "user", rules[0], "error", err)
+			}
+		}
+	}
+
+	if options.requirePass != "" {
+		a.setRequirePass(options.requirePass)
+	}
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// setRequirePass makes password the only password of the default user, or lets the default user
+// authenticate without password when empty.
+func (a *acl) setRequirePass(password string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requirePass = password
+	user := a.users[defaultUsername]
+	user.nopass = password == ""
+	user.passwords = nil
+	if password != "" {
+		user.passwords = []string{hashPassword(password)}
+	}
+}
+
+func (a *acl) getRequirePass() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.requirePass
+}
+
+// defaultNoPass tells whether clients are authenticated as the default user without AUTH.
+func (a *acl) defaultNoPass() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	user := a.users[defaultUsername]
+	return user.enabled && user.nopass
+}
+
+// connect authenticates a new client as the default user if it needs no password. Like with
+// Redis, the client stays authenticated when a password is set later.
+func (a *acl) connect(c *client) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if user := a.users[defaultUsername]; user.enabled && user.nopass {
+		c.user.Store(user)
+	}
+}
+
+// userOf returns the user c is authenticated as, or nil when it must authenticate first. Clients
+// that never authenticated are the default user while it needs no password.
+func (a *acl) userOf(c *client) *aclUser {
+	if user := c.user.Load(); user != nil {
+		return user
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if user := a.users[defaultUsername]; user.enabled && user.nopass {
+		return user
+	}
+
+	return nil
+}
+
+// whoami returns the name of the user c is authenticated as, empty when it must authenticate first.
+func (a *acl) whoami(c *client) string {
+	user := a.userOf(c)
+	if user == nil {
+		return ""
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return user.name
+}
+
+// authenticate returns the user named name if password authenticates it.
+func (a *acl) authenticate(name string, password string) (*aclUser, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	user, exists := a.users[name]
+	if !exists || !user.enabled {
+		return nil, false
+	}
+
+	if user.nopass {
+		return user, true
+	}
+
+	hash := hashPassword(password)
+	for _, candidate := range user.passwords {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(hash)) == 1 {
+			return user, true
+		}
+	}
+
+	return nil, false
+}
+
+// check returns the error replied to c when it may not run a command, and logs the denials
+// under logContext, "toplevel" or "multi".
+func (a *acl) check(c *client, entry commandDefinition, params commandParams, logContext string) error {
+	if a.protectedMode.Load() && !isLoopback(c.addr) && a.defaultNoPass() {
+		return errProtectedMode
+	}
+
+	spec := entry.spec()
+	if spec.flags&flagNoAuth != 0 {
+		return nil
+	}
+
+	user := a.userOf(c)
+	if user == nil {
+		return errNoAuth
+	}
+
+	a.mu.RLock()
+	denial := a.denied(user, entry, params)
+	username := user.name
+	a.mu.RUnlock()
+	if denial == nil {
+		return nil
+	}
+
+	a.log.add(denial.reason, logContext, denial.object, username, clientInfo(c, username))
+	switch denial.reason {
+	case aclReasonKey:
+		return errors.New("NOPERM No permissions to access a key")
+	case aclReasonChannel:
+		return errors.New("NOPERM No permissions to access a channel")
+	default:
+		return fmt.Errorf("NOPERM User %s has no permissions to run the '%s' command", username, denial.object)
+	}
+}
+
+// denied returns why user may not run a command, or nil if it may. Must be called holding mu.
+func (a *acl) denied(user *aclUser, entry commandDefinition, params commandParams) *aclDenial {
+	spec := entry.spec()
+	if spec.flags&flagNoAuth != 0 {
+		return nil
+	}
+
+	name := strings.ToLower(params[0].Val)
+	called := spec.resolve(name, params)
+	if !user.canRun(name, called) {
+		return &aclDenial{reason: aclReasonCommand, object: called.name}
+	}
+
+	for _, access := range accessedKeys(entry, params) {
+		if !user.canAccessKey(access.key, access.read, access.write) {
+			return &aclDenial{reason: aclReasonKey, object: access.key}
+		}
+	}
+
+	for _, channelSpec := range spec.channelSpecs {
+		isPattern := slices.Contains(channelSpec.flags, "pattern")
+		for _, channel := range channelSpec.find(params) {
+			if !user.canAccessChannel(channel, isPattern) {
+				return &aclDenial{reason: aclReasonChannel, object: channel}
+			}
+		}
+	}
+
+	return nil
+}
+
+// canRun tells whether the rules of the user allow the command called, a subcommand of the
+// command name when it is a container.
+func (u *aclUser) canRun(name string, called commandSpec) bool {
+	allowed := false
+	for _, rule := range u.commands {
+		allow, target := rule[0] == '+', rule[1:]
+		switch {
+		case target == "@all":
+			allowed = allow
+		case strings.HasPrefix(target, "@"):
+			if slices.Contains(called.aclCategories(), target[1:]) {
+				allowed = allow
+			}
+		case target == name || target == called.name:
+			allowed = allow
+		}
+	}
+
+	return allowed
+}
+
+// canAccessKey tells whether a single pattern of the user grants every permission needed on key.
+func (u *aclUser) canAccessKey(key string, read bool, write bool) bool {
+	for _, p := range u.keys {
+		if (!read || p.read) && (!write || p.write) && (read || write || p.read || p.write) && redislib.GlobMatch(p.pattern, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canAccessChannel tells whether the user may use channel. A pattern subscribed to with
+// PSUBSCRIBE must be one of the patterns of the user, unless the user may use every channel.
+func (u *aclUser) canAccessChannel(channel string, isPattern bool) bool {
+	for _, p := range u.channels {
+		if p == "*" || p == channel || (!isPattern && redislib.GlobMatch(p, channel)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keyAccess is a key of a call, with the permissions the command needs on it.
+type keyAccess struct {
+	key   string
+	read  bool
+	write bool
+}
+
+// accessedKeys returns the keys of a call along with how they are accessed, as declared by
+// the flags of the key specs.
+func accessedKeys(entry commandDefinition, params commandParams) []keyAccess {
+	spec := entry.spec()
+	needs := func(flags []string) (bool, bool) {
+		read := slices.Contains(flags, "access")
+		write := slices.ContainsFunc(flags, func(flag string) bool { return flag == "insert" || flag == "update" || flag == "delete" })
+		return read, write
+	}
+
+	var accesses []keyAccess
+	if rc, ok := entry.(registeredCommand); ok {
+		entry = rc.commandDefinition
+	}
+
+	if _, custom := entry.(keyedCommand); custom {
+		// The key specs do not tell which key is where, so every key needs what any of them needs
+		var flags []string
+		for _, k := range spec.keySpecs {
+			flags = append(flags, k.flags...)
+		}
+
+		read, write := needs(flags)
+		for _, key := range keysOf(entry, params) {
+			accesses = append(accesses, keyAccess{key, read, write})
+		}
+
+		return accesses
+	}
+
+	for _, k := range spec.keySpecs {
+		read, write := needs(k.flags)
+		for _, key := range k.find(params) {
+			accesses = append(accesses, keyAccess{key, read, write})
+		}
+	}
+
+	return accesses
+}
+
+func isLoopback(addr string) bool {
+	if addr == "" {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// clientInfo describes c in the ACL log.
+func clientInfo(c *client, username string) string {
+	return fmt.Sprintf("id=%d addr=%s name=%s db=%d user=%s", c.id, c.addr, c.name, c.db, username)
+}
+
+// logAuthFailure logs a failed AUTH of c as the user named username.
+func (a *acl) logAuthFailure(c *client, username string) {
+	a.log.add(aclReasonAuth, "toplevel", "AUTH", username, clientInfo(c, username))
+}
+
+// setUser creates the user named name if it does not exist, and applies rules to it. Either
+// every rule applies or, when one is invalid, none.
+func (a *acl) setUser(name string, rules []string) error {
+	if strings.ContainsAny(name, " \x00") {
+		return errUsernameWithSpace
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	existing, exists := a.users[name]
+	user := newUser(name)
+	if exists {
+		user = existing.clone()
+	}
+
+	if err := a.applyRules(user, rules); err != nil {
+		return err
+	}
+
+	if exists {
+		*existing = *user
+	} else {
+		a.users[name] = user
+	}
+
+	return nil
+}
+
+// deleteUsers deletes the users named names, and disconnects the clients authenticated as them.
+// It returns how many users existed.
+func (a *acl) deleteUsers(names []string) (int, error) {
+	if slices.Contains(names, defaultUsername) {
+		return 0, errors.New("ERR The 'default' user cannot be removed")
+	}
+
+	a.mu.Lock()
+	var deleted []*aclUser
+	for _, name := range names {
+		if user, exists := a.users[name]; exists {
+			delete(a.users, name)
+			deleted = append(deleted, user)
+		}
+	}
+	a.mu.Unlock()
+
+	a.disconnect(deleted)
+	return len(deleted), nil
+}
+
+// disconnect disconnects the clients authenticated as any of users.
+func (a *acl) disconnect(users []*aclUser) {
+	if len(users) == 0 {
+		return
+	}
+
+	for _, c := range a.clients.all() {
+		if user := c.user.Load(); user != nil && slices.Contains(users, user) {
+			slog.Info("Disconnecting client of a deleted user", "id", c.id, "user", user.name)
+			c.kill()
+		}
+	}
+}
+
+func (a *acl) getUser(name string) (aclUser, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	user, exists := a.users[name]
+	if !exists {
+		return aclUser{}, false
+	}
+
+	return *user.clone(), true
+}
+
+func (a *acl) usernames() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// list returns the users in the ACL file format, sorted by name.
+func (a *acl) list() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.users))
+	for name := range a.users {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = a.users[name].describe()
+	}
+
+	return lines
+}
+
+// configLines returns the users as user directives of the config file, with their passwords
+// hashed like in the ACL file. The default user is left out while it is unchanged. There are
+// none when an ACL file holds the users.
+func (a *acl) configLines() []string {
+	if a.file != "" {
+		return nil
+	}
+
+	unchanged := newDefaultUser().describe()
+	return slices.DeleteFunc(a.list(), func(line string) bool { return line == unchanged })
+}
+
+// load replaces the users with those of the ACL file. The users are left as they were when
+// any line of the file is invalid.
+func (a *acl) load() error {
+	if a.file == "" {
+		return errNoACLFile
+	}
+
+	f, err := os.Open(a.file)
+	if err != nil {
+		return fmt.Errorf("ERR Error loading ACLs, opening file '%s': %w", a.file, err)
+	}
+
+	defer f.Close()
+	loaded := map[string]*aclUser{}
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields, err := splitConfigLine(scanner.Text())
+		if err == nil && len(fields) > 0 && (fields[0] != "user" || len(fields) < 2) {
+			err = errors.New("line should start with user keyword followed by the username")
+		}
+
+		if err == nil && len(fields) > 0 {
+			err = a.loadUser(loaded, fields[1], fields[2:])
+		}
+
+		if err != nil {
+			return fmt.Errorf("ERR %s:%d: %w", a.file, lineNumber, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ERR Error loading ACLs, reading file '%s': %w", a.file, err)
+	}
+
+	if _, exists := loaded[defaultUsername]; !exists {
+		loaded[defaultUsername] = newDefaultUser()
+	}
+
+	a.mu.Lock()
+	var deleted []*aclUser
+	for name, user := range a.users {
+		if replacement, exists := loaded[name]; exists {
+			*user = *replacement
+			loaded[name] = user
+		} else {
+			deleted = append(deleted, user)
+		}
+	}
+
+	a.users = loaded
+	a.mu.Unlock()
+
+	a.disconnect(deleted)
+	return nil
+}
+
+func (a *acl) loadUser(loaded map[string]*aclUser, name string, rules []string) error {
+	if _, exists := loaded[name]; exists {
+		return fmt.Errorf("Duplicate user '%s' found", name)
+	}
+
+	if strings.ContainsAny(name, " \x00") {
+		return errUsernameWithSpace
+	}
+
+	user := newUser(name)
+	a.mu.RLock()
+	err := a.applyRules(user, rules)
+	a.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	loaded[name] = user
+	return nil
+}
+
+// save writes the users to the ACL file.
+func (a *acl) save() error {
+	if a.file == "" {
+		return errNoACLFile
+	}
+
+	if err := writeConfigFile(a.file, []byte(strings.Join(a.list(), "\n")+"\n")); err != nil {
+		return fmt.Errorf("ERR There was an error trying to save the ACLs: %w", err)
+	}
+
+	return nil
+}
+
+func (a *acl) applyRules(user *aclUser, rules []string) error {
+	for _, rule := range rules {
+		if err := a.applyRule(user, rule); err != nil {
+			return fmt.Errorf("Error in ACL SETUSER modifier '%s': %w", rule, err)
+		}
+	}
+
+	return nil
+}
+
+// applyRule applies a rule of ACL SETUSER to user.
+func (a *acl) applyRule(user *aclUser, rule string) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		user.enabled = true
+	case "off":
+		user.enabled = false
+	case "nopass":
+		user.nopass, user.passwords = true, nil
+	case "resetpass":
+		user.nopass, user.passwords = false, nil
+	case "allkeys":
+		user.keys = []aclKeyPattern{{pattern: "*", read: true, write: true}}
+	case "resetkeys":
+		user.keys = nil
+	case "allchannels":
+		user.channels = []string{"*"}
+	case "resetchannels":
+		user.channels = nil
+	case "allcommands":
+		user.commands = []string{"+@all"}
+	case "nocommands":
+		user.commands = []string{"-@all"}
+	case "reset":
+		*user = *newUser(user.name)
+	default:
+		return a.applyPatternRule(user, rule)
+	}
+
+	return nil
+}
+
+func (a *acl) applyPatternRule(user *aclUser, rule string) error {
+	if rule == "" {
+		return errRuleSyntax
+	}
+
+	switch rule[0] {
+	case '>':
+		if hash := hashPassword(rule[1:]); !slices.Contains(user.passwords, hash) {
+			user.passwords = append(user.passwords, hash)
+		}
+
+		user.nopass = false
+	case '<':
+		return user.removePassword(hashPassword(rule[1:]))
+	case '#':
+		hash := rule[1:]
+		if !isPasswordHash(hash) {
+			return errInvalidHash
+		}
+
+		if !slices.Contains(user.passwords, hash) {
+			user.passwords = append(user.passwords, hash)
+		}
+
+		user.nopass = false
+	case '!':
+		if !isPasswordHash(rule[1:]) {
+			return errInvalidHash
+		}
+
+		return user.removePassword(rule[1:])
+	case '~', '%':
+		pattern, err := parseKeyPattern(rule)
+		if err != nil {
+			return err
+		}
+
+		if slices.ContainsFunc(user.keys, func(p aclKeyPattern) bool { return p.pattern == "*" && p.read && p.write }) {
+			return errKeyAfterAllKeys
+		}
+
+		user.keys = append(user.keys, pattern)
+	case '&':
+		if slices.Contains(user.channels, "*") {
+			return errChannelAfterAll
+		}
+
+		if !slices.Contains(user.channels, rule[1:]) {
+			user.channels = append(user.channels, rule[1:])
+		}
+	case '+', '-':
+		return a.applyCommandRule(user, rule)
+	default:
+		return errRuleSyntax
+	}
+
+	return nil
+}
+
+func (u *aclUser) removePassword(hash string) error {
+	i := slices.Index(u.passwords, hash)
+	if i < 0 {
+		return errNoSuchPassword
+	}
+
+	u.passwords = slices.Delete(u.passwords, i, i+1)
+	return nil
+}
+
+func isPasswordHash(hash string) bool {
+	return len(hash) == sha256.Size*2 && strings.Trim(hash, "0123456789abcdef") == ""
+}
+
+// parseKeyPattern parses ~pattern, which grants reading and writing, or %R~pattern, %W~pattern
+// and %RW~pattern.
+func parseKeyPattern(rule string) (aclKeyPattern, error) {
+	if rule[0] == '~' {
+		return aclKeyPattern{pattern: rule[1:], read: true, write: true}, nil
+	}
+
+	permissions, pattern, found := strings.Cut(rule[1:], "~")
+	if !found || permissions == "" {
+		return aclKeyPattern{}, errRuleSyntax
+	}
+
+	p := aclKeyPattern{pattern: pattern}
+	for _, permission := range strings.ToUpper(permissions) {
+		switch permission {
+		case 'R':
+			p.read = true
+		case 'W':
+			p.write = true
+		default:
+			return aclKeyPattern{}, errRuleSyntax
+		}
+	}
+
+	return p, nil
+}
+
+// applyCommandRule applies +command, -command, +@category, -@category or +command|subcommand.
+// Rules that another rule supersedes are dropped, so that the rules stay short.
+// Renamed commands may be named by either name, and are kept under the original one.
+func (a *acl) applyCommandRule(user *aclUser, rule string) error {
+	target := strings.ToLower(rule[1:])
+	if category, isCategory := strings.CutPrefix(target, "@"); isCategory {
+		if category != "all" && !slices.Contains(aclCategoryNames, category) {
+			return errUnknownCommand
+		}
+	} else {
+		name, sub, hasSub := strings.Cut(target, "|")
+		entry, exists := a.commands[strings.ToUpper(name)]
+		if !exists {
+			if entry, exists = a.clientCommands[strings.ToUpper(name)]; !exists {
+				return errUnknownCommand
+			}
+
+			target = strings.ToLower(entry.moniker()) + strings.TrimPrefix(target, name)
+		}
+
+		if _, exists := entry.spec().subcommand(sub); hasSub && !exists {
+			return errUnknownCommand
+		}
+	}
+
+	rule = rule[:1] + target
+	if target == "@all" {
+		user.commands = []string{rule}
+		return nil
+	}
+
+	user.commands = slices.DeleteFunc(user.commands, func(r string) bool { return r[1:] == target })
+	user.commands = append(user.commands, rule)
+	return nil
+}
+
+func (u *aclUser) clone() *aclUser {
+	c := *u
+	c.passwords = slices.Clone(u.passwords)
+	c.commands = slices.Clone(u.commands)
+	c.keys = slices.Clone(u.keys)
+	c.channels = slices.Clone(u.channels)
+	return &c
+}
+
+// describe returns the user as a line of the ACL file, like ACL LIST.
+func (u *aclUser) describe() string {
+	fields := []string{"user", u.name, u.flags()[0]}
+	if u.nopass {
+		fields = append(fields, "nopass")
+	}
+
+	for _, hash := range u.passwords {
+		fields = append(fields, "#"+hash)
+	}
+
+	if keys := u.keyRules(); keys != "" {
+		fields = append(fields, keys)
+	}
+
+	fields = append(fields, u.channelRules(), strings.Join(u.commands, " "))
+	return strings.Join(fields, " ")
+}
+
+// flags returns the flags reported by ACL GETUSER.
+func (u *aclUser) flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+
+	return flags
+}
+
+func (u *aclUser) keyRules() string {
+	rules := make([]string, len(u.keys))
+	for i, p := range u.keys {
+		switch {
+		case p.read && p.write:
+			rules[i] = "~" + p.pattern
+		case p.read:
+			rules[i] = "%R~" + p.pattern
+		default:
+			rules[i] = "%W~" + p.pattern
+		}
+	}
+
+	return strings.Join(rules, " ")
+}
+
+func (u *aclUser) channelRules() string {
+	if len(u.channels) == 0 {
+		return "resetchannels"
+	}
+
+	rules := make([]string, len(u.channels))
+	for i, channel := range u.channels {
+		rules[i] = "&" + channel
+	}
+
+	return strings.Join(rules, " ")
+}
+
+// add logs an event, or counts it in the entry of a similar event logged recently.
+func (l *aclLog) add(reason string, context string, object string, username string, clientInfo string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for i, entry := range l.entries {
+		if entry.reason == reason && entry.context == context && entry.object == object && entry.username == username &&
+			now.Sub(entry.updated) < aclLogMergeWindow {
+			entry.count++
+			entry.updated = now
+			entry.clientInfo = clientInfo
+			l.entries = slices.Insert(slices.Delete(l.entries, i, i+1), 0, entry)
+			return
+		}
+	}
+
+	l.lastId++
+	l.entries = slices.Insert(l.entries, 0, &aclLogEntry{
+		id:         l.lastId - 1,
+		count:      1,
+		reason:     reason,
+		context:    context,
+		object:     object,
+		username:   username,
+		clientInfo: clientInfo,
+		created:    now,
+		updated:    now,
+	})
+	if maxLen := int(max(0, l.maxLen.Load())); len(l.entries) > maxLen {
+		l.entries = l.entries[:maxLen]
+	}
+}
+
+// latest returns copies of up to count entries, newest first.
+func (l *aclLog) latest(count int) []aclLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	latest := make([]aclLogEntry, min(count, len(l.entries)))
+	for i := range latest {
+		latest[i] = *l.entries[i]
+	}
+
+	return latest
+}
+
+func (l *aclLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
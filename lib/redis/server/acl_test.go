@@ -0,0 +1,251 @@
+package redisserverlib_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/logger"
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestAuth(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithRequirePass("secret"))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("requirepass", func(t *testing.T) {
+		c.expect(t, "-NOAUTH Authentication required.\r\n", "GET", "k")
+		c.expect(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", "AUTH", "wrong")
+		c.expect(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", "AUTH", "nobody", "secret")
+		c.expect(t, "+OK\r\n", "AUTH", "secret")
+		c.expect(t, "$-1\r\n", "GET", "k")
+		c.expect(t, "$7\r\ndefault\r\n", "ACL", "WHOAMI")
+		c.expect(t, "*2\r\n$11\r\nrequirepass\r\n$6\r\nsecret\r\n", "CONFIG", "GET", "requirepass")
+		if log := c.do("ACL", "LOG", "1"); !strings.Contains(log, "$6\r\nreason\r\n$4\r\nauth\r\n") || !strings.Contains(log, "$8\r\nusername\r\n$6\r\nnobody\r\n") {
+			t.Errorf("ACL LOG = %q", log)
+		}
+	})
+
+	t.Run("Removing the password", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "")
+		other := newTestClient(cp)
+		other.expect(t, "-ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?\r\n", "AUTH", "secret")
+		other.expect(t, "$-1\r\n", "GET", "k")
+	})
+
+	t.Run("Setting a password keeps connected clients authenticated", func(t *testing.T) {
+		other := newTestClient(cp)
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "x")
+		c.expect(t, "+PONG\r\n", "PING")
+		other.expect(t, "+PONG\r\n", "PING")
+		other.expect(t, "$7\r\ndefault\r\n", "ACL", "WHOAMI")
+		newTestClient(cp).expect(t, "-NOAUTH Authentication required.\r\n", "PING")
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "")
+	})
+
+	t.Run("Replicas authenticate with masterauth", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "secret")
+		port, _ := serve(t, cp)
+		r := startReplica(t, port, redisserverlib.WithMasterAuth("secret"))
+		c.expect(t, "+OK\r\n", "SET", "k", "v")
+		r.eventually(t, "$1\r\nv\r\n", "GET", "k")
+	})
+}
+
+func TestACL(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "users.acl")
+	writeFile(t, file, "user alice on >pw ~cached:* +get\n")
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(dir), redisserverlib.WithACLFile(file))
+	t.Cleanup(func() { cp.Close() })
+	admin := newTestClient(cp)
+	bob := newTestClient(cp)
+	admin.expect(t, "+OK\r\n", "ACL", "SETUSER", "bob", "on", ">pw", "%R~read:*", "%W~write:*", "~both:*", "&news.*",
+		"+@read", "+set", "-@dangerous", "+config|get", "+acl|whoami", "+publish", "+psubscribe", "+rpush", "+@transaction")
+	bob.expect(t, "+OK\r\n", "AUTH", "bob", "pw")
+
+	t.Run("Commands", func(t *testing.T) {
+		bob.expect(t, "$3\r\nbob\r\n", "ACL", "WHOAMI")
+		bob.expect(t, "-NOPERM User bob has no permissions to run the 'del' command\r\n", "DEL", "both:1")
+		bob.expect(t, "*2\r\n$10\r\nmaxclients\r\n$5\r\n10000\r\n", "CONFIG", "GET", "maxclients")
+		bob.expect(t, "-NOPERM User bob has no permissions to run the 'config|set' command\r\n", "CONFIG", "SET", "maxclients", "10")
+		bob.expect(t, "-NOPERM User bob has no permissions to run the 'acl|list' command\r\n", "ACL", "LIST")
+	})
+
+	t.Run("Peer and cluster administration", func(t *testing.T) {
+		admin.expect(t, "+OK\r\n", "ACL", "SETUSER", "low", "on", ">pw", "~allowed:*", "+@all", "-@dangerous", "-@admin")
+		low := newTestClient(cp)
+		low.expect(t, "+OK\r\n", "AUTH", "low", "pw")
+		for _, args := range [][]string{
+			{"CRDT", "APPLY", "evil", "[]"},
+			{"CRDT", "SYNC", "evil", "1", `[{"db":0,"key":"secret","value":{}}]`},
+			{"RAFT", "MESSAGE", "{}"},
+			{"CLUSTER", "ADDSLOTS", "1"},
+			{"CLUSTER", "ADDSLOTSRANGE", "1", "2"},
+			{"CLUSTER", "SETSLOT", "1", "STABLE"},
+			{"CLUSTER", "MEET", "127.0.0.1", "7000"},
+		} {
+			command := strings.ToLower(args[0] + "|" + args[1])
+			low.expect(t, "-NOPERM User low has no permissions to run the '"+command+"' command\r\n", args...)
+		}
+
+		low.expect(t, "$-1\r\n", "GET", "allowed:1")
+		admin.expect(t, ":1\r\n", "ACL", "DELUSER", "low")
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		bob.expect(t, "$-1\r\n", "GET", "read:1")
+		bob.expect(t, "-NOPERM No permissions to access a key\r\n", "GET", "write:1")
+		bob.expect(t, ":1\r\n", "RPUSH", "write:1", "a")
+		bob.expect(t, "-NOPERM No permissions to access a key\r\n", "RPUSH", "read:1", "a")
+		bob.expect(t, "-NOPERM No permissions to access a key\r\n", "SET", "write:1", "v")
+		bob.expect(t, "+OK\r\n", "SET", "both:1", "v")
+		bob.expect(t, "$1\r\nv\r\n", "GET", "both:1")
+	})
+
+	t.Run("Channels", func(t *testing.T) {
+		bob.expect(t, ":0\r\n", "PUBLISH", "news.tech", "hi")
+		bob.expect(t, "-NOPERM No permissions to access a channel\r\n", "PUBLISH", "sports", "goal")
+		bob.expect(t, "-NOPERM No permissions to access a channel\r\n", "PSUBSCRIBE", "news.t*")
+	})
+
+	t.Run("Denied commands abort transactions", func(t *testing.T) {
+		bob.expect(t, "+OK\r\n", "MULTI")
+		bob.expect(t, "-NOPERM User bob has no permissions to run the 'del' command\r\n", "DEL", "both:1")
+		bob.expect(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+
+		bob.expect(t, "+OK\r\n", "MULTI")
+		bob.expect(t, "+QUEUED\r\n", "GET", "both:1")
+		admin.expect(t, "+OK\r\n", "ACL", "SETUSER", "bob", "-get")
+		bob.expect(t, "*1\r\n-NOPERM User bob has no permissions to run the 'get' command\r\n", "EXEC")
+		admin.expect(t, "+OK\r\n", "ACL", "SETUSER", "bob", "+get")
+	})
+
+	t.Run("Introspection", func(t *testing.T) {
+		sum := sha256.Sum256([]byte("pw"))
+		hash := hex.EncodeToString(sum[:])
+		commands := "-@all +@read +set -@dangerous +config|get +acl|whoami +publish +psubscribe +rpush +@transaction +get"
+		keys := "%R~read:* %W~write:* ~both:*"
+		admin.expect(t, fmt.Sprintf("*12\r\n$5\r\nflags\r\n*1\r\n$2\r\non\r\n$9\r\npasswords\r\n*1\r\n$64\r\n%s\r\n"+
+			"$8\r\ncommands\r\n$%d\r\n%s\r\n$4\r\nkeys\r\n$%d\r\n%s\r\n$8\r\nchannels\r\n$7\r\n&news.*\r\n$9\r\nselectors\r\n*0\r\n",
+			hash, len(commands), commands, len(keys), keys), "ACL", "GETUSER", "bob")
+		admin.expect(t, "$-1\r\n", "ACL", "GETUSER", "nobody")
+		admin.expect(t, "*3\r\n$5\r\nalice\r\n$3\r\nbob\r\n$7\r\ndefault\r\n", "ACL", "USERS")
+
+		bobLine := fmt.Sprintf("user bob on #%s %s &news.* %s", hash, keys, commands)
+		defaultLine := "user default on nopass ~* &* +@all"
+		if list := admin.do("ACL", "LIST"); !strings.Contains(list, bobLine) || !strings.Contains(list, defaultLine) {
+			t.Errorf("ACL LIST = %q", list)
+		}
+
+		if cat := admin.do("ACL", "CAT"); !strings.HasPrefix(cat, "*21\r\n$8\r\nkeyspace\r\n") {
+			t.Errorf("ACL CAT = %q", cat)
+		}
+
+		admin.expect(t, "*3\r\n$4\r\nxadd\r\n$6\r\nxrange\r\n$5\r\nxread\r\n", "ACL", "CAT", "stream")
+		admin.expect(t, "-ERR Unknown category 'nosuch'\r\n", "ACL", "CAT", "nosuch")
+	})
+
+	t.Run("LOG", func(t *testing.T) {
+		log := admin.do("ACL", "LOG", "1")
+		for _, field := range []string{
+			"$5\r\ncount\r\n:1\r\n", "$6\r\nreason\r\n$7\r\ncommand\r\n", "$7\r\ncontext\r\n$5\r\nmulti\r\n",
+			"$6\r\nobject\r\n$3\r\nget\r\n", "$8\r\nusername\r\n$3\r\nbob\r\n",
+		} {
+			if !strings.Contains(log, field) {
+				t.Errorf("ACL LOG 1 = %q; Expected %q", log, field)
+			}
+		}
+
+		admin.expect(t, "+OK\r\n", "ACL", "LOG", "RESET")
+		admin.expect(t, "*0\r\n", "ACL", "LOG")
+	})
+
+	t.Run("DRYRUN", func(t *testing.T) {
+		admin.expect(t, "+OK\r\n", "ACL", "DRYRUN", "bob", "RPUSH", "write:1", "a")
+		admin.expect(t, "$52\r\nUser bob has no permissions to run the 'del' command\r\n", "ACL", "DRYRUN", "bob", "DEL", "k")
+		admin.expect(t, "$55\r\nUser bob has no permissions to access the 'write:1' key\r\n", "ACL", "DRYRUN", "bob", "GET", "write:1")
+		admin.expect(t, "$58\r\nUser bob has no permissions to access the 'sports' channel\r\n", "ACL", "DRYRUN", "bob", "PUBLISH", "sports", "x")
+		admin.expect(t, "-ERR User 'nobody' not found\r\n", "ACL", "DRYRUN", "nobody", "GET", "k")
+		admin.expect(t, "-ERR Command 'nosuch' not found\r\n", "ACL", "DRYRUN", "bob", "nosuch")
+		admin.expect(t, "*0\r\n", "ACL", "LOG")
+	})
+
+	t.Run("GENPASS", func(t *testing.T) {
+		if pass := admin.do("ACL", "GENPASS"); !strings.HasPrefix(pass, "$64\r\n") {
+			t.Errorf("ACL GENPASS = %q", pass)
+		}
+
+		if pass := admin.do("ACL", "GENPASS", "5"); !strings.HasPrefix(pass, "$2\r\n") {
+			t.Errorf("ACL GENPASS 5 = %q", pass)
+		}
+
+		admin.expect(t, "-ERR ACL GENPASS argument must be the number of bits for the output password, a positive number up to 4096\r\n", "ACL", "GENPASS", "0")
+	})
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		admin.expect(t, "-ERR Error in ACL SETUSER modifier '+nosuch': Unknown command or category name in ACL\r\n", "ACL", "SETUSER", "carol", "on", "+nosuch")
+		admin.expect(t, "-ERR Error in ACL SETUSER modifier '%X~k': Syntax error\r\n", "ACL", "SETUSER", "bob", "off", "%X~k")
+		admin.expect(t, "$-1\r\n", "ACL", "GETUSER", "carol")
+		bob.expect(t, "$3\r\nbob\r\n", "ACL", "WHOAMI")
+	})
+
+	t.Run("ACL file", func(t *testing.T) {
+		alice := newTestClient(cp)
+		alice.expect(t, "+OK\r\n", "AUTH", "alice", "pw")
+		alice.expect(t, "$-1\r\n", "GET", "cached:1")
+		alice.expect(t, "-NOPERM User alice has no permissions to run the 'set' command\r\n", "SET", "cached:1", "v")
+
+		admin.expect(t, "+OK\r\n", "ACL", "SAVE")
+		if content, err := os.ReadFile(file); err != nil || !strings.Contains(string(content), "user bob on #") {
+			t.Errorf("ACL file = %q, %v", content, err)
+		}
+
+		writeFile(t, file, "user carol on nopass ~* &* +@all\nuser dave +nosuch\n")
+		if reply := admin.do("ACL", "LOAD"); !strings.HasPrefix(reply, "-ERR "+file+":2: Error in ACL SETUSER modifier '+nosuch'") {
+			t.Errorf("ACL LOAD = %q", reply)
+		}
+
+		admin.expect(t, "*3\r\n$5\r\nalice\r\n$3\r\nbob\r\n$7\r\ndefault\r\n", "ACL", "USERS")
+		writeFile(t, file, "user carol on nopass ~* &* +@all\n")
+		admin.expect(t, "+OK\r\n", "ACL", "LOAD")
+		admin.expect(t, "*2\r\n$5\r\ncarol\r\n$7\r\ndefault\r\n", "ACL", "USERS")
+		select {
+		case <-alice.ctx.Done():
+		case <-time.After(time.Second):
+			t.Error("The client of a deleted user was not disconnected")
+		}
+	})
+
+	t.Run("DELUSER", func(t *testing.T) {
+		admin.expect(t, ":1\r\n", "ACL", "DELUSER", "carol", "nobody")
+		admin.expect(t, "-ERR The 'default' user cannot be removed\r\n", "ACL", "DELUSER", "default")
+	})
+}
+
+func TestProtectedMode(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	t.Cleanup(func() { cp.Close() })
+	local := newTestClient(cp)
+	ctx, out := cp.ConnectClient(context.WithValue(context.Background(), logger.ClientKey, "10.1.2.3:50000"))
+	remote := testClient{ctx: ctx, cp: cp, out: out}
+
+	if reply := remote.do("PING"); !strings.HasPrefix(reply, "-DENIED Redis is running in protected mode") {
+		t.Errorf("PING = %q", reply)
+	}
+
+	local.expect(t, "+PONG\r\n", "PING")
+	local.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "pw")
+	ctx, out = cp.ConnectClient(context.WithValue(context.Background(), logger.ClientKey, "10.1.2.3:50001"))
+	remote = testClient{ctx: ctx, cp: cp, out: out}
+	remote.expect(t, "-NOAUTH Authentication required.\r\n", "PING")
+	remote.expect(t, "+OK\r\n", "AUTH", "pw")
+	remote.expect(t, "+PONG\r\n", "PING")
+}
@@ -0,0 +1,323 @@
+package redisserverlib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	aclCmd struct {
+		*acl
+	}
+)
+
+const (
+	aclLogDefaultCount = 10
+	aclGenPassBits     = 256
+)
+
+func (c aclCmd) moniker() string {
+	return "ACL"
+}
+
+func (c aclCmd) spec() commandSpec {
+	return commandSpec{
+		arity:   -2,
+		group:   "server",
+		summary: "A container for Access List Control commands.",
+		since:   "6.0.0",
+		subcommands: []commandSpec{
+			{
+				name: "cat", arity: -2, summary: "Lists the ACL categories, or the commands inside a category.", since: "6.0.0",
+				complexity: "O(1) since the categories and commands are a fixed set.",
+				args:       []commandArg{{name: "category", typ: argString, optional: true}},
+			},
+			{
+				name: "deluser", arity: -3, flags: flagAdmin, summary: "Deletes ACL users, and terminates their connections.", since: "6.0.0",
+				complexity: "O(1) amortized time considering the typical user.",
+				args:       []commandArg{{name: "username", typ: argString, multiple: true}},
+			},
+			{
+				name: "dryrun", arity: -4, flags: flagAdmin, since: "7.0.0", complexity: "O(1).",
+				summary: "Simulates the execution of a command by a user, without executing the command.",
+				args: []commandArg{
+					{name: "username", typ: argString},
+					{name: "command", typ: argString},
+					{name: "arg", typ: argString, optional: true, multiple: true},
+				},
+			},
+			{
+				name: "genpass", arity: -2, since: "6.0.0", complexity: "O(1)",
+				summary: "Generates a pseudorandom, secure password that can be used to identify ACL users.",
+				args:    []commandArg{{name: "bits", typ: argInteger, optional: true}},
+			},
+			{
+				name: "getuser", arity: 3, flags: flagAdmin, summary: "Lists the ACL rules of a user.", since: "6.0.0",
+				complexity: "O(N). Where N is the number of password, command and pattern rules that the user has.",
+				args:       []commandArg{{name: "username", typ: argString}},
+			},
+			{
+				name: "list", arity: 2, flags: flagAdmin, summary: "Dumps the effective rules in ACL file format.", since: "6.0.0",
+				complexity: "O(N). Where N is the number of configured users.",
+			},
+			{
+				name: "load", arity: 2, flags: flagAdmin, summary: "Reloads the rules from the configured ACL file.", since: "6.0.0",
+				complexity: "O(N). Where N is the number of configured users.",
+			},
+			{
+				name: "log", arity: -2, flags: flagAdmin, summary: "Lists recent security events generated due to ACL rules.", since: "6.0.0",
+				complexity: "O(N) with N being the number of entries shown.",
+				args: []commandArg{{name: "operation", typ: argOneOf, optional: true, args: []commandArg{
+					{name: "count", typ: argInteger},
+					{name: "reset", typ: argPureToken, token: "RESET"},
+				}}},
+			},
+			{
+				name: "save", arity: 2, flags: flagAdmin, summary: "Saves the effective ACL rules in the configured ACL file.", since: "6.0.0",
+				complexity: "O(N). Where N is the number of configured users.",
+			},
+			{
+				name: "setuser", arity: -3, flags: flagAdmin, summary: "Creates and modifies an ACL user and its rules.", since: "6.0.0",
+				complexity: "O(N). Where N is the number of rules provided.",
+				args: []commandArg{
+					{name: "username", typ: argString},
+					{name: "rule", typ: argString, optional: true, multiple: true},
+				},
+			},
+			{
+				name: "users", arity: 2, flags: flagAdmin, summary: "Lists all ACL users.", since: "6.0.0",
+				complexity: "O(N). Where N is the number of configured users.",
+			},
+			{name: "whoami", arity: 2, summary: "Returns the authenticated username of the current connection.", since: "6.0.0", complexity: "O(1)"},
+		},
+	}
+}
+
+func (c aclCmd) getUsage() string {
+	return `
+usage:
+	ACL SETUSER username [rule [rule ...]]
+	ACL GETUSER username
+	ACL DELUSER username [username ...]
+	ACL LIST
+	ACL USERS
+	ACL WHOAMI
+	ACL CAT [category]
+	ACL LOG [count | RESET]
+	ACL DRYRUN username command [arg [arg ...]]
+	ACL GENPASS [bits]
+	ACL LOAD
+	ACL SAVE
+summary:
+	SETUSER creates a user, or modifies it, with rules applied in order:
+		on, off: enables or disables authenticating as the user.
+		>password, <password: adds or removes a password. #hash and !hash do the same with the SHA-256 hash of the password.
+		nopass: lets any password authenticate the user. resetpass removes every password and nopass.
+		~pattern: allows reading and writing the keys matching the glob-style pattern. %R~pattern only allows reading
+		them, %W~pattern only writing them. allkeys is ~*, and resetkeys forgets the key patterns.
+		&pattern: allows the channels matching the pattern. allchannels is &*, and resetchannels forgets the channel patterns.
+		+command, -command: allows or forbids a command, or a single subcommand with +command|subcommand.
+		+@category, -@category: allows or forbids the commands of an ACL category. allcommands is +@all, nocommands is -@all.
+		reset: removes every password, key pattern, channel pattern and command, and disables the user.
+	GETUSER returns the rules of a user. DELUSER deletes users and disconnects their clients. The default user cannot be deleted.
+	LIST returns every user in the ACL file format, USERS their names. WHOAMI returns the user the connection is authenticated as.
+	CAT lists the ACL categories, or the commands of a category.
+	LOG lists the latest denied commands and failed authentications, 10 by default. RESET clears the log.
+	DRYRUN tells whether a user may run a command, without running it.
+	GENPASS returns a random password of 256 bits, or of the given number of bits, in hex.
+	LOAD replaces the users with those of the ACL file, SAVE writes them to it.
+`
+}
+
+func (c aclCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR ACL requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "SETUSER" && len(params) >= 3:
+		if err := c.setUser(params[2].Val, argsOf(params[3:])); err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "GETUSER" && len(params) == 3:
+		return c.executeGetUser(params[2].Val)
+	case subcommand == "DELUSER" && len(params) >= 3:
+		deleted, err := c.deleteUsers(argsOf(params[2:]))
+		if err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.Integer{Val: int64(deleted)}
+	case subcommand == "LIST" && len(params) == 2:
+		return resptypes.ToBulkStringArray(c.list())
+	case subcommand == "USERS" && len(params) == 2:
+		return resptypes.ToBulkStringArray(c.usernames())
+	case subcommand == "WHOAMI" && len(params) == 2:
+		return resptypes.NewBulkString(c.whoami(clientFromContext(ctx)))
+	case subcommand == "CAT" && len(params) <= 3:
+		if len(params) == 2 {
+			return resptypes.ToBulkStringArray(aclCategoryNames)
+		}
+
+		return c.executeCat(strings.ToLower(params[2].Val))
+	case subcommand == "LOG" && len(params) <= 3:
+		return c.executeLog(params[2:])
+	case subcommand == "DRYRUN" && len(params) >= 4:
+		return c.executeDryRun(params[2].Val, params[3:])
+	case subcommand == "GENPASS" && len(params) <= 3:
+		return c.executeGenPass(params[2:])
+	case subcommand == "LOAD" && len(params) == 2:
+		if err := c.load(); err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "SAVE" && len(params) == 2:
+		if err := c.save(); err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown ACL subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+func (c aclCmd) executeGetUser(name string) commandResult {
+	user, exists := c.getUser(name)
+	if !exists {
+		return resptypes.NullBulkString
+	}
+
+	channels := ""
+	if len(user.channels) > 0 {
+		channels = user.channelRules()
+	}
+
+	return resptypes.Array[resptypes.RespSerializable]{
+		resptypes.NewBulkString("flags"), resptypes.ToBulkStringArray(user.flags()),
+		resptypes.NewBulkString("passwords"), resptypes.ToBulkStringArray(user.passwords),
+		resptypes.NewBulkString("commands"), resptypes.NewBulkString(strings.Join(user.commands, " ")),
+		resptypes.NewBulkString("keys"), resptypes.NewBulkString(user.keyRules()),
+		resptypes.NewBulkString("channels"), resptypes.NewBulkString(channels),
+		resptypes.NewBulkString("selectors"), resptypes.Array[resptypes.RespSerializable]{},
+	}
+}
+
+// executeCat lists the commands and subcommands of category.
+func (c aclCmd) executeCat(category string) commandResult {
+	if !slices.Contains(aclCategoryNames, category) {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown category '%s'", category)}
+	}
+
+	names := []string{}
+	for moniker, entry := range c.commands {
+		name := strings.ToLower(moniker)
+		spec := entry.spec()
+		if slices.Contains(spec.aclCategories(), category) {
+			names = append(names, name)
+		}
+
+		for _, sub := range spec.subcommands {
+			if sub = sub.withParent(spec, name); slices.Contains(sub.aclCategories(), category) {
+				names = append(names, sub.name)
+			}
+		}
+	}
+
+	slices.Sort(names)
+	return resptypes.ToBulkStringArray(slices.Compact(names))
+}
+
+func (c aclCmd) executeLog(args commandParams) commandResult {
+	count := aclLogDefaultCount
+	if len(args) == 1 {
+		if strings.EqualFold(args[0].Val, "RESET") {
+			c.log.reset()
+			return resptypes.SimpleString{Val: "OK"}
+		}
+
+		n, err := strconv.Atoi(args[0].Val)
+		if err != nil || n < 0 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR value is out of range, must be positive")}
+		}
+
+		count = n
+	}
+
+	now := time.Now()
+	entries := c.log.latest(count)
+	result := make(resptypes.Array[resptypes.RespSerializable], len(entries))
+	for i, entry := range entries {
+		result[i] = resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("count"), resptypes.Integer{Val: int64(entry.count)},
+			resptypes.NewBulkString("reason"), resptypes.NewBulkString(entry.reason),
+			resptypes.NewBulkString("context"), resptypes.NewBulkString(entry.context),
+			resptypes.NewBulkString("object"), resptypes.NewBulkString(entry.object),
+			resptypes.NewBulkString("username"), resptypes.NewBulkString(entry.username),
+			resptypes.NewBulkString("age-seconds"), resptypes.NewBulkString(strconv.FormatFloat(now.Sub(entry.created).Seconds(), 'f', 3, 64)),
+			resptypes.NewBulkString("client-info"), resptypes.NewBulkString(entry.clientInfo),
+			resptypes.NewBulkString("entry-id"), resptypes.Integer{Val: entry.id},
+			resptypes.NewBulkString("timestamp-created"), resptypes.Integer{Val: entry.created.UnixMilli()},
+			resptypes.NewBulkString("timestamp-last-updated"), resptypes.Integer{Val: entry.updated.UnixMilli()},
+		}
+	}
+
+	return result
+}
+
+// executeDryRun tells whether the user named name may run call, without logging a denial.
+func (c aclCmd) executeDryRun(name string, call commandParams) commandResult {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	user, exists := c.users[name]
+	if !exists {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR User '%s' not found", name)}
+	}
+
+	entry, exists := c.commands[strings.ToUpper(call[0].Val)]
+	if !exists {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Command '%s' not found", call[0].Val)}
+	}
+
+	if err := entry.spec().checkArity(call); err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	denial := c.denied(user, entry, call)
+	switch {
+	case denial == nil:
+		return resptypes.SimpleString{Val: "OK"}
+	case denial.reason == aclReasonCommand:
+		return resptypes.NewBulkString(fmt.Sprintf("User %s has no permissions to run the '%s' command", name, denial.object))
+	default:
+		return resptypes.NewBulkString(fmt.Sprintf("User %s has no permissions to access the '%s' %s", name, denial.object, denial.reason))
+	}
+}
+
+func (c aclCmd) executeGenPass(args commandParams) commandResult {
+	bits := aclGenPassBits
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0].Val)
+		if err != nil || n <= 0 || n > 4096 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR ACL GENPASS argument must be the number of bits for the output password, a positive number up to 4096")}
+		}
+
+		bits = n
+	}
+
+	random := make([]byte, (bits+7)/8)
+	rand.Read(random)
+	return resptypes.NewBulkString(hex.EncodeToString(random)[:(bits+3)/4])
+}
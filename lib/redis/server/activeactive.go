@@ -0,0 +1,579 @@
+package redisserverlib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// activeActive lets every instance of a group accept writes. The writes of an instance are
+	// recorded as CRDT operations, batched per request, and sent to each peer in order over a
+	// connection to its regular port. Peers merge them into their own state, so all instances
+	// converge once they received the same writes, whatever the order.
+	//
+	// Peers that missed batches no longer in the log, e.g. after a restart, get the whole state
+	// instead. The state is not persisted: a restarted instance joins with a new ID and
+	// recovers the dataset from its peers.
+	activeActive struct {
+		r     *redisCommandProcessor
+		id    string
+		links []*crdtLink
+
+		mu sync.Mutex
+		// A hybrid logical clock in microseconds, moved past every timestamp received so that
+		// a write always wins over the writes its instance had seen
+		clock uint64
+		// The last list element created here
+		elementSeq uint64
+		// The state of every key, by database
+		values []map[string]*crdtValue
+		// The batches not acknowledged by every peer yet, up to the last one
+		log []crdtBatch
+		seq uint64
+		// The last batch applied from each instance
+		applied map[string]uint64
+		// Closed and replaced whenever a batch is logged
+		logged chan struct{}
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+
+	crdtBatch struct {
+		Seq    uint64      `json:"seq"`
+		Deltas []crdtDelta `json:"deltas"`
+	}
+
+	// crdtDelta is merged into the state of a key.
+	crdtDelta struct {
+		Db    int        `json:"db"`
+		Key   string     `json:"key"`
+		Value *crdtValue `json:"value"`
+	}
+
+	// crdtLink sends the batches of the instance to a peer.
+	crdtLink struct {
+		addr string
+		// Guarded by activeActive.mu
+		acked     uint64
+		connected bool
+	}
+)
+
+const (
+	// Peers lagging by more batches get the whole state
+	crdtLogLimit       = 10000
+	crdtRetryInterval  = 100 * time.Millisecond
+	crdtRequestTimeout = 5 * time.Second
+	// How often an idle link checks that the peer still has what it was sent
+	crdtHeartbeatInterval = time.Second
+)
+
+var errActiveActiveDisabled = errors.New("ERR This instance has active-active replication disabled")
+
+func newActiveActive(r *redisCommandProcessor, options processorOptions) *activeActive {
+	ctx, cancel := context.WithCancel(context.Background())
+	aa := &activeActive{
+		r:       r,
+		id:      newNodeId(),
+		values:  make([]map[string]*crdtValue, r.dbs.Len()),
+		applied: make(map[string]uint64),
+		logged:  make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	for i := range aa.values {
+		aa.values[i] = make(map[string]*crdtValue)
+	}
+
+	for _, addr := range options.activeActivePeers {
+		aa.links = append(aa.links, &crdtLink{addr: addr})
+	}
+
+	return aa
+}
+
+func (aa *activeActive) start() {
+	for _, link := range aa.links {
+		aa.wg.Go(func() { aa.runLink(link) })
+	}
+}
+
+func (aa *activeActive) close() {
+	aa.cancel()
+	aa.wg.Wait()
+}
+
+// check rejects the commands whose effects cannot be merged.
+func (aa *activeActive) check(commandName string, entry commandDefinition) error {
+	switch commandName {
+	case "MOVE", "SWAPDB", "RESTORE", "RESTORE-ASKING", "MIGRATE", "REPLICAOF":
+		return fmt.Errorf("ERR %s is not supported in active-active mode", commandName)
+	}
+
+	// Commands added by extensions modify values of their own, which have no CRDT
+	if rc, ok := entry.(registeredCommand); ok && flagsOf(entry)&flagWrite != 0 {
+		if _, extension := rc.commandDefinition.(extensionCommand); extension {
+			return fmt.Errorf("ERR %s is not supported in active-active mode", commandName)
+		}
+	}
+
+	return nil
+}
+
+func (aa *activeActive) tickNoLock() uint64 {
+	aa.clock = max(uint64(time.Now().UnixMicro()), aa.clock+1)
+	return aa.clock
+}
+
+func (aa *activeActive) valueNoLock(db int, key string) *crdtValue {
+	v, exists := aa.values[db][key]
+	if !exists {
+		v = &crdtValue{}
+		aa.values[db][key] = v
+	}
+
+	return v
+}
+
+// record turns the write commands of a request into a batch for the peers, and returns the
+// commands to propagate, including those that align the dataset with the merged state.
+func (aa *activeActive) record(batch []propagatedCommand) []propagatedCommand {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	var deltas []crdtDelta
+	var aligned []propagatedCommand
+	for _, cmd := range batch {
+		for _, delta := range aa.deltasNoLock(cmd) {
+			v := aa.valueNoLock(delta.Db, delta.Key)
+			v.join(delta.Value)
+			deltas = append(deltas, delta)
+
+			// Lists emptied by LPOP are removed, and counters lose their expiry
+			if kind := v.kind(time.Now()); kind == crdtNone || kind == crdtCounterKind {
+				aligned = append(aligned, aa.storeNoLock(delta.Db, delta.Key, v)...)
+			}
+		}
+	}
+
+	if len(deltas) == 0 {
+		return batch
+	}
+
+	aa.seq++
+	aa.log = append(aa.log, crdtBatch{Seq: aa.seq, Deltas: deltas})
+	if len(aa.log) > crdtLogLimit {
+		aa.log = slices.Delete(aa.log, 0, len(aa.log)-crdtLogLimit)
+	}
+
+	close(aa.logged)
+	aa.logged = make(chan struct{})
+	return append(slices.Clip(batch), aligned...)
+}
+
+// deltasNoLock returns the operations of a write command that already ran.
+func (aa *activeActive) deltasNoLock(cmd propagatedCommand) []crdtDelta {
+	args, db := cmd.args, cmd.db
+	ts := aa.tickNoLock()
+	delta := func(key string, value *crdtValue) []crdtDelta {
+		return []crdtDelta{{Db: db, Key: key, Value: value}}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "SET":
+		key := args[1]
+		value := aa.valueNoLock(db, key).removal(ts, aa.id, crdtRegisterKind)
+		value.Register = crdtRegister{Ts: ts, Origin: aa.id, Value: args[2]}
+		if len(args) == 5 {
+			value.Register.ExpiresAt, _ = strconv.ParseInt(args[4], 10, 64)
+		}
+
+		return delta(key, value)
+	case "DEL":
+		var deltas []crdtDelta
+		for _, key := range args[1:] {
+			if v, exists := aa.values[db][key]; exists {
+				deltas = append(deltas, delta(key, v.removal(ts, aa.id, crdtNone))...)
+			}
+		}
+
+		return deltas
+	case "FLUSHDB", "FLUSHALL":
+		dbs := []int{db}
+		if strings.EqualFold(args[0], "FLUSHALL") {
+			dbs = nil
+			for i := range aa.values {
+				dbs = append(dbs, i)
+			}
+		}
+
+		var deltas []crdtDelta
+		for _, db := range dbs {
+			for key, v := range aa.values[db] {
+				deltas = append(deltas, crdtDelta{Db: db, Key: key, Value: v.removal(ts, aa.id, crdtNone)})
+			}
+		}
+
+		return deltas
+	case "INCRBY":
+		key := args[1]
+		v := aa.valueNoLock(db, key)
+		dsVal, exists := aa.r.dbs.DB(db).Get(key)
+		if !exists || dsVal.Type != redistypes.TypeString {
+			return nil
+		}
+
+		target, _ := strconv.ParseInt(dsVal.String.Val, 10, 64)
+		current, share := int64(0), v.Counter[aa.id]
+		value := &crdtValue{}
+		if v.kind(time.Now()) == crdtCounterKind {
+			current = v.counterValue()
+		} else {
+			// INCRBY on a string turns it into a counter starting from its value
+			value = v.removal(ts, aa.id, crdtNone)
+			share.BaseP, share.BaseN = share.P, share.N
+		}
+
+		if diff := target - current; diff >= 0 {
+			share.P += diff
+		} else {
+			share.N -= diff
+		}
+
+		if value.Counter == nil {
+			value.Counter = make(map[string]crdtShare)
+		}
+
+		value.Counter[aa.id], value.CounterTs = share, ts
+		return delta(key, value)
+	case "LPUSH", "RPUSH":
+		key := args[1]
+		elements := aa.valueNoLock(db, key).elements()
+		pos := int64(0)
+		step := int64(1)
+		if strings.EqualFold(args[0], "LPUSH") {
+			step = -1
+			if len(elements) > 0 {
+				pos = elements[0].Pos
+			}
+		} else if len(elements) > 0 {
+			pos = elements[len(elements)-1].Pos
+		}
+
+		value := &crdtValue{List: make(map[string]crdtElement), ListTs: ts}
+		for _, element := range args[2:] {
+			aa.elementSeq++
+			pos += step
+			value.List[elementDot(aa.id, aa.elementSeq)] = crdtElement{Pos: pos, Origin: aa.id, Seq: aa.elementSeq, Value: element}
+		}
+
+		return delta(key, value)
+	case "LPOP":
+		key := args[1]
+		count := 1
+		if len(args) == 3 {
+			count, _ = strconv.Atoi(args[2])
+		}
+
+		elements := aa.valueNoLock(db, key).elements()
+		value := &crdtValue{}
+		for _, element := range elements[:min(count, len(elements))] {
+			value.addRemoved(elementDot(element.Origin, element.Seq))
+		}
+
+		return delta(key, value)
+	case "XADD":
+		key := args[1]
+		var id redistypes.StreamEntryId
+		if _, err := fmt.Sscanf(args[2], "%d-%d", &id.Ms, &id.Seq); err != nil {
+			return nil
+		}
+
+		entry := crdtEntry{Id: id, Origin: aa.id, Fields: args[3:]}
+		return delta(key, &crdtValue{Stream: map[string]crdtEntry{entryDot(id, aa.id): entry}, StreamTs: ts})
+	default:
+		return nil
+	}
+}
+
+// storeNoLock aligns the dataset with the state of a key, and returns the commands
+// reproducing the change.
+func (aa *activeActive) storeNoLock(db int, key string, v *crdtValue) []propagatedCommand {
+	ds := aa.r.dbs.DB(db)
+	value, ttl, exists := v.storeValue(time.Now())
+	current, found := ds.Get(key)
+	switch {
+	case !exists || ttl < 0:
+		if !found {
+			return nil
+		}
+
+		ds.Delete(key)
+	case value.Type == redistypes.TypeList && found && current.Type == redistypes.TypeList:
+		// Keeps waking up BLPOP waiting on the list
+		current.List.PopFront(current.List.Len())
+		current.List.PushBack(value.List.GetRange(0, -1)...)
+		ds.Touch(key)
+	default:
+		ds.Set(key, value, ttl)
+		ds.Touch(key)
+	}
+
+	aa.r.persistence.dirty.Add(1)
+	aa.r.tracking.invalidate(key, nil)
+	commands := []propagatedCommand{{db: db, args: []string{"DEL", key}}}
+	if !exists || ttl < 0 {
+		return commands
+	}
+
+	switch value.Type {
+	case redistypes.TypeString:
+		args := []string{"SET", key, value.String.Val}
+		if ttl > 0 {
+			args = append(args, "PXAT", strconv.FormatInt(v.Register.ExpiresAt, 10))
+		}
+
+		commands = append(commands, propagatedCommand{db: db, args: args})
+	case redistypes.TypeList:
+		args := []string{"RPUSH", key}
+		for _, element := range value.List.GetRange(0, -1) {
+			args = append(args, element.Val)
+		}
+
+		commands = append(commands, propagatedCommand{db: db, args: args})
+	case redistypes.TypeStream:
+		value.Stream.ForEach(func(id redistypes.StreamEntryId, fields resptypes.Array[resptypes.BulkString]) {
+			args := []string{"XADD", key, fmt.Sprintf("%d-%d", id.Ms, id.Seq)}
+			for _, field := range fields {
+				args = append(args, field.Val)
+			}
+
+			commands = append(commands, propagatedCommand{db: db, args: args})
+		})
+	}
+
+	return commands
+}
+
+// hello returns the last batch applied from origin.
+func (aa *activeActive) hello(origin string) uint64 {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	return aa.applied[origin]
+}
+
+// apply merges a batch from origin. The caller must hold the transaction lock exclusively.
+func (aa *activeActive) apply(origin string, batch crdtBatch) error {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	if batch.Seq <= aa.applied[origin] {
+		// Sent again after a lost acknowledgement
+		return nil
+	}
+
+	if batch.Seq != aa.applied[origin]+1 {
+		return fmt.Errorf("ERR Expected batch %d from %s, got %d", aa.applied[origin]+1, origin, batch.Seq)
+	}
+
+	aa.mergeNoLock(batch.Deltas)
+	aa.applied[origin] = batch.Seq
+	return nil
+}
+
+// sync merges the whole state of origin, which includes its batches up to seq.
+func (aa *activeActive) sync(origin string, seq uint64, deltas []crdtDelta) {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	aa.mergeNoLock(deltas)
+	aa.applied[origin] = max(aa.applied[origin], seq)
+}
+
+func (aa *activeActive) mergeNoLock(deltas []crdtDelta) {
+	var batch []propagatedCommand
+	for _, delta := range deltas {
+		if delta.Db < 0 || delta.Db >= len(aa.values) || delta.Value == nil {
+			continue
+		}
+
+		v := aa.valueNoLock(delta.Db, delta.Key)
+		v.join(delta.Value)
+		aa.clock = max(aa.clock, delta.Value.maxTs())
+		batch = append(batch, aa.storeNoLock(delta.Db, delta.Key, v)...)
+	}
+
+	aa.r.propagator.feed(batch)
+}
+
+// runLink sends the batches to a peer until the instance closes, reconnecting as needed.
+func (aa *activeActive) runLink(link *crdtLink) {
+	for {
+		err := aa.serveLink(link)
+		aa.mu.Lock()
+		link.connected = false
+		aa.mu.Unlock()
+		slog.Debug("Active-active link down", "peer", link.addr, "error", err)
+
+		select {
+		case <-aa.ctx.Done():
+			return
+		case <-time.After(crdtRetryInterval):
+		}
+	}
+}
+
+func (aa *activeActive) serveLink(link *crdtLink) error {
+	conn, err := dialSentinelConn(aa.ctx, link.addr, crdtRequestTimeout)
+	if err != nil {
+		return err
+	}
+
+	defer conn.close()
+	stop := context.AfterFunc(aa.ctx, conn.close)
+	defer stop()
+	if auth := authCommand(aa.r.options); auth != nil {
+		reply, err := conn.do(crdtRequestTimeout, auth...)
+		if err != nil {
+			return err
+		}
+
+		if failed, ok := reply.(resptypes.SimpleError); ok {
+			return failed.Val
+		}
+	}
+
+	sent, err := aa.sendHello(conn)
+	if err != nil {
+		return err
+	}
+
+	aa.mu.Lock()
+	link.connected = true
+	aa.mu.Unlock()
+	slog.Info("Active-active link up", "peer", link.addr)
+
+	for {
+		args, next, logged := aa.nextRequest(sent)
+		if args == nil {
+			select {
+			case <-aa.ctx.Done():
+				return aa.ctx.Err()
+			case <-logged:
+			case <-time.After(crdtHeartbeatInterval):
+				// A peer that restarted has lost the batches it was sent
+				if sent, err = aa.sendHello(conn); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		reply, err := conn.do(crdtRequestTimeout, args...)
+		if err != nil {
+			return err
+		}
+
+		if failed, ok := reply.(resptypes.SimpleError); ok {
+			return failed.Val
+		}
+
+		sent = next
+		aa.acknowledge(link, sent)
+	}
+}
+
+// sendHello introduces the instance to a peer, and returns the last batch the peer has from it.
+func (aa *activeActive) sendHello(conn *sentinelConn) (uint64, error) {
+	reply, err := conn.do(crdtRequestTimeout, "CRDT", "HELLO", aa.id)
+	if err != nil {
+		return 0, err
+	}
+
+	applied, ok := reply.(resptypes.Integer)
+	if !ok || applied.Val < 0 {
+		return 0, fmt.Errorf("unexpected reply to CRDT HELLO: %s", reply.ToRespString())
+	}
+
+	return uint64(applied.Val), nil
+}
+
+// nextRequest returns the request bringing a peer that has the batches up to sent further,
+// and the last batch it then has. It returns no request when the peer is up to date.
+func (aa *activeActive) nextRequest(sent uint64) ([]string, uint64, chan struct{}) {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	if sent >= aa.seq {
+		return nil, sent, aa.logged
+	}
+
+	first := aa.seq + 1 - uint64(len(aa.log))
+	if sent+1 < first {
+		var deltas []crdtDelta
+		for db, values := range aa.values {
+			for key, v := range values {
+				deltas = append(deltas, crdtDelta{Db: db, Key: key, Value: v})
+			}
+		}
+
+		payload, _ := json.Marshal(deltas)
+		return []string{"CRDT", "SYNC", aa.id, strconv.FormatUint(aa.seq, 10), string(payload)}, aa.seq, aa.logged
+	}
+
+	batch := aa.log[sent+1-first]
+	payload, _ := json.Marshal(batch)
+	return []string{"CRDT", "APPLY", aa.id, string(payload)}, batch.Seq, aa.logged
+}
+
+// acknowledge records that a peer has the batches up to seq, and drops the batches every peer has.
+func (aa *activeActive) acknowledge(link *crdtLink, seq uint64) {
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	link.acked = seq
+	minAcked := aa.seq
+	for _, other := range aa.links {
+		minAcked = min(minAcked, other.acked)
+	}
+
+	first := aa.seq + 1 - uint64(len(aa.log))
+	if minAcked >= first {
+		aa.log = slices.Delete(aa.log, 0, int(minAcked-first+1))
+	}
+}
+
+func (aa *activeActive) info() []string {
+	if aa == nil {
+		return []string{"crdt_enabled:0"}
+	}
+
+	aa.mu.Lock()
+	defer aa.mu.Unlock()
+	fields := []string{
+		"crdt_enabled:1",
+		"crdt_id:" + aa.id,
+		fmt.Sprintf("crdt_seq:%d", aa.seq),
+		fmt.Sprintf("crdt_log_batches:%d", len(aa.log)),
+		fmt.Sprintf("crdt_peers:%d", len(aa.links)),
+	}
+	for i, link := range aa.links {
+		status := "down"
+		if link.connected {
+			status = "up"
+		}
+
+		fields = append(fields, fmt.Sprintf("crdt_peer%d:addr=%s,status=%s,acked=%d,lag=%d", i, link.addr, status, link.acked, aa.seq-link.acked))
+	}
+
+	return fields
+}
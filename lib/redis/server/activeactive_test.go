@@ -0,0 +1,271 @@
+package redisserverlib_test
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+type (
+	// linkProxy forwards the connections of one instance to another, and can cut them off
+	// to partition the network.
+	linkProxy struct {
+		listener net.Listener
+		target   string
+
+		mu    sync.Mutex
+		cut   bool
+		conns map[net.Conn]struct{}
+	}
+
+	activeActiveGroup struct {
+		t         *testing.T
+		listeners []net.Listener
+		proxies   map[[2]int]*linkProxy
+		instances []testClient
+		closers   []func()
+	}
+)
+
+func startProxy(t *testing.T, target string) *linkProxy {
+	p := &linkProxy{listener: listen(t), target: target, conns: make(map[net.Conn]struct{})}
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		p.listener.Close()
+		p.setCut(true)
+		wg.Wait()
+	})
+
+	wg.Go(func() {
+		for {
+			conn, err := p.listener.Accept()
+			if err != nil {
+				return
+			}
+
+			wg.Go(func() { p.forward(conn) })
+		}
+	})
+	return p
+}
+
+func (p *linkProxy) forward(conn net.Conn) {
+	upstream, err := net.Dial("tcp", p.target)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	p.mu.Lock()
+	if p.cut {
+		p.mu.Unlock()
+		conn.Close()
+		upstream.Close()
+		return
+	}
+
+	p.conns[conn], p.conns[upstream] = struct{}{}, struct{}{}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	pipe := func(dst net.Conn, src net.Conn) {
+		io.Copy(dst, src)
+		dst.Close()
+		src.Close()
+	}
+	wg.Go(func() { pipe(upstream, conn) })
+	wg.Go(func() { pipe(conn, upstream) })
+	wg.Wait()
+
+	p.mu.Lock()
+	delete(p.conns, conn)
+	delete(p.conns, upstream)
+	p.mu.Unlock()
+}
+
+// setCut drops the forwarded connections and refuses new ones while cut is set.
+func (p *linkProxy) setCut(cut bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cut = cut
+	if cut {
+		for conn := range p.conns {
+			conn.Close()
+		}
+	}
+}
+
+// startActiveActiveGroup starts instances whose peers are reached through proxies.
+func startActiveActiveGroup(t *testing.T, size int) *activeActiveGroup {
+	g := &activeActiveGroup{t: t, proxies: make(map[[2]int]*linkProxy)}
+	for range size {
+		g.listeners = append(g.listeners, listen(t))
+	}
+
+	for from := range size {
+		for to := range size {
+			if from != to {
+				g.proxies[[2]int{from, to}] = startProxy(t, g.listeners[to].Addr().String())
+			}
+		}
+	}
+
+	g.instances, g.closers = make([]testClient, size), make([]func(), size)
+	for i := range size {
+		g.start(i, g.listeners[i])
+	}
+
+	return g
+}
+
+func (g *activeActiveGroup) start(i int, listener net.Listener) {
+	var peers []string
+	for to := range g.listeners {
+		if to != i {
+			peers = append(peers, g.proxies[[2]int{i, to}].listener.Addr().String())
+		}
+	}
+
+	cp := redisserverlib.NewRedisCommandProcessor(
+		redisserverlib.WithDir(g.t.TempDir()),
+		redisserverlib.WithPort(listenerPort(listener)),
+		redisserverlib.WithActiveActivePeers(peers...),
+	)
+	_, drop := serveListener(g.t, listener, cp)
+	closed := false
+	g.closers[i] = func() {
+		if !closed {
+			closed = true
+			listener.Close()
+			drop()
+			cp.Close()
+		}
+	}
+	g.t.Cleanup(g.closers[i])
+	g.instances[i] = newTestClient(cp)
+}
+
+// restart replaces an instance with a new one that has an empty dataset.
+func (g *activeActiveGroup) restart(i int) {
+	g.closers[i]()
+	listener, err := net.Listen("tcp", g.listeners[i].Addr().String())
+	if err != nil {
+		g.t.Fatal(err)
+	}
+
+	g.listeners[i] = listener
+	g.start(i, listener)
+}
+
+// partition cuts the links between instances of different groups.
+func (g *activeActiveGroup) partition(groups ...[]int) {
+	groupOf := make(map[int]int)
+	for n, group := range groups {
+		for _, i := range group {
+			groupOf[i] = n
+		}
+	}
+
+	for link, p := range g.proxies {
+		p.setCut(groupOf[link[0]] != groupOf[link[1]])
+	}
+}
+
+func (g *activeActiveGroup) heal() {
+	g.partition()
+}
+
+// converged waits until every instance replies the same, expected if it is not empty.
+func (g *activeActiveGroup) converged(expected string, args ...string) string {
+	g.t.Helper()
+	var first string
+	waitFor(g.t, "the instances to converge", func() bool {
+		first = g.instances[0].do(args...)
+		for _, instance := range g.instances[1:] {
+			if instance.do(args...) != first {
+				return false
+			}
+		}
+
+		return expected == "" || first == expected
+	})
+	return first
+}
+
+func TestActiveActive(t *testing.T) {
+	g := startActiveActiveGroup(t, 3)
+	a, b, c := g.instances[0], g.instances[1], g.instances[2]
+
+	t.Run("Writes reach every peer", func(t *testing.T) {
+		a.expect(t, "+OK\r\n", "SET", "k", "v")
+		b.expect(t, ":1\r\n", "RPUSH", "q", "x")
+		c.expect(t, ":2\r\n", "INCRBY", "hits", "2")
+		g.converged("$1\r\nv\r\n", "GET", "k")
+		g.converged("*1\r\n$1\r\nx\r\n", "LRANGE", "q", "0", "-1")
+		g.converged("$1\r\n2\r\n", "GET", "hits")
+	})
+
+	t.Run("Concurrent writes converge after a partition", func(t *testing.T) {
+		g.partition([]int{0}, []int{1, 2})
+
+		// Last writer wins
+		a.expect(t, "+OK\r\n", "SET", "k", "from-a")
+		b.expect(t, "+OK\r\n", "SET", "k", "from-b")
+
+		// Increments add up
+		a.expect(t, ":7\r\n", "INCRBY", "hits", "5")
+		b.expect(t, ":3\r\n", "INCR", "hits")
+		c.eventually(t, "$1\r\n3\r\n", "GET", "hits")
+		c.expect(t, ":1\r\n", "DECRBY", "hits", "2")
+
+		// Pushes are kept, pops only remove what they saw
+		a.expect(t, "$1\r\nx\r\n", "LPOP", "q")
+		b.expect(t, ":2\r\n", "RPUSH", "q", "y")
+		a.expect(t, ":2\r\n", "RPUSH", "l", "a1", "a2")
+		c.expect(t, ":1\r\n", "LPUSH", "l", "c1")
+
+		// Streams are merged by ID
+		a.expect(t, "$3\r\n1-1\r\n", "XADD", "s", "1-1", "from", "a")
+		b.expect(t, "$3\r\n2-1\r\n", "XADD", "s", "2-1", "from", "b")
+
+		a.expect(t, "$6\r\nfrom-a\r\n", "GET", "k")
+		b.expect(t, "$6\r\nfrom-b\r\n", "GET", "k")
+
+		g.heal()
+		g.converged("$6\r\nfrom-b\r\n", "GET", "k")
+		g.converged("$1\r\n6\r\n", "GET", "hits")
+		g.converged("*1\r\n$1\r\ny\r\n", "LRANGE", "q", "0", "-1")
+		if l := g.converged("", "LRANGE", "l", "0", "-1"); l[:2] != "*3" {
+			t.Errorf("LRANGE l = %q; Expected 3 elements", l)
+		}
+
+		g.converged("*2\r\n*2\r\n$3\r\n1-1\r\n*2\r\n$4\r\nfrom\r\n$1\r\na\r\n*2\r\n$3\r\n2-1\r\n*2\r\n$4\r\nfrom\r\n$1\r\nb\r\n", "XRANGE", "s", "-", "+")
+	})
+
+	t.Run("Removals win over what they observed only", func(t *testing.T) {
+		g.partition([]int{0, 1}, []int{2})
+		a.expect(t, ":1\r\n", "DEL", "hits")
+		c.expect(t, ":7\r\n", "INCRBY", "hits", "1")
+		a.expect(t, "+OK\r\n", "SET", "n", "5")
+		a.expect(t, ":6\r\n", "INCR", "n")
+
+		g.heal()
+		// The increment c made concurrently survives the deletion
+		g.converged("$1\r\n1\r\n", "GET", "hits")
+		g.converged("$1\r\n6\r\n", "GET", "n")
+	})
+
+	t.Run("Restarted instance recovers from its peers", func(t *testing.T) {
+		g.restart(2)
+		c := g.instances[2]
+		c.eventually(t, "$6\r\nfrom-b\r\n", "GET", "k")
+		c.eventually(t, "$1\r\n1\r\n", "GET", "hits")
+		c.expect(t, ":2\r\n", "INCR", "hits")
+		g.converged("$1\r\n2\r\n", "GET", "hits")
+	})
+
+	a.expect(t, "-ERR MOVE is not supported in active-active mode\r\n", "MOVE", "k", "1")
+}
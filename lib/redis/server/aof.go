@@ -0,0 +1,544 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+)
+
+type (
+	// aofFile is one entry of the AOF manifest.
+	aofFile struct {
+		name     string
+		seq      int64
+		fileType byte
+	}
+
+	// aofManifest lists the files that make up the AOF: an optional base file with a snapshot
+	// of the dataset, followed by incremental files with the write commands executed since.
+	aofManifest struct {
+		base    *aofFile
+		incrs   []aofFile
+		history []aofFile
+	}
+
+	// aof appends write commands to the last incremental file of a multi-part AOF.
+	// A rewrite starts a new incremental file and replaces everything before it with a new base,
+	// updating the manifest at each step so that a crash never loses acknowledged writes.
+	aof struct {
+		persistence *persistence
+		latency     *latencyMonitor
+
+		mu       sync.Mutex
+		enabled  bool
+		fsync    string
+		filename string
+		dirname  string
+		manifest aofManifest
+		file     *os.File
+		// Database last selected in the current incremental file
+		db       int
+		unsynced bool
+
+		rewriting atomic.Bool
+		rewrites  sync.WaitGroup
+
+		stop chan struct{}
+		wg   sync.WaitGroup
+	}
+)
+
+const (
+	aofTypeBase    = 'b'
+	aofTypeIncr    = 'i'
+	aofTypeHistory = 'h'
+
+	fsyncAlways   = "always"
+	fsyncEverySec = "everysec"
+	fsyncNo       = "no"
+)
+
+var (
+	errAofRewriteInProgress = errors.New("ERR Background append only file rewriting already in progress")
+	errAofDisabled          = errors.New("ERR Append only file is disabled, use CONFIG SET appendonly yes")
+)
+
+func newAof(p *persistence, latency *latencyMonitor, options processorOptions) *aof {
+	a := &aof{
+		persistence: p,
+		latency:     latency,
+		enabled:     options.appendOnly,
+		fsync:       fsyncEverySec,
+		filename:    options.appendFilename,
+		dirname:     options.appendDirname,
+		db:          -1,
+		stop:        make(chan struct{}),
+	}
+
+	if err := validateFsyncPolicy(options.appendFsync); err != nil {
+		slog.Warn("Ignoring invalid appendfsync", "appendfsync", options.appendFsync, "error", err)
+	} else {
+		a.fsync = options.appendFsync
+	}
+
+	return a
+}
+
+func validateFsyncPolicy(policy string) error {
+	switch policy {
+	case fsyncAlways, fsyncEverySec, fsyncNo:
+		return nil
+	default:
+		return fmt.Errorf("argument must be one of always, everysec or no")
+	}
+}
+
+func (a *aof) isEnabled() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.enabled
+}
+
+func (a *aof) dirPath() string {
+	a.persistence.mu.Lock()
+	defer a.persistence.mu.Unlock()
+	return filepath.Join(a.persistence.dir, a.dirname)
+}
+
+func (a *aof) manifestPath() string {
+	return filepath.Join(a.dirPath(), a.filename+".manifest")
+}
+
+func parseAofManifest(r io.Reader) (aofManifest, error) {
+	m := aofManifest{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields)%2 != 0 {
+			return m, fmt.Errorf("invalid AOF manifest line %q", line)
+		}
+
+		f := aofFile{}
+		for i := 0; i < len(fields); i += 2 {
+			switch fields[i] {
+			case "file":
+				f.name = fields[i+1]
+			case "seq":
+				seq, err := strconv.ParseInt(fields[i+1], 10, 64)
+				if err != nil {
+					return m, fmt.Errorf("invalid AOF manifest line %q", line)
+				}
+				f.seq = seq
+			case "type":
+				f.fileType = fields[i+1][0]
+			}
+		}
+
+		if f.name == "" || filepath.Base(f.name) != f.name {
+			return m, fmt.Errorf("invalid AOF manifest line %q", line)
+		}
+
+		switch f.fileType {
+		case aofTypeBase:
+			if m.base != nil {
+				return m, fmt.Errorf("AOF manifest lists more than one base file")
+			}
+			m.base = &f
+		case aofTypeIncr:
+			m.incrs = append(m.incrs, f)
+		case aofTypeHistory:
+			m.history = append(m.history, f)
+		default:
+			return m, fmt.Errorf("invalid AOF manifest line %q", line)
+		}
+	}
+
+	return m, scanner.Err()
+}
+
+func (m aofManifest) String() string {
+	var sb strings.Builder
+	files := []aofFile{}
+	if m.base != nil {
+		files = append(files, *m.base)
+	}
+
+	files = append(files, m.history...)
+	files = append(files, m.incrs...)
+	for _, f := range files {
+		fmt.Fprintf(&sb, "file %s seq %d type %c\n", f.name, f.seq, f.fileType)
+	}
+
+	return sb.String()
+}
+
+// writeManifestNoLock atomically replaces the manifest. Must be called with mu held.
+func (a *aof) writeManifestNoLock() error {
+	dir := a.dirPath()
+	tmp := filepath.Join(dir, "temp-"+a.filename+".manifest")
+	if err := os.WriteFile(tmp, []byte(a.manifest.String()), 0o644); err != nil {
+		return err
+	}
+
+	f, err := os.Open(tmp)
+	if err == nil {
+		err = f.Sync()
+		f.Close()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, a.manifestPath())
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Make the rename itself durable
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// load replays the files listed in the manifest through execute, reporting whether an AOF exists.
+// inTransaction tells whether the replayed commands left a transaction open.
+func (a *aof) load(execute func(args []string) error, inTransaction func() bool) (bool, error) {
+	f, err := os.Open(a.manifestPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	manifest, err := parseAofManifest(f)
+	f.Close()
+	if err != nil {
+		return true, err
+	}
+
+	start := time.Now()
+	dir := a.dirPath()
+	if manifest.base != nil {
+		path := filepath.Join(dir, manifest.base.name)
+		if strings.HasSuffix(manifest.base.name, ".rdb") {
+			if found, err := a.persistence.loadFile(path); err != nil {
+				return true, err
+			} else if !found {
+				return true, fmt.Errorf("AOF base file %s is missing", manifest.base.name)
+			}
+		} else if err := replayAofFile(path, false, execute, inTransaction); err != nil {
+			return true, err
+		}
+	}
+
+	for i, incr := range manifest.incrs {
+		last := i == len(manifest.incrs)-1
+		if err := replayAofFile(filepath.Join(dir, incr.name), last, execute, inTransaction); err != nil {
+			return true, err
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.manifest = manifest
+	slog.Info("DB loaded from append only file", "dir", dir, "duration", time.Since(start))
+	return true, nil
+}
+
+// replayAofFile executes the commands of one AOF file. A truncated tail of the last file,
+// including a transaction that never reached EXEC, is cut off rather than failing the load.
+func replayAofFile(path string, last bool, execute func(args []string) error, inTransaction func() bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	offset := 0
+	validBeforeMulti := -1
+	for {
+		args, n, err := redislib.ReadCommand(r)
+		if err == io.EOF && !inTransaction() {
+			return nil
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if !last {
+				return fmt.Errorf("unexpected end of AOF file %s", filepath.Base(path))
+			}
+
+			validUntil := offset
+			if inTransaction() {
+				validUntil = validBeforeMulti
+			}
+
+			slog.Warn("AOF file ends with an incomplete command or transaction, truncating it",
+				"file", filepath.Base(path), "size", offset+n, "truncated_to", validUntil)
+			return os.Truncate(path, int64(validUntil))
+		}
+
+		if err != nil {
+			return fmt.Errorf("bad file format reading the append only file %s at offset %d: %w", filepath.Base(path), offset, err)
+		}
+
+		if strings.EqualFold(args[0], "MULTI") {
+			validBeforeMulti = offset
+		}
+
+		if err := execute(args); err != nil {
+			return fmt.Errorf("replaying the append only file %s at offset %d: %w", filepath.Base(path), offset, err)
+		}
+
+		offset += n
+	}
+}
+
+// openLastIncr reopens the last incremental file of a loaded AOF for appending.
+func (a *aof) openLastIncr() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.manifest.incrs) == 0 {
+		return a.switchIncrNoLock()
+	}
+
+	f, err := os.OpenFile(filepath.Join(a.dirPath(), a.manifest.incrs[len(a.manifest.incrs)-1].name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	a.file = f
+	a.db = -1
+	return nil
+}
+
+// switchIncrNoLock starts a new incremental file and records it in the manifest. Must be called with mu held.
+func (a *aof) switchIncrNoLock() error {
+	if err := os.MkdirAll(a.dirPath(), 0o755); err != nil {
+		return err
+	}
+
+	seq := int64(1)
+	if len(a.manifest.incrs) > 0 {
+		seq = a.manifest.incrs[len(a.manifest.incrs)-1].seq + 1
+	}
+
+	incr := aofFile{name: fmt.Sprintf("%s.%d.incr.aof", a.filename, seq), seq: seq, fileType: aofTypeIncr}
+	f, err := os.OpenFile(filepath.Join(a.dirPath(), incr.name), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	a.manifest.incrs = append(a.manifest.incrs, incr)
+	if err := a.writeManifestNoLock(); err != nil {
+		a.manifest.incrs = a.manifest.incrs[:len(a.manifest.incrs)-1]
+		f.Close()
+		return err
+	}
+
+	a.closeFileNoLock()
+	a.file = f
+	a.db = -1
+	return nil
+}
+
+func (a *aof) closeFileNoLock() {
+	if a.file == nil {
+		return
+	}
+
+	if err := a.file.Sync(); err != nil {
+		slog.Error("Failed to fsync the append only file", "error", err)
+	}
+
+	a.file.Close()
+	a.file = nil
+}
+
+func (a *aof) propagate(batch []propagatedCommand) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return
+	}
+
+	if _, err := a.file.Write(appendPropagated(nil, &a.db, batch)); err != nil {
+		slog.Error("Failed writing to the append only file", "error", err)
+		return
+	}
+
+	if a.fsync == fsyncAlways {
+		start := time.Now()
+		if err := a.file.Sync(); err != nil {
+			slog.Error("Failed to fsync the append only file", "error", err)
+		}
+		a.latency.since(latencyEventFsyncAlways, start)
+		return
+	}
+
+	a.unsynced = true
+}
+
+// rewrite replaces the AOF with a base file holding a snapshot of the current dataset.
+// New writes go to a fresh incremental file right away, so only writing the base may
+// happen in the background: the snapshot stops commands as for BGSAVE, see persistence.snapshot.
+// The caller must hold the transaction lock exclusively.
+func (a *aof) rewrite(background bool) error {
+	if !a.rewriting.CompareAndSwap(false, true) {
+		return errAofRewriteInProgress
+	}
+
+	a.mu.Lock()
+	err := a.switchIncrNoLock()
+	current := a.manifest.incrs[len(a.manifest.incrs)-1]
+	baseSeq := int64(1)
+	if a.manifest.base != nil {
+		baseSeq = a.manifest.base.seq + 1
+	}
+	a.mu.Unlock()
+
+	if err != nil {
+		a.rewriting.Store(false)
+		return err
+	}
+
+	dbs := a.persistence.snapshot()
+	finish := func() error {
+		defer a.rewriting.Store(false)
+		base := aofFile{name: fmt.Sprintf("%s.%d.base.rdb", a.filename, baseSeq), seq: baseSeq, fileType: aofTypeBase}
+		dir := a.dirPath()
+		if err := writeRDBFile(filepath.Join(dir, base.name), dbs); err != nil {
+			return err
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		// Everything before the incremental file started with the rewrite is now covered by the base
+		history := []aofFile{}
+		if a.manifest.base != nil {
+			history = append(history, aofFile{name: a.manifest.base.name, seq: a.manifest.base.seq, fileType: aofTypeHistory})
+		}
+
+		incrs := []aofFile{}
+		for _, incr := range a.manifest.incrs {
+			if incr.seq < current.seq {
+				history = append(history, aofFile{name: incr.name, seq: incr.seq, fileType: aofTypeHistory})
+			} else {
+				incrs = append(incrs, incr)
+			}
+		}
+
+		a.manifest.base = &base
+		a.manifest.incrs = incrs
+		a.manifest.history = history
+		if err := a.writeManifestNoLock(); err != nil {
+			return err
+		}
+
+		for _, f := range history {
+			os.Remove(filepath.Join(dir, f.name))
+		}
+
+		a.manifest.history = nil
+		if err := a.writeManifestNoLock(); err != nil {
+			return err
+		}
+
+		slog.Info("Append only file rewrite completed", "base", base.name)
+		return nil
+	}
+
+	if !background {
+		return finish()
+	}
+
+	a.rewrites.Go(func() {
+		if err := finish(); err != nil {
+			slog.Error("Background append only file rewrite failed", "error", err)
+		}
+	})
+
+	return nil
+}
+
+// enable starts appending to the AOF, creating it from the current dataset.
+// The caller must hold the transaction lock exclusively.
+func (a *aof) enable() error {
+	a.mu.Lock()
+	a.enabled = true
+	a.mu.Unlock()
+	return a.rewrite(true)
+}
+
+func (a *aof) disable() {
+	a.rewrites.Wait()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled = false
+	a.closeFileNoLock()
+}
+
+// start runs the once per second fsync of the everysec policy until close is called.
+func (a *aof) start() {
+	a.wg.Go(func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				a.mu.Lock()
+				if a.file != nil && a.unsynced && a.fsync == fsyncEverySec {
+					start := time.Now()
+					if err := a.file.Sync(); err != nil {
+						slog.Error("Failed to fsync the append only file", "error", err)
+					}
+					a.latency.since(latencyEventFsyncEverySec, start)
+					a.unsynced = false
+				}
+				a.mu.Unlock()
+			}
+		}
+	})
+}
+
+func (a *aof) close() {
+	close(a.stop)
+	a.wg.Wait()
+	a.rewrites.Wait()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closeFileNoLock()
+}
+
+func (a *aof) info() []string {
+	return []string{
+		fmt.Sprintf("aof_enabled:%d", boolToInt(a.isEnabled())),
+		fmt.Sprintf("aof_rewrite_in_progress:%d", boolToInt(a.rewriting.Load())),
+	}
+}
@@ -0,0 +1,151 @@
+package redisserverlib_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func newAofProcessor(dir string) redisserverlib.CommandProcessor {
+	return redisserverlib.NewRedisCommandProcessor(
+		redisserverlib.WithDir(dir),
+		redisserverlib.WithAppendOnly(true),
+		redisserverlib.WithAppendFsync("always"),
+	)
+}
+
+func readManifest(t *testing.T, dir string) string {
+	t.Helper()
+	manifest, err := os.ReadFile(filepath.Join(dir, "appendonlydir", "appendonly.aof.manifest"))
+	if err != nil {
+		t.Fatalf("Reading the manifest failed: %v", err)
+	}
+
+	return string(manifest)
+}
+
+func TestAppendOnlyFileReplay(t *testing.T) {
+	dir := t.TempDir()
+	cp := newAofProcessor(dir)
+	cl := newTestClient(cp)
+
+	cl.expect(t, "+OK\r\n", "SET", "str", "hello")
+	cl.expect(t, "+OK\r\n", "SET", "volatile", "v", "PX", "100000")
+	cl.expect(t, ":3\r\n", "RPUSH", "list", "a", "b", "c")
+	cl.expect(t, "*2\r\n$4\r\nlist\r\n$1\r\na\r\n", "BLPOP", "list", "1")
+	id := cl.do("XADD", "stream", "*", "f", "v")
+	cl.expect(t, "+OK\r\n", "MULTI")
+	cl.expect(t, "+QUEUED\r\n", "SELECT", "2")
+	cl.expect(t, "+QUEUED\r\n", "SET", "other", "db")
+	cl.expect(t, "*2\r\n+OK\r\n+OK\r\n", "EXEC")
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	expectedManifest := "file appendonly.aof.1.base.rdb seq 1 type b\nfile appendonly.aof.1.incr.aof seq 1 type i\n"
+	if manifest := readManifest(t, dir); manifest != expectedManifest {
+		t.Errorf("Manifest = %q; Expected: %q", manifest, expectedManifest)
+	}
+
+	incr, _ := os.ReadFile(filepath.Join(dir, "appendonlydir", "appendonly.aof.1.incr.aof"))
+	for _, expected := range []string{"PXAT", "$4\r\nLPOP\r\n", "*5\r\n$4\r\nXADD\r\n", "$5\r\nMULTI\r\n", "$6\r\nSELECT\r\n$1\r\n2\r\n"} {
+		if !strings.Contains(string(incr), expected) {
+			t.Errorf("AOF %q does not contain %q", incr, expected)
+		}
+	}
+
+	loaded := newTestClient(newAofProcessor(dir))
+	loaded.expect(t, "$5\r\nhello\r\n", "GET", "str")
+	loaded.expect(t, "$1\r\nv\r\n", "GET", "volatile")
+	loaded.expect(t, "*2\r\n$1\r\nb\r\n$1\r\nc\r\n", "LRANGE", "list", "0", "-1")
+	loaded.expect(t, "*1\r\n*2\r\n"+id+"*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "XRANGE", "stream", "-", "+")
+	loaded.expect(t, "+OK\r\n", "SELECT", "2")
+	loaded.expect(t, "$2\r\ndb\r\n", "GET", "other")
+}
+
+func TestAppendOnlyFileTruncatedTail(t *testing.T) {
+	tcs := []struct {
+		name string
+		tail string
+	}{
+		{"Partial command", "*3\r\n$3\r\nSET\r\n$1\r\nz\r\n$1"},
+		{"Transaction without EXEC", "*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\nz\r\n$1\r\n1\r\n"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cp := newAofProcessor(dir)
+			newTestClient(cp).expect(t, "+OK\r\n", "SET", "k", "v")
+			cp.Close()
+
+			path := filepath.Join(dir, "appendonlydir", "appendonly.aof.1.incr.aof")
+			complete, _ := os.ReadFile(path)
+			f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+			f.WriteString(tc.tail)
+			f.Close()
+
+			cp = newAofProcessor(dir)
+			cl := newTestClient(cp)
+			cl.expect(t, "$1\r\nv\r\n", "GET", "k")
+			cl.expect(t, "$-1\r\n", "GET", "z")
+			cl.expect(t, "+OK\r\n", "SET", "after", "v")
+			cp.Close()
+
+			truncated, _ := os.ReadFile(path)
+			if !strings.HasPrefix(string(truncated), string(complete)) || strings.Contains(string(truncated), tc.tail) {
+				t.Errorf("AOF after reload = %q; Expected the tail to be cut off", truncated)
+			}
+
+			newTestClient(newAofProcessor(dir)).expect(t, "$1\r\nv\r\n", "GET", "after")
+		})
+	}
+}
+
+func TestRewriteAppendOnlyFile(t *testing.T) {
+	dir := t.TempDir()
+	cp := newAofProcessor(dir)
+	cl := newTestClient(cp)
+
+	for range 10 {
+		cl.expect(t, "+OK\r\n", "SET", "counter", "x")
+	}
+
+	// The copy of the dataset for the base file stops commands, and is measured like BGSAVE's
+	before, _ := strconv.Atoi(cl.infoField("stats", "latest_fork_usec"))
+	cl.expect(t, ":100000\r\n", append([]string{"RPUSH", "big"}, slices.Repeat([]string{"element"}, 100000)...)...)
+	cl.expect(t, "+Background append only file rewriting started\r\n", "BGREWRITEAOF")
+	if usec, _ := strconv.Atoi(cl.infoField("stats", "latest_fork_usec")); usec <= before {
+		t.Errorf("latest_fork_usec = %d; Expected more than the %d of the empty dataset", usec, before)
+	}
+	cl.expect(t, "+OK\r\n", "SET", "after", "rewrite")
+
+	expectedManifest := "file appendonly.aof.2.base.rdb seq 2 type b\nfile appendonly.aof.2.incr.aof seq 2 type i\n"
+	deadline := time.Now().Add(2 * time.Second)
+	for readManifest(t, dir) != expectedManifest {
+		if time.Now().After(deadline) {
+			t.Fatalf("Manifest = %q; Expected: %q", readManifest(t, dir), expectedManifest)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cp.Close()
+	for _, name := range []string{"appendonly.aof.1.base.rdb", "appendonly.aof.1.incr.aof"} {
+		if _, err := os.Stat(filepath.Join(dir, "appendonlydir", name)); err == nil {
+			t.Errorf("%s still exists after the rewrite", name)
+		}
+	}
+
+	loaded := newTestClient(newAofProcessor(dir))
+	loaded.expect(t, "$1\r\nx\r\n", "GET", "counter")
+	loaded.expect(t, "$7\r\nrewrite\r\n", "GET", "after")
+	loaded.expect(t, "*2\r\n$11\r\nappendfsync\r\n$6\r\nalways\r\n", "CONFIG", "GET", "appendfsync")
+	loaded.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'appendfsync') - argument must be one of always, everysec or no\r\n", "CONFIG", "SET", "appendfsync", "sometimes")
+}
@@ -0,0 +1,53 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	asking struct {
+		*cluster
+	}
+)
+
+func (c asking) moniker() string {
+	return "ASKING"
+}
+
+func (c asking) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagFast,
+		categories: []string{"connection"},
+		group:      "cluster",
+		summary:    "Signals that a cluster client is following an -ASK redirect.",
+		since:      "3.0.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c asking) getUsage() string {
+	return `
+usage:
+	ASKING
+summary:
+	Sent by cluster clients after an -ASK redirect, so that the node serves the next command
+	for a hash slot it is still importing.
+`
+}
+
+func (c asking) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR ASKING takes no arguments! %s", c.getUsage())}
+	}
+
+	if c.cluster == nil {
+		return resptypes.SimpleError{Val: errClusterDisable}
+	}
+
+	clientFromContext(ctx).asking = true
+	return resptypes.SimpleString{Val: "OK"}
+}
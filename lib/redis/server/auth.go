@@ -0,0 +1,85 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	auth struct {
+		acl *acl
+	}
+)
+
+func (c auth) moniker() string {
+	return "AUTH"
+}
+
+func (c auth) spec() commandSpec {
+	return commandSpec{
+		arity:      -2,
+		flags:      flagFast | flagNoAuth,
+		categories: []string{"connection"},
+		group:      "connection",
+		summary:    "Authenticates the connection.",
+		since:      "1.0.0",
+		complexity: "O(N) where N is the number of passwords defined for the user",
+		args: []commandArg{
+			{name: "username", typ: argString, optional: true},
+			{name: "password", typ: argString},
+		},
+	}
+}
+
+func (c auth) getUsage() string {
+	return `
+usage:
+	AUTH [username] password
+summary:
+	Authenticates the connection as username, or as the default user when only a password is given.
+	The commands, keys and channels the connection may use are then those allowed to the user by its ACL rules.
+	Failed attempts are recorded in ACL LOG.
+`
+}
+
+func (c auth) execute(ctx context.Context, params commandParams) commandResult {
+	username, password := defaultUsername, ""
+	switch len(params) {
+	case 2:
+		if c.acl.defaultNoPass() {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")}
+		}
+
+		password = params[1].Val
+	case 3:
+		username, password = params[1].Val, params[2].Val
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+	}
+
+	client := clientFromContext(ctx)
+	user, ok := c.acl.authenticate(username, password)
+	if !ok {
+		c.acl.logAuthFailure(client, username)
+		return resptypes.SimpleError{Val: errWrongPass}
+	}
+
+	client.user.Store(user)
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+// authCommand returns the AUTH command authenticating the server to its primary or its peers,
+// or nil when no password is configured.
+func authCommand(options processorOptions) []string {
+	if options.masterAuth == "" {
+		return nil
+	}
+
+	if options.masterUser == "" {
+		return []string{"AUTH", options.masterAuth}
+	}
+
+	return []string{"AUTH", options.masterUser, options.masterAuth}
+}
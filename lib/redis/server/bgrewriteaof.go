@@ -0,0 +1,60 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	bgrewriteaof struct {
+		*aof
+	}
+)
+
+func (c bgrewriteaof) moniker() string {
+	return "BGREWRITEAOF"
+}
+
+func (c bgrewriteaof) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagExclusive | flagAdmin,
+		group:      "server",
+		summary:    "Asynchronously rewrites the append-only file to disk.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c bgrewriteaof) getUsage() string {
+	return `
+usage:
+	BGREWRITEAOF
+summary:
+	Rewrite the append only file in the background, replacing the logged commands with a snapshot of the current dataset.
+	Writes keep being logged to a new incremental file while the rewrite runs, and the manifest only switches to the new files once they are complete.
+	Like BGSAVE, commands wait while the dataset is copied for the snapshot.
+`
+}
+
+func (c bgrewriteaof) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR BGREWRITEAOF takes no arguments! %s", c.getUsage())}
+	}
+
+	if !c.isEnabled() {
+		return resptypes.SimpleError{Val: errAofDisabled}
+	}
+
+	if err := c.rewrite(true); err != nil {
+		if err == errAofRewriteInProgress {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+	}
+
+	return resptypes.SimpleString{Val: "Background append only file rewriting started"}
+}
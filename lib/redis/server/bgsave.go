@@ -0,0 +1,64 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	bgsave struct {
+		*persistence
+	}
+)
+
+func (c bgsave) moniker() string {
+	return "BGSAVE"
+}
+
+func (c bgsave) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagExclusive | flagAdmin,
+		group:      "server",
+		summary:    "Asynchronously saves the database(s) to disk.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "schedule", typ: argPureToken, token: "SCHEDULE", optional: true}},
+	}
+}
+
+func (c bgsave) getUsage() string {
+	return `
+usage:
+	BGSAVE [SCHEDULE]
+summary:
+	Save the dataset to disk in the background. The snapshot reflects the dataset at the time of the call, while commands keep running.
+	Commands wait while the dataset is copied for the snapshot, see latest_fork_usec in INFO stats and the fork event of LATENCY.
+	With SCHEDULE, a save requested while another one is in progress starts as soon as that one finishes.
+	Use LASTSAVE to check whether the save completed.
+`
+}
+
+func (c bgsave) execute(ctx context.Context, params commandParams) commandResult {
+	schedule := false
+	switch {
+	case len(params) == 2 && strings.ToUpper(params[1].Val) == "SCHEDULE":
+		schedule = true
+	case len(params) != 1:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error! %s", c.getUsage())}
+	}
+
+	if err := c.backgroundSave(); err != nil {
+		if !schedule {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		c.bgsaveScheduled.Store(true)
+		return resptypes.SimpleString{Val: "Background saving scheduled"}
+	}
+
+	return resptypes.SimpleString{Val: "Background saving started"}
+}
@@ -13,7 +13,7 @@ import (
 
 type (
 	blpop struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -21,6 +21,23 @@ func (c blpop) moniker() string {
 	return "BLPOP"
 }
 
+func (c blpop) spec() commandSpec {
+	return commandSpec{
+		arity:      -3,
+		flags:      flagBlocking | flagWrite,
+		categories: []string{"list"},
+		keySpecs:   []keySpec{{flags: keyRWDelete, index: 1, lastKey: -2, keyStep: 1}},
+		group:      "list",
+		summary:    "Removes and returns the first element in a list. Blocks until an element is available otherwise. Deletes the list if the last element was popped.",
+		since:      "2.0.0",
+		complexity: "O(N) where N is the number of provided keys.",
+		args: []commandArg{
+			{name: "key", typ: argKey, multiple: true},
+			{name: "timeout", typ: argDouble},
+		},
+	}
+}
+
 func (c blpop) getUsage() string {
 	return `
 usage:
@@ -57,7 +74,7 @@ func (c blpop) execute(ctx context.Context, params commandParams) commandResult
 		defer cancel()
 	}
 
-	dsVal := c.GetOrCreate(listName, redistypes.NewList, 0)
+	dsVal := c.getOrCreate(ctx, listName, redistypes.NewList)
 	if dsVal.Type != redistypes.TypeList {
 		return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 	}
@@ -66,6 +83,7 @@ func (c blpop) execute(ctx context.Context, params commandParams) commandResult
 
 	if err != nil {
 		slog.DebugContext(ctx, "BLPOP error occurred", "listName", listName, "error", err)
+		propagateAs(ctx)
 		if err == context.DeadlineExceeded {
 			return resptypes.NullArray
 		}
@@ -73,6 +91,9 @@ func (c blpop) execute(ctx context.Context, params commandParams) commandResult
 		return resptypes.Null{}
 	}
 
+	c.modified(ctx, listName)
+	c.notify(ctx, notifyList, "lpop", listName)
+	propagateAs(ctx, "LPOP", listName)
 	result = append([]resptypes.BulkString{params[1]}, result...)
 	return resptypes.Array[resptypes.BulkString](result)
 }
@@ -0,0 +1,389 @@
+package redisserverlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// client holds the per-connection state. Most of it is only mutated from the connection's
+	// own request loop. The outbound channel is shared with other connections pushing messages
+	// (e.g. PUBLISH), so it is guarded by mu.
+	client struct {
+		id      int64
+		addr    string
+		name    string // Set by CLIENT SETNAME
+		db      int
+		multi   *transaction
+		watched []watchedKey
+
+		// Only modified while holding the pubsub lock
+		channels map[string]struct{}
+		patterns map[string]struct{}
+
+		// Set by CLIENT CACHING yes|no for the next command only
+		caching *bool
+
+		// Write commands executed by the current request, waiting to be propagated
+		pending []propagatedCommand
+		// Set through propagateAs by commands that are propagated differently from how they were called
+		rewrite   bool
+		rewritten [][]string
+
+		// The replication offset reached by the client's last write, which WAIT waits for
+		replOffset int64
+		// Announced by replicas with REPLCONF listening-port
+		listeningPort int
+		// Set on the pseudo client applying the stream of the primary the server replicates from
+		link *primaryLink
+		// Set on pseudo clients replaying the AOF or applying the replication stream, whose
+		// commands are not subject to write checks or cluster redirection
+		internal bool
+		// Set by ASKING for the next command only
+		asking bool
+		// Set on clients connecting beyond maxclients, which are dropped on their first request
+		refused bool
+		// When the client last sent a request or got its reply, in Unix nanoseconds
+		lastInteraction atomic.Int64
+		// Set while the client waits in a blocking command
+		blocked atomic.Bool
+		// Set by AUTH, or on connection to the default user if it needs no password
+		user atomic.Pointer[aclUser]
+		// Set on the pseudo client applying the Raft log to the time the leader proposed the
+		// command, so that every node computes the same expiries and stream IDs
+		now time.Time
+
+		mu     sync.Mutex
+		out    chan string
+		closed bool
+		kill   context.CancelFunc
+	}
+
+	// transaction holds the commands queued between MULTI and EXEC.
+	transaction struct {
+		queued []commandParams
+
+		// Set when a command could not be queued; EXEC then fails with EXECABORT.
+		aborted bool
+	}
+
+	watchedKey struct {
+		ds      redistypes.DataStore
+		key     redistypes.StoreKey
+		version uint64
+	}
+
+	clientContextKey struct{}
+
+	// clientList indexes the connected clients by ID.
+	clientList struct {
+		mu   sync.RWMutex
+		byId map[int64]*client
+	}
+
+	// keyspace resolves the logical database selected by the calling client
+	// and signals reads and modifications to the subsystems that observe them.
+	keyspace struct {
+		redistypes.Databases
+		tracking *tracking
+		events   *keyspaceEvents
+		metrics  *Metrics
+		latency  *latencyMonitor
+		// Modifications since the last save
+		dirty *atomic.Int64
+	}
+)
+
+var (
+	lastClientId  atomic.Int64
+	errMaxClients = errors.New("ERR max number of clients reached")
+)
+
+func newClient() *client {
+	return &client{
+		id:       lastClientId.Add(1),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+}
+
+// push delivers an out-of-band message without blocking the caller. A client that has fallen
+// so far behind that its output buffer is full gets disconnected rather than stalling the sender.
+func (c *client) push(msg resptypes.RespSerializable) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.out == nil {
+		return false
+	}
+
+	select {
+	case c.out <- msg.ToRespString():
+		return true
+	default:
+		slog.Warn("Disconnecting client, output buffer limit reached", "id", c.id, "limit", cap(c.out))
+		c.closed = true
+		c.kill()
+		return false
+	}
+}
+
+// offer delivers a message if there is room in the output buffer and drops it otherwise.
+// Unlike push, it never disconnects the client.
+func (c *client) offer(msg string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.out == nil {
+		return false
+	}
+
+	select {
+	case c.out <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops further pushes and closes the outbound channel. Must only be called once
+// the connection's request loop has stopped sending replies.
+func (c *client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.out != nil {
+		close(c.out)
+		c.out = nil
+	}
+}
+
+func (c *client) touch() {
+	c.lastInteraction.Store(time.Now().UnixNano())
+}
+
+func (c *client) subscriptionCount() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+func contextWithClient(ctx context.Context, c *client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, c)
+}
+
+// commandTime returns the time the current command runs at, see client.now.
+func commandTime(ctx context.Context) time.Time {
+	if now := clientFromContext(ctx).now; !now.IsZero() {
+		return now
+	}
+
+	return time.Now()
+}
+
+// clientFromContext returns the client attached by ConnectClient. Requests that arrive
+// without one (e.g. from tests) get a throwaway client on database 0.
+func clientFromContext(ctx context.Context) *client {
+	if c, ok := ctx.Value(clientContextKey{}).(*client); ok {
+		return c
+	}
+
+	return newClient()
+}
+
+func (c *client) watch(ds redistypes.DataStore, key redistypes.StoreKey) {
+	for _, w := range c.watched {
+		if w.ds == ds && w.key == key {
+			return
+		}
+	}
+
+	c.watched = append(c.watched, watchedKey{ds: ds, key: key, version: ds.Watch(key)})
+}
+
+func (c *client) unwatchAll() {
+	for _, w := range c.watched {
+		w.ds.Unwatch(w.key)
+	}
+
+	c.watched = nil
+}
+
+// watchedKeysModified reports whether any watched key was touched since WATCH.
+func (c *client) watchedKeysModified() bool {
+	for _, w := range c.watched {
+		if w.ds.Version(w.key) != w.version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newClientList() *clientList {
+	return &clientList{byId: make(map[int64]*client)}
+}
+
+func (l *clientList) add(c *client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.byId[c.id] = c
+}
+
+func (l *clientList) remove(c *client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.byId, c.id)
+}
+
+func (l *clientList) len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.byId)
+}
+
+// idle returns the clients that did not interact for longer than timeout.
+func (l *clientList) idle(timeout time.Duration) []*client {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	threshold := time.Now().Add(-timeout).UnixNano()
+	var idle []*client
+	for _, c := range l.byId {
+		if c.lastInteraction.Load() < threshold {
+			idle = append(idle, c)
+		}
+	}
+
+	return idle
+}
+
+func (l *clientList) all() []*client {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	all := make([]*client, 0, len(l.byId))
+	for _, c := range l.byId {
+		all = append(all, c)
+	}
+
+	return all
+}
+
+func (l *clientList) get(id int64) (*client, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	c, exists := l.byId[id]
+	return c, exists
+}
+
+func (k keyspace) db(ctx context.Context) redistypes.DataStore {
+	return k.DB(clientFromContext(ctx).db)
+}
+
+// getOrCreate returns the value at key in the client's database, creating it with newFunc
+// (and signalling the new key) if it does not exist.
+func (k keyspace) getOrCreate(ctx context.Context, key redistypes.StoreKey, newFunc func() redistypes.StoreValue) redistypes.StoreValue {
+	created := false
+	dsVal := k.db(ctx).GetOrCreate(key, func() redistypes.StoreValue {
+		created = true
+		return newFunc()
+	}, 0)
+
+	if created {
+		k.notify(ctx, notifyNew, "new", key)
+	}
+
+	return dsVal
+}
+
+// notify emits a keyspace event for key in the client's database.
+func (k keyspace) notify(ctx context.Context, class notifyClass, event string, key redistypes.StoreKey) {
+	k.events.notify(class, event, key, clientFromContext(ctx).db)
+}
+
+// missed signals that a read command found no value at key.
+func (k keyspace) missed(ctx context.Context, key redistypes.StoreKey) {
+	k.metrics.keyspaceMisses.Add(1)
+	k.notify(ctx, notifyKeyMiss, "keymiss", key)
+}
+
+// read signals that the client has read the value at key.
+func (k keyspace) read(ctx context.Context, key redistypes.StoreKey) {
+	k.metrics.keyspaceLookups.Add(1)
+	k.tracking.remember(clientFromContext(ctx), key)
+}
+
+// modified signals that the value at key in the client's database has changed.
+func (k keyspace) modified(ctx context.Context, key redistypes.StoreKey) {
+	k.db(ctx).Touch(key)
+	k.dirty.Add(1)
+	k.tracking.invalidate(key, clientFromContext(ctx))
+}
+
+// flushed signals that every key of one or all databases was removed.
+func (k keyspace) flushed(ctx context.Context) {
+	k.dirty.Add(1)
+	k.tracking.invalidateAll(clientFromContext(ctx))
+}
+
+// expired is registered with the databases and signals that a key's expiry elapsed.
+func (k keyspace) expired(index int, key redistypes.StoreKey) {
+	defer k.latency.since(latencyEventExpireCycle, time.Now())
+	k.metrics.expiredKeys.Add(1)
+	k.events.notify(notifyExpired, "expired", key, index)
+	k.dirty.Add(1)
+	k.tracking.invalidate(key, nil)
+}
+
+// size counts the keys of the database at index and those with an expiry, along with the
+// sum of their TTLs.
+func (k keyspace) size(index int, now time.Time) (keys int, expires int, ttl time.Duration) {
+	k.DB(index).ForEach(func(key redistypes.StoreKey, value redistypes.StoreValue, expiresAt time.Time) {
+		keys++
+		if !expiresAt.IsZero() {
+			expires++
+			ttl += expiresAt.Sub(now)
+		}
+	})
+
+	return keys, expires, ttl
+}
+
+// info returns the INFO keyspace fields, one per database holding keys.
+func (k keyspace) info() []string {
+	var fields []string
+	now := time.Now()
+	for index := range k.Len() {
+		keys, expires, ttl := k.size(index, now)
+		if keys == 0 {
+			continue
+		}
+
+		avgTtl := int64(0)
+		if expires > 0 {
+			avgTtl = ttl.Milliseconds() / int64(expires)
+		}
+
+		fields = append(fields, fmt.Sprintf("db%d:keys=%d,expires=%d,avg_ttl=%d", index, keys, expires, avgTtl))
+	}
+
+	return fields
+}
+
+// parseDbIndex parses a database index argument and checks it against the configured count.
+func (k keyspace) parseDbIndex(str string) (int, error) {
+	index, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("ERR value is not an integer or out of range")
+	}
+
+	if index < 0 || index >= k.Len() {
+		return 0, fmt.Errorf("ERR DB index is out of range")
+	}
+
+	return index, nil
+}
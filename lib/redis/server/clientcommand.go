@@ -0,0 +1,223 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	clientCmd struct {
+		clients  *clientList
+		tracking *tracking
+	}
+)
+
+func (c clientCmd) moniker() string {
+	return "CLIENT"
+}
+
+func (c clientCmd) spec() commandSpec {
+	return commandSpec{
+		arity:      -2,
+		categories: []string{"connection"},
+		group:      "connection",
+		summary:    "A container for client connection commands.",
+		since:      "2.4.0",
+		subcommands: []commandSpec{
+			{name: "id", arity: 2, summary: "Returns the unique client ID of the connection.", since: "5.0.0", complexity: "O(1)"},
+			{
+				name: "setname", arity: 3, summary: "Sets the connection name.", since: "2.6.9", complexity: "O(1)",
+				args: []commandArg{{name: "connection-name", typ: argString}},
+			},
+			{name: "getname", arity: 2, summary: "Returns the name of the connection.", since: "2.6.9", complexity: "O(1)"},
+			{
+				name: "tracking", arity: -3, summary: "Controls server-assisted client-side caching for the connection.", since: "6.0.0",
+				complexity: "O(1). Some options may introduce additional complexity.",
+				args: []commandArg{
+					{name: "status", typ: argOneOf, args: []commandArg{
+						{name: "on", typ: argPureToken, token: "ON"},
+						{name: "off", typ: argPureToken, token: "OFF"},
+					}},
+					{name: "client-id", typ: argInteger, token: "REDIRECT", optional: true},
+					{name: "prefix", typ: argString, token: "PREFIX", optional: true, multiple: true},
+					{name: "bcast", typ: argPureToken, token: "BCAST", optional: true},
+					{name: "optin", typ: argPureToken, token: "OPTIN", optional: true},
+					{name: "optout", typ: argPureToken, token: "OPTOUT", optional: true},
+					{name: "noloop", typ: argPureToken, token: "NOLOOP", optional: true},
+				},
+			},
+			{
+				name: "caching", arity: 3, summary: "Instructs the server whether to track the keys in the next request.", since: "6.0.0",
+				complexity: "O(1)",
+				args: []commandArg{{name: "mode", typ: argOneOf, args: []commandArg{
+					{name: "yes", typ: argPureToken, token: "YES"},
+					{name: "no", typ: argPureToken, token: "NO"},
+				}}},
+			},
+			{
+				name: "getredir", arity: 2, summary: "Returns the client ID to which the connection's tracking notifications are redirected.",
+				since: "6.0.0", complexity: "O(1)",
+			},
+		},
+	}
+}
+
+func (c clientCmd) getUsage() string {
+	return `
+usage:
+	CLIENT ID
+	CLIENT SETNAME connection-name
+	CLIENT GETNAME
+	CLIENT TRACKING <ON | OFF> [REDIRECT client-id] [PREFIX prefix [PREFIX prefix ...]] [BCAST] [OPTIN] [OPTOUT] [NOLOOP]
+	CLIENT CACHING <YES | NO>
+	CLIENT GETREDIR
+summary:
+	ID returns the ID of the current connection.
+	SETNAME names the current connection, e.g. in the slow log. An empty name removes it.
+	GETNAME returns the name of the current connection, or nil when it has none.
+	TRACKING enables or disables server-assisted client-side caching. Keys read by the connection are remembered,
+	and an invalidation push message is sent when any of them is modified or expires.
+	With REDIRECT, invalidations are published instead to the given client, which must be subscribed to __redis__:invalidate.
+	BCAST sends invalidations for every modified key matching one of the PREFIXes, without remembering reads.
+	OPTIN only remembers keys read right after CLIENT CACHING YES, OPTOUT remembers all keys except those read right after CLIENT CACHING NO.
+	NOLOOP skips invalidations for keys modified by this connection.
+	CACHING controls whether the next command's keys are remembered in OPTIN/OPTOUT mode.
+	GETREDIR returns the client ID invalidations are redirected to, 0 when not redirecting, or -1 when tracking is off.
+`
+}
+
+func (c clientCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR CLIENT requires a subcommand! %s", c.getUsage())}
+	}
+
+	client := clientFromContext(ctx)
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "ID" && len(params) == 2:
+		return resptypes.Integer{Val: client.id}
+	case subcommand == "SETNAME" && len(params) == 3:
+		name := params[2].Val
+		if strings.IndexFunc(name, func(r rune) bool { return r <= ' ' || r > '~' }) >= 0 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Client names cannot contain spaces, newlines or special characters.")}
+		}
+
+		client.name = name
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "GETNAME" && len(params) == 2:
+		if client.name == "" {
+			return resptypes.NullBulkString
+		}
+
+		return resptypes.NewBulkString(client.name)
+	case subcommand == "TRACKING" && len(params) >= 3:
+		return c.executeTracking(client, params[2:])
+	case subcommand == "CACHING" && len(params) == 3:
+		return c.executeCaching(client, params[2].Val)
+	case subcommand == "GETREDIR" && len(params) == 2:
+		opts, enabled := c.tracking.optionsOf(client)
+		if !enabled {
+			return resptypes.Integer{Val: -1}
+		}
+
+		return resptypes.Integer{Val: opts.redirect}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown CLIENT subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+func (c clientCmd) executeTracking(client *client, args commandParams) commandResult {
+	switch strings.ToUpper(args[0].Val) {
+	case "OFF":
+		if len(args) > 1 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+		}
+
+		c.tracking.disable(client)
+		return resptypes.SimpleString{Val: "OK"}
+	case "ON":
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+	}
+
+	opts := trackingOptions{}
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i].Val) {
+		case "REDIRECT":
+			if i+1 >= len(args) {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+			}
+
+			i++
+			id, err := strconv.ParseInt(args[i].Val, 10, 64)
+			if err != nil {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+			}
+
+			if _, exists := c.clients.get(id); !exists || id == client.id {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR The client ID you want redirect to does not exist")}
+			}
+
+			opts.redirect = id
+		case "PREFIX":
+			if i+1 >= len(args) {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+			}
+
+			i++
+			opts.prefixes = append(opts.prefixes, args[i].Val)
+		case "BCAST":
+			opts.bcast = true
+		case "OPTIN":
+			opts.optIn = true
+		case "OPTOUT":
+			opts.optOut = true
+		case "NOLOOP":
+			opts.noLoop = true
+		default:
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+		}
+	}
+
+	switch {
+	case len(opts.prefixes) > 0 && !opts.bcast:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR PREFIX option requires BCAST mode to be enabled")}
+	case opts.bcast && (opts.optIn || opts.optOut):
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR OPTIN and OPTOUT are not compatible with BCAST")}
+	case opts.optIn && opts.optOut:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR You can't use both OPTIN and OPTOUT")}
+	}
+
+	c.tracking.enable(client, opts)
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+func (c clientCmd) executeCaching(client *client, arg string) commandResult {
+	opts, enabled := c.tracking.optionsOf(client)
+	if !enabled || !(opts.optIn || opts.optOut) {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")}
+	}
+
+	var caching bool
+	switch strings.ToUpper(arg) {
+	case "YES":
+		if !opts.optIn {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode")}
+		}
+		caching = true
+	case "NO":
+		if !opts.optOut {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode")}
+		}
+		caching = false
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+	}
+
+	client.caching = &caching
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -0,0 +1,602 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+)
+
+type (
+	// clusterNode is a member of the cluster as known to this node.
+	clusterNode struct {
+		id          string
+		host        string
+		port        int
+		busPort     int
+		configEpoch uint64
+
+		// Unix time in milliseconds of the last ping sent and pong received over the bus
+		pingSent     int64
+		pongReceived int64
+		connected    bool
+		// Cancels the outbound bus link, nil for the node itself
+		stopLink func()
+	}
+
+	// clusterShard is a node serving slots, as listed by CLUSTER SLOTS and CLUSTER SHARDS.
+	clusterShard struct {
+		node   clusterNode
+		ranges [][2]int
+	}
+
+	// cluster holds the cluster configuration of a node: the members, which node serves each
+	// hash slot and the slots being migrated. It is exchanged with the other members over the
+	// cluster bus and saved to the cluster config file on every change.
+	cluster struct {
+		dbs        redistypes.Databases
+		configFile string
+
+		mu           sync.Mutex
+		myself       *clusterNode
+		nodes        map[string]*clusterNode
+		slots        [redislib.ClusterSlots]*clusterNode
+		migrating    map[int]*clusterNode
+		importing    map[int]*clusterNode
+		currentEpoch uint64
+
+		listener net.Listener
+		// Cancelled by close, which stops every bus connection
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+)
+
+var (
+	errCrossSlot      = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+	errSlotNotServed  = errors.New("CLUSTERDOWN Hash slot not served")
+	errTryAgain       = errors.New("TRYAGAIN Multiple keys request during rehashing of slot")
+	errClusterDisable = errors.New("ERR This instance has cluster support disabled")
+)
+
+func newNodeId() string {
+	id := make([]byte, 20)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func newCluster(dbs redistypes.Databases, options processorOptions) *cluster {
+	busPort := options.clusterPort
+	if busPort == 0 {
+		busPort = options.port + 10000
+	}
+
+	c := &cluster{
+		dbs:        dbs,
+		configFile: filepath.Join(options.dir, options.clusterConfigFile),
+		myself:     &clusterNode{id: newNodeId(), port: options.port, busPort: busPort, connected: true},
+		migrating:  make(map[int]*clusterNode),
+		importing:  make(map[int]*clusterNode),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.nodes = map[string]*clusterNode{c.myself.id: c.myself}
+	return c
+}
+
+func (n *clusterNode) addr() string {
+	return net.JoinHostPort(n.host, strconv.Itoa(n.port))
+}
+
+func (n *clusterNode) busAddr() string {
+	return net.JoinHostPort(n.host, strconv.Itoa(n.busPort))
+}
+
+// route tells whether this node serves a request for keys, and otherwise where to redirect it.
+// flags are the command's, with flagAsking added when the client sent ASKING right before.
+func (c *cluster) route(keys []string, db redistypes.DataStore, flags commandFlags) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	slot := redislib.KeyHashSlot(keys[0])
+	for _, key := range keys[1:] {
+		if redislib.KeyHashSlot(key) != slot {
+			return errCrossSlot
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	owner := c.slots[slot]
+	switch {
+	case owner == nil:
+		return errSlotNotServed
+	case owner == c.myself:
+		target := c.migrating[slot]
+		if target == nil || flags&flagSlotMigration != 0 {
+			return nil
+		}
+
+		// Keys that were already migrated, or are yet to be created, are served by the target
+		missing := 0
+		for _, key := range keys {
+			if _, exists := db.Get(key); !exists {
+				missing++
+			}
+		}
+
+		switch missing {
+		case 0:
+			return nil
+		case len(keys):
+			return fmt.Errorf("ASK %d %s", slot, target.addr())
+		default:
+			return errTryAgain
+		}
+	case c.importing[slot] != nil && flags&(flagAsking|flagSlotMigration) != 0:
+		return nil
+	default:
+		return fmt.Errorf("MOVED %d %s", slot, owner.addr())
+	}
+}
+
+// keysInSlot returns up to count keys of slot, sorted.
+func (c *cluster) keysInSlot(slot int, count int) []string {
+	keys := []string{}
+	c.dbs.DB(0).ForEach(func(key redistypes.StoreKey, _ redistypes.StoreValue, _ time.Time) {
+		if redislib.KeyHashSlot(key) == slot {
+			keys = append(keys, key)
+		}
+	})
+
+	slices.Sort(keys)
+	return keys[:min(count, len(keys))]
+}
+
+// bumpEpochNoLock gives the node's configuration precedence over every configuration seen so far.
+func (c *cluster) bumpEpochNoLock() {
+	c.currentEpoch++
+	c.myself.configEpoch = c.currentEpoch
+}
+
+// addSlots assigns unassigned slots to this node.
+func (c *cluster) addSlots(slots []int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, slot := range slots {
+		if c.slots[slot] != nil {
+			return fmt.Errorf("ERR Slot %d is already busy", slot)
+		}
+	}
+
+	for _, slot := range slots {
+		c.slots[slot] = c.myself
+		delete(c.importing, slot)
+	}
+
+	c.saveNoLock()
+	return nil
+}
+
+func (c *cluster) setSlotMigrating(slot int, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slots[slot] != c.myself {
+		return fmt.Errorf("ERR I'm not the owner of hash slot %d", slot)
+	}
+
+	target, exists := c.nodes[id]
+	if !exists {
+		return fmt.Errorf("ERR I don't know about node %s", id)
+	}
+
+	if target == c.myself {
+		return fmt.Errorf("ERR Target node is myself")
+	}
+
+	c.migrating[slot] = target
+	c.saveNoLock()
+	return nil
+}
+
+func (c *cluster) setSlotImporting(slot int, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slots[slot] == c.myself {
+		return fmt.Errorf("ERR I'm already the owner of hash slot %d", slot)
+	}
+
+	source, exists := c.nodes[id]
+	if !exists {
+		return fmt.Errorf("ERR I don't know about node %s", id)
+	}
+
+	if source == c.myself {
+		return fmt.Errorf("ERR Source node is myself")
+	}
+
+	c.importing[slot] = source
+	c.saveNoLock()
+	return nil
+}
+
+func (c *cluster) setSlotStable(slot int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.migrating, slot)
+	delete(c.importing, slot)
+	c.saveNoLock()
+}
+
+// setSlotNode assigns slot to the node with the given ID, which completes a migration.
+func (c *cluster) setSlotNode(slot int, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	node, exists := c.nodes[id]
+	if !exists {
+		return fmt.Errorf("ERR Unknown node %s", id)
+	}
+
+	if c.slots[slot] == c.myself && node != c.myself && len(c.keysInSlot(slot, 1)) > 0 {
+		return fmt.Errorf("ERR Can't assign hashslot %d to a different node while I still hold keys for this hash slot.", slot)
+	}
+
+	delete(c.migrating, slot)
+	if node == c.myself && c.importing[slot] != nil {
+		// Make the other nodes accept the new owner over the previous one
+		c.bumpEpochNoLock()
+		slog.Info("Hash slot imported", "slot", slot, "configEpoch", c.myself.configEpoch)
+	}
+
+	delete(c.importing, slot)
+	c.slots[slot] = node
+	c.saveNoLock()
+	return nil
+}
+
+// claimSlotsNoLock applies the slots a node announces over the bus. A claim for a slot served
+// by another node only wins with a newer configuration epoch. It reports whether anything changed.
+func (c *cluster) claimSlotsNoLock(sender *clusterNode, ranges [][2]int) bool {
+	changed := false
+	for _, r := range ranges {
+		for slot := max(0, r[0]); slot <= min(r[1], redislib.ClusterSlots-1); slot++ {
+			owner := c.slots[slot]
+			if owner == sender || (owner != nil && owner.configEpoch >= sender.configEpoch) {
+				continue
+			}
+
+			if owner == c.myself {
+				slog.Info("Hash slot taken over by a node with a newer configuration", "slot", slot, "node", sender.id)
+				delete(c.migrating, slot)
+			}
+
+			if c.importing[slot] != nil {
+				delete(c.importing, slot)
+			}
+
+			c.slots[slot] = sender
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// slotRangesNoLock returns the slots served by node as ranges of consecutive slots.
+func (c *cluster) slotRangesNoLock(node *clusterNode) [][2]int {
+	ranges := [][2]int{}
+	for slot := 0; slot < redislib.ClusterSlots; slot++ {
+		if c.slots[slot] != node {
+			continue
+		}
+
+		if n := len(ranges); n > 0 && ranges[n-1][1] == slot-1 {
+			ranges[n-1][1] = slot
+		} else {
+			ranges = append(ranges, [2]int{slot, slot})
+		}
+	}
+
+	return ranges
+}
+
+// sortedNodesNoLock returns the nodes ordered by ID, so that listings are stable.
+func (c *cluster) sortedNodesNoLock() []*clusterNode {
+	nodes := make([]*clusterNode, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		nodes = append(nodes, node)
+	}
+
+	slices.SortFunc(nodes, func(a, b *clusterNode) int { return strings.Compare(a.id, b.id) })
+	return nodes
+}
+
+// nodeLineNoLock describes a node the way CLUSTER NODES and the cluster config file do.
+func (c *cluster) nodeLineNoLock(node *clusterNode) string {
+	flags := "master"
+	linkState := "connected"
+	if node == c.myself {
+		flags = "myself,master"
+	} else if !node.connected {
+		linkState = "disconnected"
+	}
+
+	fields := []string{
+		node.id,
+		fmt.Sprintf("%s@%d", node.addr(), node.busPort),
+		flags,
+		"-",
+		strconv.FormatInt(node.pingSent, 10),
+		strconv.FormatInt(node.pongReceived, 10),
+		strconv.FormatUint(node.configEpoch, 10),
+		linkState,
+	}
+
+	for _, r := range c.slotRangesNoLock(node) {
+		if r[0] == r[1] {
+			fields = append(fields, strconv.Itoa(r[0]))
+		} else {
+			fields = append(fields, fmt.Sprintf("%d-%d", r[0], r[1]))
+		}
+	}
+
+	if node == c.myself {
+		for _, slot := range slices.Sorted(maps.Keys(c.migrating)) {
+			fields = append(fields, fmt.Sprintf("[%d->-%s]", slot, c.migrating[slot].id))
+		}
+
+		for _, slot := range slices.Sorted(maps.Keys(c.importing)) {
+			fields = append(fields, fmt.Sprintf("[%d-<-%s]", slot, c.importing[slot].id))
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (c *cluster) nodesNoLock() string {
+	var sb strings.Builder
+	for _, node := range c.sortedNodesNoLock() {
+		sb.WriteString(c.nodeLineNoLock(node) + "\n")
+	}
+
+	return sb.String()
+}
+
+// saveNoLock writes the cluster config file, so that a restarted node keeps its identity and slots.
+func (c *cluster) saveNoLock() {
+	content := c.nodesNoLock() + fmt.Sprintf("vars currentEpoch %d lastVoteEpoch 0\n", c.currentEpoch)
+	tmp := c.configFile + ".tmp"
+	err := os.WriteFile(tmp, []byte(content), 0o644)
+	if err == nil {
+		err = os.Rename(tmp, c.configFile)
+	}
+
+	if err != nil {
+		slog.Error("Failed to save the cluster config file", "path", c.configFile, "error", err)
+	}
+}
+
+// load restores the configuration saved by saveNoLock. A missing file leaves a fresh node.
+func (c *cluster) load() error {
+	f, err := os.Open(c.configFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	type pending struct {
+		node  *clusterNode
+		slots []string
+	}
+
+	nodes := map[string]*clusterNode{}
+	lines := []pending{}
+	var myself *clusterNode
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "vars" {
+			for i := 1; i+1 < len(fields); i += 2 {
+				if fields[i] == "currentEpoch" {
+					c.currentEpoch, _ = strconv.ParseUint(fields[i+1], 10, 64)
+				}
+			}
+
+			continue
+		}
+
+		if len(fields) < 8 {
+			return fmt.Errorf("invalid cluster config line %q", scanner.Text())
+		}
+
+		node, err := parseNodeAddr(fields[0], fields[1])
+		if err != nil {
+			return err
+		}
+
+		node.configEpoch, _ = strconv.ParseUint(fields[6], 10, 64)
+		if slices.Contains(strings.Split(fields[2], ","), "myself") {
+			myself = node
+			node.connected = true
+		}
+
+		nodes[node.id] = node
+		lines = append(lines, pending{node: node, slots: fields[8:]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if myself == nil {
+		return fmt.Errorf("cluster config file %s does not describe this node", c.configFile)
+	}
+
+	// The node keeps its identity, but serves on the configured ports
+	myself.port, myself.busPort = c.myself.port, c.myself.busPort
+	c.myself, c.nodes = myself, nodes
+	for _, line := range lines {
+		for _, field := range line.slots {
+			if err := c.loadSlotNoLock(line.node, field); err != nil {
+				return err
+			}
+		}
+	}
+
+	slog.Info("Cluster config loaded", "path", c.configFile, "id", c.myself.id, "nodes", len(c.nodes))
+	return nil
+}
+
+func parseNodeAddr(id string, addr string) (*clusterNode, error) {
+	// <host>:<port>@<bus port>[,<hostname>]
+	addr, _, _ = strings.Cut(addr, ",")
+	hostPort, busPortStr, _ := strings.Cut(addr, "@")
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node address %q: %w", addr, err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node address %q", addr)
+	}
+
+	busPort, err := strconv.Atoi(busPortStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node address %q", addr)
+	}
+
+	return &clusterNode{id: id, host: host, port: port, busPort: busPort}, nil
+}
+
+// loadSlotNoLock applies one slot field of a config line: a slot, a range of slots or,
+// for the node itself, a migration in progress.
+func (c *cluster) loadSlotNoLock(node *clusterNode, field string) error {
+	if migration, found := strings.CutPrefix(field, "["); found {
+		migration = strings.TrimSuffix(migration, "]")
+		if slotStr, id, found := strings.Cut(migration, "->-"); found {
+			slot, err := strconv.Atoi(slotStr)
+			if other := c.nodes[id]; err == nil && other != nil {
+				c.migrating[slot] = other
+				return nil
+			}
+		}
+
+		if slotStr, id, found := strings.Cut(migration, "-<-"); found {
+			slot, err := strconv.Atoi(slotStr)
+			if other := c.nodes[id]; err == nil && other != nil {
+				c.importing[slot] = other
+				return nil
+			}
+		}
+
+		return fmt.Errorf("invalid slot migration %q", field)
+	}
+
+	startStr, endStr, isRange := strings.Cut(field, "-")
+	if !isRange {
+		endStr = startStr
+	}
+
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return fmt.Errorf("invalid slot %q", field)
+	}
+
+	end, err := strconv.Atoi(endStr)
+	if err != nil || start < 0 || end >= redislib.ClusterSlots || start > end {
+		return fmt.Errorf("invalid slot range %q", field)
+	}
+
+	for slot := start; slot <= end; slot++ {
+		c.slots[slot] = node
+	}
+
+	return nil
+}
+
+// info returns the fields of CLUSTER INFO.
+func (c *cluster) info() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assigned := 0
+	serving := map[*clusterNode]bool{}
+	for _, owner := range c.slots {
+		if owner != nil {
+			assigned++
+			serving[owner] = true
+		}
+	}
+
+	state := "fail"
+	if assigned == redislib.ClusterSlots {
+		state = "ok"
+	}
+
+	return []string{
+		"cluster_state:" + state,
+		fmt.Sprintf("cluster_slots_assigned:%d", assigned),
+		fmt.Sprintf("cluster_slots_ok:%d", assigned),
+		"cluster_slots_pfail:0",
+		"cluster_slots_fail:0",
+		fmt.Sprintf("cluster_known_nodes:%d", len(c.nodes)),
+		fmt.Sprintf("cluster_size:%d", len(serving)),
+		fmt.Sprintf("cluster_current_epoch:%d", c.currentEpoch),
+		fmt.Sprintf("cluster_my_epoch:%d", c.myself.configEpoch),
+	}
+}
+
+func (c *cluster) myId() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.myself.id
+}
+
+// nodeList returns the CLUSTER NODES listing.
+func (c *cluster) nodeList() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodesNoLock()
+}
+
+// shards returns the nodes serving slots with copies of their state, ordered by their first slot.
+func (c *cluster) shards() []clusterShard {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	shards := []clusterShard{}
+	for _, node := range c.sortedNodesNoLock() {
+		if ranges := c.slotRangesNoLock(node); len(ranges) > 0 {
+			shards = append(shards, clusterShard{node: *node, ranges: ranges})
+		}
+	}
+
+	slices.SortFunc(shards, func(a, b clusterShard) int { return a.ranges[0][0] - b.ranges[0][0] })
+	return shards
+}
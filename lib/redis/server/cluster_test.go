@@ -0,0 +1,208 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+type clusterTestNode struct {
+	testClient
+	dir     string
+	port    int
+	busPort int
+	id      string
+}
+
+// freePort returns a loopback port that was free a moment ago.
+func freePort(t *testing.T) int {
+	listener := listen(t)
+	defer listener.Close()
+	return listenerPort(listener)
+}
+
+func listenerPort(listener net.Listener) int {
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func startClusterNode(t *testing.T) *clusterTestNode {
+	listener := listen(t)
+	n := &clusterTestNode{dir: t.TempDir(), port: listenerPort(listener), busPort: freePort(t)}
+	cp := n.open()
+	serveListener(t, listener, cp)
+	n.testClient = newTestClient(cp)
+	t.Cleanup(func() { n.cp.Close() })
+	n.id = strings.Split(n.do("CLUSTER", "MYID"), "\r\n")[1]
+	return n
+}
+
+func (n *clusterTestNode) open() redisserverlib.CommandProcessor {
+	return redisserverlib.NewRedisCommandProcessor(
+		redisserverlib.WithDir(n.dir),
+		redisserverlib.WithPort(n.port),
+		redisserverlib.WithClusterEnabled(true),
+		redisserverlib.WithClusterPort(n.busPort),
+	)
+}
+
+func (n *clusterTestNode) moved(slot int) string {
+	return fmt.Sprintf("-MOVED %d 127.0.0.1:%d\r\n", slot, n.port)
+}
+
+// waitFor polls until cond holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	a := startClusterNode(t)
+	b := startClusterNode(t)
+	c := startClusterNode(t)
+
+	// foo and {foo}x hash to slot 12182, bar to slot 5061
+	a.expect(t, "+OK\r\n", "CLUSTER", "ADDSLOTSRANGE", "0", "8191")
+	b.expect(t, "+OK\r\n", "CLUSTER", "ADDSLOTSRANGE", "8192", "16383")
+	a.expect(t, "-ERR Slot 100 is already busy\r\n", "CLUSTER", "ADDSLOTS", "100")
+	a.expect(t, "+OK\r\n", "CLUSTER", "MEET", "127.0.0.1", fmt.Sprint(b.port), fmt.Sprint(b.busPort))
+	// c only meets a and learns about b through gossip
+	c.expect(t, "+OK\r\n", "CLUSTER", "MEET", "127.0.0.1", fmt.Sprint(a.port), fmt.Sprint(a.busPort))
+
+	for _, n := range []*clusterTestNode{a, b, c} {
+		waitFor(t, "the cluster to converge", func() bool {
+			info := n.do("CLUSTER", "INFO")
+			return strings.Contains(info, "cluster_state:ok") && strings.Contains(info, "cluster_known_nodes:3")
+		})
+	}
+
+	t.Run("Redirects", func(t *testing.T) {
+		b.expect(t, "+OK\r\n", "SET", "foo", "1")
+		b.expect(t, "$1\r\n1\r\n", "GET", "foo")
+		a.expect(t, b.moved(12182), "GET", "foo")
+		c.expect(t, b.moved(12182), "GET", "foo")
+		b.expect(t, a.moved(5061), "SET", "bar", "1")
+		b.expect(t, "-CROSSSLOT Keys in request don't hash to the same slot\r\n", "DEL", "foo", "bar")
+		b.expect(t, ":1\r\n", "DEL", "foo", "{foo}x")
+		a.expect(t, "-ERR SELECT is not allowed in cluster mode\r\n", "SELECT", "1")
+		c.expect(t, ":12182\r\n", "CLUSTER", "KEYSLOT", "{foo}bar")
+
+		a.expect(t, "+OK\r\n", "MULTI")
+		a.expect(t, b.moved(12182), "SET", "foo", "2")
+		a.expect(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+	})
+
+	t.Run("Slots", func(t *testing.T) {
+		expected := fmt.Sprintf("*2\r\n"+
+			"*3\r\n:0\r\n:8191\r\n*3\r\n$9\r\n127.0.0.1\r\n:%d\r\n$40\r\n%s\r\n"+
+			"*3\r\n:8192\r\n:16383\r\n*3\r\n$9\r\n127.0.0.1\r\n:%d\r\n$40\r\n%s\r\n",
+			a.port, a.id, b.port, b.id)
+		c.expect(t, expected, "CLUSTER", "SLOTS")
+
+		nodes := c.do("CLUSTER", "NODES")
+		for _, line := range []string{
+			fmt.Sprintf("%s 127.0.0.1:%d@%d master", a.id, a.port, a.busPort),
+			fmt.Sprintf("%s 127.0.0.1:%d@%d myself,master", c.id, c.port, c.busPort),
+		} {
+			if !strings.Contains(nodes, line) {
+				t.Errorf("CLUSTER NODES = %q; Expected a line starting with %q", nodes, line)
+			}
+		}
+	})
+
+	t.Run("Slot migration", func(t *testing.T) {
+		b.expect(t, "+OK\r\n", "SET", "foo", "1")
+		b.expect(t, "+OK\r\n", "SET", "{foo}x", "2")
+		// 13 elements of 10 bytes, so that the DUMP payload holds CRLF
+		b.expect(t, ":13\r\n", append([]string{"RPUSH", "{foo}l"}, slices.Repeat([]string{"0123456789"}, 13)...)...)
+		a.expect(t, "+OK\r\n", "CLUSTER", "SETSLOT", "12182", "IMPORTING", b.id)
+		b.expect(t, "+OK\r\n", "CLUSTER", "SETSLOT", "12182", "MIGRATING", a.id)
+
+		migrate := func(key string) {
+			t.Helper()
+			b.expect(t, "+OK\r\n", "MIGRATE", "127.0.0.1", fmt.Sprint(a.port), "", "0", "5000", "KEYS", key)
+		}
+
+		migrate("foo")
+		ask := fmt.Sprintf("-ASK 12182 127.0.0.1:%d\r\n", a.port)
+		b.expect(t, ask, "GET", "foo")
+		b.expect(t, "$1\r\n2\r\n", "GET", "{foo}x")
+		b.expect(t, "-TRYAGAIN Multiple keys request during rehashing of slot\r\n", "DEL", "foo", "{foo}x")
+		a.expect(t, b.moved(12182), "GET", "foo")
+		a.expect(t, "+OK\r\n", "ASKING")
+		a.expect(t, "$1\r\n1\r\n", "GET", "foo")
+		a.expect(t, b.moved(12182), "GET", "foo")
+		b.expect(t, ":2\r\n", "CLUSTER", "COUNTKEYSINSLOT", "12182")
+		b.expect(t, "-ERR Can't assign hashslot 12182 to a different node while I still hold keys for this hash slot.\r\n",
+			"CLUSTER", "SETSLOT", "12182", "NODE", a.id)
+
+		migrate("{foo}x")
+		migrate("{foo}l")
+		b.expect(t, "+NOKEY\r\n", "MIGRATE", "127.0.0.1", fmt.Sprint(a.port), "{foo}x", "0", "5000")
+		b.expect(t, ":0\r\n", "CLUSTER", "COUNTKEYSINSLOT", "12182")
+		a.expect(t, "+OK\r\n", "CLUSTER", "SETSLOT", "12182", "NODE", a.id)
+		b.expect(t, "+OK\r\n", "CLUSTER", "SETSLOT", "12182", "NODE", a.id)
+
+		a.expect(t, "$1\r\n2\r\n", "GET", "{foo}x")
+		a.expect(t, ":13\r\n", "LLEN", "{foo}l")
+		b.expect(t, a.moved(12182), "GET", "foo")
+		c.eventually(t, a.moved(12182), "GET", "foo")
+		c.expect(t, b.moved(15495), "GET", "a")
+	})
+
+	t.Run("Config file", func(t *testing.T) {
+		waitFor(t, "the config to spread", func() bool {
+			return strings.Contains(c.do("CLUSTER", "NODES"), "0-8191 12182")
+		})
+
+		c.cp.Close()
+		c.testClient = newTestClient(c.open())
+		c.expect(t, fmt.Sprintf("$40\r\n%s\r\n", c.id), "CLUSTER", "MYID")
+		c.expect(t, a.moved(12182), "GET", "foo")
+		c.expect(t, b.moved(15495), "GET", "a")
+	})
+}
+
+func TestDumpRestore(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	defer cp.Close()
+	cl := newTestClient(cp)
+
+	cl.expect(t, "$-1\r\n", "DUMP", "missing")
+	cl.expect(t, "+OK\r\n", "SET", "k", "10")
+	cl.expect(t, "$13\r\n\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n\r\n", "DUMP", "k")
+	cl.expect(t, ":1\r\n", "DEL", "k")
+	cl.expect(t, "+OK\r\n", "RESTORE", "k", "0", "\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n")
+	cl.expect(t, "$2\r\n10\r\n", "GET", "k")
+	cl.expect(t, "-BUSYKEY Target key name already exists.\r\n", "RESTORE", "k", "0", "\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\n")
+	cl.expect(t, "-ERR DUMP payload version or checksum are wrong\r\n", "RESTORE", "k", "0", "\x00\xc0\n\t\x00\xbem\x06\x89Z(\x00\x00", "REPLACE")
+
+	cl.expect(t, ":3\r\n", "RPUSH", "l", "a", "b", "c")
+	payload := strings.SplitN(cl.do("DUMP", "l"), "\r\n", 2)[1]
+	payload = strings.TrimSuffix(payload, "\r\n")
+	cl.expect(t, "+OK\r\n", "RESTORE", "k", "100000", payload, "REPLACE")
+	cl.expect(t, "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n", "LRANGE", "k", "0", "-1")
+	cl.expect(t, ":2\r\n", "DEL", "k", "l", "missing")
+
+	// 13 elements of 10 bytes, so that the payload holds CRLF
+	cl.expect(t, ":13\r\n", append([]string{"RPUSH", "l"}, slices.Repeat([]string{"0123456789"}, 13)...)...)
+	payload = strings.TrimSuffix(strings.SplitN(cl.do("DUMP", "l"), "\r\n", 2)[1], "\r\n")
+	if !strings.Contains(payload, "\r\n") {
+		t.Fatalf("DUMP l = %q; Expected a payload holding CRLF", payload)
+	}
+
+	cl.expect(t, "+OK\r\n", "RESTORE", "l2", "0", payload)
+	cl.expect(t, ":13\r\n", "LLEN", "l2")
+	cl.expect(t, "-ERR This instance has cluster support disabled\r\n", "CLUSTER", "INFO")
+}
@@ -0,0 +1,308 @@
+package redisserverlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+)
+
+type (
+	// busNode describes a node in a cluster bus message.
+	busNode struct {
+		Id          string `json:"id"`
+		Host        string `json:"host"`
+		Port        int    `json:"port"`
+		BusPort     int    `json:"busPort"`
+		ConfigEpoch uint64 `json:"configEpoch"`
+	}
+
+	// busMessage is exchanged between the nodes of a cluster, one JSON document per message.
+	// Every message carries the sender's slots, so that configuration changes spread with the
+	// regular pings, and gossip about the other nodes the sender knows, so that nodes that
+	// were introduced to one member get to know the whole cluster.
+	busMessage struct {
+		Type         string    `json:"type"`
+		Sender       busNode   `json:"sender"`
+		CurrentEpoch uint64    `json:"currentEpoch"`
+		Slots        [][2]int  `json:"slots"`
+		Gossip       []busNode `json:"gossip,omitempty"`
+	}
+)
+
+const (
+	// Sent to join the receiver to the sender's cluster, and as the first message of every link
+	busMeet = "meet"
+	busPing = "ping"
+	busPong = "pong"
+
+	clusterPingPeriod = 100 * time.Millisecond
+	// How long a bus connection may stay silent before it is considered broken
+	clusterNodeTimeout = 15 * time.Second
+)
+
+func hostOf(addr net.Addr) string {
+	host, _, _ := net.SplitHostPort(addr.String())
+	return host
+}
+
+// start listens on the cluster bus port and connects to the nodes from the config file.
+func (c *cluster) start() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", c.myself.busPort))
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listener = listener
+	for _, node := range c.nodes {
+		if node != c.myself {
+			c.startLinkNoLock(node)
+		}
+	}
+
+	c.wg.Go(func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			c.wg.Go(func() { c.serveBus(conn) })
+		}
+	})
+
+	slog.Info("Cluster bus listening", "port", c.myself.busPort, "id", c.myself.id)
+	return nil
+}
+
+func (c *cluster) close() {
+	c.mu.Lock()
+	c.cancel()
+	c.mu.Unlock()
+	if c.listener != nil {
+		c.listener.Close()
+	}
+
+	c.wg.Wait()
+}
+
+// serveBus answers the messages of another node's link with pongs.
+func (c *cluster) serveBus(conn net.Conn) {
+	defer conn.Close()
+	defer context.AfterFunc(c.ctx, func() { conn.Close() })()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(clusterNodeTimeout))
+		var msg busMessage
+		if err := decoder.Decode(&msg); err != nil {
+			return
+		}
+
+		c.mu.Lock()
+		c.learnHostNoLock(conn)
+		sender := c.nodes[msg.Sender.Id]
+		if sender == nil && msg.Type == busMeet && msg.Sender.Id != c.myself.id {
+			sender = c.addNodeNoLock(msg.Sender, hostOf(conn.RemoteAddr()))
+		}
+
+		if sender != nil {
+			c.processNoLock(sender, msg)
+		}
+
+		reply := c.messageNoLock(busPong)
+		c.mu.Unlock()
+
+		conn.SetWriteDeadline(time.Now().Add(clusterNodeTimeout))
+		if err := encoder.Encode(reply); err != nil {
+			return
+		}
+	}
+}
+
+// learnHostNoLock adopts the address other nodes reach this node at, which it cannot know otherwise.
+func (c *cluster) learnHostNoLock(conn net.Conn) {
+	if c.myself.host == "" {
+		c.myself.host = hostOf(conn.LocalAddr())
+		c.saveNoLock()
+	}
+}
+
+func (c *cluster) addNodeNoLock(info busNode, host string) *clusterNode {
+	node := &clusterNode{id: info.Id, host: host, port: info.Port, busPort: info.BusPort, configEpoch: info.ConfigEpoch}
+	c.nodes[node.id] = node
+	c.startLinkNoLock(node)
+	c.saveNoLock()
+	slog.Info("Cluster node added", "id", node.id, "addr", node.addr())
+	return node
+}
+
+// processNoLock applies the configuration a node announced.
+func (c *cluster) processNoLock(sender *clusterNode, msg busMessage) {
+	changed := false
+	if msg.CurrentEpoch > c.currentEpoch {
+		c.currentEpoch = msg.CurrentEpoch
+		changed = true
+	}
+
+	if msg.Sender.ConfigEpoch != sender.configEpoch {
+		sender.configEpoch = msg.Sender.ConfigEpoch
+		changed = true
+	}
+
+	changed = c.claimSlotsNoLock(sender, msg.Slots) || changed
+	for _, info := range msg.Gossip {
+		if info.Host != "" && info.Id != c.myself.id && c.nodes[info.Id] == nil {
+			c.addNodeNoLock(info, info.Host)
+		}
+	}
+
+	if changed {
+		c.saveNoLock()
+	}
+}
+
+func (c *cluster) messageNoLock(msgType string) busMessage {
+	info := func(node *clusterNode) busNode {
+		return busNode{Id: node.id, Host: node.host, Port: node.port, BusPort: node.busPort, ConfigEpoch: node.configEpoch}
+	}
+
+	msg := busMessage{
+		Type:         msgType,
+		Sender:       info(c.myself),
+		CurrentEpoch: c.currentEpoch,
+		Slots:        c.slotRangesNoLock(c.myself),
+	}
+	for _, node := range c.nodes {
+		if node != c.myself {
+			msg.Gossip = append(msg.Gossip, info(node))
+		}
+	}
+
+	return msg
+}
+
+func (c *cluster) startLinkNoLock(node *clusterNode) {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	node.stopLink = cancel
+	c.wg.Go(func() {
+		for ctx.Err() == nil {
+			err := c.runLink(ctx, node)
+			c.mu.Lock()
+			if node.connected {
+				slog.Warn("Cluster bus link lost", "id", node.id, "error", err)
+			}
+			node.connected = false
+			c.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(clusterPingPeriod):
+			}
+		}
+	})
+}
+
+// runLink pings node until the connection breaks.
+func (c *cluster) runLink(ctx context.Context, node *clusterNode) error {
+	c.mu.Lock()
+	addr := node.busAddr()
+	c.mu.Unlock()
+
+	conn, err := (&net.Dialer{Timeout: clusterNodeTimeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer context.AfterFunc(ctx, func() { conn.Close() })()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	msgType := busMeet
+	for {
+		c.mu.Lock()
+		c.learnHostNoLock(conn)
+		msg := c.messageNoLock(msgType)
+		node.pingSent = time.Now().UnixMilli()
+		c.mu.Unlock()
+		msgType = busPing
+
+		conn.SetWriteDeadline(time.Now().Add(clusterNodeTimeout))
+		if err := encoder.Encode(msg); err != nil {
+			return err
+		}
+
+		conn.SetReadDeadline(time.Now().Add(clusterNodeTimeout))
+		var reply busMessage
+		if err := decoder.Decode(&reply); err != nil {
+			return err
+		}
+
+		c.mu.Lock()
+		node.pingSent, node.pongReceived, node.connected = 0, time.Now().UnixMilli(), true
+		if reply.Sender.Id == node.id {
+			c.processNoLock(node, reply)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(clusterPingPeriod):
+		}
+	}
+}
+
+// meet introduces the node at host to the cluster. The node is added once it answers.
+func (c *cluster) meet(host string, busPort int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	c.wg.Go(func() {
+		addr := net.JoinHostPort(host, strconv.Itoa(busPort))
+		conn, err := (&net.Dialer{Timeout: clusterNodeTimeout}).DialContext(c.ctx, "tcp", addr)
+		if err != nil {
+			slog.Warn("CLUSTER MEET failed", "addr", addr, "error", err)
+			return
+		}
+		defer conn.Close()
+
+		c.mu.Lock()
+		c.learnHostNoLock(conn)
+		msg := c.messageNoLock(busMeet)
+		c.mu.Unlock()
+
+		conn.SetDeadline(time.Now().Add(clusterNodeTimeout))
+		var reply busMessage
+		if err := json.NewEncoder(conn).Encode(msg); err != nil {
+			slog.Warn("CLUSTER MEET failed", "addr", addr, "error", err)
+			return
+		}
+
+		if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+			slog.Warn("CLUSTER MEET failed", "addr", addr, "error", err)
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if reply.Sender.Id == c.myself.id || c.nodes[reply.Sender.Id] != nil {
+			return
+		}
+
+		c.processNoLock(c.addNodeNoLock(reply.Sender, host), reply)
+	})
+}
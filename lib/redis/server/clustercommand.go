@@ -0,0 +1,336 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	clusterCmd struct {
+		*cluster
+	}
+)
+
+func (c clusterCmd) moniker() string {
+	return "CLUSTER"
+}
+
+func (c clusterCmd) spec() commandSpec {
+	return commandSpec{
+		arity: -2,
+		// Keeps slot changes from interleaving with the commands they redirect
+		flags:   flagExclusive,
+		group:   "cluster",
+		summary: "A container for Redis Cluster commands.",
+		since:   "3.0.0",
+		subcommands: []commandSpec{
+			{name: "info", arity: 2, summary: "Returns information about the state of a node.", since: "3.0.0", complexity: "O(1)"},
+			{name: "myid", arity: 2, summary: "Returns the ID of a node.", since: "3.0.0", complexity: "O(1)"},
+			{
+				name: "nodes", arity: 2, summary: "Returns the cluster configuration for a node.", since: "3.0.0",
+				complexity: "O(N) where N is the total number of Cluster nodes",
+			},
+			{
+				name: "slots", arity: 2, summary: "Returns the mapping of cluster slots to nodes.", since: "3.0.0",
+				complexity: "O(N) where N is the total number of Cluster nodes",
+			},
+			{
+				name: "shards", arity: 2, summary: "Returns the mapping of cluster slots to shards.", since: "7.0.0",
+				complexity: "O(N) where N is the total number of cluster nodes",
+			},
+			{
+				name: "keyslot", arity: 3, summary: "Returns the hash slot for a key.", since: "3.0.0",
+				complexity: "O(N) where N is the number of bytes in the key",
+				args:       []commandArg{{name: "key", typ: argString}},
+			},
+			{
+				name: "countkeysinslot", arity: 3, summary: "Returns the number of keys in a hash slot.", since: "3.0.0", complexity: "O(1)",
+				args: []commandArg{{name: "slot", typ: argInteger}},
+			},
+			{
+				name: "getkeysinslot", arity: 4, summary: "Returns the key names in a hash slot.", since: "3.0.0",
+				complexity: "O(N) where N is the number of requested keys",
+				args:       []commandArg{{name: "slot", typ: argInteger}, {name: "count", typ: argInteger}},
+			},
+			{
+				name: "addslots", arity: -3, flags: flagAdmin, summary: "Assigns new hash slots to a node.", since: "3.0.0",
+				complexity: "O(N) where N is the total number of hash slot arguments",
+				args:       []commandArg{{name: "slot", typ: argInteger, multiple: true}},
+			},
+			{
+				name: "addslotsrange", arity: -4, flags: flagAdmin, summary: "Assigns new hash slot ranges to a node.", since: "7.0.0",
+				complexity: "O(N) where N is the total number of the slots between the start slot and end slot arguments.",
+				args: []commandArg{{name: "range", typ: argBlock, multiple: true, args: []commandArg{
+					{name: "start-slot", typ: argInteger},
+					{name: "end-slot", typ: argInteger},
+				}}},
+			},
+			{
+				name: "setslot", arity: -4, flags: flagAdmin, summary: "Binds a hash slot to a node.", since: "3.0.0", complexity: "O(1)",
+				args: []commandArg{
+					{name: "slot", typ: argInteger},
+					{name: "subcommand", typ: argOneOf, args: []commandArg{
+						{name: "importing", typ: argString, token: "IMPORTING"},
+						{name: "migrating", typ: argString, token: "MIGRATING"},
+						{name: "node", typ: argString, token: "NODE"},
+						{name: "stable", typ: argPureToken, token: "STABLE"},
+					}},
+				},
+			},
+			{
+				name: "meet", arity: -4, flags: flagAdmin, summary: "Forces a node to handshake with another node.", since: "3.0.0", complexity: "O(1)",
+				args: []commandArg{
+					{name: "ip", typ: argString},
+					{name: "port", typ: argInteger},
+					{name: "cluster-bus-port", typ: argInteger, optional: true},
+				},
+			},
+		},
+	}
+}
+
+func (c clusterCmd) getUsage() string {
+	return `
+usage:
+	CLUSTER INFO
+	CLUSTER MYID
+	CLUSTER NODES
+	CLUSTER SLOTS
+	CLUSTER SHARDS
+	CLUSTER KEYSLOT key
+	CLUSTER COUNTKEYSINSLOT slot
+	CLUSTER GETKEYSINSLOT slot count
+	CLUSTER ADDSLOTS slot [slot ...]
+	CLUSTER ADDSLOTSRANGE start-slot end-slot [start-slot end-slot ...]
+	CLUSTER SETSLOT slot IMPORTING node-id | MIGRATING node-id | STABLE | NODE node-id
+	CLUSTER MEET ip port [cluster-bus-port]
+summary:
+	Inspects and changes the cluster configuration of the node.
+	ADDSLOTS and ADDSLOTSRANGE assign unassigned hash slots to the node. MEET introduces another node,
+	after which the nodes learn about each other's slots and the rest of the cluster over the cluster bus.
+	To migrate a slot, mark it IMPORTING on the target and MIGRATING on the source, move its keys with MIGRATE,
+	then assign it with SETSLOT NODE on both nodes. Meanwhile the source redirects requests for keys
+	it no longer holds with -ASK.
+`
+}
+
+func (c clusterCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR CLUSTER requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	if subcommand == "KEYSLOT" && len(params) == 3 {
+		// Works without cluster support, to help clients plan
+		return resptypes.Integer{Val: int64(redislib.KeyHashSlot(params[2].Val))}
+	}
+
+	if c.cluster == nil {
+		return resptypes.SimpleError{Val: errClusterDisable}
+	}
+
+	switch {
+	case subcommand == "INFO" && len(params) == 2:
+		return resptypes.NewBulkString(strings.Join(c.info(), "\r\n") + "\r\n")
+	case subcommand == "MYID" && len(params) == 2:
+		return resptypes.NewBulkString(c.myId())
+	case subcommand == "NODES" && len(params) == 2:
+		return resptypes.NewBulkString(c.nodeList())
+	case subcommand == "SLOTS" && len(params) == 2:
+		return c.slotsReply()
+	case subcommand == "SHARDS" && len(params) == 2:
+		return c.shardsReply()
+	case subcommand == "COUNTKEYSINSLOT" && len(params) == 3:
+		slot, err := parseSlot(params[2].Val)
+		if err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.Integer{Val: int64(len(c.keysInSlot(slot, math.MaxInt)))}
+	case subcommand == "GETKEYSINSLOT" && len(params) == 4:
+		slot, err := parseSlot(params[2].Val)
+		if err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		count, err := strconv.Atoi(params[3].Val)
+		if err != nil || count < 0 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid number of keys")}
+		}
+
+		return resptypes.ToBulkStringArray(c.keysInSlot(slot, count))
+	case subcommand == "ADDSLOTS" && len(params) >= 3:
+		slots := []int{}
+		for _, param := range params[2:] {
+			slot, err := parseSlot(param.Val)
+			if err != nil {
+				return resptypes.SimpleError{Val: err}
+			}
+
+			slots = append(slots, slot)
+		}
+
+		return c.addSlotsReply(slots)
+	case subcommand == "ADDSLOTSRANGE" && len(params) >= 4 && len(params)%2 == 0:
+		slots := []int{}
+		for i := 2; i < len(params); i += 2 {
+			start, err := parseSlot(params[i].Val)
+			if err != nil {
+				return resptypes.SimpleError{Val: err}
+			}
+
+			end, err := parseSlot(params[i+1].Val)
+			if err != nil {
+				return resptypes.SimpleError{Val: err}
+			}
+
+			if start > end {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR start slot number %d is greater than end slot number %d", start, end)}
+			}
+
+			for slot := start; slot <= end; slot++ {
+				slots = append(slots, slot)
+			}
+		}
+
+		return c.addSlotsReply(slots)
+	case subcommand == "SETSLOT" && len(params) >= 4:
+		return c.setSlot(params)
+	case subcommand == "MEET" && (len(params) == 4 || len(params) == 5):
+		port, err := strconv.Atoi(params[3].Val)
+		if err != nil || port <= 0 || port > 65535 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid base port specified: %s", params[3].Val)}
+		}
+
+		busPort := port + 10000
+		if len(params) == 5 {
+			busPort, err = strconv.Atoi(params[4].Val)
+			if err != nil {
+				busPort = 0
+			}
+		}
+
+		if busPort <= 0 || busPort > 65535 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid bus port specified: %s", params[len(params)-1].Val)}
+		}
+
+		c.meet(params[2].Val, busPort)
+		return resptypes.SimpleString{Val: "OK"}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown CLUSTER subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+func parseSlot(s string) (int, error) {
+	slot, err := strconv.Atoi(s)
+	if err != nil || slot < 0 || slot >= redislib.ClusterSlots {
+		return 0, fmt.Errorf("ERR Invalid or out of range slot")
+	}
+
+	return slot, nil
+}
+
+func (c clusterCmd) addSlotsReply(slots []int) commandResult {
+	if err := c.addSlots(slots); err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+func (c clusterCmd) setSlot(params commandParams) commandResult {
+	slot, err := parseSlot(params[2].Val)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	action := strings.ToUpper(params[3].Val)
+	switch {
+	case action == "STABLE" && len(params) == 4:
+		c.setSlotStable(slot)
+	case action == "MIGRATING" && len(params) == 5:
+		err = c.setSlotMigrating(slot, params[4].Val)
+	case action == "IMPORTING" && len(params) == 5:
+		err = c.setSlotImporting(slot, params[4].Val)
+	case action == "NODE" && len(params) == 5:
+		err = c.setSlotNode(slot, params[4].Val)
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP")}
+	}
+
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+func (c clusterCmd) slotsReply() commandResult {
+	type slotRange struct {
+		start, end int
+		node       resptypes.Array[resptypes.RespSerializable]
+	}
+
+	ranges := []slotRange{}
+	for _, shard := range c.shards() {
+		node := resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString(shard.node.host),
+			resptypes.Integer{Val: int64(shard.node.port)},
+			resptypes.NewBulkString(shard.node.id),
+		}
+
+		for _, r := range shard.ranges {
+			ranges = append(ranges, slotRange{start: r[0], end: r[1], node: node})
+		}
+	}
+
+	slices.SortFunc(ranges, func(a, b slotRange) int { return a.start - b.start })
+	result := make(resptypes.Array[resptypes.RespSerializable], len(ranges))
+	for i, r := range ranges {
+		result[i] = resptypes.Array[resptypes.RespSerializable]{
+			resptypes.Integer{Val: int64(r.start)},
+			resptypes.Integer{Val: int64(r.end)},
+			r.node,
+		}
+	}
+
+	return result
+}
+
+func (c clusterCmd) shardsReply() commandResult {
+	result := resptypes.Array[resptypes.RespSerializable]{}
+	for _, shard := range c.shards() {
+		slots := resptypes.Array[resptypes.RespSerializable]{}
+		for _, r := range shard.ranges {
+			slots = append(slots, resptypes.Integer{Val: int64(r[0])}, resptypes.Integer{Val: int64(r[1])})
+		}
+
+		health := "online"
+		if !shard.node.connected {
+			health = "fail"
+		}
+
+		node := resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("id"), resptypes.NewBulkString(shard.node.id),
+			resptypes.NewBulkString("port"), resptypes.Integer{Val: int64(shard.node.port)},
+			resptypes.NewBulkString("ip"), resptypes.NewBulkString(shard.node.host),
+			resptypes.NewBulkString("endpoint"), resptypes.NewBulkString(shard.node.host),
+			resptypes.NewBulkString("role"), resptypes.NewBulkString("master"),
+			resptypes.NewBulkString("health"), resptypes.NewBulkString(health),
+		}
+
+		result = append(result, resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("slots"), slots,
+			resptypes.NewBulkString("nodes"), resptypes.Array[resptypes.RespSerializable]{node},
+		})
+	}
+
+	return result
+}
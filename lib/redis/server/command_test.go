@@ -0,0 +1,98 @@
+package redisserverlib_test
+
+import (
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestCommand(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("Arity is enforced before execution", func(t *testing.T) {
+		c.expect(t, "-ERR wrong number of arguments for 'get' command\r\n", "GET")
+		c.expect(t, "-ERR wrong number of arguments for 'get' command\r\n", "get", "k", "extra")
+		c.expect(t, "-ERR wrong number of arguments for 'rpush' command\r\n", "RPUSH", "l")
+		c.expect(t, "-ERR wrong number of arguments for 'config|get' command\r\n", "CONFIG", "GET")
+		c.expect(t, "-ERR wrong number of arguments for 'slowlog|len' command\r\n", "SLOWLOG", "LEN", "extra")
+		c.expect(t, "$-1\r\n", "GET", "k")
+		if stat := c.infoField("commandstats", "cmdstat_get"); !strings.Contains(stat, "rejected_calls=2") {
+			t.Errorf("cmdstat_get = %s", stat)
+		}
+	})
+
+	t.Run("Arity errors abort transactions", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "MULTI")
+		c.expect(t, "-ERR wrong number of arguments for 'set' command\r\n", "SET", "k")
+		c.expect(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+	})
+
+	t.Run("COUNT", func(t *testing.T) {
+		if count := c.do("COMMAND", "COUNT"); !strings.HasPrefix(count, ":") || count == ":0\r\n" {
+			t.Errorf("COMMAND COUNT = %q", count)
+		}
+	})
+
+	t.Run("INFO", func(t *testing.T) {
+		c.expect(t, "*2\r\n"+
+			"*10\r\n$3\r\nget\r\n:2\r\n*2\r\n+readonly\r\n+fast\r\n:1\r\n:1\r\n:1\r\n*3\r\n+@read\r\n+@string\r\n+@fast\r\n*0\r\n"+
+			"*1\r\n*6\r\n$5\r\nflags\r\n*2\r\n+RO\r\n+access\r\n"+
+			"$12\r\nbegin_search\r\n*4\r\n$4\r\ntype\r\n$5\r\nindex\r\n$4\r\nspec\r\n*2\r\n$5\r\nindex\r\n:1\r\n"+
+			"$9\r\nfind_keys\r\n*4\r\n$4\r\ntype\r\n$5\r\nrange\r\n$4\r\nspec\r\n*6\r\n$7\r\nlastkey\r\n:0\r\n$7\r\nkeystep\r\n:1\r\n$5\r\nlimit\r\n:0\r\n"+
+			"*0\r\n"+
+			"*-1\r\n", "COMMAND", "INFO", "get", "nosuchcommand")
+
+		if info := c.do("COMMAND", "INFO", "BLPOP"); !strings.HasPrefix(info, "*1\r\n*10\r\n$5\r\nblpop\r\n:-3\r\n*2\r\n+write\r\n+blocking\r\n:1\r\n:-2\r\n:1\r\n*4\r\n+@write\r\n+@list\r\n+@slow\r\n+@blocking\r\n") {
+			t.Errorf("COMMAND INFO BLPOP = %q", info)
+		}
+
+		if info := c.do("COMMAND", "INFO", "xread"); !strings.HasPrefix(info, "*1\r\n*10\r\n$5\r\nxread\r\n:-4\r\n*2\r\n+readonly\r\n+movablekeys\r\n:0\r\n:0\r\n:0\r\n") {
+			t.Errorf("COMMAND INFO XREAD = %q", info)
+		}
+
+		if info := c.do("COMMAND", "INFO", "config"); !strings.Contains(info, "*10\r\n$10\r\nconfig|get\r\n:-3\r\n*1\r\n+admin\r\n") {
+			t.Errorf("COMMAND INFO CONFIG = %q", info)
+		}
+
+		if all := c.do("COMMAND"); !strings.Contains(all, "$3\r\nset\r\n:-3\r\n") || !strings.Contains(all, "$7\r\ncommand\r\n:-1\r\n") {
+			t.Errorf("COMMAND = %q", all)
+		}
+	})
+
+	t.Run("DOCS", func(t *testing.T) {
+		c.expect(t, "*2\r\n$4\r\nllen\r\n*10\r\n"+
+			"$7\r\nsummary\r\n$29\r\nReturns the length of a list.\r\n$5\r\nsince\r\n$5\r\n1.0.0\r\n$5\r\ngroup\r\n$4\r\nlist\r\n$10\r\ncomplexity\r\n$4\r\nO(1)\r\n"+
+			"$9\r\narguments\r\n*1\r\n*6\r\n$4\r\nname\r\n$3\r\nkey\r\n$4\r\ntype\r\n$3\r\nkey\r\n$14\r\nkey_spec_index\r\n:0\r\n",
+			"COMMAND", "DOCS", "LLEN", "nosuchcommand")
+
+		if docs := c.do("COMMAND", "DOCS", "set"); !strings.Contains(docs, "$5\r\ntoken\r\n$2\r\nPX\r\n") || !strings.Contains(docs, "$5\r\nflags\r\n*1\r\n+optional\r\n") {
+			t.Errorf("COMMAND DOCS SET = %q", docs)
+		}
+
+		if docs := c.do("COMMAND", "DOCS", "slowlog"); !strings.Contains(docs, "$11\r\nsubcommands\r\n*6\r\n$11\r\nslowlog|get\r\n") {
+			t.Errorf("COMMAND DOCS SLOWLOG = %q", docs)
+		}
+	})
+
+	t.Run("GETKEYS", func(t *testing.T) {
+		c.expect(t, "*1\r\n$1\r\nk\r\n", "COMMAND", "GETKEYS", "SET", "k", "v")
+		c.expect(t, "*2\r\n$1\r\na\r\n$1\r\nb\r\n", "COMMAND", "GETKEYS", "BLPOP", "a", "b", "0")
+		c.expect(t, "*2\r\n$2\r\ns1\r\n$2\r\ns2\r\n", "COMMAND", "GETKEYS", "XREAD", "STREAMS", "s1", "s2", "0", "0")
+		c.expect(t, "*2\r\n$1\r\na\r\n$1\r\nb\r\n", "COMMAND", "GETKEYS", "MIGRATE", "host", "6379", "", "0", "100", "KEYS", "a", "b")
+		c.expect(t, "-ERR Invalid command specified\r\n", "COMMAND", "GETKEYS", "NOSUCHCOMMAND", "k")
+		c.expect(t, "-ERR Invalid number of arguments specified for command\r\n", "COMMAND", "GETKEYS", "GET", "a", "b")
+		c.expect(t, "-ERR The command has no key arguments\r\n", "COMMAND", "GETKEYS", "PING")
+	})
+
+	t.Run("LIST", func(t *testing.T) {
+		c.expect(t, "*2\r\n$4\r\nlpop\r\n$5\r\nlpush\r\n", "COMMAND", "LIST", "FILTERBY", "PATTERN", "lp*")
+		c.expect(t, "*3\r\n$4\r\nxadd\r\n$6\r\nxrange\r\n$5\r\nxread\r\n", "COMMAND", "LIST", "FILTERBY", "ACLCAT", "stream")
+		c.expect(t, "*0\r\n", "COMMAND", "LIST", "FILTERBY", "MODULE", "json")
+		if list := c.do("COMMAND", "LIST"); !strings.Contains(list, "$3\r\nget\r\n") {
+			t.Errorf("COMMAND LIST = %q", list)
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	commandCmd struct {
+		commands commandMap
+	}
+)
+
+func (c commandCmd) moniker() string {
+	return "COMMAND"
+}
+
+func (c commandCmd) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		categories: []string{"connection"},
+		group:      "server",
+		summary:    "Returns detailed information about all commands.",
+		since:      "2.8.13",
+		complexity: "O(N) where N is the total number of Redis commands",
+		subcommands: []commandSpec{
+			{name: "count", arity: 2, summary: "Returns a count of commands.", since: "2.8.13", complexity: "O(1)"},
+			{
+				name: "docs", arity: -2, summary: "Returns documentary information about one, multiple or all commands.", since: "7.0.0",
+				complexity: "O(N) where N is the number of commands to look up",
+				args:       []commandArg{{name: "command-name", typ: argString, optional: true, multiple: true}},
+			},
+			{
+				name: "getkeys", arity: -3, summary: "Extracts the key names from an arbitrary command.", since: "2.8.13",
+				complexity: "O(N) where N is the number of arguments to the command",
+				args:       []commandArg{{name: "command", typ: argString}, {name: "arg", typ: argString, optional: true, multiple: true}},
+			},
+			{
+				name: "info", arity: -2, summary: "Returns information about one, multiple or all commands.", since: "2.8.13",
+				complexity: "O(N) where N is the number of commands to look up",
+				args:       []commandArg{{name: "command-name", typ: argString, optional: true, multiple: true}},
+			},
+			{
+				name: "list", arity: -2, summary: "Returns a list of command names.", since: "7.0.0",
+				complexity: "O(N) where N is the total number of Redis commands",
+				args: []commandArg{{name: "filterby", typ: argOneOf, token: "FILTERBY", optional: true, args: []commandArg{
+					{name: "module-name", typ: argString, token: "MODULE"},
+					{name: "category", typ: argString, token: "ACLCAT"},
+					{name: "pattern", typ: argPattern, token: "PATTERN"},
+				}}},
+			},
+		},
+	}
+}
+
+func (c commandCmd) getUsage() string {
+	return `
+usage:
+	COMMAND
+	COMMAND COUNT
+	COMMAND INFO [command-name ...]
+	COMMAND DOCS [command-name ...]
+	COMMAND GETKEYS command [arg ...]
+	COMMAND LIST [FILTERBY MODULE module-name | ACLCAT category | PATTERN pattern]
+summary:
+	Introspects the commands of the server.
+	COMMAND and COMMAND INFO return, for every command or the given ones, its name, arity, flags, first key, last key
+	and step, ACL categories, tips, key specifications and subcommands. Unknown commands are nil.
+	COUNT returns the number of commands.
+	DOCS returns the summary, version, group, complexity, arguments and subcommands of the commands.
+	GETKEYS returns the keys of the given command call.
+	LIST returns the names of the commands, optionally those of an ACL category or matching a glob-style pattern.
+`
+}
+
+func (c commandCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) == 1 {
+		return c.executeInfo(c.names())
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "COUNT" && len(params) == 2:
+		return resptypes.Integer{Val: int64(len(c.commands))}
+	case subcommand == "INFO":
+		names := argsOf(params[2:])
+		if len(names) == 0 {
+			names = c.names()
+		}
+
+		return c.executeInfo(names)
+	case subcommand == "DOCS":
+		names := argsOf(params[2:])
+		if len(names) == 0 {
+			names = c.names()
+		}
+
+		return c.executeDocs(names)
+	case subcommand == "GETKEYS" && len(params) >= 3:
+		return c.executeGetKeys(params[2:])
+	case subcommand == "LIST" && (len(params) == 2 || len(params) == 5):
+		return c.executeList(params[2:])
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown COMMAND subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+// names returns the names of the commands in lowercase, sorted.
+func (c commandCmd) names() []string {
+	names := make([]string, 0, len(c.commands))
+	for _, name := range slices.Sorted(maps.Keys(c.commands)) {
+		names = append(names, strings.ToLower(name))
+	}
+
+	return names
+}
+
+func (c commandCmd) executeInfo(names []string) commandResult {
+	result := make(resptypes.Array[resptypes.RespSerializable], len(names))
+	for i, name := range names {
+		result[i] = resptypes.NullArray
+		if entry, exists := c.commands[strings.ToUpper(name)]; exists {
+			result[i] = commandInfoReply(strings.ToLower(name), entry.spec())
+		}
+	}
+
+	return result
+}
+
+func (c commandCmd) executeDocs(names []string) commandResult {
+	result := resptypes.Array[resptypes.RespSerializable]{}
+	for _, name := range names {
+		if entry, exists := c.commands[strings.ToUpper(name)]; exists {
+			result = append(result, resptypes.NewBulkString(strings.ToLower(name)), commandDocsReply(strings.ToLower(name), entry.spec()))
+		}
+	}
+
+	return result
+}
+
+func (c commandCmd) executeGetKeys(call commandParams) commandResult {
+	entry, exists := c.commands[strings.ToUpper(call[0].Val)]
+	if !exists {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid command specified")}
+	}
+
+	if entry.spec().checkArity(call) != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid number of arguments specified for command")}
+	}
+
+	keys := keysOf(entry, call)
+	if len(keys) == 0 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR The command has no key arguments")}
+	}
+
+	return resptypes.ToBulkStringArray(keys)
+}
+
+func (c commandCmd) executeList(filter commandParams) commandResult {
+	names := c.names()
+	if len(filter) == 0 {
+		return resptypes.ToBulkStringArray(names)
+	}
+
+	if !strings.EqualFold(filter[0].Val, "FILTERBY") {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+	}
+
+	value := filter[2].Val
+	matches := func(name string) bool { return false }
+	switch strings.ToUpper(filter[1].Val) {
+	case "MODULE":
+		// There are no modules
+	case "ACLCAT":
+		matches = func(name string) bool {
+			return slices.Contains(c.commands[strings.ToUpper(name)].spec().aclCategories(), strings.ToLower(value))
+		}
+	case "PATTERN":
+		matches = func(name string) bool { return redislib.GlobMatch(strings.ToLower(value), name) }
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+	}
+
+	return resptypes.ToBulkStringArray(slices.DeleteFunc(names, func(name string) bool { return !matches(name) }))
+}
+
+// commandInfoReply returns the reply of COMMAND INFO for a command or subcommand.
+func commandInfoReply(name string, spec commandSpec) resptypes.Array[resptypes.RespSerializable] {
+	first, last, step := spec.legacyKeys()
+	keySpecs := make(resptypes.Array[resptypes.RespSerializable], len(spec.keySpecs))
+	for i, k := range spec.keySpecs {
+		keySpecs[i] = k.reply()
+	}
+
+	subcommands := make(resptypes.Array[resptypes.RespSerializable], len(spec.subcommands))
+	for i, sub := range spec.subcommands {
+		sub = sub.withParent(spec, name)
+		subcommands[i] = commandInfoReply(sub.name, sub)
+	}
+
+	return resptypes.Array[resptypes.RespSerializable]{
+		resptypes.NewBulkString(name),
+		resptypes.Integer{Val: int64(spec.arity)},
+		toSimpleStringArray(spec.flagNames(), ""),
+		resptypes.Integer{Val: int64(first)},
+		resptypes.Integer{Val: int64(last)},
+		resptypes.Integer{Val: int64(step)},
+		toSimpleStringArray(spec.aclCategories(), "@"),
+		// Tips
+		resptypes.Array[resptypes.RespSerializable]{},
+		keySpecs,
+		subcommands,
+	}
+}
+
+// commandDocsReply returns the reply of COMMAND DOCS for a command or subcommand, as a flat
+// list of fields and values where the fields that do not apply are left out.
+func commandDocsReply(name string, spec commandSpec) resptypes.Array[resptypes.RespSerializable] {
+	docs := resptypes.Array[resptypes.RespSerializable]{}
+	for _, field := range [][2]string{{"summary", spec.summary}, {"since", spec.since}, {"group", spec.group}, {"complexity", spec.complexity}} {
+		if field[1] != "" {
+			docs = append(docs, resptypes.NewBulkString(field[0]), resptypes.NewBulkString(field[1]))
+		}
+	}
+
+	if len(spec.args) > 0 {
+		docs = append(docs, resptypes.NewBulkString("arguments"), argsReply(spec.args))
+	}
+
+	if len(spec.subcommands) > 0 {
+		subcommands := resptypes.Array[resptypes.RespSerializable]{}
+		for _, sub := range spec.subcommands {
+			sub = sub.withParent(spec, name)
+			subcommands = append(subcommands, resptypes.NewBulkString(sub.name), commandDocsReply(sub.name, sub))
+		}
+
+		docs = append(docs, resptypes.NewBulkString("subcommands"), subcommands)
+	}
+
+	return docs
+}
+
+func argsReply(args []commandArg) resptypes.Array[resptypes.RespSerializable] {
+	result := make(resptypes.Array[resptypes.RespSerializable], len(args))
+	for i, arg := range args {
+		fields := resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("name"), resptypes.NewBulkString(arg.name),
+			resptypes.NewBulkString("type"), resptypes.NewBulkString(arg.typ),
+		}
+		if arg.typ == argKey {
+			fields = append(fields, resptypes.NewBulkString("key_spec_index"), resptypes.Integer{Val: int64(arg.keySpec)})
+		}
+
+		if arg.token != "" {
+			fields = append(fields, resptypes.NewBulkString("token"), resptypes.NewBulkString(arg.token))
+		}
+
+		flags := []string{}
+		if arg.optional {
+			flags = append(flags, "optional")
+		}
+
+		if arg.multiple {
+			flags = append(flags, "multiple")
+		}
+
+		if len(flags) > 0 {
+			fields = append(fields, resptypes.NewBulkString("flags"), toSimpleStringArray(flags, ""))
+		}
+
+		if len(arg.args) > 0 {
+			fields = append(fields, resptypes.NewBulkString("arguments"), argsReply(arg.args))
+		}
+
+		result[i] = fields
+	}
+
+	return result
+}
+
+// reply returns the key spec as COMMAND INFO reports it.
+func (k keySpec) reply() resptypes.Array[resptypes.RespSerializable] {
+	beginSearch := resptypes.Array[resptypes.RespSerializable]{
+		resptypes.NewBulkString("type"), resptypes.NewBulkString("index"),
+		resptypes.NewBulkString("spec"), resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("index"), resptypes.Integer{Val: int64(k.index)},
+		},
+	}
+	if k.keyword != "" {
+		beginSearch = resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("type"), resptypes.NewBulkString("keyword"),
+			resptypes.NewBulkString("spec"), resptypes.Array[resptypes.RespSerializable]{
+				resptypes.NewBulkString("keyword"), resptypes.NewBulkString(k.keyword),
+				resptypes.NewBulkString("startfrom"), resptypes.Integer{Val: int64(k.startFrom)},
+			},
+		}
+	}
+
+	return resptypes.Array[resptypes.RespSerializable]{
+		resptypes.NewBulkString("flags"), toSimpleStringArray(k.flags, ""),
+		resptypes.NewBulkString("begin_search"), beginSearch,
+		resptypes.NewBulkString("find_keys"), resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("type"), resptypes.NewBulkString("range"),
+			resptypes.NewBulkString("spec"), resptypes.Array[resptypes.RespSerializable]{
+				resptypes.NewBulkString("lastkey"), resptypes.Integer{Val: int64(k.lastKey)},
+				resptypes.NewBulkString("keystep"), resptypes.Integer{Val: int64(max(1, k.keyStep))},
+				resptypes.NewBulkString("limit"), resptypes.Integer{Val: int64(k.limit)},
+			},
+		},
+	}
+}
+
+func toSimpleStringArray(values []string, prefix string) resptypes.Array[resptypes.SimpleString] {
+	result := make(resptypes.Array[resptypes.SimpleString], len(values))
+	for i, value := range values {
+		result[i] = resptypes.SimpleString{Val: prefix + value}
+	}
+
+	return result
+}
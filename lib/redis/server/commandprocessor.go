@@ -1,11 +1,19 @@
 package redisserverlib
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/lib/logger"
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
 	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
 	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
 )
@@ -22,96 +30,1307 @@ type (
 		commandUsage
 		execute(ctx context.Context, params commandParams) commandResult
 		moniker() string
+		// spec declares the arity, flags, keys and documentation of the command.
+		spec() commandSpec
+	}
+
+	// commandFlags mark properties of a command that the dispatcher needs to know about.
+	commandFlags uint
+
+	// keyedCommand is implemented by commands whose keys cannot be found by their key specs
+	// alone, so that cluster mode can tell which slot a request belongs to.
+	keyedCommand interface {
+		keys(params commandParams) []string
+	}
+
+	// registeredCommand keeps the spec of a command, which is built once when it is registered.
+	registeredCommand struct {
+		commandDefinition
+		cachedSpec commandSpec
 	}
 
 	commandMap map[string]commandDefinition
 
 	redisCommandProcessor struct {
-		ds       redistypes.DataStore
+		dbs      redistypes.Databases
 		commands commandMap
+		// The commands as clients call them, see WithRenameCommand
+		clientCommands commandMap
+		clients        *clientList
+		pubsub         *pubsub
+		tracking       *tracking
+		persistence    *persistence
+		aof            *aof
+		replication    *replication
+		propagator     *propagator
+		cluster        *cluster      // nil unless cluster mode is enabled
+		consensus      *consensus    // nil unless Raft mode is enabled
+		activeActive   *activeActive // nil unless active-active mode is enabled
+		metrics        *Metrics
+		slowLog        *slowLog
+		monitors       *monitors
+		latency        *latencyMonitor
+		acl            *acl
+		metricsServer  *metricsServer // nil unless metrics-addr is set
+		options        processorOptions
+		*middlewareChain
+
+		// Idle clients are disconnected after timeout, unless it is 0
+		timeout    atomic.Int64
+		maxClients atomic.Int64
+		// Set once Close is called
+		shuttingDown atomic.Bool
+		stop         chan struct{}
+		wg           sync.WaitGroup
+
+		// Read commands hold the read lock while executing. Write commands and EXEC hold the
+		// write lock, so that no other command interleaves with a transaction and writes are
+		// propagated in the order they were applied.
+		txMu sync.RWMutex
 	}
 
 	CommandProcessor interface {
+		// ConnectClient returns a context carrying fresh per-connection state (selected database etc.)
+		// and the channel of serialized replies and pushed messages to write to the connection.
+		// All requests from one connection should be executed with a context derived from it.
+		// The context is cancelled when the server decides to drop the connection.
+		ConnectClient(ctx context.Context) (context.Context, chan string)
+		// DisconnectClient releases the state created by ConnectClient and closes its channel.
+		// It must be called once no more replies will be sent on the channel.
+		DisconnectClient(ctx context.Context)
 		ExecuteCommand(ctx context.Context, respStr string) resptypes.RespSerializable
+		// Use adds a middleware around the execution of the commands sent by clients. The first
+		// added is the outermost. Commands replayed from the AOF, or applied from a primary or
+		// the Raft log, do not pass through middlewares.
+		Use(middleware Middleware)
+		// Close stops background work and flushes the AOF. When save points are configured,
+		// it saves a final snapshot.
+		Close() error
+	}
+
+	processorOptions struct {
+		databases               int
+		outputBufferLimit       int
+		notifyKeyspaceEvents    string
+		dir                     string
+		dbfilename              string
+		save                    string
+		appendOnly              bool
+		appendFsync             string
+		appendFilename          string
+		appendDirname           string
+		port                    int
+		replicaOf               string
+		replBacklogSize         int
+		replicaReadOnly         bool
+		minReplicasToWrite      int
+		minReplicasMaxLag       int
+		replicaPriority         int
+		clusterEnabled          bool
+		clusterPort             int
+		clusterConfigFile       string
+		raftMembers             []string
+		raftAddr                string
+		raftTickInterval        time.Duration
+		raftSnapshotEntries     int
+		activeActivePeers       []string
+		metrics                 *Metrics
+		configFile              string
+		timeout                 time.Duration
+		maxClients              int
+		slowlogLogSlowerThan    time.Duration
+		slowlogMaxLen           int
+		latencyMonitorThreshold time.Duration
+		metricsAddr             string
+		logLevel                *slog.LevelVar
+		requirePass             string
+		aclFile                 string
+		// The name and rules of each user to create at startup
+		aclUsers      [][]string
+		protectedMode bool
+		aclLogMaxLen  int
+		masterUser    string
+		masterAuth    string
+		// Added by extensions
+		extensionCommands []Command
+		valueTypes        []*redistypes.CustomType
+		renamedCommands   []commandRename
+		// Set by the loglevel directive, applied to logLevel at startup
+		initialLogLevel *slog.Level
+
+		sentinelMonitors        []sentinelMonitor
+		sentinelDownAfter       time.Duration
+		sentinelFailoverTimeout time.Duration
+	}
+
+	// commandRename renames a command for clients, or disables it when newName is empty, like
+	// the rename-command directive.
+	commandRename struct {
+		name    string
+		newName string
 	}
+
+	// sentinelMonitor is a primary for Sentinel mode to monitor, like the sentinel monitor directive.
+	sentinelMonitor struct {
+		name   string
+		host   string
+		port   int
+		quorum int
+	}
+
+	Option func(*processorOptions)
 )
 
+const (
+	// The command may wait for data. It never holds the transaction lock, and inside
+	// a transaction it behaves as if its timeout elapsed immediately.
+	flagBlocking commandFlags = 1 << iota
+	// The command controls transactions and runs immediately even after MULTI.
+	flagTransaction
+	// The command is allowed while the client is subscribed to channels or patterns.
+	flagPubSub
+	// The command holds the transaction lock exclusively, so no other command runs concurrently.
+	flagExclusive
+	// The command may modify the dataset. It holds the transaction lock exclusively
+	// and its effects are propagated to the AOF.
+	flagWrite
+	// In cluster mode, the command is redirected as if it was preceded by ASKING.
+	flagAsking
+	// In cluster mode, the command is served by the node whenever it is migrating or
+	// importing the slot of its keys, so that keys can be moved freely.
+	flagSlotMigration
+	// The command administers the server. It is not shown to MONITOR.
+	flagAdmin
+	// The command only reads data. Only reported by COMMAND.
+	flagReadonly
+	// The command runs in constant or logarithmic time. Only reported by COMMAND.
+	flagFast
+	// The command is allowed before the client authenticates, and regardless of ACL rules.
+	flagNoAuth
+)
+
+func flagsOf(cd commandDefinition) commandFlags {
+	return cd.spec().flags
+}
+
+func keysOf(cd commandDefinition, params commandParams) []string {
+	if rc, ok := cd.(registeredCommand); ok {
+		cd = rc.commandDefinition
+	}
+
+	if kc, ok := cd.(keyedCommand); ok {
+		return kc.keys(params)
+	}
+
+	return cd.spec().findKeys(params)
+}
+
+// keyIndexesOf returns the positions of the keys of a call among its arguments.
+func keyIndexesOf(cd commandDefinition, params commandParams) []int {
+	if rc, ok := cd.(registeredCommand); ok {
+		cd = rc.commandDefinition
+	}
+
+	indexes := []int{}
+	if kc, ok := cd.(keyedCommand); ok {
+		// Custom key lookups return the keys in the order of the arguments
+		next := 1
+		for _, key := range kc.keys(params) {
+			for i := next; i < len(params); i++ {
+				if params[i].Val == key {
+					indexes = append(indexes, i)
+					next = i + 1
+					break
+				}
+			}
+		}
+
+		return indexes
+	}
+
+	for _, spec := range cd.spec().keySpecs {
+		indexes = append(indexes, spec.indexes(params)...)
+	}
+
+	return indexes
+}
+
+// argIndexes returns the index of every step-th argument from first to last, where a negative
+// last counts from the end. Missing arguments are left out.
+func argIndexes(params commandParams, first int, last int, step int) []int {
+	if last < 0 {
+		last += len(params)
+	}
+
+	indexes := []int{}
+	for i := first; i <= last && i < len(params); i += step {
+		indexes = append(indexes, i)
+	}
+
+	return indexes
+}
+
+// WithDatabases sets the number of logical databases, like the databases directive in redis.conf.
+func WithDatabases(count int) Option {
+	return func(o *processorOptions) {
+		o.databases = count
+	}
+}
+
+// WithNotifyKeyspaceEvents enables keyspace notifications for the classes given with
+// Redis' flag letters (e.g. "KEA"). It can be changed later with CONFIG SET notify-keyspace-events.
+func WithNotifyKeyspaceEvents(flags string) Option {
+	return func(o *processorOptions) {
+		o.notifyKeyspaceEvents = flags
+	}
+}
+
+// WithDir sets the directory the RDB file is loaded from and saved to.
+func WithDir(dir string) Option {
+	return func(o *processorOptions) {
+		o.dir = dir
+	}
+}
+
+// WithDbFilename sets the name of the RDB file within the directory set by WithDir.
+func WithDbFilename(name string) Option {
+	return func(o *processorOptions) {
+		o.dbfilename = name
+	}
+}
+
+// WithSave sets the save points in the redis.conf form "<seconds> <changes> ...": a background
+// save starts once both at least that many seconds passed and that many changes happened since
+// the last save. Automatic saving is disabled by default.
+func WithSave(params string) Option {
+	return func(o *processorOptions) {
+		o.save = params
+	}
+}
+
+// WithAppendOnly enables the append only file, which logs every write command. At startup the
+// dataset is then loaded from the AOF instead of the RDB file.
+func WithAppendOnly(enabled bool) Option {
+	return func(o *processorOptions) {
+		o.appendOnly = enabled
+	}
+}
+
+// WithAppendFsync sets when the AOF is flushed to disk: "always" after every write,
+// "everysec" once per second (the default) or "no" to leave it to the operating system.
+func WithAppendFsync(policy string) Option {
+	return func(o *processorOptions) {
+		o.appendFsync = policy
+	}
+}
+
+// WithAppendFilename sets the base name of the AOF files.
+func WithAppendFilename(name string) Option {
+	return func(o *processorOptions) {
+		o.appendFilename = name
+	}
+}
+
+// WithAppendDirname sets the directory, within the directory set by WithDir, holding the AOF files.
+func WithAppendDirname(name string) Option {
+	return func(o *processorOptions) {
+		o.appendDirname = name
+	}
+}
+
+// WithPort sets the port the server listens on, which it announces to its primary when replicating.
+func WithPort(port int) Option {
+	return func(o *processorOptions) {
+		o.port = port
+	}
+}
+
+// WithReplicaOf makes the server replicate from the primary at "<host> <port>" from startup,
+// like the replicaof directive in redis.conf. An empty address leaves the server a primary.
+func WithReplicaOf(address string) Option {
+	return func(o *processorOptions) {
+		o.replicaOf = address
+	}
+}
+
+// WithReplBacklogSize sets how many bytes of the replication stream are kept, so that replicas
+// reconnecting after a short disconnect can continue without a full resynchronization.
+func WithReplBacklogSize(bytes int) Option {
+	return func(o *processorOptions) {
+		o.replBacklogSize = bytes
+	}
+}
+
+// WithReplicaReadOnly sets whether a replica rejects write commands from its clients, the default.
+func WithReplicaReadOnly(readOnly bool) Option {
+	return func(o *processorOptions) {
+		o.replicaReadOnly = readOnly
+	}
+}
+
+// WithMinReplicasToWrite makes a primary reject writes with -NOREPLICAS unless at least count
+// replicas are connected and acknowledged the stream recently, see WithMinReplicasMaxLag.
+// It is disabled by default.
+func WithMinReplicasToWrite(count int) Option {
+	return func(o *processorOptions) {
+		o.minReplicasToWrite = count
+	}
+}
+
+// WithMinReplicasMaxLag sets how many seconds may pass since a replica's last acknowledgement
+// for it to count towards WithMinReplicasToWrite. Defaults to 10.
+func WithMinReplicasMaxLag(seconds int) Option {
+	return func(o *processorOptions) {
+		o.minReplicasMaxLag = seconds
+	}
+}
+
+// WithReplicaPriority sets the priority a replica announces to Sentinel, which promotes the
+// replica with the lowest priority first. Replicas with priority 0 are never promoted. Defaults to 100.
+func WithReplicaPriority(priority int) Option {
+	return func(o *processorOptions) {
+		o.replicaPriority = priority
+	}
+}
+
+// WithClusterEnabled enables cluster mode: the server serves only the hash slots assigned to it
+// and redirects requests for other keys with -MOVED and -ASK.
+func WithClusterEnabled(enabled bool) Option {
+	return func(o *processorOptions) {
+		o.clusterEnabled = enabled
+	}
+}
+
+// WithClusterPort sets the port of the cluster bus, which defaults to the port set by WithPort plus 10000.
+func WithClusterPort(port int) Option {
+	return func(o *processorOptions) {
+		o.clusterPort = port
+	}
+}
+
+// WithClusterConfigFile sets the name of the file, within the directory set by WithDir, that the
+// cluster configuration is saved to. Defaults to nodes.conf.
+func WithClusterConfigFile(name string) Option {
+	return func(o *processorOptions) {
+		o.clusterConfigFile = name
+	}
+}
+
+// WithRaftMembers enables Raft mode: write commands are committed to a log replicated to
+// the members, given as host:port of their Redis port including this server, before they run.
+// Only the elected leader serves keys, the other members redirect clients to it with -REDIRECT.
+func WithRaftMembers(addrs ...string) Option {
+	return func(o *processorOptions) {
+		o.raftMembers = addrs
+	}
+}
+
+// WithRaftAddr sets the address identifying the server among the Raft members.
+// Defaults to 127.0.0.1 with the port set by WithPort.
+func WithRaftAddr(addr string) Option {
+	return func(o *processorOptions) {
+		o.raftAddr = addr
+	}
+}
+
+// WithRaftTickInterval sets the period of Raft heartbeats. Elections start after 10 to 20 ticks
+// without a leader. Defaults to 100ms.
+func WithRaftTickInterval(d time.Duration) Option {
+	return func(o *processorOptions) {
+		o.raftTickInterval = d
+	}
+}
+
+// WithRaftSnapshotEntries sets how many entries are applied before the Raft log is compacted
+// into a snapshot of the dataset. Defaults to 10000.
+func WithRaftSnapshotEntries(entries int) Option {
+	return func(o *processorOptions) {
+		o.raftSnapshotEntries = entries
+	}
+}
+
+// WithActiveActivePeers enables active-active mode: the server accepts writes like its peers,
+// given as host:port of their Redis port, and exchanges them with the peers so that values
+// converge. Strings are last-writer-wins, counters updated with INCRBY and its variants add up
+// the increments of every peer, lists keep the elements pushed anywhere and streams hold the
+// entries added anywhere.
+func WithActiveActivePeers(addrs ...string) Option {
+	return func(o *processorOptions) {
+		o.activeActivePeers = addrs
+	}
+}
+
+// WithSentinelMonitor makes a processor created with NewSentinelProcessor monitor the primary at
+// host:port under name. It is considered down once quorum sentinels agree it stopped replying.
+func WithSentinelMonitor(name string, host string, port int, quorum int) Option {
+	return func(o *processorOptions) {
+		o.sentinelMonitors = append(o.sentinelMonitors, sentinelMonitor{name: name, host: host, port: port, quorum: quorum})
+	}
+}
+
+// WithSentinelDownAfter sets how long a monitored instance may stop replying before a sentinel
+// considers it down. Defaults to 30 seconds.
+func WithSentinelDownAfter(d time.Duration) Option {
+	return func(o *processorOptions) {
+		o.sentinelDownAfter = d
+	}
+}
+
+// WithSentinelFailoverTimeout sets how long a failover may take before it is aborted. A sentinel
+// does not start another failover of the same primary within twice that time. Defaults to 3 minutes.
+func WithSentinelFailoverTimeout(d time.Duration) Option {
+	return func(o *processorOptions) {
+		o.sentinelFailoverTimeout = d
+	}
+}
+
+// WithOutputBufferLimit sets how many replies and pushed messages may be pending for a single
+// connection. Clients that exceed it, typically slow subscribers, are disconnected.
+func WithOutputBufferLimit(messages int) Option {
+	return func(o *processorOptions) {
+		o.outputBufferLimit = messages
+	}
+}
+
+// WithMetrics sets the registry the processor reports its counters to, so that the loop serving
+// the connections can add the network traffic. A processor has a registry of its own by default.
+func WithMetrics(m *Metrics) Option {
+	return func(o *processorOptions) {
+		o.metrics = m
+	}
+}
+
+// WithConfigFile sets the config file the server was started with, which CONFIG REWRITE updates.
+// Options for the directives of the file are returned by Config.Options.
+func WithConfigFile(path string) Option {
+	return func(o *processorOptions) {
+		o.configFile = path
+	}
+}
+
+// WithTimeout makes the server disconnect clients idle for longer than d. Clients subscribed to
+// channels or waiting in a blocking command are not disconnected. It is disabled by default.
+func WithTimeout(d time.Duration) Option {
+	return func(o *processorOptions) {
+		o.timeout = d
+	}
+}
+
+// WithMaxClients sets how many clients may be connected at the same time. Clients connecting
+// beyond the limit get an error and are disconnected. Defaults to 10000.
+func WithMaxClients(count int) Option {
+	return func(o *processorOptions) {
+		o.maxClients = count
+	}
+}
+
+// WithSlowlogLogSlowerThan sets how long a command must run for to be added to the slow log.
+// A negative duration disables the slow log, and 0 logs every command. Defaults to 10ms.
+func WithSlowlogLogSlowerThan(d time.Duration) Option {
+	return func(o *processorOptions) {
+		o.slowlogLogSlowerThan = d
+	}
+}
+
+// WithSlowlogMaxLen sets how many entries the slow log keeps. Defaults to 128.
+func WithSlowlogMaxLen(count int) Option {
+	return func(o *processorOptions) {
+		o.slowlogMaxLen = count
+	}
+}
+
+// WithLatencyMonitorThreshold makes the server record the events, such as commands or fsyncs,
+// that take at least d, for LATENCY LATEST and HISTORY. The latency monitor is disabled by default.
+func WithLatencyMonitorThreshold(d time.Duration) Option {
+	return func(o *processorOptions) {
+		o.latencyMonitorThreshold = d
+	}
+}
+
+// WithMetricsAddr makes the server serve its metrics in the Prometheus text format on
+// http://<addr>/metrics, along with health checks on /healthz and /readyz. /healthz fails once
+// the server is shutting down, and /readyz also fails while the dataset is being loaded.
+func WithMetricsAddr(addr string) Option {
+	return func(o *processorOptions) {
+		o.metricsAddr = addr
+	}
+}
+
+// WithLogLevel sets the level of the logger the server logs with, so that CONFIG SET loglevel
+// can change it at runtime.
+func WithLogLevel(level *slog.LevelVar) Option {
+	return func(o *processorOptions) {
+		o.logLevel = level
+	}
+}
+
+// WithRequirePass sets the password of the default user, which clients must then give with AUTH.
+// It can be changed later with CONFIG SET requirepass, an empty password removing it.
+func WithRequirePass(password string) Option {
+	return func(o *processorOptions) {
+		o.requirePass = password
+	}
+}
+
+// WithACLFile sets the file the users are loaded from at startup and by ACL LOAD, and saved to
+// by ACL SAVE. Each line of the file is "user <name> <rules>", in the format of ACL SETUSER.
+func WithACLFile(path string) Option {
+	return func(o *processorOptions) {
+		o.aclFile = path
+	}
+}
+
+// WithACLUser creates a user at startup with the rules of ACL SETUSER, like the user directive
+// in redis.conf. It is ignored when an ACL file is set with WithACLFile.
+func WithACLUser(name string, rules ...string) Option {
+	return func(o *processorOptions) {
+		o.aclUsers = append(o.aclUsers, append([]string{name}, rules...))
+	}
+}
+
+// WithProtectedMode sets whether clients connecting from other hosts than the loopback
+// interface are refused while the default user has no password, the default.
+func WithProtectedMode(enabled bool) Option {
+	return func(o *processorOptions) {
+		o.protectedMode = enabled
+	}
+}
+
+// WithACLLogMaxLen sets how many entries ACL LOG keeps. Defaults to 128.
+func WithACLLogMaxLen(count int) Option {
+	return func(o *processorOptions) {
+		o.aclLogMaxLen = count
+	}
+}
+
+// WithMasterAuth sets the password the server authenticates with to its primary when
+// replicating, and to the other members in Raft and active-active modes.
+func WithMasterAuth(password string) Option {
+	return func(o *processorOptions) {
+		o.masterAuth = password
+	}
+}
+
+// WithMasterUser sets the user the server authenticates as with the password set by
+// WithMasterAuth. Defaults to the default user.
+func WithMasterUser(name string) Option {
+	return func(o *processorOptions) {
+		o.masterUser = name
+	}
+}
+
+// WithCommand adds a command to the processor. Commands whose name or spec is invalid, or whose
+// name is taken by another command, are logged and ignored.
+func WithCommand(cmd Command) Option {
+	return func(o *processorOptions) {
+		o.extensionCommands = append(o.extensionCommands, cmd)
+	}
+}
+
+// WithValueType registers a custom value type for the commands added with WithCommand, so that its
+// values are reported by TYPE, and can be saved to RDB files and moved with DUMP and RESTORE. Value
+// types are registered for the whole process, see redistypes.RegisterType.
+func WithValueType(t *redistypes.CustomType) Option {
+	return func(o *processorOptions) {
+		o.valueTypes = append(o.valueTypes, t)
+	}
+}
+
+// WithRenameCommand makes clients call the command name by newName instead, like the
+// rename-command directive of redis.conf. An empty newName disables the command for clients.
+// Renamed commands are still written to the AOF and sent to replicas under their original
+// name. ACL rules accept either name, and are listed with the original one.
+func WithRenameCommand(name string, newName string) Option {
+	return func(o *processorOptions) {
+		o.renamedCommands = append(o.renamedCommands, commandRename{name: name, newName: newName})
+	}
+}
+
 func (m *commandMap) registerCommand(cd commandDefinition) {
-	(*m)[cd.moniker()] = cd
+	(*m)[cd.moniker()] = registeredCommand{cd, cd.spec()}
 }
 
-func NewRedisCommandProcessor() CommandProcessor {
-	redisDataStore := redistypes.NewRedisDataStore()
+func (c registeredCommand) spec() commandSpec {
+	return c.cachedSpec
+}
+
+// rename returns the commands as clients call them, once renamed or disabled by renames in order.
+func (m commandMap) rename(renames []commandRename) commandMap {
+	renamed := maps.Clone(m)
+	for _, r := range renames {
+		name, newName := strings.ToUpper(r.name), strings.ToUpper(r.newName)
+		entry, exists := renamed[name]
+		if !exists {
+			slog.Error("Ignoring the rename of an unknown command", "command", r.name)
+			continue
+		}
+
+		if _, taken := renamed[newName]; taken && newName != name {
+			slog.Error("Ignoring the rename of a command to the name of another", "command", r.name, "name", r.newName)
+			continue
+		}
+
+		delete(renamed, name)
+		if newName != "" {
+			renamed[newName] = entry
+		}
+	}
+
+	return renamed
+}
+
+// newProcessorOptions applies opts over the defaults.
+func newProcessorOptions(opts []Option) processorOptions {
+	options := processorOptions{
+		databases:         redistypes.DefaultDatabaseCount,
+		outputBufferLimit: 4096,
+		dir:               ".",
+		dbfilename:        "dump.rdb",
+		appendFsync:       fsyncEverySec,
+		appendFilename:    "appendonly.aof",
+		appendDirname:     "appendonlydir",
+		port:              6379,
+		replBacklogSize:   1 << 20,
+		replicaReadOnly:   true,
+		minReplicasMaxLag: 10,
+		replicaPriority:   100,
+		clusterConfigFile: "nodes.conf",
+		maxClients:        10000,
+
+		slowlogLogSlowerThan: 10 * time.Millisecond,
+		slowlogMaxLen:        128,
+
+		protectedMode: true,
+		aclLogMaxLen:  128,
+
+		raftSnapshotEntries: 10000,
+
+		sentinelDownAfter:       30 * time.Second,
+		sentinelFailoverTimeout: 3 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.metrics == nil {
+		options.metrics = NewMetrics()
+	}
+
+	if options.logLevel == nil {
+		options.logLevel = new(slog.LevelVar)
+	}
+
+	if options.initialLogLevel != nil {
+		options.logLevel.Set(*options.initialLogLevel)
+	}
+
+	return options
+}
+
+func NewRedisCommandProcessor(opts ...Option) CommandProcessor {
+	options := newProcessorOptions(opts)
+	for _, t := range options.valueTypes {
+		if _, err := redistypes.RegisterType(t); err != nil {
+			slog.Error("Ignoring invalid value type", "error", err)
+		}
+	}
+
+	dbs := redistypes.NewRedisDatabases(max(1, options.databases))
+	clients := newClientList()
+	ps := newPubSub()
+	events := newKeyspaceEvents(ps)
+	if classes, err := parseNotifyClasses(options.notifyKeyspaceEvents); err != nil {
+		slog.Warn("Ignoring invalid notify-keyspace-events", "flags", options.notifyKeyspaceEvents, "error", err)
+	} else {
+		events.setClasses(classes)
+	}
 
 	// https://redis.io/docs/latest/commands/redis-8-6-commands/
 	commands := make(commandMap)
+	clientCommands := make(commandMap)
+	r := &redisCommandProcessor{
+		dbs:            dbs,
+		commands:       commands,
+		clientCommands: clientCommands,
+		clients:        clients,
+		pubsub:         ps,
+		propagator:     &propagator{},
+		metrics:        options.metrics,
+		slowLog:        newSlowLog(options),
+		monitors:       newMonitors(),
+		latency:        newLatencyMonitor(options),
+		acl:            newACL(commands, clientCommands, clients, options),
+		options:        options,
+		stop:           make(chan struct{}),
+	}
+	r.middlewareChain = newMiddlewareChain(dispatchHandler(r.dispatch))
+	r.timeout.Store(int64(options.timeout))
+	r.maxClients.Store(int64(options.maxClients))
+	r.persistence = newPersistence(dbs, &r.txMu, r.latency, options)
+	r.aof = newAof(r.persistence, r.latency, options)
+	r.propagator.addSink(r.aof)
+	r.replication = newReplication(r, options)
+	r.propagator.addSink(r.replication)
+	if options.clusterEnabled {
+		r.cluster = newCluster(dbs, options)
+		if err := r.cluster.load(); err != nil {
+			slog.Error("Failed to load the cluster config file", "file", r.cluster.configFile, "error", err)
+		}
+	}
+
+	if len(options.raftMembers) > 0 {
+		r.consensus = newConsensus(r, options)
+		if len(options.activeActivePeers) > 0 {
+			slog.Warn("Ignoring the active-active peers in Raft mode")
+		}
+	} else if len(options.activeActivePeers) > 0 {
+		r.activeActive = newActiveActive(r, options)
+	}
+
+	redisKeyspace := keyspace{
+		Databases: dbs,
+		tracking:  newTracking(clients, ps),
+		events:    events,
+		metrics:   r.metrics,
+		latency:   r.latency,
+		dirty:     &r.persistence.dirty,
+	}
+	r.tracking = redisKeyspace.tracking
+	dbs.OnExpire(redisKeyspace.expired)
 
 	// Connection commands
 	commands.registerCommand(ping{})
 	commands.registerCommand(echo{})
+	commands.registerCommand(auth{r.acl})
+	commands.registerCommand(selectCmd{redisKeyspace, r.cluster})
+	commands.registerCommand(clientCmd{clients, redisKeyspace.tracking})
 
 	// String commands
-	commands.registerCommand(set{redisDataStore})
-	commands.registerCommand(get{redisDataStore})
+	commands.registerCommand(set{redisKeyspace})
+	commands.registerCommand(get{redisKeyspace})
+	commands.registerCommand(incr{redisKeyspace})
+	commands.registerCommand(incrby{redisKeyspace})
+	commands.registerCommand(decr{redisKeyspace})
+	commands.registerCommand(decrby{redisKeyspace})
 
 	// List commands
-	commands.registerCommand(rpush{redisDataStore})
-	commands.registerCommand(lrange{redisDataStore})
-	commands.registerCommand(lpush{redisDataStore})
-	commands.registerCommand(llen{redisDataStore})
-	commands.registerCommand(lpop{redisDataStore})
-	commands.registerCommand(blpop{redisDataStore})
+	commands.registerCommand(rpush{redisKeyspace})
+	commands.registerCommand(lrange{redisKeyspace})
+	commands.registerCommand(lpush{redisKeyspace})
+	commands.registerCommand(llen{redisKeyspace})
+	commands.registerCommand(lpop{redisKeyspace})
+	commands.registerCommand(blpop{redisKeyspace})
 
 	// Stream commands
-	commands.registerCommand(xadd{redisDataStore})
-	commands.registerCommand(xrange{redisDataStore})
-	commands.registerCommand(xread{redisDataStore})
+	commands.registerCommand(xadd{redisKeyspace})
+	commands.registerCommand(xrange{redisKeyspace})
+	commands.registerCommand(xread{redisKeyspace})
 
 	// Generic commands
-	commands.registerCommand(typeCmd{redisDataStore})
-	commands.registerCommand(help{commands})
+	commands.registerCommand(typeCmd{redisKeyspace})
+	commands.registerCommand(move{redisKeyspace})
+	commands.registerCommand(del{redisKeyspace})
+	commands.registerCommand(dump{redisKeyspace})
+	commands.registerCommand(restore{keyspace: redisKeyspace})
+	commands.registerCommand(restore{keyspace: redisKeyspace, asking: true})
+	commands.registerCommand(migrate{redisKeyspace})
+	commands.registerCommand(help{clientCommands})
+	commands.registerCommand(commandCmd{clientCommands})
+
+	// Server commands
+	commands.registerCommand(dbsize{redisKeyspace})
+	commands.registerCommand(flushdb{redisKeyspace})
+	commands.registerCommand(flushall{redisKeyspace})
+	commands.registerCommand(swapdb{redisKeyspace})
+	commands.registerCommand(save{r.persistence})
+	commands.registerCommand(bgsave{r.persistence})
+	commands.registerCommand(lastsave{r.persistence})
+	commands.registerCommand(bgrewriteaof{r.aof})
+	commands.registerCommand(slowlogCmd{r.slowLog})
+	commands.registerCommand(monitorCmd{r.monitors})
+	commands.registerCommand(latencyCmd{r.latency, r.metrics})
+	commands.registerCommand(aclCmd{r.acl})
+	commands.registerCommand(info{[]infoSection{
+		{title: "Server", fields: r.infoServer},
+		{title: "Clients", fields: func() []string { return r.metrics.clientsInfo(r.clients.len()) }},
+		{title: "Memory", fields: r.metrics.memoryInfo},
+		{title: "Persistence", fields: func() []string { return append(r.persistence.info(), r.aof.info()...) }},
+		{title: "Stats", fields: func() []string {
+			return slices.Concat(r.metrics.statsInfo(), r.persistence.statsInfo(), r.replication.infoStats(), r.monitors.info())
+		}},
+		{title: "Replication", fields: r.replication.info},
+		{title: "Raft", fields: r.consensus.info},
+		{title: "CRDT", fields: r.activeActive.info},
+		{title: "Commandstats", fields: r.metrics.commandStatsInfo, extra: true},
+		{title: "Errorstats", fields: r.metrics.errorStatsInfo},
+		{title: "Keyspace", fields: redisKeyspace.info},
+	}})
+
+	// Replication commands
+	commands.registerCommand(replicaof{r.replication})
+	commands.registerCommand(replconf{r.replication})
+	commands.registerCommand(psync{r.replication})
+	commands.registerCommand(wait{r.replication})
+
+	// Cluster commands
+	commands.registerCommand(clusterCmd{r.cluster})
+	commands.registerCommand(asking{r.cluster})
+
+	// Raft commands
+	commands.registerCommand(raftCmd{r.consensus})
+
+	// Active-active commands
+	commands.registerCommand(crdtCmd{r.activeActive})
+
+	// Transaction commands
+	commands.registerCommand(multi{})
+	commands.registerCommand(exec{r})
+	commands.registerCommand(discard{})
+	commands.registerCommand(watch{redisKeyspace})
+	commands.registerCommand(unwatch{})
+
+	// Pub/Sub commands
+	commands.registerCommand(subscribe{r.pubsub})
+	commands.registerCommand(psubscribe{r.pubsub})
+	commands.registerCommand(unsubscribe{r.pubsub})
+	commands.registerCommand(punsubscribe{r.pubsub})
+	commands.registerCommand(publish{r.pubsub})
+	commands.registerCommand(pubsubCmd{r.pubsub})
+
+	// Server commands that need the assembled processor
+	commands.registerCommand(configCmd{
+		params:    newConfigParams(r, events),
+		file:      options.configFile,
+		resetStat: r.resetStat,
+		acl:       r.acl,
+	})
 
-	return &redisCommandProcessor{
-		ds:       redisDataStore,
-		commands: commands,
+	// Commands added by extensions
+	for _, cmd := range options.extensionCommands {
+		ec, err := newExtensionCommand(cmd, redisKeyspace)
+		if err == nil {
+			if _, exists := commands[ec.moniker()]; exists {
+				err = fmt.Errorf("command %s already exists", ec.moniker())
+			}
+		}
+
+		if err != nil {
+			slog.Error("Ignoring invalid command", "error", err)
+			continue
+		}
+
+		commands.registerCommand(ec)
+	}
+
+	// Filled once every command is registered, as HELP and COMMAND already hold it
+	maps.Copy(clientCommands, commands.rename(options.renamedCommands))
+
+	r.acl.start(options)
+	if options.metricsAddr != "" {
+		// Started before loading the dataset, so that /readyz tells when it is loaded
+		r.metricsServer = newMetricsServer(r, redisKeyspace)
+		if err := r.metricsServer.start(options.metricsAddr); err != nil {
+			slog.Error("Failed to start the metrics endpoint", "addr", options.metricsAddr, "error", err)
+			r.metricsServer = nil
+		}
+	}
+
+	if r.activeActive != nil {
+		// The dataset is recovered from the peers
+		r.aof.disable()
+		r.activeActive.start()
+	} else if r.consensus != nil {
+		// The Raft log holds the dataset
+		r.aof.disable()
+		if err := r.consensus.start(); err != nil {
+			slog.Error("Failed to start Raft", "error", err)
+			r.consensus = nil
+		}
+	} else if err := r.loadData(); err != nil {
+		slog.Error("Failed to load the dataset, automatic saving is disabled", "error", err)
 	}
+
+	r.wg.Go(r.disconnectIdleClients)
+	r.persistence.start()
+	r.aof.start()
+	r.replication.start()
+	if r.cluster != nil {
+		if err := r.cluster.start(); err != nil {
+			slog.Error("Failed to start the cluster bus", "error", err)
+		}
+	}
+
+	if host, port, ok := parseReplicaOf(options.replicaOf); ok {
+		r.replication.replicaOf(host, port)
+	} else if options.replicaOf != "" {
+		slog.Warn("Ignoring invalid replicaof", "address", options.replicaOf)
+	}
+
+	return r
 }
 
-func (r *redisCommandProcessor) ExecuteCommand(ctx context.Context, respStr string) resptypes.RespSerializable {
-	slog.DebugContext(ctx, "Command received", "respStr", respStr)
+// loadData loads the dataset from the AOF when it is enabled and exists, or from the RDB file.
+// An enabled AOF that does not exist yet is created from the loaded dataset.
+func (r *redisCommandProcessor) loadData() error {
+	r.persistence.loading.Store(true)
+	defer r.persistence.loading.Store(false)
+	defer r.persistence.dirty.Store(0)
+	if r.aof.isEnabled() {
+		replayClient := newClient()
+		replayClient.internal = true
+		replayCtx := contextWithClient(context.Background(), replayClient)
+		found, err := r.aof.load(func(args []string) error {
+			if _, exists := r.commands[strings.ToUpper(args[0])]; !exists {
+				return fmt.Errorf("unknown command '%s'", args[0])
+			}
+
+			r.dispatch(replayCtx, resptypes.ToBulkStringArray(args))
+			return nil
+		}, func() bool {
+			return clientFromContext(replayCtx).multi != nil
+		})
+
+		if err != nil {
+			r.persistence.loadFailed = true
+			r.aof.disable()
+			return err
+		}
+
+		if found {
+			return r.aof.openLastIncr()
+		}
+	}
 
-	parsed, byteCount := resptypes.ParseRespString(respStr)
-	if byteCount == 0 {
-		return parsed
+	if err := r.persistence.load(); err != nil {
+		return err
 	}
 
-	respArr, ok := parsed.(resptypes.Array[resptypes.RespSerializable])
-	if !ok {
-		return resptypes.SimpleError{Val: fmt.Errorf("NOTEXPECTED Parsed request was not a RESP array! Got: %v", respStr)}
+	if !r.aof.isEnabled() {
+		return nil
 	}
 
-	if len(respArr) <= 0 {
-		return resptypes.SimpleError{Val: fmt.Errorf("NOTEXPECTED Array is empty! Got: %d", len(respArr))}
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	return r.aof.rewrite(false)
+}
+
+func (r *redisCommandProcessor) ConnectClient(ctx context.Context) (context.Context, chan string) {
+	c := newClient()
+	if addr, ok := ctx.Value(logger.ClientKey).(string); ok {
+		c.addr = addr
 	}
 
-	bulkStrings := make(resptypes.Array[resptypes.BulkString], len(respArr))
-	for i, e := range respArr {
-		switch e := e.(type) {
-		case resptypes.BulkString:
-			bulkStrings[i] = e
-			continue
-		default:
-			return resptypes.SimpleError{Val: fmt.Errorf("NOTEXPECTED Parsed request was not an array of bulk strings! Got: %v", respStr)}
+	ctx, c.kill = context.WithCancel(ctx)
+	c.out = make(chan string, max(1, r.options.outputBufferLimit))
+	c.touch()
+	r.metrics.connectionsReceived.Add(1)
+	if int64(r.clients.len()) >= r.maxClients.Load() {
+		// The connection is dropped on its first request, once the error was delivered
+		c.refused = true
+		c.push(resptypes.SimpleError{Val: errMaxClients})
+		return contextWithClient(ctx, c), c.out
+	}
+
+	r.acl.connect(c)
+	r.clients.add(c)
+	return contextWithClient(ctx, c), c.out
+}
+
+func (r *redisCommandProcessor) DisconnectClient(ctx context.Context) {
+	c := clientFromContext(ctx)
+	c.multi = nil
+	c.unwatchAll()
+	r.pubsub.removeClient(c)
+	r.monitors.remove(c)
+	r.tracking.disable(c)
+	r.replication.removeReplica(c)
+	r.clients.remove(c)
+	c.close()
+}
+
+func (r *redisCommandProcessor) Close() error {
+	r.shuttingDown.Store(true)
+	if r.metricsServer != nil {
+		defer r.metricsServer.close()
+	}
+
+	close(r.stop)
+	r.wg.Wait()
+	if r.consensus != nil {
+		r.consensus.close()
+	}
+
+	if r.activeActive != nil {
+		r.activeActive.close()
+	}
+
+	if r.cluster != nil {
+		r.cluster.close()
+	}
+
+	r.replication.close()
+	r.aof.close()
+	return r.persistence.close()
+}
+
+func (r *redisCommandProcessor) ExecuteCommand(ctx context.Context, respStr string) resptypes.RespSerializable {
+	c := clientFromContext(ctx)
+	if c.refused {
+		return resptypes.Null{}
+	}
+
+	params, errResult := parseRequest(respStr)
+	if errResult != nil {
+		// Logged here, as requests that do not parse never reach the middlewares
+		slog.DebugContext(ctx, "Malformed request received", "respStr", respStr)
+		if err, failed := errResult.(resptypes.SimpleError); failed {
+			r.metrics.errorReplied(err.Val)
 		}
+
+		return errResult
+	}
+
+	c.touch()
+	defer c.touch()
+	result := r.serve(ctx, newRequest(c, r.acl.whoami(c), params, r.clientCommands))
+	if err, failed := result.(resptypes.SimpleError); failed {
+		r.metrics.errorReplied(err.Val)
+	}
+
+	return result
+}
+
+func (r *redisCommandProcessor) infoServer() []string {
+	mode := "standalone"
+	if r.cluster != nil {
+		mode = "cluster"
 	}
 
-	commandName := bulkStrings[0].Val
+	return r.metrics.serverInfo(mode, r.options.port, r.options.configFile)
+}
+
+// parseRequest parses a request sent as a RESP array of bulk strings. When it is malformed,
+// the error reply is returned instead. The arguments are read by their length, as values such
+// as DUMP payloads may hold any byte, CRLF included.
+func parseRequest(respStr string) (commandParams, commandResult) {
+	args, _, err := redislib.ReadCommand(bufio.NewReader(strings.NewReader(respStr)))
+	if err != nil {
+		return nil, resptypes.SimpleError{Val: fmt.Errorf("ERRPARSE %w", err)}
+	}
+
+	return resptypes.ToBulkStringArray(args), nil
+}
+
+func (r *redisCommandProcessor) dispatch(ctx context.Context, params commandParams) commandResult {
+	c := clientFromContext(ctx)
+	commands := r.commands
+	if !c.internal {
+		commands = r.clientCommands
+	}
+
+	commandName := params[0].Val
 	commandName = strings.ToUpper(commandName)
-	entry, ok := r.commands[commandName]
+	entry, ok := commands[commandName]
 	if !ok {
+		if c.multi != nil {
+			c.multi.aborted = true
+		}
+
 		return resptypes.SimpleError{Val: fmt.Errorf("NOTSUPPORTED Command '%s' is not supported!", commandName)}
 	}
 
-	result := entry.execute(ctx, bulkStrings)
+	if err := entry.spec().checkArity(params); err != nil {
+		return r.reject(c, commandName, err)
+	}
+
+	if moniker := entry.moniker(); moniker != commandName {
+		// Renamed commands run, and are propagated, under their original name
+		commandName = moniker
+		params = slices.Concat(commandParams{*resptypes.NewBulkString(moniker)}, params[1:])
+	}
+
+	if !c.internal {
+		logContext := "toplevel"
+		if c.multi != nil {
+			logContext = "multi"
+		}
+
+		if err := r.acl.check(c, entry, params, logContext); err != nil {
+			return r.reject(c, commandName, err)
+		}
+	}
+
+	flags := flagsOf(entry)
+	if c.subscriptionCount() > 0 && flags&flagPubSub == 0 {
+		return r.reject(c, commandName, fmt.Errorf("ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context", strings.ToLower(commandName)))
+	}
+
+	if r.cluster != nil && !c.internal {
+		routeFlags := flags
+		if c.asking {
+			routeFlags |= flagAsking
+		}
+
+		if err := r.cluster.route(keysOf(entry, params), r.dbs.DB(c.db), routeFlags); err != nil {
+			return r.reject(c, commandName, err)
+		}
+	}
+
+	if flags&flagWrite != 0 {
+		if err := r.replication.checkWrite(c); err != nil {
+			return r.reject(c, commandName, err)
+		}
+	}
+
+	if r.activeActive != nil && !c.internal {
+		if err := r.activeActive.check(commandName, entry); err != nil {
+			return r.reject(c, commandName, err)
+		}
+	}
+
+	if r.consensus != nil && !c.internal {
+		if result, done := r.consensus.dispatch(ctx, c, commandName, entry, params); done {
+			return result
+		}
+	}
+
+	if c.multi != nil && flags&flagTransaction == 0 {
+		c.multi.queued = append(c.multi.queued, params)
+		return resptypes.SimpleString{Val: "QUEUED"}
+	}
+
+	switch {
+	case flags&(flagBlocking|flagTransaction) != 0:
+	case flags&(flagExclusive|flagWrite) != 0:
+		r.txMu.Lock()
+		defer r.txMu.Unlock()
+	default:
+		r.txMu.RLock()
+		defer r.txMu.RUnlock()
+	}
+
+	if flags&flagBlocking != 0 {
+		r.metrics.blockedClients.Add(1)
+		c.blocked.Store(true)
+		defer func() {
+			c.blocked.Store(false)
+			r.metrics.blockedClients.Add(-1)
+		}()
+	}
+
+	result := r.call(ctx, entry, params)
+	r.propagate(c, c.pending)
+	if commandName != "CLIENT" {
+		// CLIENT CACHING only applies to the command right after it
+		c.caching = nil
+	}
+
+	if commandName != "ASKING" {
+		c.asking = false
+	}
+
+	return result
+}
+
+// disconnectIdleClients disconnects the clients idle for longer than the timeout until Close is called.
+func (r *redisCommandProcessor) disconnectIdleClients() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		timeout := time.Duration(r.timeout.Load())
+		if timeout <= 0 {
+			continue
+		}
+
+		for _, c := range r.clients.idle(timeout) {
+			if !c.blocked.Load() && !r.pubsub.isSubscribed(c) {
+				slog.Info("Disconnecting idle client", "id", c.id, "addr", c.addr)
+				c.kill()
+				// Not reaped again while the connection is being torn down
+				r.clients.remove(c)
+			}
+		}
+	}
+}
+
+// resetStat zeroes the statistics reported by INFO, like CONFIG RESETSTAT.
+func (r *redisCommandProcessor) resetStat() {
+	r.metrics.reset()
+	r.replication.resetStats()
+	r.monitors.dropped.Store(0)
+}
+
+// reject replies with err to a command refused before it could run, which aborts the
+// transaction it was to be queued in.
+func (r *redisCommandProcessor) reject(c *client, commandName string, err error) commandResult {
+	if c.multi != nil {
+		c.multi.aborted = true
+	}
+
+	r.metrics.rejected(commandName)
+	return resptypes.SimpleError{Val: err}
+}
+
+// propagate feeds the write commands of a client's request and clears them from the client.
+func (r *redisCommandProcessor) propagate(c *client, batch []propagatedCommand) {
+	c.pending = nil
+	if len(batch) == 0 {
+		return
+	}
+
+	if r.activeActive != nil {
+		batch = r.activeActive.record(batch)
+	}
+
+	r.propagator.feed(batch)
+	c.replOffset = r.replication.currentOffset()
+}
+
+// call runs a single command and queues its effects on the client for propagation.
+// Callers are responsible for transaction locking and for feeding the queued commands.
+func (r *redisCommandProcessor) call(ctx context.Context, entry commandDefinition, params commandParams) commandResult {
+	c := clientFromContext(ctx)
+	if c.link != nil && c.link.ctx.Err() != nil {
+		// REPLICAOF detached the replica while the command was waiting for the transaction lock
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", errLinkClosed)}
+	}
+
+	db := c.db
+	c.rewrite, c.rewritten = false, nil
+	start := time.Now()
+	result := entry.execute(ctx, params)
+	elapsed := time.Since(start)
+	_, failed := result.(resptypes.SimpleError)
+	r.metrics.called(entry.moniker(), elapsed, failed)
+	flags := flagsOf(entry)
+	if flags&flagBlocking == 0 {
+		// Blocking commands mostly spend their time waiting
+		r.slowLog.record(c, params, elapsed)
+		r.latency.add(latencyEventCommand, elapsed)
+	}
+
+	// Checked on the subcommand, as not every subcommand of ACL is administrative
+	if entry.spec().resolve(strings.ToLower(params[0].Val), params).flags&flagAdmin == 0 {
+		r.monitors.feed(c, db, start, params)
+	}
+
+	if failed || flags&flagWrite == 0 {
+		return result
+	}
+
+	if !c.rewrite {
+		c.rewritten = [][]string{argsOf(params)}
+	}
+
+	for _, args := range c.rewritten {
+		c.pending = append(c.pending, propagatedCommand{db: db, args: args})
+	}
+
 	return result
 }
@@ -0,0 +1,310 @@
+package redisserverlib
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type (
+	// commandSpec declares a command: how many arguments it takes, how the dispatcher treats it,
+	// where its keys are and how it is documented. COMMAND serves it to clients.
+	commandSpec struct {
+		// Only set for subcommands, in lowercase. Commands are named by their moniker.
+		name string
+		// The number of arguments including the command name, or -n for at least n
+		arity int
+		flags commandFlags
+		// ACL categories, without the @, besides those implied by the flags
+		categories []string
+		keySpecs   []keySpec
+		// Where the channels of Pub/Sub commands are, flagged "pattern" for PSUBSCRIBE patterns
+		channelSpecs []keySpec
+
+		// Documentation, as shown by COMMAND DOCS
+		group      string
+		summary    string
+		since      string
+		complexity string
+		args       []commandArg
+
+		// The subcommands of a container command such as CONFIG. Their arity counts the command
+		// name and the subcommand name, and they share the flags and categories of the command.
+		subcommands []commandSpec
+	}
+
+	// keySpec tells where the keys of a command are in its arguments, like the key specifications
+	// of Redis. The search begins at index, or right after keyword, and takes the arguments from
+	// there to lastKey, every keyStep.
+	keySpec struct {
+		// RO, RW, OW or RM, followed by the access, insert, update or delete flags
+		flags []string
+		index int
+		// When set, the keyword is searched for from startFrom, or backwards from the end if negative
+		keyword   string
+		startFrom int
+		// Relative to the first key, or to the end of the arguments if negative
+		lastKey int
+		keyStep int
+		// When lastKey is -1, only the first 1/limit of the remaining arguments are keys
+		limit int
+	}
+
+	// commandArg describes an argument of a command for COMMAND DOCS.
+	commandArg struct {
+		name string
+		typ  string
+		// The literal that precedes the argument, if any
+		token    string
+		optional bool
+		multiple bool
+		// The index of the key spec of a key argument
+		keySpec int
+		// The alternatives of a oneof argument, or the members of a block
+		args []commandArg
+	}
+
+	// commandFlagName is a flag reported by COMMAND INFO, and the command flags that imply it.
+	commandFlagName struct {
+		name  string
+		flags commandFlags
+	}
+)
+
+const (
+	// Argument types
+	argKey       = "key"
+	argString    = "string"
+	argInteger   = "integer"
+	argDouble    = "double"
+	argPattern   = "pattern"
+	argUnixTime  = "unix-time"
+	argPureToken = "pure-token"
+	argOneOf     = "oneof"
+	argBlock     = "block"
+)
+
+var (
+	// The ACL categories in the order Redis lists them
+	aclCategoryNames = []string{
+		"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string", "bitmap", "hyperloglog",
+		"geo", "stream", "pubsub", "admin", "fast", "slow", "blocking", "dangerous", "connection", "transaction",
+		"scripting",
+	}
+
+	commandFlagNames = []commandFlagName{
+		{"write", flagWrite},
+		{"readonly", flagReadonly},
+		{"admin", flagAdmin},
+		{"blocking", flagBlocking},
+		{"asking", flagAsking},
+		{"fast", flagFast},
+		{"no_auth", flagNoAuth},
+	}
+
+	// Key spec flags
+	keyRO       = []string{"RO", "access"}
+	keyRW       = []string{"RW", "access", "update"}
+	keyRWInsert = []string{"RW", "insert"}
+	keyRWDelete = []string{"RW", "access", "delete"}
+	keyOW       = []string{"OW", "update"}
+	keyRM       = []string{"RM", "delete"}
+	// MIGRATE may not find every key, as its key argument is empty when KEYS is used
+	keyMigrated = []string{"RW", "access", "delete", "incomplete"}
+)
+
+// firstKey returns the key spec of the commands whose only key is their first argument.
+func firstKey(flags []string) []keySpec {
+	return []keySpec{{flags: flags, index: 1, keyStep: 1}}
+}
+
+// checkArity returns the error replied to a call with too few or too many arguments for the
+// command, or for its subcommand.
+func (s commandSpec) checkArity(params commandParams) error {
+	name := strings.ToLower(params[0].Val)
+	if !arityAllows(s.arity, len(params)) {
+		return fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+	}
+
+	if len(params) > 1 {
+		if sub, exists := s.subcommand(params[1].Val); exists && !arityAllows(sub.arity, len(params)) {
+			return fmt.Errorf("ERR wrong number of arguments for '%s|%s' command", name, sub.name)
+		}
+	}
+
+	return nil
+}
+
+func arityAllows(arity int, argc int) bool {
+	return argc == arity || (arity < 0 && argc >= -arity)
+}
+
+func (s commandSpec) subcommand(name string) (commandSpec, bool) {
+	name = strings.ToLower(name)
+	for _, sub := range s.subcommands {
+		if sub.name == name {
+			return sub, true
+		}
+	}
+
+	return commandSpec{}, false
+}
+
+// findKeys returns the keys of a call, as found by the key specs.
+func (s commandSpec) findKeys(params commandParams) []string {
+	keys := []string{}
+	for _, spec := range s.keySpecs {
+		keys = append(keys, spec.find(params)...)
+	}
+
+	return keys
+}
+
+func (k keySpec) find(params commandParams) []string {
+	keys := []string{}
+	for _, i := range k.indexes(params) {
+		keys = append(keys, params[i].Val)
+	}
+
+	return keys
+}
+
+// indexes returns the positions of the keys found by the key spec.
+func (k keySpec) indexes(params commandParams) []int {
+	first := k.index
+	if k.keyword != "" {
+		first = -1
+		if k.startFrom >= 0 {
+			for i := k.startFrom; i < len(params) && first < 0; i++ {
+				if strings.EqualFold(params[i].Val, k.keyword) {
+					first = i + 1
+				}
+			}
+		} else {
+			for i := len(params) + k.startFrom; i > 0 && first < 0; i-- {
+				if strings.EqualFold(params[i].Val, k.keyword) {
+					first = i + 1
+				}
+			}
+		}
+
+		if first < 0 {
+			return nil
+		}
+	}
+
+	last := first + k.lastKey
+	if k.lastKey < 0 {
+		last = len(params) + k.lastKey
+		if k.limit > 1 {
+			last = first + (last-first+1)/k.limit - 1
+		}
+	}
+
+	if last < first {
+		return nil
+	}
+
+	return argIndexes(params, first, last, max(1, k.keyStep))
+}
+
+// legacyKeys returns the first key, last key and step that COMMAND INFO reports for clients
+// that predate key specs. They only describe a key spec that begins at an index.
+func (s commandSpec) legacyKeys() (int, int, int) {
+	if len(s.keySpecs) == 0 || s.keySpecs[0].keyword != "" {
+		return 0, 0, 0
+	}
+
+	k := s.keySpecs[0]
+	last := k.lastKey
+	if last >= 0 {
+		last += k.index
+	}
+
+	return k.index, last, max(1, k.keyStep)
+}
+
+// movableKeys tells whether the keys cannot be found by the legacy first key, last key and step.
+func (s commandSpec) movableKeys() bool {
+	return slices.ContainsFunc(s.keySpecs, func(k keySpec) bool { return k.keyword != "" })
+}
+
+// flagNames returns the flags that COMMAND INFO reports.
+func (s commandSpec) flagNames() []string {
+	names := []string{}
+	for _, flag := range commandFlagNames {
+		if s.flags&flag.flags != 0 {
+			names = append(names, flag.name)
+		}
+	}
+
+	if slices.Contains(s.categories, "pubsub") {
+		names = append(names, "pubsub")
+	}
+
+	if s.movableKeys() {
+		names = append(names, "movablekeys")
+	}
+
+	return names
+}
+
+// aclCategories returns the ACL categories of the command, those implied by its flags included.
+func (s commandSpec) aclCategories() []string {
+	categories := slices.Clone(s.categories)
+	if s.flags&flagWrite != 0 {
+		categories = append(categories, "write")
+	}
+
+	if s.flags&flagReadonly != 0 {
+		categories = append(categories, "read")
+	}
+
+	if s.flags&flagAdmin != 0 {
+		categories = append(categories, "admin", "dangerous")
+	}
+
+	if s.flags&flagBlocking != 0 {
+		categories = append(categories, "blocking")
+	}
+
+	if s.flags&flagFast != 0 {
+		categories = append(categories, "fast")
+	} else {
+		categories = append(categories, "slow")
+	}
+
+	result := []string{}
+	for _, category := range aclCategoryNames {
+		if slices.Contains(categories, category) {
+			result = append(result, category)
+		}
+	}
+
+	return result
+}
+
+// resolve returns the spec of the command or subcommand called with params, named like ACL
+// rules name it.
+func (s commandSpec) resolve(name string, params commandParams) commandSpec {
+	if len(params) > 1 {
+		if sub, exists := s.subcommand(params[1].Val); exists {
+			return sub.withParent(s, name)
+		}
+	}
+
+	s.name = name
+	return s
+}
+
+// withParent returns the spec of a subcommand of parent, named after both.
+func (s commandSpec) withParent(parent commandSpec, parentName string) commandSpec {
+	s.name = parentName + "|" + s.name
+	s.flags |= parent.flags
+	s.categories = slices.Concat(parent.categories, s.categories)
+	if s.group == "" {
+		s.group = parent.group
+	}
+
+	return s
+}
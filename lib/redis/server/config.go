@@ -0,0 +1,632 @@
+package redisserverlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// configParam exposes one runtime parameter to CONFIG GET/SET.
+	configParam struct {
+		get func() string
+		// nil for parameters that can only be set at startup
+		set func(value string) error
+		// The value the parameter has unless configured, which CONFIG REWRITE leaves out
+		def string
+		// Set for parameters whose value is written as several arguments in the config file
+		multiArg bool
+	}
+
+	configParams map[string]configParam
+
+	configCmd struct {
+		params configParams
+		// The config file the server was started with, empty if none
+		file      string
+		resetStat func()
+		// Written back as user directives, see acl.configLines
+		acl *acl
+	}
+)
+
+const (
+	// Marks the parameters CONFIG REWRITE added to the config file
+	configRewriteMarker = "# Generated by CONFIG REWRITE"
+)
+
+var (
+	// Former names of parameters still accepted in config files
+	configAliases = map[string]string{
+		"slaveof":         "replicaof",
+		"slave-read-only": "replica-read-only",
+	}
+)
+
+func (c configCmd) moniker() string {
+	return "CONFIG"
+}
+
+func (c configCmd) spec() commandSpec {
+	return commandSpec{
+		arity: -2,
+		// Some parameters, like appendonly, need a consistent view of the dataset when applied
+		flags:   flagExclusive | flagAdmin,
+		group:   "server",
+		summary: "A container for server configuration commands.",
+		since:   "2.0.0",
+		subcommands: []commandSpec{
+			{
+				name: "get", arity: -3, summary: "Returns the effective values of configuration parameters.", since: "2.0.0",
+				complexity: "O(N) when N is the number of configuration parameters provided",
+				args:       []commandArg{{name: "parameter", typ: argPattern, multiple: true}},
+			},
+			{
+				name: "set", arity: -4, summary: "Sets configuration parameters in-flight.", since: "2.0.0",
+				complexity: "O(N) when N is the number of configuration parameters provided",
+				args: []commandArg{{name: "data", typ: argBlock, multiple: true, args: []commandArg{
+					{name: "parameter", typ: argString},
+					{name: "value", typ: argString},
+				}}},
+			},
+			{name: "rewrite", arity: 2, summary: "Persists the effective configuration to file.", since: "2.8.0", complexity: "O(1)"},
+			{name: "resetstat", arity: 2, summary: "Resets the server's statistics.", since: "2.0.0", complexity: "O(1)"},
+		},
+	}
+}
+
+func (c configCmd) getUsage() string {
+	return `
+usage:
+	CONFIG GET parameter [parameter ...]
+	CONFIG SET parameter value [parameter value ...]
+	CONFIG REWRITE
+	CONFIG RESETSTAT
+summary:
+	GET returns the values of the configuration parameters matching the given glob-style patterns.
+	SET changes configuration parameters at runtime, without restarting the server. Either every
+	parameter is changed or, when a value is invalid, none.
+	REWRITE writes the current configuration to the config file the server was started with,
+	keeping its comments and the directives CONFIG does not handle.
+	RESETSTAT resets the statistics reported by INFO.
+`
+}
+
+func (c configCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR CONFIG requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "GET" && len(params) >= 3:
+		return c.executeGet(params[2:])
+	case subcommand == "SET" && len(params) >= 4 && len(params)%2 == 0:
+		return c.executeSet(params[2:])
+	case subcommand == "REWRITE" && len(params) == 2:
+		if err := c.rewrite(); err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "RESETSTAT" && len(params) == 2:
+		c.resetStat()
+		return resptypes.SimpleString{Val: "OK"}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown CONFIG subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+func (c configCmd) executeGet(patterns commandParams) commandResult {
+	names := make([]string, 0, len(c.params))
+	for name := range c.params {
+		for _, pattern := range patterns {
+			if redislib.GlobMatch(strings.ToLower(pattern.Val), name) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	result := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		result = append(result, name, c.params[name].get())
+	}
+
+	return resptypes.ToBulkStringArray(result)
+}
+
+func (c configCmd) executeSet(pairs commandParams) commandResult {
+	// Check every name first so that either all parameters are applied or none
+	seen := make(map[string]bool)
+	for i := 0; i < len(pairs); i += 2 {
+		name := strings.ToLower(pairs[i].Val)
+		param, exists := c.params[name]
+		if !exists {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", pairs[i].Val)}
+		}
+
+		if param.set == nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - can't set immutable config", name)}
+		}
+
+		if seen[name] {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - duplicate parameter", name)}
+		}
+
+		seen[name] = true
+	}
+
+	// Values are validated as they are applied, so the ones applied before an invalid one are restored
+	previous := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		name := strings.ToLower(pairs[i].Val)
+		previous = append(previous, c.params[name].get())
+		if err := c.params[name].set(pairs[i+1].Val); err != nil {
+			for j := i - 2; j >= 0; j -= 2 {
+				c.params[strings.ToLower(pairs[j].Val)].set(previous[j/2])
+			}
+
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR CONFIG SET failed (possibly related to argument '%s') - %w", name, err)}
+		}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+// rewrite updates the config file with the current value of the parameters. Lines setting a
+// parameter are replaced in place, and parameters missing from the file are appended unless
+// they have their default value. The user directives are replaced by the current ACL users.
+// Comments and other directives are left as they are.
+func (c configCmd) rewrite() error {
+	if c.file == "" {
+		return fmt.Errorf("ERR The server is running without a config file")
+	}
+
+	content, err := os.ReadFile(c.file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("ERR Rewriting config file: %w", err)
+	}
+
+	var lines []string
+	if text := strings.TrimRight(string(content), "\n"); text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	var out []string
+	written := make(map[string]bool)
+	marked := false
+	users := c.acl.configLines()
+	for _, line := range lines {
+		fields, err := splitConfigLine(line)
+		if err != nil || len(fields) == 0 {
+			marked = marked || strings.TrimSpace(line) == configRewriteMarker
+			out = append(out, line)
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		if alias, exists := configAliases[name]; exists {
+			name = alias
+		}
+
+		// The users go where the first of them was, so that the passwords are never written in clear
+		if name == "user" && c.acl.file == "" {
+			if !written[name] {
+				written[name] = true
+				out = append(out, users...)
+			}
+
+			continue
+		}
+
+		param, exists := c.params[name]
+		if !exists {
+			out = append(out, line)
+			continue
+		}
+
+		// The first line of a parameter holds its value, later ones are dropped
+		if !written[name] {
+			written[name] = true
+			out = append(out, param.configLine(name))
+		}
+	}
+
+	names := make([]string, 0, len(c.params))
+	for name := range c.params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		param := c.params[name]
+		if written[name] || param.get() == param.def {
+			continue
+		}
+
+		if !marked {
+			out = append(out, configRewriteMarker)
+			marked = true
+		}
+
+		out = append(out, param.configLine(name))
+	}
+
+	if !written["user"] && len(users) > 0 {
+		if !marked {
+			out = append(out, configRewriteMarker)
+		}
+
+		out = append(out, users...)
+	}
+
+	if err := writeConfigFile(c.file, []byte(strings.Join(out, "\n")+"\n")); err != nil {
+		return fmt.Errorf("ERR Rewriting config file: %w", err)
+	}
+
+	return nil
+}
+
+// configLine returns the directive setting the parameter to its current value.
+func (p configParam) configLine(name string) string {
+	args := []string{p.get()}
+	if fields := strings.Fields(args[0]); p.multiArg && len(fields) > 0 {
+		args = fields
+	}
+
+	for i, arg := range args {
+		args[i] = formatConfigArg(arg)
+	}
+
+	return name + " " + strings.Join(args, " ")
+}
+
+// writeConfigFile replaces the config file at path, so that it is never left partially written.
+func writeConfigFile(path string, data []byte) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf("temp-%d-%d.conf", os.Getpid(), lastTempFileId.Add(1)))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if info, statErr := os.Stat(path); err == nil && statErr == nil {
+		err = os.Chmod(tmp, info.Mode())
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+	}
+
+	return err
+}
+
+// newConfigParams describes the parameters of a processor.
+func newConfigParams(r *redisCommandProcessor, events *keyspaceEvents) configParams {
+	p := r.persistence
+	defaults := newProcessorOptions(nil)
+	return configParams{
+		"databases": {
+			get: func() string { return strconv.Itoa(r.dbs.Len()) },
+			def: strconv.Itoa(defaults.databases),
+		},
+		"dir": {
+			get: func() string {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				return p.dir
+			},
+			set: func(value string) error {
+				if info, err := os.Stat(value); err != nil || !info.IsDir() {
+					return fmt.Errorf("No such directory")
+				}
+
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				p.dir = value
+				return nil
+			},
+			def: defaults.dir,
+		},
+		"dbfilename": {
+			get: func() string {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				return p.dbfilename
+			},
+			set: func(value string) error {
+				if err := validateDbFilename(value); err != nil {
+					return fmt.Errorf("dbfilename %w", err)
+				}
+
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				p.dbfilename = value
+				return nil
+			},
+			def: defaults.dbfilename,
+		},
+		"save": {
+			get: func() string {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				return formatSaveParams(p.saveParams)
+			},
+			set: func(value string) error {
+				params, err := parseSaveParams(value)
+				if err != nil {
+					return err
+				}
+
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				p.saveParams = params
+				return nil
+			},
+			def:      defaults.save,
+			multiArg: true,
+		},
+		"appendonly": {
+			get: func() string { return formatYesNo(r.aof.isEnabled()) },
+			set: func(value string) error {
+				enable, err := parseYesNo(value)
+				if err != nil || enable == r.aof.isEnabled() {
+					return err
+				}
+
+				if !enable {
+					r.aof.disable()
+					return nil
+				}
+
+				return r.aof.enable()
+			},
+			def: formatYesNo(defaults.appendOnly),
+		},
+		"appendfsync": {
+			get: func() string {
+				r.aof.mu.Lock()
+				defer r.aof.mu.Unlock()
+				return r.aof.fsync
+			},
+			set: func(value string) error {
+				value = strings.ToLower(value)
+				if err := validateFsyncPolicy(value); err != nil {
+					return err
+				}
+
+				r.aof.mu.Lock()
+				defer r.aof.mu.Unlock()
+				r.aof.fsync = value
+				return nil
+			},
+			def: defaults.appendFsync,
+		},
+		"appendfilename": {
+			get: func() string { return r.aof.filename },
+			def: defaults.appendFilename,
+		},
+		"appenddirname": {
+			get: func() string { return r.aof.dirname },
+			def: defaults.appendDirname,
+		},
+		"replica-read-only": {
+			get: func() string { return formatYesNo(r.replication.readOnly.Load()) },
+			set: func(value string) error {
+				readOnly, err := parseYesNo(value)
+				if err == nil {
+					r.replication.readOnly.Store(readOnly)
+				}
+
+				return err
+			},
+			def: formatYesNo(defaults.replicaReadOnly),
+		},
+		"min-replicas-to-write": {
+			get: func() string { return strconv.FormatInt(r.replication.minReplicas.Load(), 10) },
+			set: func(value string) error { return setNonNegative(&r.replication.minReplicas, value) },
+			def: strconv.Itoa(defaults.minReplicasToWrite),
+		},
+		"min-replicas-max-lag": {
+			get: func() string { return strconv.FormatInt(r.replication.maxLag.Load(), 10) },
+			set: func(value string) error { return setNonNegative(&r.replication.maxLag, value) },
+			def: strconv.Itoa(defaults.minReplicasMaxLag),
+		},
+		"replica-priority": {
+			get: func() string { return strconv.FormatInt(r.replication.priority.Load(), 10) },
+			set: func(value string) error { return setNonNegative(&r.replication.priority, value) },
+			def: strconv.Itoa(defaults.replicaPriority),
+		},
+		"repl-backlog-size": {
+			get: func() string { return strconv.Itoa(len(r.replication.backlog.buf)) },
+			def: strconv.Itoa(defaults.replBacklogSize),
+		},
+		"replicaof": {
+			get: func() string {
+				rp := r.replication
+				rp.mu.Lock()
+				defer rp.mu.Unlock()
+				if rp.primary == nil {
+					return ""
+				}
+
+				return fmt.Sprintf("%s %d", rp.primary.host, rp.primary.port)
+			},
+			def:      defaults.replicaOf,
+			multiArg: true,
+		},
+		"port": {
+			get: func() string { return strconv.Itoa(r.replication.port) },
+			def: strconv.Itoa(defaults.port),
+		},
+		"notify-keyspace-events": {
+			get: func() string { return events.getClasses().String() },
+			set: func(value string) error {
+				classes, err := parseNotifyClasses(value)
+				if err != nil {
+					return err
+				}
+
+				events.setClasses(classes)
+				return nil
+			},
+			def: defaults.notifyKeyspaceEvents,
+		},
+		"loglevel": {
+			get: func() string { return formatLogLevel(r.options.logLevel.Level()) },
+			set: func(value string) error {
+				level, err := parseLogLevel(value)
+				if err == nil {
+					r.options.logLevel.Set(level)
+				}
+
+				return err
+			},
+			def: formatLogLevel(defaults.logLevel.Level()),
+		},
+		"timeout": {
+			get: func() string { return strconv.FormatInt(r.timeout.Load()/int64(time.Second), 10) },
+			set: func(value string) error {
+				seconds, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || seconds < 0 || seconds > math.MaxInt64/int64(time.Second) {
+					return fmt.Errorf("argument must be a non-negative integer")
+				}
+
+				r.timeout.Store(seconds * int64(time.Second))
+				return nil
+			},
+			def: strconv.FormatInt(int64(defaults.timeout/time.Second), 10),
+		},
+		"maxclients": {
+			get: func() string { return strconv.FormatInt(r.maxClients.Load(), 10) },
+			set: func(value string) error {
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || n < 1 {
+					return fmt.Errorf("argument must be a positive integer")
+				}
+
+				r.maxClients.Store(n)
+				return nil
+			},
+			def: strconv.Itoa(defaults.maxClients),
+		},
+		"slowlog-log-slower-than": {
+			get: func() string { return strconv.FormatInt(r.slowLog.slowerThan.Load(), 10) },
+			set: func(value string) error {
+				micros, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return fmt.Errorf("argument couldn't be parsed into an integer")
+				}
+
+				r.slowLog.slowerThan.Store(micros)
+				return nil
+			},
+			def: strconv.FormatInt(defaults.slowlogLogSlowerThan.Microseconds(), 10),
+		},
+		"latency-monitor-threshold": {
+			get: func() string { return strconv.FormatInt(r.latency.threshold.Load(), 10) },
+			set: func(value string) error { return setNonNegative(&r.latency.threshold, value) },
+			def: strconv.FormatInt(defaults.latencyMonitorThreshold.Milliseconds(), 10),
+		},
+		"metrics-addr": {
+			get: func() string { return r.options.metricsAddr },
+		},
+		"slowlog-max-len": {
+			get: func() string { return strconv.FormatInt(r.slowLog.maxLen.Load(), 10) },
+			set: func(value string) error { return setNonNegative(&r.slowLog.maxLen, value) },
+			def: strconv.Itoa(defaults.slowlogMaxLen),
+		},
+		"requirepass": {
+			get: r.acl.getRequirePass,
+			set: func(value string) error {
+				r.acl.setRequirePass(value)
+				return nil
+			},
+			def: defaults.requirePass,
+		},
+		"aclfile": {
+			get: func() string { return r.acl.file },
+			def: defaults.aclFile,
+		},
+		"protected-mode": {
+			get: func() string { return formatYesNo(r.acl.protectedMode.Load()) },
+			set: func(value string) error {
+				enabled, err := parseYesNo(value)
+				if err == nil {
+					r.acl.protectedMode.Store(enabled)
+				}
+
+				return err
+			},
+			def: formatYesNo(defaults.protectedMode),
+		},
+		"acllog-max-len": {
+			get: func() string { return strconv.FormatInt(r.acl.log.maxLen.Load(), 10) },
+			set: func(value string) error { return setNonNegative(&r.acl.log.maxLen, value) },
+			def: strconv.Itoa(defaults.aclLogMaxLen),
+		},
+		"masteruser": {
+			get: func() string { return r.options.masterUser },
+			def: defaults.masterUser,
+		},
+		"masterauth": {
+			get: func() string { return r.options.masterAuth },
+			def: defaults.masterAuth,
+		},
+	}
+}
+
+func setNonNegative(param *atomic.Int64, value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("argument must be a non-negative integer")
+	}
+
+	param.Store(n)
+	return nil
+}
+
+func parseYesNo(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("argument must be 'yes' or 'no'")
+	}
+}
+
+func formatYesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+
+	return "no"
+}
@@ -0,0 +1,532 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Config holds the directives of a redis.conf file, followed by the overrides given on the
+	// command line, in the order they apply. Options converts them into processor options.
+	Config struct {
+		file       string
+		directives []configDirective
+	}
+
+	configDirective struct {
+		name string
+		args []string
+		// Where the directive comes from, as file:line, for error messages
+		source string
+	}
+
+	// directiveParser validates the arguments of a directive and returns the option applying it.
+	directiveParser func(args []string) (Option, error)
+)
+
+const (
+	// How deeply include directives may nest, which also stops include cycles
+	maxIncludeDepth = 10
+)
+
+var (
+	errConfigArgs = errors.New("wrong number of arguments")
+
+	logLevels = []struct {
+		name  string
+		level slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"verbose", slog.LevelDebug + 2},
+		{"notice", slog.LevelInfo},
+		{"warning", slog.LevelWarn},
+		{"nothing", slog.LevelError + 4},
+	}
+)
+
+// NewConfig returns an empty configuration, for a server started without a config file.
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// LoadConfig reads a config file in the redis.conf format: one directive per line, with its
+// arguments separated by spaces and quoted with double or single quotes when needed. Lines
+// starting with # are comments. "include path" reads another file in place of the directive;
+// relative paths are resolved against the directory of the including file and may be globs.
+func LoadConfig(path string) (*Config, error) {
+	c := &Config{file: path}
+	if err := c.read(path, 0); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Config) read(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested more than %d levels deep", path, maxIncludeDepth)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		source := fmt.Sprintf("%s:%d", path, lineNumber)
+		fields, err := splitConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		if name != "include" {
+			c.directives = append(c.directives, configDirective{name: name, args: fields[1:], source: source})
+			continue
+		}
+
+		if len(fields) != 2 {
+			return fmt.Errorf("%s: include requires a path", source)
+		}
+
+		pattern := fields[1]
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			return fmt.Errorf("%s: no file to include matches %s", source, fields[1])
+		}
+
+		for _, match := range matches {
+			if err := c.read(match, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Set overrides a directive, like an option given on the command line after the config file.
+func (c *Config) Set(name string, args ...string) {
+	c.directives = append(c.directives, configDirective{name: strings.ToLower(name), args: args, source: "command line"})
+}
+
+// Get returns the arguments of the directive that applies last for name.
+func (c *Config) Get(name string) ([]string, bool) {
+	name = strings.ToLower(name)
+	for i := len(c.directives) - 1; i >= 0; i-- {
+		if c.directives[i].name == name {
+			return c.directives[i].args, true
+		}
+	}
+
+	return nil, false
+}
+
+// Options validates the directives and returns the options applying them. Save points add up
+// across save directives, until a save "" directive clears them.
+func (c *Config) Options() ([]Option, error) {
+	var opts []Option
+	if c.file != "" {
+		path, err := filepath.Abs(c.file)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, WithConfigFile(path))
+	}
+
+	var save []string
+	saveSet := false
+	for _, d := range c.directives {
+		if d.name == "save" {
+			fields := splitArgs(d.args)
+			if len(fields) == 0 {
+				save = nil
+			} else {
+				save = append(save, fields...)
+			}
+
+			saveSet = true
+			continue
+		}
+
+		parse, exists := configDirectives[d.name]
+		if !exists {
+			return nil, fmt.Errorf("%s: unknown directive '%s'", d.source, d.name)
+		}
+
+		opt, err := parse(d.args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid %s: %w", d.source, d.name, err)
+		}
+
+		opts = append(opts, opt)
+	}
+
+	if saveSet {
+		value := strings.Join(save, " ")
+		if _, err := parseSaveParams(value); err != nil {
+			return nil, fmt.Errorf("invalid save: %w", err)
+		}
+
+		opts = append(opts, WithSave(value))
+	}
+
+	return opts, nil
+}
+
+// configDirectives parse the directives of a config file, by name.
+var configDirectives = map[string]directiveParser{
+	"port":                   intDirective(0, 65535, WithPort),
+	"databases":              intDirective(1, 1<<16, WithDatabases),
+	"notify-keyspace-events": validatedDirective(func(value string) error { _, err := parseNotifyClasses(value); return err }, WithNotifyKeyspaceEvents),
+	"dir":                    stringDirective(WithDir),
+	"dbfilename":             validatedDirective(validateDbFilename, WithDbFilename),
+	"appendonly":             yesNoDirective(WithAppendOnly),
+	"appendfsync":            validatedDirective(validateFsyncPolicy, WithAppendFsync),
+	"appendfilename":         validatedDirective(validateDbFilename, WithAppendFilename),
+	"appenddirname":          validatedDirective(validateDbFilename, WithAppendDirname),
+	"replicaof":              replicaOfDirective,
+	"slaveof":                replicaOfDirective,
+	"repl-backlog-size": func(args []string) (Option, error) {
+		if len(args) != 1 {
+			return nil, errConfigArgs
+		}
+
+		bytes, err := parseMemory(args[0])
+		if err != nil || bytes < 1 {
+			return nil, fmt.Errorf("argument must be a memory value")
+		}
+
+		return WithReplBacklogSize(int(bytes)), nil
+	},
+	"replica-read-only":                yesNoDirective(WithReplicaReadOnly),
+	"slave-read-only":                  yesNoDirective(WithReplicaReadOnly),
+	"min-replicas-to-write":            intDirective(0, 1<<31-1, WithMinReplicasToWrite),
+	"min-replicas-max-lag":             intDirective(0, 1<<31-1, WithMinReplicasMaxLag),
+	"replica-priority":                 intDirective(0, 1<<31-1, WithReplicaPriority),
+	"cluster-enabled":                  yesNoDirective(WithClusterEnabled),
+	"cluster-port":                     intDirective(0, 65535, WithClusterPort),
+	"cluster-config-file":              validatedDirective(validateDbFilename, WithClusterConfigFile),
+	"raft-members":                     listDirective(WithRaftMembers),
+	"raft-addr":                        stringDirective(WithRaftAddr),
+	"raft-snapshot-entries":            intDirective(1, 1<<31-1, WithRaftSnapshotEntries),
+	"active-active-peers":              listDirective(WithActiveActivePeers),
+	"sentinel-down-after-milliseconds": intDirective(1, 1<<31-1, func(ms int) Option { return WithSentinelDownAfter(time.Duration(ms) * time.Millisecond) }),
+	"sentinel-failover-timeout":        intDirective(1, 1<<31-1, func(ms int) Option { return WithSentinelFailoverTimeout(time.Duration(ms) * time.Millisecond) }),
+	"sentinel-monitor": func(args []string) (Option, error) {
+		fields := splitArgs(args)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("expected <name> <host> <port> <quorum>")
+		}
+
+		port, err := strconv.Atoi(fields[2])
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port")
+		}
+
+		quorum, err := strconv.Atoi(fields[3])
+		if err != nil || quorum < 1 {
+			return nil, fmt.Errorf("quorum must be a positive integer")
+		}
+
+		return WithSentinelMonitor(fields[0], fields[1], port, quorum), nil
+	},
+	"loglevel": func(args []string) (Option, error) {
+		if len(args) != 1 {
+			return nil, errConfigArgs
+		}
+
+		level, err := parseLogLevel(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return func(o *processorOptions) { o.initialLogLevel = &level }, nil
+	},
+	"timeout":    intDirective(0, 1<<31-1, func(seconds int) Option { return WithTimeout(time.Duration(seconds) * time.Second) }),
+	"maxclients": intDirective(1, 1<<31-1, WithMaxClients),
+	"slowlog-log-slower-than": intDirective(-1<<31, 1<<31-1, func(micros int) Option {
+		return WithSlowlogLogSlowerThan(time.Duration(micros) * time.Microsecond)
+	}),
+	"slowlog-max-len": intDirective(0, 1<<31-1, WithSlowlogMaxLen),
+	"metrics-addr":    stringDirective(WithMetricsAddr),
+	"requirepass":     stringDirective(WithRequirePass),
+	"aclfile":         stringDirective(WithACLFile),
+	"protected-mode":  yesNoDirective(WithProtectedMode),
+	"acllog-max-len":  intDirective(0, 1<<31-1, WithACLLogMaxLen),
+	"masteruser":      stringDirective(WithMasterUser),
+	"masterauth":      stringDirective(WithMasterAuth),
+	"user": func(args []string) (Option, error) {
+		if len(args) == 0 {
+			return nil, errConfigArgs
+		}
+
+		return WithACLUser(args[0], args[1:]...), nil
+	},
+	"rename-command": func(args []string) (Option, error) {
+		// rename-command CONFIG "" disables the command
+		fields := splitArgs(args)
+		switch len(fields) {
+		case 1:
+			return WithRenameCommand(fields[0], ""), nil
+		case 2:
+			return WithRenameCommand(fields[0], fields[1]), nil
+		default:
+			return nil, fmt.Errorf("expected <command> <new-name>")
+		}
+	},
+	"latency-monitor-threshold": intDirective(0, 1<<31-1, func(ms int) Option {
+		return WithLatencyMonitorThreshold(time.Duration(ms) * time.Millisecond)
+	}),
+}
+
+func intDirective(minValue int, maxValue int, opt func(int) Option) directiveParser {
+	return func(args []string) (Option, error) {
+		if len(args) != 1 {
+			return nil, errConfigArgs
+		}
+
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < minValue || n > maxValue {
+			return nil, fmt.Errorf("argument must be an integer between %d and %d", minValue, maxValue)
+		}
+
+		return opt(n), nil
+	}
+}
+
+func stringDirective(opt func(string) Option) directiveParser {
+	return validatedDirective(func(string) error { return nil }, opt)
+}
+
+func validatedDirective(validate func(string) error, opt func(string) Option) directiveParser {
+	return func(args []string) (Option, error) {
+		if len(args) != 1 {
+			return nil, errConfigArgs
+		}
+
+		if err := validate(args[0]); err != nil {
+			return nil, err
+		}
+
+		return opt(args[0]), nil
+	}
+}
+
+func yesNoDirective(opt func(bool) Option) directiveParser {
+	return func(args []string) (Option, error) {
+		if len(args) != 1 {
+			return nil, errConfigArgs
+		}
+
+		value, err := parseYesNo(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return opt(value), nil
+	}
+}
+
+// listDirective takes addresses as separate arguments or separated by commas.
+func listDirective(opt func(...string) Option) directiveParser {
+	return func(args []string) (Option, error) {
+		var values []string
+		for _, arg := range args {
+			for value := range strings.SplitSeq(arg, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					values = append(values, value)
+				}
+			}
+		}
+
+		return opt(values...), nil
+	}
+}
+
+func replicaOfDirective(args []string) (Option, error) {
+	fields := splitArgs(args)
+	if len(fields) == 0 || len(fields) == 2 && strings.EqualFold(fields[0], "no") && strings.EqualFold(fields[1], "one") {
+		return WithReplicaOf(""), nil
+	}
+
+	address := strings.Join(fields, " ")
+	if _, _, ok := parseReplicaOf(address); !ok {
+		return nil, fmt.Errorf("expected <host> <port>")
+	}
+
+	return WithReplicaOf(address), nil
+}
+
+// splitArgs splits arguments holding several values separated by spaces, as given on the
+// command line with --save "3600 1 300 100".
+func splitArgs(args []string) []string {
+	var fields []string
+	for _, arg := range args {
+		fields = append(fields, strings.Fields(arg)...)
+	}
+
+	return fields
+}
+
+func validateDbFilename(value string) error {
+	if value == "" || filepath.Base(value) != value {
+		return fmt.Errorf("can't be a path, just a filename")
+	}
+
+	return nil
+}
+
+// parseMemory parses a size with an optional unit like in redis.conf: 1k is 1000 bytes,
+// 1kb is 1024 bytes, and so on with m, mb, g and gb.
+func parseMemory(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000}, {"b", 1},
+	}
+
+	value = strings.ToLower(value)
+	factor := int64(1)
+	for _, unit := range units {
+		if number, found := strings.CutSuffix(value, unit.suffix); found {
+			value, factor = number, unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory value")
+	}
+
+	return n * factor, nil
+}
+
+func parseLogLevel(value string) (slog.Level, error) {
+	for _, l := range logLevels {
+		if strings.EqualFold(l.name, value) {
+			return l.level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("argument must be one of debug, verbose, notice, warning or nothing")
+}
+
+func formatLogLevel(level slog.Level) string {
+	name := logLevels[0].name
+	for _, l := range logLevels {
+		if level >= l.level {
+			name = l.name
+		}
+	}
+
+	return name
+}
+
+// splitConfigLine splits a line of a config file into its directive and arguments.
+// Arguments in double quotes may contain escapes like \n, \" and \xhh, and arguments in
+// single quotes are taken literally except for \'.
+func splitConfigLine(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return nil, nil
+	}
+
+	var fields []string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		var sb strings.Builder
+		switch quote := line[i]; quote {
+		case '"', '\'':
+			i++
+			closed := false
+			for i < len(line) && !closed {
+				ch := line[i]
+				switch {
+				case ch == quote:
+					closed = true
+				case ch == '\\' && i+1 < len(line) && quote == '"':
+					i++
+					switch esc := line[i]; esc {
+					case 'n':
+						sb.WriteByte('\n')
+					case 'r':
+						sb.WriteByte('\r')
+					case 't':
+						sb.WriteByte('\t')
+					case 'x':
+						if i+2 < len(line) {
+							if b, err := strconv.ParseUint(line[i+1:i+3], 16, 8); err == nil {
+								sb.WriteByte(byte(b))
+								i += 2
+								break
+							}
+						}
+
+						sb.WriteByte(esc)
+					default:
+						sb.WriteByte(esc)
+					}
+				case ch == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					sb.WriteByte('\'')
+				default:
+					sb.WriteByte(ch)
+				}
+
+				i++
+			}
+
+			if !closed {
+				return nil, fmt.Errorf("unbalanced quotes")
+			}
+
+			if i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				return nil, fmt.Errorf("closing quote must be followed by a space")
+			}
+		default:
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				sb.WriteByte(line[i])
+				i++
+			}
+		}
+
+		fields = append(fields, sb.String())
+	}
+
+	return fields, nil
+}
+
+// formatConfigArg quotes an argument for a config file when it would not read back as is.
+func formatConfigArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\r\n\"'\\#") {
+		return arg
+	}
+
+	return strconv.Quote(arg)
+}
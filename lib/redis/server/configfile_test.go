@@ -0,0 +1,215 @@
+package redisserverlib_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startWithConfig starts a processor configured by the config file at path.
+func startWithConfig(t *testing.T, config *redisserverlib.Config) testClient {
+	t.Helper()
+	opts, err := config.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := redisserverlib.NewRedisCommandProcessor(append([]redisserverlib.Option{redisserverlib.WithDir(t.TempDir())}, opts...)...)
+	t.Cleanup(func() { cp.Close() })
+	return newTestClient(cp)
+}
+
+func TestConfigFile(t *testing.T) {
+	t.Run("Includes and overrides", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "redis.conf")
+		writeFile(t, path, "# Server\ndatabases 4\n\ninclude conf.d/*.conf\nnotify-keyspace-events \"Kx\"\n")
+		os.Mkdir(filepath.Join(dir, "conf.d"), 0o755)
+		writeFile(t, filepath.Join(dir, "conf.d", "a.conf"), "databases 8\nsave 900 1\nsave 60 100\n")
+
+		config, err := redisserverlib.LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config.Set("notify-keyspace-events", "KEA")
+		c := startWithConfig(t, config)
+		c.expect(t, "*2\r\n$9\r\ndatabases\r\n$1\r\n8\r\n", "CONFIG", "GET", "databases")
+		c.expect(t, "*2\r\n$4\r\nsave\r\n$12\r\n900 1 60 100\r\n", "CONFIG", "GET", "save")
+		c.expect(t, "*2\r\n$22\r\nnotify-keyspace-events\r\n$3\r\nAKE\r\n", "CONFIG", "GET", "notify-keyspace-events")
+		if file := c.infoField("server", "config_file"); file != path {
+			t.Errorf("config_file = %q; Expected: %q", file, path)
+		}
+	})
+
+	t.Run("Invalid directives", func(t *testing.T) {
+		for _, tc := range []struct {
+			content  string
+			expected string
+		}{
+			{"databases zero\n", "databases"},
+			{"appendonly maybe\n", "appendonly"},
+			{"no-such-directive 1\n", "no-such-directive"},
+			{"dir \"unterminated\n", ":1"},
+			{"include missing.conf\n", "missing.conf"},
+		} {
+			path := filepath.Join(t.TempDir(), "redis.conf")
+			writeFile(t, path, tc.content)
+			config, err := redisserverlib.LoadConfig(path)
+			if err == nil {
+				_, err = config.Options()
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("%q: error = %v; Expected it to mention %q", tc.content, err, tc.expected)
+			}
+		}
+	})
+}
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.conf")
+	writeFile(t, path, "# Keep this comment\nmaxclients 100\n# Spaces too\nappendonly no\ncluster-config-file nodes-1.conf\n")
+	config, err := redisserverlib.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := startWithConfig(t, config)
+
+	t.Run("SET validates every parameter before applying any", func(t *testing.T) {
+		c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'maxclients') - argument must be a positive integer\r\n",
+			"CONFIG", "SET", "timeout", "30", "maxclients", "many")
+		c.expect(t, "*2\r\n$7\r\ntimeout\r\n$1\r\n0\r\n", "CONFIG", "GET", "timeout")
+		c.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'loglevel') - duplicate parameter\r\n",
+			"CONFIG", "SET", "loglevel", "notice", "LOGLEVEL", "debug")
+	})
+
+	t.Run("SET and GET loglevel", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "loglevel", "warning")
+		c.expect(t, "*2\r\n$8\r\nloglevel\r\n$7\r\nwarning\r\n", "CONFIG", "GET", "loglevel")
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "loglevel", "notice")
+	})
+
+	t.Run("REWRITE keeps comments and appends what is missing", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "maxclients", "50", "timeout", "300")
+		c.expect(t, "+OK\r\n", "CONFIG", "REWRITE")
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := "# Keep this comment\nmaxclients 50\n# Spaces too\nappendonly no\ncluster-config-file nodes-1.conf\n# Generated by CONFIG REWRITE\n"
+		if !strings.HasPrefix(string(content), expected) || !strings.HasSuffix(string(content), "\ntimeout 300\n") {
+			t.Errorf("config file = %q", content)
+		}
+
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "timeout", "0")
+		c.expect(t, "+OK\r\n", "CONFIG", "REWRITE")
+		content, _ = os.ReadFile(path)
+		if strings.Count(string(content), "# Generated by CONFIG REWRITE") != 1 || !strings.Contains(string(content), "timeout 0\n") {
+			t.Errorf("config file after a second rewrite = %q", content)
+		}
+	})
+
+	t.Run("RESETSTAT", func(t *testing.T) {
+		c.do("PING")
+		c.expect(t, "+OK\r\n", "CONFIG", "RESETSTAT")
+		if processed := c.infoField("stats", "total_commands_processed"); processed != "1" {
+			t.Errorf("total_commands_processed = %s; Expected only the INFO itself", processed)
+		}
+	})
+
+	t.Run("REWRITE without a config file", func(t *testing.T) {
+		other := startWithConfig(t, redisserverlib.NewConfig())
+		other.expect(t, "-ERR The server is running without a config file\r\n", "CONFIG", "REWRITE")
+	})
+}
+
+func TestConfigRewriteUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.conf")
+	writeFile(t, path, "# Users\nuser alice on >secret ~* +@all\nport 6380\nuser bob on nopass +get\n")
+	config, err := redisserverlib.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := startWithConfig(t, config)
+	c.expect(t, "+OK\r\n", "ACL", "SETUSER", "carol", "on", ">other", "+ping")
+	c.expect(t, "+OK\r\n", "CONFIG", "REWRITE")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256([]byte("secret"))
+	expected := "# Users\nuser alice on #" + hex.EncodeToString(hash[:]) + " ~* resetchannels +@all\n"
+	if !strings.HasPrefix(string(content), expected) || strings.Contains(string(content), "secret") || strings.Contains(string(content), "other") ||
+		!strings.Contains(string(content), "\nuser carol on #") || strings.Count(string(content), "user bob") != 1 || strings.Contains(string(content), "user default") {
+		t.Errorf("config file = %q", content)
+	}
+
+	config, err = redisserverlib.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := startWithConfig(t, config)
+	restarted.expect(t, "+OK\r\n", "AUTH", "alice", "secret")
+	restarted.expect(t, "+OK\r\n", "AUTH", "carol", "other")
+}
+
+func TestClientLimits(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()),
+		redisserverlib.WithMaxClients(2), redisserverlib.WithTimeout(time.Second))
+	t.Cleanup(func() { cp.Close() })
+
+	t.Run("maxclients", func(t *testing.T) {
+		a, b := newTestClient(cp), newTestClient(cp)
+		refused := newTestClient(cp)
+		if reply := <-refused.out; reply != "-ERR max number of clients reached\r\n" {
+			t.Errorf("reply = %q", reply)
+		}
+
+		if reply := refused.do("PING"); reply != "_\r\n" {
+			t.Errorf("PING from a refused client = %q", reply)
+		}
+
+		a.expect(t, "+PONG\r\n", "PING")
+		cp.DisconnectClient(b.ctx)
+		cp.DisconnectClient(refused.ctx)
+		c := newTestClient(cp)
+		c.expect(t, "+PONG\r\n", "PING")
+		cp.DisconnectClient(a.ctx)
+		cp.DisconnectClient(c.ctx)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		a := newTestClient(cp)
+		subscriber := newTestClient(cp)
+		subscriber.do("SUBSCRIBE", "ch")
+
+		select {
+		case <-a.ctx.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("The idle client was not disconnected")
+		}
+
+		if subscriber.ctx.Err() != nil {
+			t.Error("The subscribed client was disconnected")
+		}
+	})
+}
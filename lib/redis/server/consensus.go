@@ -0,0 +1,437 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/raft"
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	redisrdblib "github.com/codecrafters-io/redis-starter-go/lib/redis/rdb"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// consensus commits write commands to a Raft log before they run, so that every member
+	// applies them in the same order and an acknowledged write survives the loss of a minority.
+	// Only the leader serves commands that access keys: reads wait for a read index, which
+	// makes them linearizable, and the other members redirect clients to the leader.
+	consensus struct {
+		r         *redisCommandProcessor
+		id        string
+		members   []string
+		options   processorOptions
+		node      raft.Node
+		storage   *raft.FileStorage
+		transport *raftTransport
+		// Carries the pseudo client applying committed entries
+		applyCtx context.Context
+
+		mu sync.Mutex
+		// Closed and replaced whenever an entry is applied, to retry blocking commands
+		changed chan struct{}
+	}
+
+	// raftEntry is the data of a log entry: commands to run in a database at a given time.
+	raftEntry struct {
+		Db       int        `json:"db"`
+		Time     int64      `json:"time"`
+		Commands [][]string `json:"commands"`
+	}
+
+	// raftTransport sends messages to the other members with RAFT MESSAGE. Each member has a
+	// queue drained by its own connection, and messages are dropped while it is full.
+	raftTransport struct {
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+		// Sent first on every connection, unless nil
+		auth []string
+
+		mu     sync.Mutex
+		queues map[string]chan raft.Message
+	}
+)
+
+const (
+	raftDirname = "raft"
+	// How long a command waits for its entry to commit, or for a read index
+	raftRequestTimeout = 5 * time.Second
+	raftDialTimeout    = time.Second
+	raftQueueSize      = 256
+)
+
+var errRaftDisabled = errors.New("ERR This instance has Raft disabled")
+
+func newConsensus(r *redisCommandProcessor, options processorOptions) *consensus {
+	applyClient := newClient()
+	applyClient.internal = true
+	return &consensus{
+		r:        r,
+		id:       cmp.Or(options.raftAddr, net.JoinHostPort("127.0.0.1", strconv.Itoa(options.port))),
+		members:  options.raftMembers,
+		options:  options,
+		applyCtx: contextWithClient(context.Background(), applyClient),
+		changed:  make(chan struct{}),
+	}
+}
+
+// start opens the log in the raft directory and joins the cluster. The dataset is rebuilt
+// from the latest snapshot and the entries following it.
+func (cs *consensus) start() error {
+	storage, err := raft.NewFileStorage(filepath.Join(cs.options.dir, raftDirname))
+	if err != nil {
+		return err
+	}
+
+	cs.storage, cs.transport = storage, newRaftTransport(authCommand(cs.options))
+	cs.node, err = raft.NewNode(raft.Config{
+		ID:              cs.id,
+		Members:         cs.members,
+		StateMachine:    cs,
+		Transport:       cs.transport,
+		Storage:         storage,
+		TickInterval:    cs.options.raftTickInterval,
+		SnapshotEntries: uint64(max(0, cs.options.raftSnapshotEntries)),
+	})
+	if err != nil {
+		cs.transport.close()
+		storage.Close()
+		cs.storage, cs.transport = nil, nil
+		return err
+	}
+
+	return nil
+}
+
+func (cs *consensus) close() {
+	if cs.node == nil {
+		return
+	}
+
+	cs.node.Stop()
+	cs.transport.close()
+	cs.storage.Close()
+}
+
+// Apply runs the commands of a committed entry on the pseudo client. Their effects are
+// propagated like those of any client, so the AOF and replicas follow the log.
+func (cs *consensus) Apply(data []byte) any {
+	var entry raftEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Corrupt Raft entry: %w", err)}
+	}
+
+	c := clientFromContext(cs.applyCtx)
+	c.db, c.now, c.multi = entry.Db, time.UnixMilli(entry.Time), nil
+	// Blocking commands never wait while the log is applied, the leader retries them instead
+	ctx, cancel := context.WithDeadline(cs.applyCtx, time.Now())
+	defer cancel()
+
+	var result commandResult
+	for _, args := range entry.Commands {
+		result = cs.r.dispatch(ctx, resptypes.ToBulkStringArray(args))
+	}
+
+	cs.mu.Lock()
+	close(cs.changed)
+	cs.changed = make(chan struct{})
+	cs.mu.Unlock()
+	return result
+}
+
+func (cs *consensus) Snapshot() ([]byte, error) {
+	cs.r.txMu.Lock()
+	dbs := cs.r.persistence.snapshot()
+	cs.r.txMu.Unlock()
+
+	var buf bytes.Buffer
+	if err := redisrdblib.Write(&buf, dbs); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (cs *consensus) Restore(data []byte) error {
+	r := cs.r
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	for i := range r.dbs.Len() {
+		r.dbs.DB(i).Clear(false)
+	}
+
+	r.tracking.invalidateAll(nil)
+	r.persistence.dirty.Add(1)
+	return r.persistence.loadFrom(bytes.NewReader(data), "Raft snapshot")
+}
+
+// dispatch commits the request to the log when it writes, and waits for a read index when
+// it reads keys. It returns false when the request is to run locally as usual.
+func (cs *consensus) dispatch(ctx context.Context, c *client, commandName string, entry commandDefinition, params commandParams) (commandResult, bool) {
+	flags := flagsOf(entry)
+	switch {
+	case commandName == "WATCH" || commandName == "REPLICAOF":
+		// Keys may change between WATCH and the entry committing EXEC, and the log is the only primary
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %s is not supported in Raft mode", commandName)}, true
+	case c.multi != nil && flags&flagTransaction == 0:
+		// Queued until EXEC
+		return nil, false
+	case commandName == "EXEC":
+		if c.multi == nil || c.multi.aborted {
+			return nil, false
+		}
+
+		if !slices.ContainsFunc(c.multi.queued, cs.writes) {
+			return cs.readOrRun(ctx, c)
+		}
+
+		commands := [][]string{{"MULTI"}}
+		for _, queued := range c.multi.queued {
+			commands = append(commands, argsOf(queued))
+		}
+
+		c.multi = nil
+		return cs.propose(ctx, c, append(commands, []string{"EXEC"})), true
+	case flags&flagWrite != 0 && flags&flagBlocking != 0:
+		return cs.proposeBlocking(ctx, c, params), true
+	case flags&flagWrite != 0:
+		return cs.propose(ctx, c, [][]string{argsOf(params)}), true
+	case len(keysOf(entry, params)) > 0:
+		return cs.readOrRun(ctx, c)
+	default:
+		return nil, false
+	}
+}
+
+// writes reports whether a queued request may modify the dataset.
+func (cs *consensus) writes(params commandParams) bool {
+	entry, exists := cs.r.commands[strings.ToUpper(params[0].Val)]
+	return exists && flagsOf(entry)&flagWrite != 0
+}
+
+// readOrRun waits until the local dataset reflects every write committed so far, so that the
+// request may run locally, or fails the request when it cannot.
+func (cs *consensus) readOrRun(ctx context.Context, c *client) (commandResult, bool) {
+	ctx, cancel := context.WithTimeout(ctx, raftRequestTimeout)
+	defer cancel()
+	if err := cs.node.ReadIndex(ctx); err != nil {
+		c.multi = nil
+		return cs.errorReply(err), true
+	}
+
+	return nil, false
+}
+
+// propose commits commands to the log and returns the result of the last one.
+func (cs *consensus) propose(ctx context.Context, c *client, commands [][]string) commandResult {
+	data, err := json.Marshal(raftEntry{Db: c.db, Time: time.Now().UnixMilli(), Commands: commands})
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, raftRequestTimeout)
+	defer cancel()
+	result, err := cs.node.Propose(ctx, data)
+	if err != nil {
+		return cs.errorReply(err)
+	}
+
+	return result.(commandResult)
+}
+
+// proposeBlocking commits a blocking command, and commits it again whenever an entry was
+// applied while it found nothing to serve, until its timeout elapses.
+func (cs *consensus) proposeBlocking(ctx context.Context, c *client, params commandParams) commandResult {
+	waitCtx := ctx
+	if seconds, err := strconv.ParseFloat(params[len(params)-1].Val, 64); err == nil && seconds > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, time.Duration(seconds*float64(time.Second)))
+		defer cancel()
+	}
+
+	args := argsOf(params)
+	for {
+		cs.mu.Lock()
+		changed := cs.changed
+		cs.mu.Unlock()
+
+		result := cs.propose(ctx, c, [][]string{args})
+		if arr, ok := result.(resptypes.Array[resptypes.RespSerializable]); !ok || arr != nil {
+			return result
+		}
+
+		select {
+		case <-changed:
+		case <-waitCtx.Done():
+			return resptypes.NullArray
+		}
+	}
+}
+
+func (cs *consensus) errorReply(err error) commandResult {
+	switch {
+	case errors.Is(err, raft.ErrNotLeader):
+		if leader := cs.node.Status().Leader; leader != "" {
+			return resptypes.SimpleError{Val: fmt.Errorf("REDIRECT %s", leader)}
+		}
+
+		return resptypes.SimpleError{Val: errors.New("NOLEADER No Raft leader is elected")}
+	case errors.Is(err, context.DeadlineExceeded):
+		return resptypes.SimpleError{Val: errors.New("TIMEOUT The Raft log did not commit in time")}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+	}
+}
+
+func (cs *consensus) info() []string {
+	if cs == nil || cs.node == nil {
+		return []string{"raft_enabled:0"}
+	}
+
+	status := cs.node.Status()
+	return []string{
+		"raft_enabled:1",
+		"raft_node_id:" + status.ID,
+		"raft_role:" + status.Role,
+		fmt.Sprintf("raft_term:%d", status.Term),
+		"raft_leader:" + status.Leader,
+		fmt.Sprintf("raft_members:%d", len(cs.members)),
+		fmt.Sprintf("raft_commit_index:%d", status.Commit),
+		fmt.Sprintf("raft_applied_index:%d", status.Applied),
+		fmt.Sprintf("raft_last_index:%d", status.LastIndex),
+		fmt.Sprintf("raft_snapshot_index:%d", status.SnapshotIndex),
+	}
+}
+
+// argsOf returns the arguments of a request as strings.
+func argsOf(params commandParams) []string {
+	args := make([]string, len(params))
+	for i, param := range params {
+		args[i] = param.Val
+	}
+
+	return args
+}
+
+func newRaftTransport(auth []string) *raftTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &raftTransport{ctx: ctx, cancel: cancel, auth: auth, queues: make(map[string]chan raft.Message)}
+}
+
+func (t *raftTransport) Send(msg raft.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ctx.Err() != nil {
+		return
+	}
+
+	queue, exists := t.queues[msg.To]
+	if !exists {
+		queue = make(chan raft.Message, raftQueueSize)
+		t.queues[msg.To] = queue
+		t.wg.Go(func() { t.run(msg.To, queue) })
+	}
+
+	select {
+	case queue <- msg:
+	default:
+	}
+}
+
+// run delivers the queued messages to addr, pipelining whatever is pending at once.
+func (t *raftTransport) run(addr string, queue chan raft.Message) {
+	var conn net.Conn
+	var reader *bufio.Reader
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		var batch []raft.Message
+		select {
+		case <-t.ctx.Done():
+			return
+		case msg := <-queue:
+			batch = append(batch, msg)
+		}
+
+	drain:
+		for len(batch) < raftQueueSize {
+			select {
+			case msg := <-queue:
+				batch = append(batch, msg)
+			default:
+				break drain
+			}
+		}
+
+		var buf []byte
+		count := len(batch)
+		if conn == nil {
+			c, err := (&net.Dialer{Timeout: raftDialTimeout}).DialContext(t.ctx, "tcp", addr)
+			if err != nil {
+				slog.Debug("Raft member unreachable", "addr", addr, "error", err)
+				continue
+			}
+
+			conn, reader = c, bufio.NewReader(c)
+			if t.auth != nil {
+				buf = redislib.AppendCommand(buf, t.auth...)
+				count++
+			}
+		}
+
+		for _, msg := range batch {
+			payload, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+
+			buf = redislib.AppendCommand(buf, "RAFT", "MESSAGE", string(payload))
+		}
+
+		if err := t.exchange(conn, reader, buf, count); err != nil {
+			slog.Debug("Lost the connection to a Raft member", "addr", addr, "error", err)
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// exchange writes requests and reads their count replies.
+func (t *raftTransport) exchange(conn net.Conn, reader *bufio.Reader, requests []byte, count int) error {
+	conn.SetDeadline(time.Now().Add(raftDialTimeout))
+	if _, err := conn.Write(requests); err != nil {
+		return err
+	}
+
+	for range count {
+		if _, err := redislib.ReadReply(reader); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *raftTransport) close() {
+	t.mu.Lock()
+	t.cancel()
+	t.mu.Unlock()
+	t.wg.Wait()
+}
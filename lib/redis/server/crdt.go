@@ -0,0 +1,300 @@
+package redisserverlib
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	crdtKind int
+
+	// crdtValue is the replicated state of a key in active-active mode. It holds one CRDT per
+	// kind of value, and the key takes the kind written last among those that are not empty.
+	//
+	// Operations are shipped as partial values merged with join, which is commutative,
+	// associative and idempotent, so instances converge whatever order they receive them in.
+	// Removals are observed-remove: they only remove what the removing instance had seen,
+	// and concurrent additions survive.
+	crdtValue struct {
+		// Strings are last-writer-wins registers
+		Register crdtRegister `json:"reg"`
+		// Counters are PN-counters with one share per instance
+		Counter   map[string]crdtShare `json:"counter,omitempty"`
+		CounterTs uint64               `json:"counterTs,omitempty"`
+		// Lists are sequences of uniquely identified elements, by dot
+		List   map[string]crdtElement `json:"list,omitempty"`
+		ListTs uint64                 `json:"listTs,omitempty"`
+		// Streams are the union of the entries added anywhere, by entry ID and origin
+		Stream   map[string]crdtEntry `json:"stream,omitempty"`
+		StreamTs uint64               `json:"streamTs,omitempty"`
+		// The list elements and stream entries removed so far. They are kept so that an
+		// addition received after its removal stays removed.
+		Removed map[string]bool `json:"removed,omitempty"`
+	}
+
+	// crdtRegister is written by the write with the highest timestamp, ties broken by origin.
+	crdtRegister struct {
+		Ts        uint64 `json:"ts,omitempty"`
+		Origin    string `json:"origin,omitempty"`
+		Value     string `json:"value,omitempty"`
+		ExpiresAt int64  `json:"expiresAt,omitempty"`
+		Deleted   bool   `json:"deleted,omitempty"`
+	}
+
+	// crdtShare is what one instance added to a counter. Each field only grows: the Base
+	// fields are what removals observed, so the share counts for (P - BaseP) - (N - BaseN).
+	crdtShare struct {
+		P     int64 `json:"p,omitempty"`
+		N     int64 `json:"n,omitempty"`
+		BaseP int64 `json:"baseP,omitempty"`
+		BaseN int64 `json:"baseN,omitempty"`
+	}
+
+	// crdtElement is ordered by position, ties between concurrent pushes broken by dot.
+	crdtElement struct {
+		Pos    int64  `json:"pos"`
+		Origin string `json:"origin"`
+		Seq    uint64 `json:"seq"`
+		Value  string `json:"value"`
+	}
+
+	// crdtEntry is a stream entry. Instances adding the same ID concurrently keep the entry
+	// of the greatest origin.
+	crdtEntry struct {
+		Id     redistypes.StreamEntryId `json:"id"`
+		Origin string                   `json:"origin"`
+		Fields []string                 `json:"fields"`
+	}
+)
+
+const (
+	crdtNone crdtKind = iota
+	crdtRegisterKind
+	crdtCounterKind
+	crdtListKind
+	crdtStreamKind
+)
+
+func elementDot(origin string, seq uint64) string {
+	return origin + "/" + strconv.FormatUint(seq, 10)
+}
+
+func entryDot(id redistypes.StreamEntryId, origin string) string {
+	return fmt.Sprintf("%d-%d/%s", id.Ms, id.Seq, origin)
+}
+
+func compareElements(a crdtElement, b crdtElement) int {
+	return cmp.Or(cmp.Compare(a.Pos, b.Pos), cmp.Compare(a.Origin, b.Origin), cmp.Compare(a.Seq, b.Seq))
+}
+
+func compareEntryIds(a redistypes.StreamEntryId, b redistypes.StreamEntryId) int {
+	return cmp.Or(cmp.Compare(a.Ms, b.Ms), cmp.Compare(a.Seq, b.Seq))
+}
+
+// newer reports whether r wins over other.
+func (r crdtRegister) newer(other crdtRegister) bool {
+	return cmp.Or(cmp.Compare(r.Ts, other.Ts), cmp.Compare(r.Origin, other.Origin)) > 0
+}
+
+func (s crdtShare) value() int64 {
+	return max(0, s.P-s.BaseP) - max(0, s.N-s.BaseN)
+}
+
+func (s crdtShare) empty() bool {
+	return s.P <= s.BaseP && s.N <= s.BaseN
+}
+
+// join merges other into v.
+func (v *crdtValue) join(other *crdtValue) {
+	if other.Register.newer(v.Register) {
+		v.Register = other.Register
+	}
+
+	for origin, share := range other.Counter {
+		if v.Counter == nil {
+			v.Counter = make(map[string]crdtShare)
+		}
+
+		current := v.Counter[origin]
+		v.Counter[origin] = crdtShare{
+			P:     max(current.P, share.P),
+			N:     max(current.N, share.N),
+			BaseP: max(current.BaseP, share.BaseP),
+			BaseN: max(current.BaseN, share.BaseN),
+		}
+	}
+
+	for dot := range other.Removed {
+		if v.Removed == nil {
+			v.Removed = make(map[string]bool)
+		}
+
+		v.Removed[dot] = true
+		delete(v.List, dot)
+		delete(v.Stream, dot)
+	}
+
+	for dot, element := range other.List {
+		if !v.Removed[dot] {
+			if v.List == nil {
+				v.List = make(map[string]crdtElement)
+			}
+
+			v.List[dot] = element
+		}
+	}
+
+	for dot, entry := range other.Stream {
+		if !v.Removed[dot] {
+			if v.Stream == nil {
+				v.Stream = make(map[string]crdtEntry)
+			}
+
+			v.Stream[dot] = entry
+		}
+	}
+
+	v.CounterTs = max(v.CounterTs, other.CounterTs)
+	v.ListTs = max(v.ListTs, other.ListTs)
+	v.StreamTs = max(v.StreamTs, other.StreamTs)
+}
+
+// maxTs returns the latest timestamp of the writes v holds.
+func (v *crdtValue) maxTs() uint64 {
+	return max(v.Register.Ts, v.CounterTs, v.ListTs, v.StreamTs)
+}
+
+// kind returns the kind of value the key holds at now.
+func (v *crdtValue) kind(now time.Time) crdtKind {
+	kind, latest := crdtNone, uint64(0)
+	candidate := func(k crdtKind, ts uint64, present bool) {
+		if present && (kind == crdtNone || ts >= latest) {
+			kind, latest = k, ts
+		}
+	}
+
+	reg := v.Register
+	candidate(crdtRegisterKind, reg.Ts, reg.Ts > 0 && !reg.Deleted && (reg.ExpiresAt == 0 || reg.ExpiresAt > now.UnixMilli()))
+	candidate(crdtCounterKind, v.CounterTs, v.hasCounter())
+	candidate(crdtListKind, v.ListTs, len(v.List) > 0)
+	candidate(crdtStreamKind, v.StreamTs, len(v.Stream) > 0)
+	return kind
+}
+
+func (v *crdtValue) hasCounter() bool {
+	for _, share := range v.Counter {
+		if !share.empty() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *crdtValue) counterValue() int64 {
+	total := int64(0)
+	for _, share := range v.Counter {
+		total += share.value()
+	}
+
+	return total
+}
+
+// elements returns the list elements in order.
+func (v *crdtValue) elements() []crdtElement {
+	return slices.SortedFunc(maps.Values(v.List), compareElements)
+}
+
+// entries returns the visible stream entries in ID order.
+func (v *crdtValue) entries() []crdtEntry {
+	byId := make(map[redistypes.StreamEntryId]crdtEntry)
+	for _, entry := range v.Stream {
+		if current, exists := byId[entry.Id]; !exists || entry.Origin > current.Origin {
+			byId[entry.Id] = entry
+		}
+	}
+
+	return slices.SortedFunc(maps.Values(byId), func(a crdtEntry, b crdtEntry) int { return compareEntryIds(a.Id, b.Id) })
+}
+
+// removal returns the partial value removing everything v holds as of ts, written by origin.
+// The value of kind keep is left alone.
+func (v *crdtValue) removal(ts uint64, origin string, keep crdtKind) *crdtValue {
+	removal := &crdtValue{}
+	if keep != crdtRegisterKind && v.Register.Ts > 0 && !v.Register.Deleted {
+		removal.Register = crdtRegister{Ts: ts, Origin: origin, Deleted: true}
+	}
+
+	if keep != crdtCounterKind {
+		for origin, share := range v.Counter {
+			if !share.empty() {
+				if removal.Counter == nil {
+					removal.Counter = make(map[string]crdtShare)
+				}
+
+				removal.Counter[origin] = crdtShare{P: share.P, N: share.N, BaseP: share.P, BaseN: share.N}
+			}
+		}
+	}
+
+	if keep != crdtListKind {
+		for dot := range v.List {
+			removal.addRemoved(dot)
+		}
+	}
+
+	if keep != crdtStreamKind {
+		for dot := range v.Stream {
+			removal.addRemoved(dot)
+		}
+	}
+
+	return removal
+}
+
+func (v *crdtValue) addRemoved(dot string) {
+	if v.Removed == nil {
+		v.Removed = make(map[string]bool)
+	}
+
+	v.Removed[dot] = true
+}
+
+// storeValue returns the value the key holds in the datastore and its time to live,
+// or false when the key does not exist.
+func (v *crdtValue) storeValue(now time.Time) (redistypes.StoreValue, time.Duration, bool) {
+	switch v.kind(now) {
+	case crdtRegisterKind:
+		ttl := time.Duration(0)
+		if v.Register.ExpiresAt != 0 {
+			ttl = time.UnixMilli(v.Register.ExpiresAt).Sub(now)
+		}
+
+		return redistypes.NewString(v.Register.Value)(), ttl, true
+	case crdtCounterKind:
+		return redistypes.NewString(strconv.FormatInt(v.counterValue(), 10))(), 0, true
+	case crdtListKind:
+		list := redistypes.NewList()
+		for _, element := range v.elements() {
+			list.List.PushBack(*resptypes.NewBulkString(element.Value))
+		}
+
+		return list, 0, true
+	case crdtStreamKind:
+		stream := redistypes.NewStream()
+		for _, entry := range v.entries() {
+			stream.Stream.AddEntry(redistypes.AddStreamEntryId{StreamEntryId: entry.Id}, resptypes.ToBulkStringArray(entry.Fields))
+		}
+
+		return stream, 0, true
+	default:
+		return redistypes.StoreValue{}, 0, false
+	}
+}
@@ -0,0 +1,103 @@
+package redisserverlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	crdtCmd struct {
+		*activeActive
+	}
+)
+
+func (c crdtCmd) moniker() string {
+	return "CRDT"
+}
+
+func (c crdtCmd) spec() commandSpec {
+	return commandSpec{
+		arity: -2,
+		// Merges change keys the way write commands do, without being propagated as they are
+		flags:   flagExclusive,
+		group:   "server",
+		summary: "A container for active-active replication commands.",
+		subcommands: []commandSpec{
+			{name: "info", arity: 2, summary: "Returns the active-active state of the instance."},
+			{
+				name: "hello", arity: 3, summary: "Returns the last batch applied from a peer.",
+				args: []commandArg{{name: "origin", typ: argString}},
+			},
+			{
+				name: "apply", arity: 4, flags: flagAdmin, summary: "Merges the next batch of writes of a peer.",
+				args: []commandArg{{name: "origin", typ: argString}, {name: "batch", typ: argString}},
+			},
+			{
+				name: "sync", arity: 5, flags: flagAdmin, summary: "Merges the whole state of a peer.",
+				args: []commandArg{{name: "origin", typ: argString}, {name: "seq", typ: argInteger}, {name: "state", typ: argString}},
+			},
+		},
+	}
+}
+
+func (c crdtCmd) getUsage() string {
+	return `
+usage:
+	CRDT INFO
+	CRDT HELLO origin
+	CRDT APPLY origin batch
+	CRDT SYNC origin seq state
+summary:
+	Inspects the active-active state of the instance, or exchanges writes with a peer.
+	HELLO returns the last batch applied from the peer with the given ID, APPLY merges the next batch of the peer
+	and SYNC merges its whole state. They are sent by the peers to each other and are not meant for clients.
+`
+}
+
+func (c crdtCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR CRDT requires a subcommand! %s", c.getUsage())}
+	}
+
+	if c.activeActive == nil {
+		return resptypes.SimpleError{Val: errActiveActiveDisabled}
+	}
+
+	switch subcommand := strings.ToUpper(params[1].Val); {
+	case subcommand == "INFO" && len(params) == 2:
+		return resptypes.NewBulkString(strings.Join(c.info(), "\r\n") + "\r\n")
+	case subcommand == "HELLO" && len(params) == 3:
+		return resptypes.Integer{Val: int64(c.hello(params[2].Val))}
+	case subcommand == "APPLY" && len(params) == 4:
+		var batch crdtBatch
+		if err := json.Unmarshal([]byte(params[3].Val), &batch); err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid CRDT batch: %w", err)}
+		}
+
+		if err := c.apply(params[2].Val, batch); err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "SYNC" && len(params) == 5:
+		seq, err := strconv.ParseUint(params[3].Val, 10, 64)
+		if err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid CRDT sequence number '%s'", params[3].Val)}
+		}
+
+		var deltas []crdtDelta
+		if err := json.Unmarshal([]byte(params[4].Val), &deltas); err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid CRDT state: %w", err)}
+		}
+
+		c.sync(params[2].Val, seq, deltas)
+		return resptypes.SimpleString{Val: "OK"}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown CRDT subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
@@ -0,0 +1,75 @@
+package redisserverlib_test
+
+import (
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestDatabases(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithDatabases(4))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("Invalid indexes", func(t *testing.T) {
+		for _, index := range []string{"4", "-1", "100"} {
+			c.expect(t, "-ERR DB index is out of range\r\n", "SELECT", index)
+			c.expect(t, "-ERR DB index is out of range\r\n", "MOVE", "k", index)
+			c.expect(t, "-ERR DB index is out of range\r\n", "SWAPDB", "0", index)
+		}
+
+		for _, index := range []string{"one", "1.5", ""} {
+			c.expect(t, "-ERR value is not an integer or out of range\r\n", "SELECT", index)
+			c.expect(t, "-ERR value is not an integer or out of range\r\n", "MOVE", "k", index)
+			c.expect(t, "-ERR value is not an integer or out of range\r\n", "SWAPDB", index, "0")
+		}
+
+		c.expect(t, "-ERR source and destination objects are the same\r\n", "MOVE", "k", "0")
+	})
+
+	t.Run("MOVE", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SET", "k", "v", "PX", "200")
+		c.expect(t, ":1\r\n", "MOVE", "k", "1")
+		c.expect(t, "$-1\r\n", "GET", "k")
+		c.expect(t, ":0\r\n", "MOVE", "k", "1")
+
+		c.expect(t, "+OK\r\n", "SELECT", "1")
+		c.expect(t, "$1\r\nv\r\n", "GET", "k")
+		waitFor(t, "the moved key to expire", func() bool { return c.do("GET", "k") == "$-1\r\n" })
+
+		c.expect(t, "+OK\r\n", "SET", "taken", "dst")
+		c.expect(t, "+OK\r\n", "SELECT", "0")
+		c.expect(t, "+OK\r\n", "SET", "taken", "src")
+		c.expect(t, ":0\r\n", "MOVE", "taken", "1")
+		c.expect(t, "$3\r\nsrc\r\n", "GET", "taken")
+		c.expect(t, "+OK\r\n", "SELECT", "1")
+		c.expect(t, "$3\r\ndst\r\n", "GET", "taken")
+		c.expect(t, "+OK\r\n", "SELECT", "0")
+	})
+
+	t.Run("SWAPDB is seen by clients with the database selected", func(t *testing.T) {
+		other := newTestClient(cp)
+		other.expect(t, "+OK\r\n", "SELECT", "3")
+		other.expect(t, "$-1\r\n", "GET", "swapped")
+
+		c.expect(t, "+OK\r\n", "SELECT", "2")
+		c.expect(t, "+OK\r\n", "SET", "swapped", "v")
+		c.expect(t, "+OK\r\n", "SWAPDB", "2", "3")
+		other.expect(t, "$1\r\nv\r\n", "GET", "swapped")
+		c.expect(t, "$-1\r\n", "GET", "swapped")
+		c.expect(t, "+OK\r\n", "SELECT", "0")
+	})
+
+	t.Run("FLUSHDB ASYNC", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SELECT", "2")
+		c.expect(t, "+OK\r\n", "SET", "a", "1")
+		c.expect(t, "+OK\r\n", "SET", "b", "2")
+		c.expect(t, ":2\r\n", "DBSIZE")
+		c.expect(t, "+OK\r\n", "FLUSHDB", "ASYNC")
+		c.expect(t, ":0\r\n", "DBSIZE")
+		c.expect(t, "$-1\r\n", "GET", "a")
+		c.expect(t, "-ERR syntax error\r\n", "FLUSHDB", "LATER")
+		c.expect(t, "+OK\r\n", "SELECT", "0")
+		c.expect(t, ":1\r\n", "DBSIZE")
+	})
+}
@@ -0,0 +1,47 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	dbsize struct {
+		keyspace
+	}
+)
+
+func (c dbsize) moniker() string {
+	return "DBSIZE"
+}
+
+func (c dbsize) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagReadonly | flagFast,
+		categories: []string{"keyspace"},
+		group:      "server",
+		summary:    "Returns the number of keys in the database.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c dbsize) getUsage() string {
+	return `
+usage:
+	DBSIZE
+summary:
+	Return the number of keys in the currently-selected database.
+`
+}
+
+func (c dbsize) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR DBSIZE takes no arguments! %s", c.getUsage())}
+	}
+
+	return resptypes.Integer{Val: int64(c.db(ctx).Len())}
+}
@@ -0,0 +1,58 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	decrby struct {
+		keyspace
+	}
+)
+
+func (c decrby) moniker() string {
+	return "DECRBY"
+}
+
+func (c decrby) spec() commandSpec {
+	return commandSpec{
+		arity:      3,
+		flags:      flagWrite | flagFast,
+		categories: []string{"string"},
+		keySpecs:   firstKey(keyRW),
+		group:      "string",
+		summary:    "Decrements a number from the integer value of a key. Uses 0 as initial value if the key doesn't exist.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "decrement", typ: argInteger}},
+	}
+}
+
+func (c decrby) getUsage() string {
+	return `
+usage:
+	decrby key decrement
+summary:
+	Decrements the number stored at key by decrement. If the key does not exist, it is set to 0 before performing the operation.
+	An error is returned if the key contains a value of the wrong type or a string that can not be represented as a 64 bit signed integer.
+	The time to live of the key is kept.
+`
+}
+
+func (c decrby) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR DECRBY requires a key and a decrement! %s", c.getUsage())}
+	}
+
+	delta, err := strconv.ParseInt(params[2].Val, 10, 64)
+	if err != nil || delta == math.MinInt64 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	return c.incrementBy(ctx, params[1].Val, -delta)
+}
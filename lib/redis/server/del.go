@@ -0,0 +1,66 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	del struct {
+		keyspace
+	}
+)
+
+func (c del) moniker() string {
+	return "DEL"
+}
+
+func (c del) spec() commandSpec {
+	return commandSpec{
+		arity:      -2,
+		flags:      flagWrite,
+		categories: []string{"keyspace"},
+		keySpecs:   []keySpec{{flags: keyRM, index: 1, lastKey: -1, keyStep: 1}},
+		group:      "generic",
+		summary:    "Deletes one or more keys.",
+		since:      "1.0.0",
+		complexity: "O(N) where N is the number of keys that will be removed.",
+		args:       []commandArg{{name: "key", typ: argKey, multiple: true}},
+	}
+}
+
+func (c del) getUsage() string {
+	return `
+usage:
+	DEL key [key ...]
+summary:
+	Removes the specified keys. A key is ignored if it does not exist.
+	Returns the number of keys that were removed.
+`
+}
+
+func (c del) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR DEL requires at least one key! %s", c.getUsage())}
+	}
+
+	removed := int64(0)
+	for _, param := range params[1:] {
+		if _, exists := c.db(ctx).Get(param.Val); !exists {
+			continue
+		}
+
+		c.db(ctx).Delete(param.Val)
+		c.modified(ctx, param.Val)
+		c.notify(ctx, notifyGeneric, "del", param.Val)
+		removed++
+	}
+
+	if removed == 0 {
+		propagateAs(ctx)
+	}
+
+	return resptypes.Integer{Val: removed}
+}
@@ -0,0 +1,49 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	discard struct{}
+)
+
+func (c discard) moniker() string {
+	return "DISCARD"
+}
+
+func (c discard) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagTransaction | flagFast,
+		categories: []string{"transaction"},
+		group:      "transactions",
+		summary:    "Discards a transaction.",
+		since:      "2.0.0",
+		complexity: "O(N), when N is the number of queued commands",
+	}
+}
+
+func (c discard) getUsage() string {
+	return `
+usage:
+	DISCARD
+summary:
+	Flushes all previously queued commands in a transaction and restores the connection state to normal.
+	If WATCH was used, DISCARD unwatches all keys watched by the connection.
+`
+}
+
+func (c discard) execute(ctx context.Context, params commandParams) commandResult {
+	client := clientFromContext(ctx)
+	if client.multi == nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR DISCARD without MULTI")}
+	}
+
+	client.multi = nil
+	client.unwatchAll()
+	return resptypes.SimpleString{Val: "OK"}
+}
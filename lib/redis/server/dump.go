@@ -0,0 +1,65 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	redisrdblib "github.com/codecrafters-io/redis-starter-go/lib/redis/rdb"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	dump struct {
+		keyspace
+	}
+)
+
+func (c dump) moniker() string {
+	return "DUMP"
+}
+
+func (c dump) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagReadonly,
+		categories: []string{"keyspace"},
+		keySpecs:   firstKey(keyRO),
+		group:      "generic",
+		summary:    "Returns a serialized representation of the value stored at a key.",
+		since:      "2.6.0",
+		complexity: "O(1) to access the key and additional O(N*M) to serialize it, where N is the number of Redis objects composing the value and M their average size.",
+		args:       []commandArg{{name: "key", typ: argKey}},
+	}
+}
+
+func (c dump) getUsage() string {
+	return `
+usage:
+	DUMP key
+summary:
+	Serialize the value stored at key in a Redis-specific format and return it to the user.
+	The returned value can be synthesized back into a Redis key using the RESTORE command.
+	Returns nil if key does not exist.
+`
+}
+
+func (c dump) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR DUMP requires exactly one argument! %s", c.getUsage())}
+	}
+
+	key := params[1].Val
+	c.read(ctx, key)
+	dsVal, exists := c.db(ctx).Get(key)
+	if !exists {
+		c.missed(ctx, key)
+		return resptypes.BulkString{Length: -1}
+	}
+
+	payload, err := redisrdblib.Dump(dsVal)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+	}
+
+	return resptypes.NewBulkString(string(payload))
+}
@@ -15,6 +15,19 @@ func (c echo) moniker() string {
 	return "ECHO"
 }
 
+func (c echo) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagFast,
+		categories: []string{"connection"},
+		group:      "connection",
+		summary:    "Returns the given string.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "message", typ: argString}},
+	}
+}
+
 func (c echo) getUsage() string {
 	return `
 usage:
@@ -0,0 +1,92 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	exec struct {
+		*redisCommandProcessor
+	}
+)
+
+func (c exec) moniker() string {
+	return "EXEC"
+}
+
+func (c exec) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagTransaction,
+		categories: []string{"transaction"},
+		group:      "transactions",
+		summary:    "Executes all commands in a transaction.",
+		since:      "1.2.0",
+		complexity: "Depends on commands in the transaction",
+	}
+}
+
+func (c exec) getUsage() string {
+	return `
+usage:
+	EXEC
+summary:
+	Executes all previously queued commands in a transaction and restores the connection state to normal.
+	When using WATCH, EXEC will execute commands only if the watched keys were not modified, allowing for a check-and-set mechanism.
+	Returns an array with the reply of each command, or a null reply if the transaction was aborted because of WATCH.
+`
+}
+
+func (c exec) execute(ctx context.Context, params commandParams) commandResult {
+	client := clientFromContext(ctx)
+	if client.multi == nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR EXEC without MULTI")}
+	}
+
+	tx := client.multi
+	client.multi = nil
+	defer client.unwatchAll()
+
+	if tx.aborted {
+		return resptypes.SimpleError{Val: fmt.Errorf("EXECABORT Transaction discarded because of previous errors.")}
+	}
+
+	c.txMu.Lock()
+	defer c.txMu.Unlock()
+
+	if client.watchedKeysModified() {
+		return resptypes.NullArray
+	}
+
+	// Blocking commands must not wait while the whole server is locked
+	nonBlockingCtx, cancel := context.WithDeadline(ctx, time.Now())
+	defer cancel()
+
+	results := make(resptypes.Array[resptypes.RespSerializable], len(tx.queued))
+	for i, queued := range tx.queued {
+		entry := c.commands[strings.ToUpper(queued[0].Val)]
+		if !client.internal {
+			// The rules of the user may have changed since the command was queued
+			if err := c.acl.check(client, entry, queued, "multi"); err != nil {
+				results[i] = resptypes.SimpleError{Val: err}
+				continue
+			}
+		}
+
+		cmdCtx := ctx
+		if flagsOf(entry)&flagBlocking != 0 {
+			cmdCtx = nonBlockingCtx
+		}
+
+		results[i] = c.call(cmdCtx, entry, queued)
+	}
+
+	c.propagate(client, wrapInTransaction(client.pending))
+
+	return results
+}
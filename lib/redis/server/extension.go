@@ -0,0 +1,201 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// Command is a command added to a processor with WithCommand.
+	Command interface {
+		// Name is the name clients call the command by, in any case.
+		Name() string
+		Spec() CommandSpec
+		// Usage is shown by HELP.
+		Usage() string
+		// Execute runs a call of the command, once its arity was checked. args holds the command
+		// name followed by its arguments. Write commands run exclusively, and are propagated to
+		// the AOF and replicas as they were called, so they must have the same effect wherever
+		// they run.
+		Execute(ctx context.Context, keyspace Keyspace, args []string) resptypes.RespSerializable
+	}
+
+	// CommandSpec declares a command added with WithCommand.
+	CommandSpec struct {
+		// The number of arguments including the command name, or -n for at least n
+		Arity int
+		// Any of "write", "readonly", "admin" and "fast", as reported by COMMAND INFO
+		Flags []string
+		// ACL categories, without the @, besides those implied by the flags
+		Categories []string
+		// The keys are the arguments from FirstKey to LastKey, every KeyStep, like the first,
+		// last and step of COMMAND INFO. LastKey defaults to FirstKey, and counts from the end of
+		// the arguments when negative. The command has no keys when FirstKey is 0.
+		FirstKey int
+		LastKey  int
+		KeyStep  int
+		// The flags of the keys as reported by COMMAND INFO, e.g. RW and update. Defaults to RW,
+		// access and update for write commands, and RO and access for the others.
+		KeyFlags []string
+
+		// Documentation, as shown by COMMAND DOCS. The group defaults to module.
+		Group      string
+		Summary    string
+		Since      string
+		Complexity string
+	}
+
+	// Keyspace gives the commands added with WithCommand access to the database selected by the
+	// calling client. Its methods keep the statistics, client-side caching, WATCH and keyspace
+	// notifications up to date as built-in commands do.
+	Keyspace struct {
+		keyspace keyspace
+	}
+
+	// extensionCommand adapts a Command to the commands of the processor.
+	extensionCommand struct {
+		cmd      Command
+		keyspace Keyspace
+	}
+)
+
+var (
+	// The flags commands added with WithCommand may have
+	extensionFlags = []string{"write", "readonly", "admin", "fast"}
+)
+
+// Get returns the value at key. It counts as a read of the key, or as a miss if it does not exist.
+func (k Keyspace) Get(ctx context.Context, key string) (redistypes.StoreValue, bool) {
+	k.keyspace.read(ctx, key)
+	value, exists := k.keyspace.db(ctx).Get(key)
+	if !exists {
+		k.keyspace.missed(ctx, key)
+	}
+
+	return value, exists
+}
+
+// GetOrCreate returns the value at key, creating it with newFunc if it does not exist.
+func (k Keyspace) GetOrCreate(ctx context.Context, key string, newFunc func() redistypes.StoreValue) redistypes.StoreValue {
+	return k.keyspace.getOrCreate(ctx, key, newFunc)
+}
+
+// Set replaces the value at key, and removes its expiry.
+func (k Keyspace) Set(ctx context.Context, key string, value redistypes.StoreValue) {
+	k.keyspace.db(ctx).Set(key, value, 0)
+	k.keyspace.modified(ctx, key)
+}
+
+// Delete removes key and returns whether it existed.
+func (k Keyspace) Delete(ctx context.Context, key string) bool {
+	if _, exists := k.keyspace.db(ctx).Get(key); !exists {
+		return false
+	}
+
+	k.keyspace.db(ctx).Delete(key)
+	k.keyspace.modified(ctx, key)
+	k.keyspace.notify(ctx, notifyGeneric, "del", key)
+	return true
+}
+
+// Modified signals that the value at key was changed in place, and emits event as a generic
+// keyspace event unless it is empty.
+func (k Keyspace) Modified(ctx context.Context, key string, event string) {
+	k.keyspace.modified(ctx, key)
+	if event != "" {
+		k.keyspace.notify(ctx, notifyGeneric, event, key)
+	}
+}
+
+// newExtensionCommand checks the spec of cmd, and returns it as a command of the processor.
+func newExtensionCommand(cmd Command, k keyspace) (extensionCommand, error) {
+	name := cmd.Name()
+	if name == "" || strings.ContainsAny(name, " |@") {
+		return extensionCommand{}, fmt.Errorf("invalid command name %q", name)
+	}
+
+	spec := cmd.Spec()
+	if spec.Arity == 0 {
+		return extensionCommand{}, fmt.Errorf("invalid arity 0 of command %s", name)
+	}
+
+	for _, flag := range spec.Flags {
+		if !slices.Contains(extensionFlags, flag) {
+			return extensionCommand{}, fmt.Errorf("invalid flag %q of command %s, must be one of %s", flag, name, strings.Join(extensionFlags, ", "))
+		}
+	}
+
+	for _, category := range spec.Categories {
+		if !slices.Contains(aclCategoryNames, category) {
+			return extensionCommand{}, fmt.Errorf("unknown ACL category %q of command %s", category, name)
+		}
+	}
+
+	if spec.FirstKey < 0 || spec.KeyStep < 0 {
+		return extensionCommand{}, fmt.Errorf("invalid key positions of command %s", name)
+	}
+
+	return extensionCommand{cmd: cmd, keyspace: Keyspace{k}}, nil
+}
+
+func (c extensionCommand) moniker() string {
+	return strings.ToUpper(c.cmd.Name())
+}
+
+func (c extensionCommand) getUsage() string {
+	return c.cmd.Usage()
+}
+
+func (c extensionCommand) spec() commandSpec {
+	s := c.cmd.Spec()
+	spec := commandSpec{
+		arity:      s.Arity,
+		categories: s.Categories,
+		group:      s.Group,
+		summary:    s.Summary,
+		since:      s.Since,
+		complexity: s.Complexity,
+	}
+	if spec.group == "" {
+		spec.group = "module"
+	}
+
+	for _, name := range s.Flags {
+		for _, flag := range commandFlagNames {
+			if flag.name == name {
+				spec.flags |= flag.flags
+			}
+		}
+	}
+
+	if s.FirstKey > 0 {
+		keyFlags := s.KeyFlags
+		if len(keyFlags) == 0 {
+			keyFlags = keyRO
+			if spec.flags&flagWrite != 0 {
+				keyFlags = keyRW
+			}
+		}
+
+		lastKey := 0
+		switch {
+		case s.LastKey < 0:
+			lastKey = s.LastKey
+		case s.LastKey > s.FirstKey:
+			lastKey = s.LastKey - s.FirstKey
+		}
+
+		spec.keySpecs = []keySpec{{flags: keyFlags, index: s.FirstKey, lastKey: lastKey, keyStep: max(1, s.KeyStep)}}
+	}
+
+	return spec
+}
+
+func (c extensionCommand) execute(ctx context.Context, params commandParams) commandResult {
+	return c.cmd.Execute(ctx, c.keyspace, argsOf(params))
+}
@@ -0,0 +1,61 @@
+package redisserverlib_test
+
+import (
+	"context"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+// testCommand replies with its name, and copies its keys to name:key when it writes.
+type testCommand struct {
+	name string
+	spec redisserverlib.CommandSpec
+}
+
+func (c testCommand) Name() string {
+	return c.name
+}
+
+func (c testCommand) Spec() redisserverlib.CommandSpec {
+	return c.spec
+}
+
+func (c testCommand) Usage() string {
+	return c.name + " key [key ...]"
+}
+
+func (c testCommand) Execute(ctx context.Context, keyspace redisserverlib.Keyspace, args []string) resptypes.RespSerializable {
+	for _, key := range args[1:] {
+		if v, exists := keyspace.Get(ctx, key); exists {
+			keyspace.Set(ctx, c.name+":"+key, v)
+		}
+	}
+
+	return resptypes.SimpleString{Val: c.name}
+}
+
+func TestExtensionCommands(t *testing.T) {
+	copyCmd := testCommand{name: "Ext.Copy", spec: redisserverlib.CommandSpec{Arity: -2, Flags: []string{"write"}, FirstKey: 1, LastKey: -1}}
+	cp := redisserverlib.NewRedisCommandProcessor(
+		redisserverlib.WithDir(t.TempDir()),
+		redisserverlib.WithCommand(copyCmd),
+		redisserverlib.WithCommand(testCommand{name: "GET", spec: redisserverlib.CommandSpec{Arity: 2}}),
+		redisserverlib.WithCommand(testCommand{name: "EXT.BLOCK", spec: redisserverlib.CommandSpec{Arity: 2, Flags: []string{"blocking"}}}),
+		redisserverlib.WithCommand(testCommand{name: "EXT|SUB", spec: redisserverlib.CommandSpec{Arity: 2}}),
+	)
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	c.expect(t, "+OK\r\n", "SET", "a", "1")
+	c.expect(t, "+Ext.Copy\r\n", "ext.copy", "a", "missing")
+	c.expect(t, "$1\r\n1\r\n", "GET", "Ext.Copy:a")
+	c.expect(t, "*2\r\n$1\r\na\r\n$1\r\nb\r\n", "COMMAND", "GETKEYS", "EXT.COPY", "a", "b")
+	c.expect(t, "-ERR wrong number of arguments for 'ext.copy' command\r\n", "EXT.COPY")
+
+	// Commands taking the name of another command, or with an invalid spec, are ignored
+	c.expect(t, "$-1\r\n", "GET", "missing")
+	c.expect(t, "-NOTSUPPORTED Command 'EXT.BLOCK' is not supported!\r\n", "EXT.BLOCK", "k")
+	c.expect(t, "-NOTSUPPORTED Command 'EXT|SUB' is not supported!\r\n", "EXT|SUB", "k")
+}
@@ -0,0 +1,57 @@
+package redisserverlib
+
+import (
+	"context"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	flushall struct {
+		keyspace
+	}
+)
+
+func (c flushall) moniker() string {
+	return "FLUSHALL"
+}
+
+func (c flushall) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagWrite,
+		categories: []string{"keyspace", "dangerous"},
+		group:      "server",
+		summary:    "Removes all keys from all databases.",
+		since:      "1.0.0",
+		complexity: "O(N) where N is the total number of keys in all databases",
+		args: []commandArg{{name: "flush-type", typ: argOneOf, optional: true, args: []commandArg{
+			{name: "async", typ: argPureToken, token: "ASYNC"},
+			{name: "sync", typ: argPureToken, token: "SYNC"},
+		}}},
+	}
+}
+
+func (c flushall) getUsage() string {
+	return `
+usage:
+	FLUSHALL [ASYNC | SYNC]
+summary:
+	Delete all the keys of all the existing databases, not just the currently selected one. This command never fails.
+	ASYNC frees the deleted keys in the background, SYNC (the default) frees them before replying.
+`
+}
+
+func (c flushall) execute(ctx context.Context, params commandParams) commandResult {
+	async, err := parseFlushMode(params)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	for i := range c.Len() {
+		c.DB(i).Clear(async)
+	}
+	c.flushed(ctx)
+
+	return resptypes.SimpleString{Val: "OK"}
+}
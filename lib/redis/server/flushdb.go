@@ -0,0 +1,73 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	flushdb struct {
+		keyspace
+	}
+)
+
+func (c flushdb) moniker() string {
+	return "FLUSHDB"
+}
+
+func (c flushdb) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagWrite,
+		categories: []string{"keyspace", "dangerous"},
+		group:      "server",
+		summary:    "Removes all keys from the current database.",
+		since:      "1.0.0",
+		complexity: "O(N) where N is the number of keys in the selected database",
+		args: []commandArg{{name: "flush-type", typ: argOneOf, optional: true, args: []commandArg{
+			{name: "async", typ: argPureToken, token: "ASYNC"},
+			{name: "sync", typ: argPureToken, token: "SYNC"},
+		}}},
+	}
+}
+
+func (c flushdb) getUsage() string {
+	return `
+usage:
+	FLUSHDB [ASYNC | SYNC]
+summary:
+	Delete all the keys of the currently selected DB. This command never fails.
+	ASYNC frees the deleted keys in the background, SYNC (the default) frees them before replying.
+`
+}
+
+func (c flushdb) execute(ctx context.Context, params commandParams) commandResult {
+	async, err := parseFlushMode(params)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	c.db(ctx).Clear(async)
+	c.flushed(ctx)
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+// parseFlushMode parses the optional ASYNC/SYNC argument shared by FLUSHDB and FLUSHALL.
+func parseFlushMode(params commandParams) (bool, error) {
+	switch len(params) {
+	case 1:
+		return false, nil
+	case 2:
+		switch strings.ToUpper(params[1].Val) {
+		case "ASYNC":
+			return true, nil
+		case "SYNC":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("ERR syntax error")
+}
@@ -10,7 +10,7 @@ import (
 
 type (
 	get struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -18,6 +18,20 @@ func (c get) moniker() string {
 	return "GET"
 }
 
+func (c get) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagReadonly | flagFast,
+		categories: []string{"string"},
+		keySpecs:   firstKey(keyRO),
+		group:      "string",
+		summary:    "Returns the string value of a key.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}},
+	}
+}
+
 func (c get) getUsage() string {
 	return `
 usage:
@@ -34,7 +48,8 @@ func (c get) execute(ctx context.Context, params commandParams) commandResult {
 	}
 
 	key := params[1].Val
-	if dsVal, exists := c.Get(key); exists {
+	c.read(ctx, key)
+	if dsVal, exists := c.db(ctx).Get(key); exists {
 		if dsVal.Type != redistypes.TypeString {
 			return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 		}
@@ -42,5 +57,6 @@ func (c get) execute(ctx context.Context, params commandParams) commandResult {
 		return dsVal.String
 	}
 
+	c.missed(ctx, key)
 	return resptypes.BulkString{Length: -1}
 }
@@ -3,6 +3,9 @@ package redisserverlib
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
 )
@@ -17,6 +20,19 @@ func (c help) moniker() string {
 	return "HELP"
 }
 
+func (c help) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		categories: []string{"connection"},
+		group:      "connection",
+		summary:    "Shows the usage of a command, or the commands of a category.",
+		args: []commandArg{{name: "topic", typ: argOneOf, optional: true, args: []commandArg{
+			{name: "commandname", typ: argString},
+			{name: "category", typ: argString},
+		}}},
+	}
+}
+
 func (c help) getUsage() string {
 	return `
 usage:
@@ -24,22 +40,50 @@ usage:
 
 summary:
 	HELP <commandname> shows specific help for the command given as argument.
-	HELP @<category> shows all the commands about a given category.
-
-	Only HELP <commandname> is currently implemented.
+	HELP @<category> shows all the commands about a given category, with a summary of each.
+	The categories are those of the ACL: @string, @list, @stream, @keyspace, @connection, @read, @write,
+	@blocking, @fast, @slow and so on.
 `
 }
 
+// execute replies with bulk strings, as a simple string cannot hold the line breaks of the usages.
 func (c help) execute(ctx context.Context, params commandParams) commandResult {
 	if len(params) != 2 {
-		return resptypes.SimpleString{Val: c.getUsage()}
+		return resptypes.NewBulkString(c.getUsage())
 	}
 
-	commandName := params[1].Val
+	if category, found := strings.CutPrefix(params[1].Val, "@"); found {
+		return c.executeCategory(strings.ToLower(category))
+	}
+
+	commandName := strings.ToUpper(params[1].Val)
 	command, exists := c.usages[commandName]
 	if !exists {
 		return resptypes.SimpleError{Val: fmt.Errorf("NOTSUPPORTED Command '%s' is not supported", commandName)}
 	}
 
-	return resptypes.SimpleString{Val: command.getUsage()}
+	// The usage of a renamed command names it as it was written, so tell which command it is
+	if original := command.moniker(); original != commandName {
+		return resptypes.NewBulkString(fmt.Sprintf("\nrenamed from:\n\t%s%s", original, command.getUsage()))
+	}
+
+	return resptypes.NewBulkString(command.getUsage())
+}
+
+// executeCategory lists the commands of an ACL category with their summary.
+func (c help) executeCategory(category string) commandResult {
+	if !slices.Contains(aclCategoryNames, category) {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown category '@%s'", category)}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\ncategory:\n\t@%s\ncommands:\n", category)
+	for _, name := range slices.Sorted(maps.Keys(c.usages)) {
+		spec := c.usages[name].spec()
+		if slices.Contains(spec.aclCategories(), category) {
+			fmt.Fprintf(&sb, "\t%-16s %s\n", name, spec.summary)
+		}
+	}
+
+	return resptypes.NewBulkString(sb.String())
 }
@@ -0,0 +1,62 @@
+package redisserverlib_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+// bulkStringOf returns the contents of a bulk string reply, failing the test for any other reply.
+func bulkStringOf(t *testing.T, reply string) string {
+	t.Helper()
+	header, body, _ := strings.Cut(reply, "\r\n")
+	if !strings.HasPrefix(header, "$") || header != "$"+strconv.Itoa(len(body)-2) || !strings.HasSuffix(body, "\r\n") {
+		t.Fatalf("%q is not a bulk string", reply)
+	}
+
+	return strings.TrimSuffix(body, "\r\n")
+}
+
+func TestHelp(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("Command names are case-insensitive", func(t *testing.T) {
+		if usage := bulkStringOf(t, c.do("HELP", "lrange")); !strings.Contains(usage, "lrange key start stop") {
+			t.Errorf("HELP lrange = %q", usage)
+		}
+
+		if usage := bulkStringOf(t, c.do("HELP")); !strings.Contains(usage, "HELP (<commandname> | @<category>)") {
+			t.Errorf("HELP = %q", usage)
+		}
+
+		c.expect(t, "-NOTSUPPORTED Command 'NOSUCHCOMMAND' is not supported\r\n", "HELP", "nosuchcommand")
+	})
+
+	t.Run("Categories", func(t *testing.T) {
+		usage := bulkStringOf(t, c.do("HELP", "@List"))
+
+		for _, line := range []string{
+			"\tBLPOP            Removes and returns the first element in a list.",
+			"\tLLEN             Returns the length of a list.\n",
+			"\tRPUSH            Appends one or more elements to a list.",
+		} {
+			if !strings.Contains(usage, line) {
+				t.Errorf("HELP @list is missing %q", line)
+			}
+		}
+
+		if strings.Contains(usage, "\tGET ") {
+			t.Errorf("HELP @list = %q", usage)
+		}
+
+		if usage := c.do("HELP", "@blocking"); !strings.Contains(usage, "\tBLPOP ") || !strings.Contains(usage, "\tWAIT ") || strings.Contains(usage, "\tLPOP ") {
+			t.Errorf("HELP @blocking = %q", usage)
+		}
+
+		c.expect(t, "-ERR Unknown category '@nosuchcategory'\r\n", "HELP", "@nosuchcategory")
+	})
+}
@@ -0,0 +1,50 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	incr struct {
+		keyspace
+	}
+)
+
+func (c incr) moniker() string {
+	return "INCR"
+}
+
+func (c incr) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagWrite | flagFast,
+		categories: []string{"string"},
+		keySpecs:   firstKey(keyRW),
+		group:      "string",
+		summary:    "Increments the integer value of a key by one. Uses 0 as initial value if the key doesn't exist.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}},
+	}
+}
+
+func (c incr) getUsage() string {
+	return `
+usage:
+	incr key
+summary:
+	Increments the number stored at key by one. If the key does not exist, it is set to 0 before performing the operation.
+	An error is returned if the key contains a value of the wrong type or a string that can not be represented as a 64 bit signed integer.
+`
+}
+
+func (c incr) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR INCR requires a key! %s", c.getUsage())}
+	}
+
+	return c.incrementBy(ctx, params[1].Val, 1)
+}
@@ -0,0 +1,91 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	incrby struct {
+		keyspace
+	}
+)
+
+func (c incrby) moniker() string {
+	return "INCRBY"
+}
+
+func (c incrby) spec() commandSpec {
+	return commandSpec{
+		arity:      3,
+		flags:      flagWrite | flagFast,
+		categories: []string{"string"},
+		keySpecs:   firstKey(keyRW),
+		group:      "string",
+		summary:    "Increments the integer value of a key by a number. Uses 0 as initial value if the key doesn't exist.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "increment", typ: argInteger}},
+	}
+}
+
+func (c incrby) getUsage() string {
+	return `
+usage:
+	incrby key increment
+summary:
+	Increments the number stored at key by increment. If the key does not exist, it is set to 0 before performing the operation.
+	An error is returned if the key contains a value of the wrong type or a string that can not be represented as a 64 bit signed integer.
+	The time to live of the key is kept.
+`
+}
+
+func (c incrby) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR INCRBY requires a key and an increment! %s", c.getUsage())}
+	}
+
+	delta, err := strconv.ParseInt(params[2].Val, 10, 64)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	return c.incrementBy(ctx, params[1].Val, delta)
+}
+
+// incrementBy adds delta to the integer stored at key, and is propagated as INCRBY whichever
+// command of the family was called.
+func (k keyspace) incrementBy(ctx context.Context, key string, delta int64) commandResult {
+	current := int64(0)
+	dsVal, ttl, exists := k.db(ctx).GetWithTTL(key)
+	if exists {
+		if dsVal.Type != redistypes.TypeString {
+			return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
+		}
+
+		var err error
+		if current, err = strconv.ParseInt(dsVal.String.Val, 10, 64); err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+		}
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR increment or decrement would overflow")}
+	}
+
+	current += delta
+	k.db(ctx).Set(key, redistypes.NewString(strconv.FormatInt(current, 10))(), ttl)
+	if !exists {
+		k.notify(ctx, notifyNew, "new", key)
+	}
+
+	k.modified(ctx, key)
+	k.notify(ctx, notifyString, "incrby", key)
+	propagateAs(ctx, "INCRBY", key, strconv.FormatInt(delta, 10))
+	return resptypes.Integer{Val: current}
+}
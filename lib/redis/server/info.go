@@ -0,0 +1,82 @@
+package redisserverlib
+
+import (
+	"context"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// infoSection is one section of the INFO report, a list of "field:value" lines.
+	infoSection struct {
+		title  string
+		fields func() []string
+		// Left out of the default report, only listed when named or with all or everything
+		extra bool
+	}
+
+	info struct {
+		sections []infoSection
+	}
+)
+
+func (c info) moniker() string {
+	return "INFO"
+}
+
+func (c info) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		categories: []string{"dangerous"},
+		group:      "server",
+		summary:    "Returns information and statistics about the server.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "section", typ: argString, optional: true, multiple: true}},
+	}
+}
+
+func (c info) getUsage() string {
+	return `
+usage:
+	INFO [section [section ...]]
+summary:
+	Return information and statistics about the server, for the given sections or all of them.
+	Sections are matched case-insensitively. Without arguments or with default, every section but
+	commandstats is returned; all and everything return every section.
+`
+}
+
+func (c info) execute(ctx context.Context, params commandParams) commandResult {
+	selected := make(map[string]bool)
+	all, defaults := false, len(params) == 1
+	for _, param := range params[1:] {
+		switch name := strings.ToLower(param.Val); name {
+		case "all", "everything":
+			all = true
+		case "default":
+			defaults = true
+		default:
+			selected[name] = true
+		}
+	}
+
+	var sb strings.Builder
+	for _, section := range c.sections {
+		if !all && !(defaults && !section.extra) && !selected[strings.ToLower(section.title)] {
+			continue
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteString("\r\n")
+		}
+
+		sb.WriteString("# " + section.title + "\r\n")
+		for _, field := range section.fields() {
+			sb.WriteString(field + "\r\n")
+		}
+	}
+
+	return resptypes.NewBulkString(sb.String())
+}
@@ -0,0 +1,108 @@
+package redisserverlib_test
+
+import (
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestInfo(t *testing.T) {
+	metrics := redisserverlib.NewMetrics()
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithMetrics(metrics))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("Sections", func(t *testing.T) {
+		report := c.do("INFO")
+		for _, title := range []string{"# Server", "# Clients", "# Memory", "# Persistence", "# Stats", "# Replication", "# Keyspace"} {
+			if !strings.Contains(report, title) {
+				t.Errorf("INFO is missing %s", title)
+			}
+		}
+
+		if strings.Contains(report, "# Commandstats") {
+			t.Errorf("INFO lists commandstats by default")
+		}
+
+		if report := c.do("INFO", "all"); !strings.Contains(report, "# Commandstats") {
+			t.Errorf("INFO all is missing commandstats")
+		}
+
+		report = c.do("INFO", "CLIENTS", "memory")
+		if !strings.HasPrefix(report, "$") || !strings.Contains(report, "# Clients\r\nconnected_clients:1\r\n") || strings.Contains(report, "# Server") {
+			t.Errorf("INFO clients memory = %q", report)
+		}
+
+		if version := c.infoField("server", "redis_version"); version == "" {
+			t.Errorf("redis_version is missing")
+		}
+	})
+
+	t.Run("Keyspace hits and misses", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SET", "k", "v")
+		c.expect(t, "$1\r\nv\r\n", "GET", "k")
+		c.expect(t, "$-1\r\n", "GET", "missing")
+		c.expect(t, "$-1\r\n", "GET", "missing")
+		if hits := c.infoField("stats", "keyspace_hits"); hits != "1" {
+			t.Errorf("keyspace_hits = %s", hits)
+		}
+
+		if misses := c.infoField("stats", "keyspace_misses"); misses != "2" {
+			t.Errorf("keyspace_misses = %s", misses)
+		}
+
+		c.expect(t, "+OK\r\n", "SET", "short", "v", "PX", "10")
+		waitFor(t, "the key to expire", func() bool { return c.infoField("stats", "expired_keys") == "1" })
+	})
+
+	t.Run("Command statistics", func(t *testing.T) {
+		c.expect(t, ":1\r\n", "RPUSH", "l", "a")
+		c.expect(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "INCR", "l")
+
+		subscriber := newTestClient(cp)
+		subscriber.do("SUBSCRIBE", "ch")
+		subscriber.do("GET", "k")
+
+		if stat := c.infoField("commandstats", "cmdstat_incr"); !strings.HasPrefix(stat, "calls=1,") || !strings.HasSuffix(stat, ",rejected_calls=0,failed_calls=1") {
+			t.Errorf("cmdstat_incr = %s", stat)
+		}
+
+		if stat := c.infoField("commandstats", "cmdstat_get"); !strings.HasSuffix(stat, ",rejected_calls=1,failed_calls=0") {
+			t.Errorf("cmdstat_get = %s", stat)
+		}
+
+		if errors := c.infoField("stats", "total_error_replies"); errors != "2" {
+			t.Errorf("total_error_replies = %s", errors)
+		}
+
+		if keyspace := c.infoField("keyspace", "db0"); keyspace != "keys=2,expires=0,avg_ttl=0" {
+			t.Errorf("db0 = %s", keyspace)
+		}
+	})
+
+	t.Run("Blocked clients", func(t *testing.T) {
+		blocked := newTestClient(cp)
+		popped := make(chan string)
+		go func() { popped <- blocked.do("BLPOP", "q", "5") }()
+
+		waitFor(t, "the client to block", func() bool { return c.infoField("clients", "blocked_clients") == "1" })
+		c.expect(t, ":1\r\n", "RPUSH", "q", "e")
+		<-popped
+		if count := c.infoField("clients", "blocked_clients"); count != "0" {
+			t.Errorf("blocked_clients = %s", count)
+		}
+	})
+
+	t.Run("Network traffic is reported by the server loop", func(t *testing.T) {
+		metrics.AddNetInput(10)
+		metrics.AddNetOutput(20)
+		if input := c.infoField("stats", "total_net_input_bytes"); input != "10" {
+			t.Errorf("total_net_input_bytes = %s", input)
+		}
+
+		if output := c.infoField("stats", "total_net_output_bytes"); output != "20" {
+			t.Errorf("total_net_output_bytes = %s", output)
+		}
+	})
+}
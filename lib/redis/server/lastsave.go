@@ -0,0 +1,48 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	lastsave struct {
+		*persistence
+	}
+)
+
+func (c lastsave) moniker() string {
+	return "LASTSAVE"
+}
+
+func (c lastsave) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagFast,
+		categories: []string{"admin", "dangerous"},
+		group:      "server",
+		summary:    "Returns the Unix timestamp of the last successful save to disk.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c lastsave) getUsage() string {
+	return `
+usage:
+	LASTSAVE
+summary:
+	Return the UNIX time of the last successful save to disk.
+	Clients can check whether a BGSAVE succeeded by comparing the value before and after it.
+`
+}
+
+func (c lastsave) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR LASTSAVE takes no arguments! %s", c.getUsage())}
+	}
+
+	return resptypes.Integer{Val: c.lastSave.Load()}
+}
@@ -0,0 +1,233 @@
+package redisserverlib
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// latencyMonitor records the latency spikes of events, such as commands or fsyncs, that
+	// took at least latency-monitor-threshold milliseconds, for LATENCY LATEST and HISTORY.
+	latencyMonitor struct {
+		// In milliseconds, 0 disables the monitor
+		threshold atomic.Int64
+
+		mu     sync.Mutex
+		events map[string]*latencyEvent
+	}
+
+	latencyEvent struct {
+		// Oldest first, at most one per second
+		samples []latencySample
+		// The worst latency since the event was created or reset, in milliseconds
+		max int64
+	}
+
+	latencySample struct {
+		time    int64
+		latency int64
+	}
+
+	// latencyHistogram counts durations in buckets whose bounds are powers of 2 microseconds.
+	// Bucket i holds the durations d such that bits.Len64(d) == i.
+	latencyHistogram [65]int64
+)
+
+const (
+	// Samples kept per event
+	latencyHistoryLen = 160
+
+	// Latency events
+	latencyEventCommand       = "command"
+	latencyEventExpireCycle   = "expire-cycle"
+	latencyEventFsyncAlways   = "aof-fsync-always"
+	latencyEventFsyncEverySec = "aof-fsync-everysec"
+	// Copying the dataset for BGSAVE, BGREWRITEAOF, a full resynchronization or a Raft snapshot
+	latencyEventFork = "fork"
+)
+
+func newLatencyMonitor(options processorOptions) *latencyMonitor {
+	m := &latencyMonitor{events: make(map[string]*latencyEvent)}
+	m.threshold.Store(options.latencyMonitorThreshold.Milliseconds())
+	return m
+}
+
+// add records a sample of event if d reaches the threshold. Samples of the same second are
+// merged, keeping the worst.
+func (m *latencyMonitor) add(event string, d time.Duration) {
+	threshold, latency := m.threshold.Load(), d.Milliseconds()
+	if threshold <= 0 || latency < threshold {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, exists := m.events[event]
+	if !exists {
+		e = &latencyEvent{}
+		m.events[event] = e
+	}
+
+	e.max = max(e.max, latency)
+	now := time.Now().Unix()
+	if last := len(e.samples) - 1; last >= 0 && e.samples[last].time == now {
+		e.samples[last].latency = max(e.samples[last].latency, latency)
+		return
+	}
+
+	e.samples = append(e.samples, latencySample{time: now, latency: latency})
+	if len(e.samples) > latencyHistoryLen {
+		e.samples = append([]latencySample(nil), e.samples[1:]...)
+	}
+}
+
+// since records a sample of event for the time elapsed since start.
+func (m *latencyMonitor) since(event string, start time.Time) {
+	m.add(event, time.Since(start))
+}
+
+// names returns the events having samples, sorted.
+func (m *latencyMonitor) names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.events))
+}
+
+// history returns a copy of the samples and the worst latency of event.
+func (m *latencyMonitor) history(event string) ([]latencySample, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, exists := m.events[event]
+	if !exists {
+		return nil, 0
+	}
+
+	return slices.Clone(e.samples), e.max
+}
+
+// reset drops the samples of the given events, or of every event when none are given, and
+// returns how many events were reset.
+func (m *latencyMonitor) reset(events ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(events) == 0 {
+		count := len(m.events)
+		clear(m.events)
+		return count
+	}
+
+	count := 0
+	for _, event := range events {
+		if _, exists := m.events[event]; exists {
+			delete(m.events, event)
+			count++
+		}
+	}
+
+	return count
+}
+
+// doctor analyzes the recorded spikes and returns a report for humans, like LATENCY DOCTOR.
+func (m *latencyMonitor) doctor() string {
+	if m.threshold.Load() <= 0 {
+		return "I'm sorry, Dave, I can't do that. Latency monitoring is disabled in this Redis instance. " +
+			"You may use \"CONFIG SET latency-monitor-threshold <milliseconds>.\" in order to enable it.\n"
+	}
+
+	names := m.names()
+	if len(names) == 0 {
+		return "Dave, no latency spike was observed during the lifetime of this Redis instance, not in the slightest bit. " +
+			"I honestly think you ought to sleep tonight.\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Dave, I have observed latency spikes in this Redis instance. You don't mind talking about it, do you Dave?\n\n")
+	advices := make(map[string]bool)
+	for i, name := range names {
+		samples, worst := m.history(name)
+		if len(samples) == 0 {
+			continue
+		}
+
+		total := int64(0)
+		for _, sample := range samples {
+			total += sample.latency
+		}
+
+		avg := float64(total) / float64(len(samples))
+		deviation := 0.0
+		for _, sample := range samples {
+			deviation += math.Abs(float64(sample.latency) - avg)
+		}
+
+		deviation /= float64(len(samples))
+		period := 0.0
+		if len(samples) > 1 {
+			period = float64(samples[len(samples)-1].time-samples[0].time) / float64(len(samples)-1)
+		}
+
+		fmt.Fprintf(&sb, "%d. %s: %d latency spikes (average %.0fms, mean deviation %.0fms, period %.2f sec). Worst all time event %dms.\n",
+			i+1, name, len(samples), avg, deviation, period, worst)
+		switch name {
+		case latencyEventCommand:
+			advices["command"] = true
+		case latencyEventExpireCycle:
+			advices["expire"] = true
+		case latencyEventFsyncAlways, latencyEventFsyncEverySec:
+			advices["fsync"] = true
+		case latencyEventFork:
+			advices["fork"] = true
+		}
+	}
+
+	sb.WriteString("\nI have a few advices for you:\n\n")
+	if advices["command"] {
+		sb.WriteString("- Check your slow log with SLOWLOG GET for commands that are slow to run, e.g. LRANGE or XRANGE over many elements, " +
+			"and use LATENCY HISTOGRAM to see which commands have a long tail.\n")
+	}
+
+	if advices["expire"] {
+		sb.WriteString("- Many keys with the same expire time are expiring together, notifying keyspace events and invalidating client caches. " +
+			"Consider spreading their expire times over a longer period.\n")
+	}
+
+	if advices["fsync"] {
+		sb.WriteString("- The disk holding the append only file is slow to fsync. Consider appendfsync everysec instead of always, " +
+			"or a faster disk.\n")
+	}
+
+	if advices["fork"] {
+		sb.WriteString("- Commands wait while the dataset is copied for a background save, an AOF rewrite or a full resynchronization. " +
+			"Consider fewer save points, or splitting the dataset among more instances.\n")
+	}
+
+	return sb.String()
+}
+
+func (h *latencyHistogram) add(d time.Duration) {
+	h[bits.Len64(uint64(max(0, d.Microseconds())))]++
+}
+
+// cumulative returns the upper bound in microseconds and the number of durations up to it,
+// for the buckets where the number grows.
+func (h *latencyHistogram) cumulative() [][2]int64 {
+	var points [][2]int64
+	count := int64(0)
+	for i, n := range h {
+		if n == 0 {
+			continue
+		}
+
+		count += n
+		points = append(points, [2]int64{int64(1) << min(i, 62), count})
+	}
+
+	return points
+}
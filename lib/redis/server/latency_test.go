@@ -0,0 +1,66 @@
+package redisserverlib_test
+
+import (
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestLatency(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("HISTOGRAM", func(t *testing.T) {
+		c.do("SET", "k", "v")
+		c.do("SET", "k", "w")
+		c.do("GET", "k")
+
+		histogram := c.do("LATENCY", "HISTOGRAM", "set", "NOSUCHCOMMAND")
+		if !strings.HasPrefix(histogram, "*2\r\n$3\r\nset\r\n*4\r\n$5\r\ncalls\r\n:2\r\n$14\r\nhistogram_usec\r\n*") || !strings.HasSuffix(histogram, ":2\r\n") {
+			t.Errorf("LATENCY HISTOGRAM set = %q", histogram)
+		}
+
+		if histogram := c.do("LATENCY", "HISTOGRAM"); !strings.Contains(histogram, "$3\r\nget\r\n*4\r\n$5\r\ncalls\r\n:1\r\n") {
+			t.Errorf("LATENCY HISTOGRAM = %q", histogram)
+		}
+	})
+
+	t.Run("Spikes are not recorded by default", func(t *testing.T) {
+		c.expect(t, "*0\r\n", "LATENCY", "LATEST")
+		if report := c.do("LATENCY", "DOCTOR"); !strings.Contains(report, "Latency monitoring is disabled") {
+			t.Errorf("LATENCY DOCTOR = %q", report)
+		}
+	})
+
+	t.Run("Slow commands", func(t *testing.T) {
+		args := []string{"RPUSH", "l"}
+		for range 200000 {
+			args = append(args, "element")
+		}
+
+		c.do(args...)
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "latency-monitor-threshold", "1")
+		c.do("LRANGE", "l", "0", "-1")
+
+		if latest := c.do("LATENCY", "LATEST"); !strings.HasPrefix(latest, "*1\r\n*4\r\n$7\r\ncommand\r\n:") {
+			t.Errorf("LATENCY LATEST = %q", latest)
+		}
+
+		if history := c.do("LATENCY", "HISTORY", "command"); !strings.HasPrefix(history, "*1\r\n*2\r\n:") {
+			t.Errorf("LATENCY HISTORY command = %q", history)
+		}
+
+		if report := c.do("LATENCY", "DOCTOR"); !strings.Contains(report, "1. command: 1 latency spikes") || !strings.Contains(report, "SLOWLOG GET") {
+			t.Errorf("LATENCY DOCTOR = %q", report)
+		}
+
+		c.expect(t, ":0\r\n", "LATENCY", "RESET", "expire-cycle")
+		c.expect(t, ":1\r\n", "LATENCY", "RESET")
+		c.expect(t, "*0\r\n", "LATENCY", "HISTORY", "command")
+		if report := c.do("LATENCY", "DOCTOR"); !strings.Contains(report, "no latency spike was observed") {
+			t.Errorf("LATENCY DOCTOR = %q", report)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	latencyCmd struct {
+		monitor *latencyMonitor
+		metrics *Metrics
+	}
+)
+
+func (c latencyCmd) moniker() string {
+	return "LATENCY"
+}
+
+func (c latencyCmd) spec() commandSpec {
+	return commandSpec{
+		arity:   -2,
+		flags:   flagAdmin,
+		group:   "server",
+		summary: "A container for latency diagnostics commands.",
+		since:   "2.8.13",
+		subcommands: []commandSpec{
+			{
+				name: "histogram", arity: -2, summary: "Returns the cumulative distribution of latencies of a subset or all commands.",
+				since: "7.0.0", complexity: "O(N) where N is the number of commands with latency information being retrieved.",
+				args: []commandArg{{name: "command", typ: argString, optional: true, multiple: true}},
+			},
+			{name: "latest", arity: 2, summary: "Returns the latest latency samples for all events.", since: "2.8.13", complexity: "O(1)"},
+			{
+				name: "history", arity: 3, summary: "Returns timestamp-latency samples for an event.", since: "2.8.13", complexity: "O(1)",
+				args: []commandArg{{name: "event", typ: argString}},
+			},
+			{
+				name: "reset", arity: -2, summary: "Resets the latency data for one or more events.", since: "2.8.13", complexity: "O(1)",
+				args: []commandArg{{name: "event", typ: argString, optional: true, multiple: true}},
+			},
+			{name: "doctor", arity: 2, summary: "Returns a human-readable latency analysis report.", since: "2.8.13", complexity: "O(1)"},
+		},
+	}
+}
+
+func (c latencyCmd) getUsage() string {
+	return `
+usage:
+	LATENCY HISTOGRAM [command ...]
+	LATENCY LATEST
+	LATENCY HISTORY event
+	LATENCY RESET [event ...]
+	LATENCY DOCTOR
+summary:
+	HISTOGRAM returns, for the given commands or every command that ran, the number of calls and the
+	cumulative distribution of their latency: how many calls took at most 1, 2, 4, 8... microseconds.
+	The other subcommands report the latency spikes of events that took at least latency-monitor-threshold
+	milliseconds, which is 0 by default to disable monitoring. The events are:
+	command              commands taking long to run
+	expire-cycle         the handling of expired keys
+	aof-fsync-always     the fsync after each write with appendfsync always
+	aof-fsync-everysec   the fsync once per second with appendfsync everysec
+	LATEST returns, for each event, the time of its latest spike, its latency and the worst latency in milliseconds.
+	HISTORY returns the time and latency of the latest spikes of the event, at most one per second.
+	RESET drops the spikes of the given events, or of every event, and returns the number of events reset.
+	DOCTOR returns an analysis of the spikes with advices.
+`
+}
+
+func (c latencyCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR LATENCY requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "HISTOGRAM":
+		return c.executeHistogram(argsOf(params[2:]))
+	case subcommand == "LATEST" && len(params) == 2:
+		return c.executeLatest()
+	case subcommand == "HISTORY" && len(params) == 3:
+		samples, _ := c.monitor.history(strings.ToLower(params[2].Val))
+		result := make(resptypes.Array[resptypes.RespSerializable], len(samples))
+		for i, sample := range samples {
+			result[i] = resptypes.Array[resptypes.Integer]{{Val: sample.time}, {Val: sample.latency}}
+		}
+
+		return result
+	case subcommand == "RESET":
+		events := argsOf(params[2:])
+		for i, event := range events {
+			events[i] = strings.ToLower(event)
+		}
+
+		return resptypes.Integer{Val: int64(c.monitor.reset(events...))}
+	case subcommand == "DOCTOR" && len(params) == 2:
+		return resptypes.NewBulkString(c.monitor.doctor())
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown LATENCY subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+func (c latencyCmd) executeHistogram(names []string) commandResult {
+	histograms := c.metrics.latencyHistograms(names...)
+	result := make(resptypes.Array[resptypes.RespSerializable], 0, 2*len(histograms))
+	for _, name := range slices.Sorted(maps.Keys(histograms)) {
+		stats := histograms[name]
+		distribution := resptypes.Array[resptypes.Integer]{}
+		for _, point := range stats.latency.cumulative() {
+			distribution = append(distribution, resptypes.Integer{Val: point[0]}, resptypes.Integer{Val: point[1]})
+		}
+
+		result = append(result, resptypes.NewBulkString(strings.ToLower(name)), resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("calls"),
+			resptypes.Integer{Val: stats.calls},
+			resptypes.NewBulkString("histogram_usec"),
+			distribution,
+		})
+	}
+
+	return result
+}
+
+func (c latencyCmd) executeLatest() commandResult {
+	result := resptypes.Array[resptypes.RespSerializable]{}
+	for _, name := range c.monitor.names() {
+		samples, worst := c.monitor.history(name)
+		if len(samples) == 0 {
+			continue
+		}
+
+		latest := samples[len(samples)-1]
+		result = append(result, resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString(name),
+			resptypes.Integer{Val: latest.time},
+			resptypes.Integer{Val: latest.latency},
+			resptypes.Integer{Val: worst},
+		})
+	}
+
+	return result
+}
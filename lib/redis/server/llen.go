@@ -10,7 +10,7 @@ import (
 
 type (
 	llen struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -18,6 +18,20 @@ func (c llen) moniker() string {
 	return "LLEN"
 }
 
+func (c llen) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagReadonly | flagFast,
+		categories: []string{"list"},
+		keySpecs:   firstKey(keyRO),
+		group:      "list",
+		summary:    "Returns the length of a list.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}},
+	}
+}
+
 func (c llen) getUsage() string {
 	return `
 usage:
@@ -35,7 +49,8 @@ func (c llen) execute(ctx context.Context, params commandParams) commandResult {
 	}
 
 	listName := params[1].Val
-	dsVal, exists := c.Get(listName)
+	c.read(ctx, listName)
+	dsVal, exists := c.db(ctx).Get(listName)
 	if exists {
 		if dsVal.Type != redistypes.TypeList {
 			return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
@@ -44,5 +59,6 @@ func (c llen) execute(ctx context.Context, params commandParams) commandResult {
 		return resptypes.Integer{Val: int64(dsVal.List.Len())}
 	}
 
+	c.missed(ctx, listName)
 	return resptypes.Integer{Val: 0}
 }
@@ -11,7 +11,7 @@ import (
 
 type (
 	lpop struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -19,6 +19,20 @@ func (c lpop) moniker() string {
 	return "LPOP"
 }
 
+func (c lpop) spec() commandSpec {
+	return commandSpec{
+		arity:      -2,
+		flags:      flagWrite | flagFast,
+		categories: []string{"list"},
+		keySpecs:   firstKey(keyRWDelete),
+		group:      "list",
+		summary:    "Returns the first elements in a list after removing it. Deletes the list if the last element was popped.",
+		since:      "1.0.0",
+		complexity: "O(N) where N is the number of elements returned",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "count", typ: argInteger, optional: true}},
+	}
+}
+
 func (c lpop) getUsage() string {
 	return `
 usage:
@@ -51,12 +65,17 @@ func (c lpop) execute(ctx context.Context, params commandParams) commandResult {
 		return resptypes.SimpleError{Val: fmt.Errorf("ERR Count must be a positive integer!")}
 	}
 
-	if dsVal, exists := c.Get(listName); exists {
+	if dsVal, exists := c.db(ctx).Get(listName); exists {
 		if dsVal.Type != redistypes.TypeList {
 			return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 		}
 
 		result := resptypes.Array[resptypes.BulkString](dsVal.List.PopFront(count))
+		if len(result) > 0 {
+			c.modified(ctx, listName)
+			c.notify(ctx, notifyList, "lpop", listName)
+		}
+
 		if count == 1 {
 			return result[0]
 		}
@@ -10,7 +10,7 @@ import (
 
 type (
 	lpush struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -18,6 +18,20 @@ func (c lpush) moniker() string {
 	return "LPUSH"
 }
 
+func (c lpush) spec() commandSpec {
+	return commandSpec{
+		arity:      -3,
+		flags:      flagWrite | flagFast,
+		categories: []string{"list"},
+		keySpecs:   firstKey(keyRWInsert),
+		group:      "list",
+		summary:    "Prepends one or more elements to a list. Creates the key if it doesn't exist.",
+		since:      "1.0.0",
+		complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "element", typ: argString, multiple: true}},
+	}
+}
+
 func (c lpush) getUsage() string {
 	return `
 usage:
@@ -35,11 +49,13 @@ func (c lpush) execute(ctx context.Context, params commandParams) commandResult
 	}
 
 	listName := params[1].Val
-	dsVal := c.GetOrCreate(listName, redistypes.NewList, 0)
+	dsVal := c.getOrCreate(ctx, listName, redistypes.NewList)
 	if dsVal.Type != redistypes.TypeList {
 		return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 	}
 
 	newLen := dsVal.List.PushFront(params[2:]...)
+	c.modified(ctx, listName)
+	c.notify(ctx, notifyList, "lpush", listName)
 	return resptypes.Integer{Val: int64(newLen)}
 }
@@ -11,7 +11,7 @@ import (
 
 type (
 	lrange struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -19,6 +19,20 @@ func (c lrange) moniker() string {
 	return "LRANGE"
 }
 
+func (c lrange) spec() commandSpec {
+	return commandSpec{
+		arity:      4,
+		flags:      flagReadonly,
+		categories: []string{"list"},
+		keySpecs:   firstKey(keyRO),
+		group:      "list",
+		summary:    "Returns a range of elements from a list.",
+		since:      "1.0.0",
+		complexity: "O(S+N) where S is the distance of start offset from HEAD for small lists, from nearest end (HEAD or TAIL) for large lists; and N is the number of elements in the specified range.",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "start", typ: argInteger}, {name: "stop", typ: argInteger}},
+	}
+}
+
 func (c lrange) getUsage() string {
 	return `
 usage:
@@ -56,7 +70,8 @@ func (c lrange) execute(ctx context.Context, params commandParams) commandResult
 		return resptypes.SimpleError{Val: fmt.Errorf("ERR Stop index '%s' could not be converted to int! Err: %w", stopIndexStr, err)}
 	}
 
-	if dsVal, exists := c.Get(listName); exists {
+	c.read(ctx, listName)
+	if dsVal, exists := c.db(ctx).Get(listName); exists {
 		if dsVal.Type != redistypes.TypeList {
 			return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 		}
@@ -65,5 +80,6 @@ func (c lrange) execute(ctx context.Context, params commandParams) commandResult
 		return bulkStrings
 	}
 
+	c.missed(ctx, listName)
 	return resptypes.Array[resptypes.RespSerializable]{}
 }
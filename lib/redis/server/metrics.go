@@ -0,0 +1,283 @@
+package redisserverlib
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Metrics is the registry of the counters reported by INFO. The processor counts commands,
+	// clients and keyspace accesses itself, while the network traffic is reported by the loop
+	// serving the connections, which shares the registry with the processor through WithMetrics.
+	Metrics struct {
+		start time.Time
+		runId string
+
+		connectionsReceived atomic.Int64
+		netInputBytes       atomic.Int64
+		netOutputBytes      atomic.Int64
+		commandsProcessed   atomic.Int64
+		errorReplies        atomic.Int64
+		keyspaceLookups     atomic.Int64
+		keyspaceMisses      atomic.Int64
+		expiredKeys         atomic.Int64
+		blockedClients      atomic.Int64
+		peakMemory          atomic.Uint64
+
+		mu       sync.Mutex
+		commands map[string]*commandStats
+		// Error replies by prefix, e.g. ERR or WRONGTYPE
+		errors map[string]int64
+	}
+
+	// commandStats are the counters of one command, reported by INFO commandstats.
+	commandStats struct {
+		calls int64
+		usec  int64
+		// Rejected before running, e.g. because of a redirection or an aborted transaction
+		rejected int64
+		// Ran and replied with an error
+		failed  int64
+		latency latencyHistogram
+	}
+)
+
+const (
+	redisVersion = "8.6.0"
+)
+
+// NewMetrics returns an empty registry, with the uptime counting from now.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		start:    time.Now(),
+		runId:    newNodeId(),
+		commands: make(map[string]*commandStats),
+		errors:   make(map[string]int64),
+	}
+}
+
+// AddNetInput counts bytes read from a client connection.
+func (m *Metrics) AddNetInput(bytes int) {
+	m.netInputBytes.Add(int64(bytes))
+}
+
+// AddNetOutput counts bytes written to a client connection.
+func (m *Metrics) AddNetOutput(bytes int) {
+	m.netOutputBytes.Add(int64(bytes))
+}
+
+func (m *Metrics) commandNoLock(name string) *commandStats {
+	stats, exists := m.commands[name]
+	if !exists {
+		stats = &commandStats{}
+		m.commands[name] = stats
+	}
+
+	return stats
+}
+
+// called records a command that ran for d.
+func (m *Metrics) called(name string, d time.Duration, failed bool) {
+	m.commandsProcessed.Add(1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.commandNoLock(name)
+	stats.calls++
+	stats.usec += d.Microseconds()
+	stats.latency.add(d)
+	if failed {
+		stats.failed++
+	}
+}
+
+// errorReplied records an error reply, by the prefix of its message.
+func (m *Metrics) errorReplied(err error) {
+	m.errorReplies.Add(1)
+	prefix, _, _ := strings.Cut(err.Error(), " ")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[prefix]++
+}
+
+// rejected records a command that was refused before it could run.
+func (m *Metrics) rejected(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commandNoLock(name).rejected++
+}
+
+// reset zeroes the statistics, like CONFIG RESETSTAT. Gauges such as the blocked clients are kept.
+func (m *Metrics) reset() {
+	for _, counter := range []*atomic.Int64{
+		&m.connectionsReceived, &m.netInputBytes, &m.netOutputBytes, &m.commandsProcessed,
+		&m.errorReplies, &m.keyspaceLookups, &m.keyspaceMisses, &m.expiredKeys,
+	} {
+		counter.Store(0)
+	}
+
+	m.peakMemory.Store(0)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.commands)
+	clear(m.errors)
+}
+
+func (m *Metrics) serverInfo(mode string, port int, configFile string) []string {
+	uptime := time.Since(m.start)
+	executable, _ := os.Executable()
+	return []string{
+		"redis_version:" + redisVersion,
+		"redis_mode:" + mode,
+		fmt.Sprintf("os:%s %s", runtime.GOOS, runtime.GOARCH),
+		fmt.Sprintf("arch_bits:%d", strconv.IntSize),
+		"go_version:" + runtime.Version(),
+		fmt.Sprintf("process_id:%d", os.Getpid()),
+		"run_id:" + m.runId,
+		fmt.Sprintf("tcp_port:%d", port),
+		fmt.Sprintf("server_time_usec:%d", time.Now().UnixMicro()),
+		fmt.Sprintf("uptime_in_seconds:%d", int64(uptime/time.Second)),
+		fmt.Sprintf("uptime_in_days:%d", int64(uptime/(24*time.Hour))),
+		"executable:" + executable,
+		"config_file:" + configFile,
+	}
+}
+
+func (m *Metrics) clientsInfo(connected int) []string {
+	return []string{
+		fmt.Sprintf("connected_clients:%d", connected),
+		fmt.Sprintf("blocked_clients:%d", m.blockedClients.Load()),
+	}
+}
+
+// memory returns the memory of the Go runtime, which holds the dataset among other things:
+// the bytes allocated, obtained from the OS, and the most ever allocated.
+func (m *Metrics) memory() (used uint64, rss uint64, peak uint64, gcCycles uint32) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	used = stats.HeapAlloc
+	rss = stats.Sys - stats.HeapReleased
+	for {
+		peak = m.peakMemory.Load()
+		if used <= peak || m.peakMemory.CompareAndSwap(peak, used) {
+			break
+		}
+	}
+
+	return used, rss, m.peakMemory.Load(), stats.NumGC
+}
+
+func (m *Metrics) memoryInfo() []string {
+	used, rss, peak, gcCycles := m.memory()
+	return []string{
+		fmt.Sprintf("used_memory:%d", used),
+		"used_memory_human:" + humanBytes(used),
+		fmt.Sprintf("used_memory_rss:%d", rss),
+		"used_memory_rss_human:" + humanBytes(rss),
+		fmt.Sprintf("used_memory_peak:%d", peak),
+		"used_memory_peak_human:" + humanBytes(peak),
+		fmt.Sprintf("mem_fragmentation_ratio:%.2f", float64(rss)/float64(max(used, 1))),
+		fmt.Sprintf("gc_cycles:%d", gcCycles),
+		"maxmemory:0",
+		"maxmemory_policy:noeviction",
+	}
+}
+
+func (m *Metrics) statsInfo() []string {
+	misses := m.keyspaceMisses.Load()
+	return []string{
+		fmt.Sprintf("total_connections_received:%d", m.connectionsReceived.Load()),
+		fmt.Sprintf("total_commands_processed:%d", m.commandsProcessed.Load()),
+		fmt.Sprintf("total_net_input_bytes:%d", m.netInputBytes.Load()),
+		fmt.Sprintf("total_net_output_bytes:%d", m.netOutputBytes.Load()),
+		fmt.Sprintf("expired_keys:%d", m.expiredKeys.Load()),
+		"evicted_keys:0",
+		fmt.Sprintf("keyspace_hits:%d", max(0, m.keyspaceLookups.Load()-misses)),
+		fmt.Sprintf("keyspace_misses:%d", misses),
+		fmt.Sprintf("total_error_replies:%d", m.errorReplies.Load()),
+	}
+}
+
+func (m *Metrics) commandStatsInfo() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var fields []string
+	for _, name := range slices.Sorted(maps.Keys(m.commands)) {
+		stats := m.commands[name]
+		perCall := 0.0
+		if stats.calls > 0 {
+			perCall = float64(stats.usec) / float64(stats.calls)
+		}
+
+		fields = append(fields, fmt.Sprintf("cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d",
+			strings.ToLower(name), stats.calls, stats.usec, perCall, stats.rejected, stats.failed))
+	}
+
+	return fields
+}
+
+// errorStatsInfo returns the INFO errorstats fields, one per error prefix.
+func (m *Metrics) errorStatsInfo() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var fields []string
+	for _, prefix := range slices.Sorted(maps.Keys(m.errors)) {
+		fields = append(fields, fmt.Sprintf("errorstat_%s:count=%d", prefix, m.errors[prefix]))
+	}
+
+	return fields
+}
+
+// snapshot returns a copy of the statistics of every command and error prefix.
+func (m *Metrics) snapshot() (map[string]commandStats, map[string]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	commands := make(map[string]commandStats, len(m.commands))
+	for name, stats := range m.commands {
+		commands[name] = *stats
+	}
+
+	return commands, maps.Clone(m.errors)
+}
+
+// latencyHistograms returns the latency histograms of the given commands, or of every command
+// that ran when none are given. Commands that never ran are left out.
+func (m *Metrics) latencyHistograms(names ...string) map[string]commandStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(names) == 0 {
+		names = slices.Collect(maps.Keys(m.commands))
+	}
+
+	histograms := make(map[string]commandStats)
+	for _, name := range names {
+		if stats, exists := m.commands[strings.ToUpper(name)]; exists && stats.calls > 0 {
+			histograms[strings.ToUpper(name)] = *stats
+		}
+	}
+
+	return histograms
+}
+
+// humanBytes formats a size like the _human fields of INFO memory, e.g. 1.50M.
+func humanBytes(bytes uint64) string {
+	const units = "KMGT"
+	if bytes < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	size, unit := float64(bytes)/1024, 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	return fmt.Sprintf("%.2f%c", size, units[unit])
+}
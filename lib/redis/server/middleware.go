@@ -0,0 +1,124 @@
+package redisserverlib
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// Request is a command sent by a client, as seen by middlewares.
+	Request struct {
+		// The command name as sent by the client, followed by its arguments. Middlewares may
+		// change them before calling the next handler, e.g. to prefix keys.
+		Args   []string
+		Client ClientInfo
+
+		commands commandMap
+	}
+
+	// ClientInfo describes the connection a request was sent on.
+	ClientInfo struct {
+		ID   int64
+		Addr string
+		// Set by CLIENT SETNAME
+		Name string
+		// The selected database
+		DB int
+		// The ACL user the client is authenticated as, empty when it must authenticate first
+		User string
+	}
+
+	// Handler runs a request and returns its reply.
+	Handler func(ctx context.Context, req *Request) resptypes.RespSerializable
+
+	// Middleware wraps the handler running requests. It may inspect or change the request, the
+	// reply of next, or reply without calling next at all.
+	Middleware func(next Handler) Handler
+
+	// middlewareChain runs requests through the middlewares added by Use, in the order they
+	// were added, before handing them to the processor.
+	middlewareChain struct {
+		mu          sync.Mutex
+		middlewares []Middleware
+		base        Handler
+		handler     atomic.Pointer[Handler]
+	}
+)
+
+var errEmptyRequest = errors.New("ERR empty command")
+
+// KeyIndexes returns the positions of the keys of the command in Args. Unknown commands have none.
+func (r *Request) KeyIndexes() []int {
+	if len(r.Args) == 0 {
+		return []int{}
+	}
+
+	entry, exists := r.commands[strings.ToUpper(r.Args[0])]
+	if !exists {
+		return []int{}
+	}
+
+	return keyIndexesOf(entry, resptypes.ToBulkStringArray(r.Args))
+}
+
+// newMiddlewareChain returns the chain handing requests to base, which logs every request.
+func newMiddlewareChain(base Handler) *middlewareChain {
+	m := &middlewareChain{base: base}
+	m.Use(logRequests)
+	return m
+}
+
+// Use adds a middleware around the command dispatch. Middlewares see the requests of clients once
+// parsed, before anything else happens to them, and the first added is the outermost. Commands
+// replayed from the AOF, or applied from a primary or the Raft log, do not pass through them.
+func (m *middlewareChain) Use(middleware Middleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.middlewares = append(m.middlewares, middleware)
+	handler := m.base
+	for i := len(m.middlewares) - 1; i >= 0; i-- {
+		handler = m.middlewares[i](handler)
+	}
+
+	m.handler.Store(&handler)
+}
+
+func (m *middlewareChain) serve(ctx context.Context, req *Request) resptypes.RespSerializable {
+	return (*m.handler.Load())(ctx, req)
+}
+
+// logRequests logs every request at debug level, with its credentials redacted. Malformed
+// requests are logged by ExecuteCommand, as they never reach the chain.
+func logRequests(next Handler) Handler {
+	return func(ctx context.Context, req *Request) resptypes.RespSerializable {
+		slog.DebugContext(ctx, "Command received", "args", redactArgs(req.Args))
+		return next(ctx, req)
+	}
+}
+
+// dispatchHandler returns the handler at the end of the chain, dispatching the possibly
+// rewritten arguments.
+func dispatchHandler(dispatch func(ctx context.Context, params commandParams) commandResult) Handler {
+	return func(ctx context.Context, req *Request) resptypes.RespSerializable {
+		if len(req.Args) == 0 {
+			return resptypes.SimpleError{Val: errEmptyRequest}
+		}
+
+		return dispatch(ctx, resptypes.ToBulkStringArray(req.Args))
+	}
+}
+
+// newRequest returns the request of c for params.
+func newRequest(c *client, user string, params commandParams, commands commandMap) *Request {
+	return &Request{
+		Args:     argsOf(params),
+		Client:   ClientInfo{ID: c.id, Addr: c.addr, Name: c.name, DB: c.db, User: user},
+		commands: commands,
+	}
+}
@@ -0,0 +1,120 @@
+package redisserverlib_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/logger"
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+func TestMiddleware(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	t.Cleanup(func() { cp.Close() })
+
+	var seen []redisserverlib.ClientInfo
+	var replies []string
+	cp.Use(func(next redisserverlib.Handler) redisserverlib.Handler {
+		return func(ctx context.Context, req *redisserverlib.Request) resptypes.RespSerializable {
+			seen = append(seen, req.Client)
+			reply := next(ctx, req)
+			replies = append(replies, reply.ToRespString())
+			return reply
+		}
+	})
+	cp.Use(func(next redisserverlib.Handler) redisserverlib.Handler {
+		return func(ctx context.Context, req *redisserverlib.Request) resptypes.RespSerializable {
+			if req.Args[0] == "FLUSHALL" {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR FLUSHALL is forbidden")}
+			}
+
+			return next(ctx, req)
+		}
+	})
+	cp.Use(func(next redisserverlib.Handler) redisserverlib.Handler {
+		return func(ctx context.Context, req *redisserverlib.Request) resptypes.RespSerializable {
+			if req.Client.Name == "tenant" {
+				for _, i := range req.KeyIndexes() {
+					req.Args[i] = "tenant:" + req.Args[i]
+				}
+			}
+
+			return next(ctx, req)
+		}
+	})
+
+	ctx, out := cp.ConnectClient(context.WithValue(context.Background(), logger.ClientKey, "127.0.0.1:50000"))
+	c := testClient{ctx: ctx, cp: cp, out: out}
+	admin := newTestClient(cp)
+
+	t.Run("Short-circuit", func(t *testing.T) {
+		admin.expect(t, "+OK\r\n", "SET", "k", "v")
+		c.expect(t, "-ERR FLUSHALL is forbidden\r\n", "FLUSHALL")
+		if last := replies[len(replies)-1]; last != "-ERR FLUSHALL is forbidden\r\n" {
+			t.Errorf("Outer middleware saw %q", last)
+		}
+
+		admin.expect(t, "$1\r\nv\r\n", "GET", "k")
+	})
+
+	t.Run("Client info", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SELECT", "2")
+		c.expect(t, "+PONG\r\n", "PING")
+		info := seen[len(seen)-1]
+		if info.Addr != "127.0.0.1:50000" || info.DB != 2 || info.User != "default" || info.ID == 0 {
+			t.Errorf("Client = %+v", info)
+		}
+
+		c.expect(t, "+OK\r\n", "SELECT", "0")
+	})
+
+	t.Run("Malformed requests are logged", func(t *testing.T) {
+		var logs lockedBuffer
+		previous := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		defer slog.SetDefault(previous)
+
+		if reply := c.cp.ExecuteCommand(c.ctx, "*x\r\n").ToRespString(); !strings.HasPrefix(reply, "-") {
+			t.Errorf("Malformed request = %q", reply)
+		}
+
+		if !strings.Contains(logs.String(), `msg="Malformed request received" respStr="*x\r\n"`) {
+			t.Errorf("logs = %q", logs.String())
+		}
+	})
+
+	t.Run("Rewriting keys", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CLIENT", "SETNAME", "tenant")
+		c.expect(t, "+OK\r\n", "SET", "a", "1")
+		c.expect(t, ":1\r\n", "RPUSH", "b", "2")
+		c.expect(t, "$1\r\n1\r\n", "GET", "a")
+		admin.expect(t, "$-1\r\n", "GET", "a")
+		admin.expect(t, "$1\r\n1\r\n", "GET", "tenant:a")
+		c.expect(t, ":2\r\n", "DEL", "a", "b")
+		admin.expect(t, ":0\r\n", "DEL", "tenant:a", "tenant:b")
+	})
+}
+
+// lockedBuffer collects the logs of background goroutines too.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
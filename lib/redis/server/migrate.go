@@ -0,0 +1,252 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	redisrdblib "github.com/codecrafters-io/redis-starter-go/lib/redis/rdb"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	migrate struct {
+		keyspace
+	}
+
+	migrateOptions struct {
+		keys    []string
+		copy    bool
+		replace bool
+		// AUTH or AUTH2 arguments to send to the target, if any
+		auth []string
+	}
+)
+
+func (c migrate) moniker() string {
+	return "MIGRATE"
+}
+
+func (c migrate) spec() commandSpec {
+	return commandSpec{
+		arity:      -6,
+		flags:      flagWrite | flagSlotMigration,
+		categories: []string{"keyspace", "dangerous"},
+		keySpecs: []keySpec{
+			{flags: keyMigrated, index: 3, keyStep: 1},
+			{flags: keyMigrated, keyword: "KEYS", startFrom: -2, lastKey: -1, keyStep: 1},
+		},
+		group:      "generic",
+		summary:    "Atomically transfers a key from one Redis instance to another.",
+		since:      "2.6.0",
+		complexity: "This command actually executes a DUMP+DEL in the source instance, and a RESTORE in the target instance. See the pages of these commands for time complexity. Also an O(N) data transfer between the two instances is performed.",
+		args: []commandArg{
+			{name: "host", typ: argString},
+			{name: "port", typ: argInteger},
+			{name: "key-selector", typ: argOneOf, args: []commandArg{
+				{name: "key", typ: argKey},
+				{name: "empty-string", typ: argPureToken, token: `""`},
+			}},
+			{name: "destination-db", typ: argInteger},
+			{name: "timeout", typ: argInteger},
+			{name: "copy", typ: argPureToken, token: "COPY", optional: true},
+			{name: "replace", typ: argPureToken, token: "REPLACE", optional: true},
+			{name: "authentication", typ: argOneOf, optional: true, args: []commandArg{
+				{name: "auth", typ: argString, token: "AUTH"},
+				{name: "auth2", typ: argBlock, token: "AUTH2", args: []commandArg{
+					{name: "username", typ: argString},
+					{name: "password", typ: argString},
+				}},
+			}},
+			{name: "keys", typ: argKey, token: "KEYS", optional: true, multiple: true, keySpec: 1},
+		},
+	}
+}
+
+func (c migrate) keys(params commandParams) []string {
+	options, err := parseMigrateOptions(params)
+	if err != nil {
+		return nil
+	}
+
+	return options.keys
+}
+
+func (c migrate) getUsage() string {
+	return `
+usage:
+	MIGRATE host port key|"" destination-db timeout [COPY] [REPLACE] [AUTH password | AUTH2 username password] [KEYS key [key ...]]
+summary:
+	Atomically transfer keys from the source instance to the destination instance. On success the keys are
+	deleted from the source, unless COPY is given. With KEYS, key must be the empty string.
+	The transfer fails with BUSYKEY if a key already exists on the destination, unless REPLACE is given.
+	timeout is the maximum idle time in milliseconds when communicating with the destination.
+	Returns OK, or NOKEY if none of the keys exist in the source.
+`
+}
+
+func parseMigrateOptions(params commandParams) (migrateOptions, error) {
+	options := migrateOptions{}
+	if len(params) < 6 {
+		return options, fmt.Errorf("ERR MIGRATE requires host, port, key, destination-db and timeout!")
+	}
+
+	for i := 6; i < len(params); i++ {
+		switch option := strings.ToUpper(params[i].Val); {
+		case option == "COPY":
+			options.copy = true
+		case option == "REPLACE":
+			options.replace = true
+		case option == "AUTH" && i+1 < len(params):
+			options.auth = []string{"AUTH", params[i+1].Val}
+			i++
+		case option == "AUTH2" && i+2 < len(params):
+			options.auth = []string{"AUTH", params[i+1].Val, params[i+2].Val}
+			i += 2
+		case option == "KEYS":
+			if params[3].Val != "" {
+				return options, fmt.Errorf("ERR When using MIGRATE KEYS option, the key argument must be set to the empty string")
+			}
+
+			for _, key := range params[i+1:] {
+				options.keys = append(options.keys, key.Val)
+			}
+
+			i = len(params)
+		default:
+			return options, fmt.Errorf("ERR syntax error")
+		}
+	}
+
+	if params[3].Val != "" {
+		options.keys = []string{params[3].Val}
+	}
+
+	return options, nil
+}
+
+func (c migrate) execute(ctx context.Context, params commandParams) commandResult {
+	options, err := parseMigrateOptions(params)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	destinationDb, err := strconv.Atoi(params[4].Val)
+	if err != nil || destinationDb < 0 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	timeoutMs, err := strconv.ParseInt(params[5].Val, 10, 64)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	timeout := time.Duration(timeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	// The keys are serialized up front; the write lock keeps them unchanged until they are deleted
+	keys := []string{}
+	restores := [][]string{}
+	for _, key := range options.keys {
+		dsVal, ttl, exists := c.db(ctx).GetWithTTL(key)
+		if !exists {
+			continue
+		}
+
+		payload, err := redisrdblib.Dump(dsVal)
+		if err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+		}
+
+		ttlMs := "0"
+		if ttl > 0 {
+			ttlMs = strconv.FormatInt(max(1, ttl.Milliseconds()), 10)
+		}
+
+		restore := []string{"RESTORE-ASKING", key, ttlMs, string(payload)}
+		if options.replace {
+			restore = append(restore, "REPLACE")
+		}
+
+		keys = append(keys, key)
+		restores = append(restores, restore)
+	}
+
+	if len(keys) == 0 {
+		propagateAs(ctx)
+		return resptypes.SimpleString{Val: "NOKEY"}
+	}
+
+	migrated, err := sendMigration(net.JoinHostPort(params[1].Val, params[2].Val), timeout, options.auth, destinationDb, restores)
+	if options.copy || migrated == 0 {
+		propagateAs(ctx)
+	} else {
+		propagateAs(ctx, append([]string{"DEL"}, keys[:migrated]...)...)
+		for _, key := range keys[:migrated] {
+			c.db(ctx).Delete(key)
+			c.modified(ctx, key)
+			c.notify(ctx, notifyGeneric, "del", key)
+		}
+	}
+
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
+
+// sendMigration pipelines the RESTORE commands to the target and returns how many of them
+// succeeded before the first failure.
+func sendMigration(addr string, timeout time.Duration, auth []string, db int, restores [][]string) (int, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("IOERR error or timeout connecting to the client")
+	}
+	defer conn.Close()
+
+	buf := []byte{}
+	preamble := 0
+	if len(auth) > 0 {
+		buf = redislib.AppendCommand(buf, auth...)
+		preamble++
+	}
+
+	buf = redislib.AppendCommand(buf, "SELECT", strconv.Itoa(db))
+	preamble++
+	for _, restore := range restores {
+		buf = redislib.AppendCommand(buf, restore...)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(buf); err != nil {
+		return 0, fmt.Errorf("IOERR error or timeout writing to target instance")
+	}
+
+	reader := bufio.NewReader(conn)
+	migrated := 0
+	for i := range preamble + len(restores) {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return migrated, fmt.Errorf("IOERR error or timeout reading to target instance")
+		}
+
+		if reply, failed := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "-"); failed {
+			return migrated, fmt.Errorf("ERR Target instance replied with error: %s", reply)
+		}
+
+		if i >= preamble {
+			migrated++
+		}
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,173 @@
+package redisserverlib
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// monitors streams the commands the server runs to the clients that called MONITOR.
+	// Lines are offered to each monitor without waiting, so a monitor that does not keep up
+	// loses lines instead of slowing down the commands.
+	monitors struct {
+		mu      sync.RWMutex
+		clients map[int64]*client
+		// Tells feed whether it has to format lines without taking the lock
+		count atomic.Int64
+		// Lines not delivered because the output buffer of a monitor was full
+		dropped atomic.Int64
+	}
+)
+
+const (
+	redactedArg = "(redacted)"
+)
+
+func newMonitors() *monitors {
+	return &monitors{clients: make(map[int64]*client)}
+}
+
+// add makes c a monitor. The confirmation is pushed while holding the lock so that the
+// monitor never sees a line before it.
+func (m *monitors) add(c *client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c.push(resptypes.SimpleString{Val: "OK"})
+	if _, exists := m.clients[c.id]; !exists {
+		m.clients[c.id] = c
+		m.count.Add(1)
+	}
+}
+
+func (m *monitors) remove(c *client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.clients[c.id]; exists {
+		delete(m.clients, c.id)
+		m.count.Add(-1)
+	}
+}
+
+// feed sends the command c ran at start on database db to every monitor.
+func (m *monitors) feed(c *client, db int, start time.Time, params commandParams) {
+	if m.count.Load() == 0 {
+		return
+	}
+
+	addr := c.addr
+	if addr == "" {
+		// E.g. the pseudo clients replaying the AOF or applying the replication stream
+		addr = "internal"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "+%d.%06d [%d %s]", start.Unix(), start.Nanosecond()/1000, db, addr)
+	for _, arg := range redactArgs(argsOf(params)) {
+		sb.WriteByte(' ')
+		writeRepr(&sb, arg)
+	}
+
+	sb.WriteString("\r\n")
+	line := sb.String()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, monitor := range m.clients {
+		if !monitor.offer(line) {
+			m.dropped.Add(1)
+		}
+	}
+}
+
+func (m *monitors) info() []string {
+	return []string{fmt.Sprintf("monitor_dropped_lines:%d", m.dropped.Load())}
+}
+
+// redactArgs hides the credentials given to commands, keeping the arguments that are not secret.
+func redactArgs(args []string) []string {
+	redacted := func(from int, count int) []string {
+		args = append([]string(nil), args...)
+		for i := from; i < min(from+count, len(args)); i++ {
+			args[i] = redactedArg
+		}
+
+		return args
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		return redacted(1, len(args))
+	case "HELLO":
+		for i := 1; i < len(args); i++ {
+			if strings.EqualFold(args[i], "AUTH") {
+				return redacted(i+1, 2)
+			}
+		}
+	case "ACL":
+		if len(args) > 3 && strings.EqualFold(args[1], "SETUSER") {
+			args = append([]string(nil), args...)
+			for i := 3; i < len(args); i++ {
+				if strings.IndexAny(args[i], "><#!") == 0 {
+					args[i] = redactedArg
+				}
+			}
+		}
+	case "CONFIG":
+		if len(args) > 3 && strings.EqualFold(args[1], "SET") {
+			args = append([]string(nil), args...)
+			for i := 2; i+1 < len(args); i += 2 {
+				switch strings.ToLower(args[i]) {
+				case "requirepass", "masterauth", "masteruser":
+					args[i+1] = redactedArg
+				}
+			}
+		}
+	case "MIGRATE":
+		for i := 6; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "AUTH":
+				return redacted(i+1, 1)
+			case "AUTH2":
+				return redacted(i+1, 2)
+			}
+		}
+	}
+
+	return args
+}
+
+// writeRepr writes s quoted like Redis does in MONITOR lines, with the bytes that are not
+// printable escaped.
+func writeRepr(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			if b < ' ' || b > '~' {
+				fmt.Fprintf(sb, `\x%02x`, b)
+			} else {
+				sb.WriteByte(b)
+			}
+		}
+	}
+
+	sb.WriteByte('"')
+}
@@ -0,0 +1,72 @@
+package redisserverlib_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+var monitorTimestamp = regexp.MustCompile(`^\+\d+\.\d{6} `)
+
+// expectMonitorLine reads the next MONITOR line, ignoring its timestamp.
+func (c testClient) expectMonitorLine(t *testing.T, expected string) {
+	t.Helper()
+	select {
+	case line := <-c.out:
+		if !monitorTimestamp.MatchString(line) || monitorTimestamp.ReplaceAllString(line, "") != expected {
+			t.Errorf("MONITOR line %q; Expected: %q", line, expected)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for a MONITOR line; Expected: %q", expected)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithOutputBufferLimit(4))
+	t.Cleanup(func() { cp.Close() })
+	monitor := newTestClient(cp)
+	c := newTestClient(cp)
+
+	monitor.expect(t, "", "MONITOR")
+	monitor.expectPush(t, "+OK\r\n")
+
+	t.Run("Commands of every client", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SELECT", "2")
+		monitor.expectMonitorLine(t, "[0 internal] \"SELECT\" \"2\"\r\n")
+		c.expect(t, "+OK\r\n", "set", "k", "a \"quoted\"\nvalue\x01")
+		monitor.expectMonitorLine(t, "[2 internal] \"set\" \"k\" \"a \\\"quoted\\\"\\nvalue\\x01\"\r\n")
+	})
+
+	t.Run("Administrative commands are left out", func(t *testing.T) {
+		c.do("CONFIG", "GET", "maxclients")
+		c.do("SLOWLOG", "LEN")
+		c.do("PING")
+		monitor.expectMonitorLine(t, "[2 internal] \"PING\"\r\n")
+	})
+
+	t.Run("Credentials are redacted", func(t *testing.T) {
+		c.do("MIGRATE", "127.0.0.1", "1", "k", "0", "10", "AUTH", "secret")
+		monitor.expectMonitorLine(t, "[2 internal] \"MIGRATE\" \"127.0.0.1\" \"1\" \"k\" \"0\" \"10\" \"AUTH\" \"(redacted)\"\r\n")
+		c.do("MIGRATE", "127.0.0.1", "1", "", "0", "10", "AUTH2", "user", "secret", "KEYS", "k")
+		monitor.expectMonitorLine(t, "[2 internal] \"MIGRATE\" \"127.0.0.1\" \"1\" \"\" \"0\" \"10\" \"AUTH2\" \"(redacted)\" \"(redacted)\" \"KEYS\" \"k\"\r\n")
+	})
+
+	t.Run("Slow monitors lose lines", func(t *testing.T) {
+		for range 10 {
+			c.do("PING")
+		}
+
+		if dropped := c.infoField("stats", "monitor_dropped_lines"); dropped != "6" {
+			t.Errorf("monitor_dropped_lines = %s", dropped)
+		}
+
+		for range 4 {
+			monitor.expectMonitorLine(t, "[2 internal] \"PING\"\r\n")
+		}
+	})
+
+	cp.DisconnectClient(monitor.ctx)
+	c.do("PING")
+}
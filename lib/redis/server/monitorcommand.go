@@ -0,0 +1,50 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	monitorCmd struct {
+		*monitors
+	}
+)
+
+func (c monitorCmd) moniker() string {
+	return "MONITOR"
+}
+
+func (c monitorCmd) spec() commandSpec {
+	return commandSpec{
+		arity:   1,
+		flags:   flagAdmin,
+		group:   "server",
+		summary: "Listens for all requests received by the server in real-time.",
+		since:   "1.0.0",
+	}
+}
+
+func (c monitorCmd) getUsage() string {
+	return `
+usage:
+	MONITOR
+summary:
+	Streams every command processed by the server, as lines like
+	+1700000000.123456 [0 127.0.0.1:51234] "SET" "key" "value"
+	holding the time, the database and the address of the client that ran it.
+	Administrative commands are not shown and credentials are redacted. Lines a slow monitor
+	cannot receive in time are dropped and counted in the monitor_dropped_lines field of INFO stats.
+`
+}
+
+func (c monitorCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR MONITOR takes no arguments! %s", c.getUsage())}
+	}
+
+	c.add(clientFromContext(ctx))
+	return resptypes.NoReply{}
+}
@@ -0,0 +1,83 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	move struct {
+		keyspace
+	}
+)
+
+func (c move) moniker() string {
+	return "MOVE"
+}
+
+func (c move) spec() commandSpec {
+	return commandSpec{
+		arity:      3,
+		flags:      flagWrite | flagFast,
+		categories: []string{"keyspace"},
+		keySpecs:   firstKey(keyRWDelete),
+		group:      "generic",
+		summary:    "Moves a key to another database.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "db", typ: argInteger}},
+	}
+}
+
+func (c move) getUsage() string {
+	return `
+usage:
+	MOVE key db
+summary:
+	Move key from the currently selected database to the specified destination database.
+	When key already exists in the destination database, or it does not exist in the source database, it does nothing.
+	Returns 1 if key was moved, 0 otherwise.
+`
+}
+
+func (c move) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR MOVE requires key and db! %s", c.getUsage())}
+	}
+
+	key := params[1].Val
+	dstIndex, err := c.parseDbIndex(params[2].Val)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	if dstIndex == clientFromContext(ctx).db {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR source and destination objects are the same")}
+	}
+
+	src := c.db(ctx)
+	dsVal, ttl, exists := src.GetWithTTL(key)
+	if !exists {
+		return resptypes.Integer{Val: 0}
+	}
+
+	moved := false
+	c.DB(dstIndex).GetOrCreate(key, func() redistypes.StoreValue {
+		moved = true
+		return dsVal
+	}, ttl)
+
+	if !moved {
+		return resptypes.Integer{Val: 0}
+	}
+
+	c.DB(dstIndex).Touch(key)
+	src.Delete(key)
+	c.modified(ctx, key)
+	c.notify(ctx, notifyGeneric, "move_from", key)
+	c.events.notify(notifyGeneric, "move_to", key, dstIndex)
+	return resptypes.Integer{Val: 1}
+}
@@ -0,0 +1,52 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	multi struct{}
+)
+
+func (c multi) moniker() string {
+	return "MULTI"
+}
+
+func (c multi) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagTransaction | flagFast,
+		categories: []string{"transaction"},
+		group:      "transactions",
+		summary:    "Starts a transaction.",
+		since:      "1.2.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c multi) getUsage() string {
+	return `
+usage:
+	MULTI
+summary:
+	Marks the start of a transaction block. Subsequent commands will be queued for atomic execution using EXEC.
+`
+}
+
+func (c multi) execute(ctx context.Context, params commandParams) commandResult {
+	client := clientFromContext(ctx)
+	if client.multi != nil {
+		client.multi.aborted = true
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR MULTI calls can not be nested")}
+	}
+
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR MULTI takes no arguments! %s", c.getUsage())}
+	}
+
+	client.multi = &transaction{}
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -0,0 +1,129 @@
+package redisserverlib
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+type (
+	// notifyClass is a set of keyspace event classes, as configured by notify-keyspace-events.
+	notifyClass uint32
+
+	// keyspaceEvents publishes keyspace and keyevent notifications through Pub/Sub.
+	keyspaceEvents struct {
+		classes atomic.Uint32
+		pubsub  *pubsub
+	}
+)
+
+const (
+	notifyKeyspace notifyClass = 1 << iota // K
+	notifyKeyevent                         // E
+	notifyGeneric                          // g
+	notifyString                           // $
+	notifyList                             // l
+	notifySet                              // s
+	notifyHash                             // h
+	notifyZset                             // z
+	notifyExpired                          // x
+	notifyEvicted                          // e
+	notifyStream                           // t
+	notifyKeyMiss                          // m
+	notifyNew                              // n
+
+	// A is an alias for every class except key misses and new keys
+	notifyAll = notifyGeneric | notifyString | notifyList | notifySet | notifyHash | notifyZset | notifyExpired | notifyEvicted | notifyStream
+)
+
+var (
+	// In the canonical order used when printing the flags back
+	notifyFlagLetters = []struct {
+		letter byte
+		class  notifyClass
+	}{
+		{'g', notifyGeneric},
+		{'$', notifyString},
+		{'l', notifyList},
+		{'s', notifySet},
+		{'h', notifyHash},
+		{'z', notifyZset},
+		{'x', notifyExpired},
+		{'e', notifyEvicted},
+		{'t', notifyStream},
+		{'m', notifyKeyMiss},
+		{'n', notifyNew},
+		{'K', notifyKeyspace},
+		{'E', notifyKeyevent},
+	}
+)
+
+func parseNotifyClasses(flags string) (notifyClass, error) {
+	classes := notifyClass(0)
+	for i := 0; i < len(flags); i++ {
+		if flags[i] == 'A' {
+			classes |= notifyAll
+			continue
+		}
+
+		found := false
+		for _, f := range notifyFlagLetters {
+			if f.letter == flags[i] {
+				classes |= f.class
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("Invalid event class character '%c'", flags[i])
+		}
+	}
+
+	return classes, nil
+}
+
+func (c notifyClass) String() string {
+	var sb strings.Builder
+	if c&notifyAll == notifyAll {
+		sb.WriteByte('A')
+	}
+
+	for _, f := range notifyFlagLetters {
+		if c&f.class == 0 || (f.class&notifyAll != 0 && c&notifyAll == notifyAll) {
+			continue
+		}
+
+		sb.WriteByte(f.letter)
+	}
+
+	return sb.String()
+}
+
+func newKeyspaceEvents(ps *pubsub) *keyspaceEvents {
+	return &keyspaceEvents{pubsub: ps}
+}
+
+func (e *keyspaceEvents) setClasses(classes notifyClass) {
+	e.classes.Store(uint32(classes))
+}
+
+func (e *keyspaceEvents) getClasses() notifyClass {
+	return notifyClass(e.classes.Load())
+}
+
+// notify publishes event for key in database db if the event's class is enabled.
+func (e *keyspaceEvents) notify(class notifyClass, event string, key string, db int) {
+	classes := e.getClasses()
+	if classes&class == 0 {
+		return
+	}
+
+	if classes&notifyKeyspace != 0 {
+		e.pubsub.publish(fmt.Sprintf("__keyspace@%d__:%s", db, key), event)
+	}
+
+	if classes&notifyKeyevent != 0 {
+		e.pubsub.publish(fmt.Sprintf("__keyevent@%d__:%s", db, event), key)
+	}
+}
@@ -0,0 +1,38 @@
+package redisserverlib_test
+
+import (
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestKeyspaceNotifications(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithNotifyKeyspaceEvents("KE$g"))
+	sub := newTestClient(cp)
+	cl := newTestClient(cp)
+
+	cl.expect(t, "*2\r\n$22\r\nnotify-keyspace-events\r\n$4\r\ng$KE\r\n", "CONFIG", "GET", "notify-*")
+
+	sub.expect(t, "", "PSUBSCRIBE", "__key*@0__:*")
+	sub.expectPush(t, "*3\r\n$10\r\npsubscribe\r\n$12\r\n__key*@0__:*\r\n:1\r\n")
+
+	cl.expect(t, "+OK\r\n", "SET", "k", "v")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@0__:*\r\n$16\r\n__keyspace@0__:k\r\n$3\r\nset\r\n")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@0__:*\r\n$18\r\n__keyevent@0__:set\r\n$1\r\nk\r\n")
+
+	// Lists are not enabled
+	cl.expect(t, ":1\r\n", "RPUSH", "l", "a")
+	sub.expectNoPush(t)
+
+	cl.expect(t, "+OK\r\n", "CONFIG", "SET", "notify-keyspace-events", "Exmn")
+	cl.expect(t, "$-1\r\n", "GET", "missing")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@0__:*\r\n$22\r\n__keyevent@0__:keymiss\r\n$7\r\nmissing\r\n")
+
+	cl.expect(t, "+OK\r\n", "SET", "e", "v", "PX", "10")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@0__:*\r\n$18\r\n__keyevent@0__:new\r\n$1\r\ne\r\n")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$12\r\n__key*@0__:*\r\n$22\r\n__keyevent@0__:expired\r\n$1\r\ne\r\n")
+
+	cl.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'notify-keyspace-events') - Invalid event class character 'Q'\r\n", "CONFIG", "SET", "notify-keyspace-events", "Q")
+	cl.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'databases') - can't set immutable config\r\n", "CONFIG", "SET", "databases", "4")
+	cl.expect(t, "*2\r\n$22\r\nnotify-keyspace-events\r\n$4\r\nxmnE\r\n", "CONFIG", "GET", "notify-keyspace-events")
+}
@@ -0,0 +1,382 @@
+package redisserverlib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	redisrdblib "github.com/codecrafters-io/redis-starter-go/lib/redis/rdb"
+	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
+)
+
+type (
+	// saveParam triggers a background save once at least changes modifications
+	// happened and seconds passed since the last successful save.
+	saveParam struct {
+		seconds int64
+		changes int64
+	}
+
+	// persistence writes RDB snapshots of the databases and loads them at startup.
+	persistence struct {
+		dbs redistypes.Databases
+		// Held exclusively while a snapshot is taken, so that no command modifies the data meanwhile
+		lock    sync.Locker
+		latency *latencyMonitor
+		// How long the last snapshot stopped the commands, in microseconds
+		lastSnapshotUsec atomic.Int64
+
+		mu         sync.Mutex
+		dir        string
+		dbfilename string
+		saveParams []saveParam
+
+		// Modifications since the last successful save
+		dirty            atomic.Int64
+		lastSave         atomic.Int64
+		lastBgsaveOk     atomic.Bool
+		lastBgsaveTry    atomic.Int64
+		bgsaveInProgress atomic.Bool
+		bgsaveScheduled  atomic.Bool
+
+		// Set when the file could not be loaded, so that automatic saves don't overwrite it
+		loadFailed bool
+		// Set while the dataset is loaded from the disk or from a primary
+		loading atomic.Bool
+
+		stop chan struct{}
+		wg   sync.WaitGroup
+	}
+)
+
+const (
+	// How often the save points are checked
+	saveCheckInterval = 100 * time.Millisecond
+	// How long to wait before retrying a failed automatic background save
+	bgsaveRetryDelay = 5 * time.Second
+)
+
+var (
+	errBgsaveInProgress = errors.New("ERR Background save already in progress")
+	lastTempFileId      atomic.Int64
+)
+
+func newPersistence(dbs redistypes.Databases, lock sync.Locker, latency *latencyMonitor, options processorOptions) *persistence {
+	p := &persistence{
+		dbs:        dbs,
+		lock:       lock,
+		latency:    latency,
+		dir:        options.dir,
+		dbfilename: options.dbfilename,
+		stop:       make(chan struct{}),
+	}
+
+	if params, err := parseSaveParams(options.save); err != nil {
+		slog.Warn("Ignoring invalid save", "save", options.save, "error", err)
+	} else {
+		p.saveParams = params
+	}
+
+	p.lastSave.Store(time.Now().Unix())
+	p.lastBgsaveOk.Store(true)
+	return p
+}
+
+// parseSaveParams parses save points in the redis.conf form "<seconds> <changes> [<seconds> <changes> ...]".
+// An empty string disables automatic saving.
+func parseSaveParams(str string) ([]saveParam, error) {
+	fields := strings.Fields(str)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("Invalid save parameters")
+	}
+
+	params := make([]saveParam, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil || seconds < 1 {
+			return nil, fmt.Errorf("Invalid save parameters")
+		}
+
+		changes, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil || changes < 0 {
+			return nil, fmt.Errorf("Invalid save parameters")
+		}
+
+		params = append(params, saveParam{seconds: seconds, changes: changes})
+	}
+
+	return params, nil
+}
+
+func formatSaveParams(params []saveParam) string {
+	fields := make([]string, 0, 2*len(params))
+	for _, param := range params {
+		fields = append(fields, strconv.FormatInt(param.seconds, 10), strconv.FormatInt(param.changes, 10))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (p *persistence) path() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return filepath.Join(p.dir, p.dbfilename)
+}
+
+// load replaces the content of the databases with the RDB file, if there is one.
+func (p *persistence) load() error {
+	if _, err := p.loadFile(p.path()); err != nil {
+		p.loadFailed = true
+		return err
+	}
+
+	return nil
+}
+
+// loadFile adds the keys of an RDB file to the databases. It reports whether the file exists.
+func (p *persistence) loadFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	if err := p.loadFrom(f, path); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
+
+// loadFrom adds the keys of an RDB snapshot to the databases, skipping keys whose expiry
+// already elapsed. source names the snapshot in logs and errors.
+func (p *persistence) loadFrom(r io.Reader, source string) error {
+	start := time.Now()
+	loaded := 0
+	err := redisrdblib.Read(r, func(db int, entry redisrdblib.Entry) error {
+		if db < 0 || db >= p.dbs.Len() {
+			return fmt.Errorf("database %d is out of range, only %d are configured", db, p.dbs.Len())
+		}
+
+		ttl := time.Duration(0)
+		if !entry.ExpiresAt.IsZero() {
+			if ttl = time.Until(entry.ExpiresAt); ttl <= 0 {
+				return nil
+			}
+		}
+
+		p.dbs.DB(db).Set(entry.Key, entry.Value, ttl)
+		loaded++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", source, err)
+	}
+
+	slog.Info("DB loaded", "source", source, "keys", loaded, "duration", time.Since(start))
+	return nil
+}
+
+// snapshot copies the databases. The caller must hold the lock exclusively, so every command
+// waits for the copy of the whole dataset, which takes as much memory again. This is what
+// Redis' fork stands for, and it is measured the same way: by the fork latency event and
+// latest_fork_usec.
+func (p *persistence) snapshot() []redisrdblib.Database {
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		p.lastSnapshotUsec.Store(elapsed.Microseconds())
+		p.latency.add(latencyEventFork, elapsed)
+	}()
+
+	dbs := make([]redisrdblib.Database, p.dbs.Len())
+	for i := range dbs {
+		dbs[i].Index = i
+		p.dbs.DB(i).ForEach(func(key redistypes.StoreKey, value redistypes.StoreValue, expiresAt time.Time) {
+			dbs[i].Entries = append(dbs[i].Entries, redisrdblib.Entry{Key: key, Value: value.Clone(), ExpiresAt: expiresAt})
+		})
+	}
+
+	return dbs
+}
+
+// write stores a snapshot in the RDB file. dirty is the modification count at the time of the snapshot.
+func (p *persistence) write(dbs []redisrdblib.Database, dirty int64) error {
+	if err := writeRDBFile(p.path(), dbs); err != nil {
+		return err
+	}
+
+	p.dirty.Add(-dirty)
+	p.lastSave.Store(time.Now().Unix())
+	return nil
+}
+
+// writeRDBFile writes a snapshot to a temporary file which replaces path once complete,
+// so that a crash never leaves a partial file behind.
+func writeRDBFile(path string, dbs []redisrdblib.Database) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf("temp-%d-%d.rdb", os.Getpid(), lastTempFileId.Add(1)))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = redisrdblib.Write(f, dbs)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+	}
+
+	return err
+}
+
+// save synchronously writes a snapshot. The caller must hold the lock exclusively.
+func (p *persistence) save() error {
+	if p.bgsaveInProgress.Load() {
+		return errBgsaveInProgress
+	}
+
+	if err := p.write(p.snapshot(), p.dirty.Load()); err != nil {
+		slog.Error("Failed saving the DB", "error", err)
+		return err
+	}
+
+	slog.Info("DB saved on disk", "path", p.path())
+	return nil
+}
+
+// backgroundSave takes a snapshot and writes it while commands keep running. Only the
+// writing happens in the background, see snapshot. The caller must hold the lock exclusively.
+func (p *persistence) backgroundSave() error {
+	if !p.bgsaveInProgress.CompareAndSwap(false, true) {
+		return errBgsaveInProgress
+	}
+
+	p.bgsaveScheduled.Store(false)
+	p.lastBgsaveTry.Store(time.Now().Unix())
+	dbs := p.snapshot()
+	dirty := p.dirty.Load()
+
+	p.wg.Go(func() {
+		defer p.bgsaveInProgress.Store(false)
+		if err := p.write(dbs, dirty); err != nil {
+			slog.Error("Background saving failed", "error", err)
+			p.lastBgsaveOk.Store(false)
+			return
+		}
+
+		p.lastBgsaveOk.Store(true)
+		slog.Info("Background saving terminated with success", "path", p.path())
+	})
+
+	return nil
+}
+
+// shouldSave reports whether a save point or a scheduled BGSAVE calls for a background save.
+func (p *persistence) shouldSave() bool {
+	if p.loadFailed || p.bgsaveInProgress.Load() {
+		return false
+	}
+
+	if p.bgsaveScheduled.Load() {
+		return true
+	}
+
+	now := time.Now().Unix()
+	if !p.lastBgsaveOk.Load() && now-p.lastBgsaveTry.Load() < int64(bgsaveRetryDelay/time.Second) {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	dirty := p.dirty.Load()
+	for _, param := range p.saveParams {
+		if dirty > 0 && dirty >= param.changes && now-p.lastSave.Load() >= param.seconds {
+			return true
+		}
+	}
+
+	return false
+}
+
+// start runs the save point checks until close is called.
+func (p *persistence) start() {
+	p.wg.Go(func() {
+		ticker := time.NewTicker(saveCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				if !p.shouldSave() {
+					continue
+				}
+
+				p.lock.Lock()
+				p.backgroundSave()
+				p.lock.Unlock()
+			}
+		}
+	})
+}
+
+// close stops the save point checks, waits for a running background save and,
+// like a Redis shutdown, saves a final snapshot when save points are configured.
+func (p *persistence) close() error {
+	close(p.stop)
+	p.wg.Wait()
+
+	p.mu.Lock()
+	saveOnClose := len(p.saveParams) > 0 && !p.loadFailed
+	p.mu.Unlock()
+	if !saveOnClose {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.save()
+}
+
+func (p *persistence) statsInfo() []string {
+	return []string{fmt.Sprintf("latest_fork_usec:%d", p.lastSnapshotUsec.Load())}
+}
+
+func (p *persistence) info() []string {
+	status := "ok"
+	if !p.lastBgsaveOk.Load() {
+		status = "err"
+	}
+
+	return []string{
+		fmt.Sprintf("loading:%d", boolToInt(p.loading.Load())),
+		fmt.Sprintf("rdb_changes_since_last_save:%d", p.dirty.Load()),
+		fmt.Sprintf("rdb_bgsave_in_progress:%d", boolToInt(p.bgsaveInProgress.Load())),
+		fmt.Sprintf("rdb_last_save_time:%d", p.lastSave.Load()),
+		"rdb_last_bgsave_status:" + status,
+	}
+}
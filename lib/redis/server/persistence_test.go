@@ -0,0 +1,113 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(dir))
+	cl := newTestClient(cp)
+
+	cl.expect(t, "+OK\r\n", "SET", "str", "hello")
+	cl.expect(t, "+OK\r\n", "SET", "volatile", "v", "PX", "100000")
+	cl.expect(t, "+OK\r\n", "SET", "gone", "v", "PX", "50")
+	cl.expect(t, ":2\r\n", "RPUSH", "list", "a", "b")
+	cl.expect(t, "$3\r\n1-1\r\n", "XADD", "stream", "1-1", "f", "v")
+	cl.expect(t, "+OK\r\n", "SELECT", "3")
+	cl.expect(t, "+OK\r\n", "SET", "other", "db")
+	cl.expect(t, "+OK\r\n", "SAVE")
+
+	before := cl.do("LASTSAVE")
+	if before == "" || before[0] != ':' {
+		t.Errorf("LASTSAVE = %q; Expected an integer", before)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	loaded := newTestClient(redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(dir)))
+	loaded.expect(t, "$5\r\nhello\r\n", "GET", "str")
+	loaded.expect(t, "$1\r\nv\r\n", "GET", "volatile")
+	loaded.expect(t, "$-1\r\n", "GET", "gone")
+	loaded.expect(t, "*2\r\n$1\r\na\r\n$1\r\nb\r\n", "LRANGE", "list", "0", "-1")
+	loaded.expect(t, "*1\r\n*2\r\n$3\r\n1-1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "XRANGE", "stream", "-", "+")
+	loaded.expect(t, "+OK\r\n", "SELECT", "3")
+	loaded.expect(t, "$2\r\ndb\r\n", "GET", "other")
+}
+
+func TestBackgroundSaveIsPointInTime(t *testing.T) {
+	dir := t.TempDir()
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(dir), redisserverlib.WithDbFilename("snap.rdb"))
+	cl := newTestClient(cp)
+
+	cl.expect(t, ":1\r\n", "RPUSH", "list", "before")
+	cl.expect(t, "+Background saving started\r\n", "BGSAVE")
+	cl.expect(t, ":2\r\n", "RPUSH", "list", "after")
+	cl.expect(t, "+OK\r\n", "SET", "after", "v")
+
+	// Close waits for the background save and does not save again without save points
+	if err := cp.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	loaded := newTestClient(redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(dir), redisserverlib.WithDbFilename("snap.rdb")))
+	loaded.expect(t, "*1\r\n$6\r\nbefore\r\n", "LRANGE", "list", "0", "-1")
+	loaded.expect(t, "$-1\r\n", "GET", "after")
+}
+
+func TestBackgroundSaveStallIsMeasured(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	defer cp.Close()
+	cl := newTestClient(cp)
+
+	if usec := cl.infoField("stats", "latest_fork_usec"); usec != "0" {
+		t.Errorf("latest_fork_usec before any snapshot = %q", usec)
+	}
+
+	for i := range 100 {
+		cl.do(append([]string{"RPUSH", fmt.Sprint("list", i)}, slices.Repeat([]string{"element"}, 1000)...)...)
+	}
+
+	cl.expect(t, "+Background saving started\r\n", "BGSAVE")
+	if usec, err := strconv.Atoi(cl.infoField("stats", "latest_fork_usec")); err != nil || usec <= 0 {
+		t.Errorf("latest_fork_usec = %d, %v; Expected the time the copy took", usec, err)
+	}
+}
+
+func TestSavePoints(t *testing.T) {
+	dir := t.TempDir()
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(dir), redisserverlib.WithSave("1 2"))
+	defer cp.Close()
+	cl := newTestClient(cp)
+
+	cl.expect(t, "*2\r\n$4\r\nsave\r\n$3\r\n1 2\r\n", "CONFIG", "GET", "save")
+	cl.expect(t, "+OK\r\n", "SET", "a", "1")
+	time.Sleep(1200 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(dir, "dump.rdb")); err == nil {
+		t.Fatalf("Saved after a single change; Expected the save point to require 2")
+	}
+
+	cl.expect(t, "+OK\r\n", "SET", "b", "2")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "dump.rdb")); err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("No snapshot written after reaching the save point")
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cl.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'dbfilename') - dbfilename can't be a path, just a filename\r\n", "CONFIG", "SET", "dbfilename", "../x.rdb")
+	cl.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'save') - Invalid save parameters\r\n", "CONFIG", "SET", "save", "10")
+}
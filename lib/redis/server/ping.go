@@ -14,6 +14,19 @@ func (c ping) moniker() string {
 	return "PING"
 }
 
+func (c ping) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagPubSub | flagFast,
+		categories: []string{"connection"},
+		group:      "connection",
+		summary:    "Returns the server's liveliness response.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "message", typ: argString, optional: true}},
+	}
+}
+
 func (c ping) getUsage() string {
 	return `
 usage:
@@ -29,6 +42,15 @@ summary:
 }
 
 func (c ping) execute(ctx context.Context, params commandParams) commandResult {
+	if clientFromContext(ctx).subscriptionCount() > 0 {
+		message := resptypes.BulkString{}
+		if len(params) > 1 {
+			message = params[1]
+		}
+
+		return resptypes.Array[resptypes.RespSerializable]{resptypes.NewBulkString("pong"), message}
+	}
+
 	if len(params) > 1 {
 		return params[1]
 	}
@@ -0,0 +1,181 @@
+package redisserverlib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// metricsServer serves the metrics in the Prometheus text exposition format on /metrics,
+	// and the health checks of the server on /healthz and /readyz.
+	metricsServer struct {
+		r        *redisCommandProcessor
+		keyspace keyspace
+		server   *http.Server
+		wg       sync.WaitGroup
+	}
+
+	// promWriter writes metric families in the Prometheus text exposition format.
+	promWriter struct {
+		w io.Writer
+	}
+)
+
+var (
+	promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func newMetricsServer(r *redisCommandProcessor, k keyspace) *metricsServer {
+	s := &metricsServer{r: r, keyspace: k}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", s.metrics)
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// start listens on addr, as "<host>:<port>", and serves the requests in the background.
+func (s *metricsServer) start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Serving metrics", "endpoint", listener.Addr().String())
+	s.wg.Go(func() {
+		if err := s.server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics endpoint failed", "error", err)
+		}
+	})
+	return nil
+}
+
+func (s *metricsServer) close() {
+	s.server.Close()
+	s.wg.Wait()
+}
+
+// healthz reports whether the server is alive, which it is until it shuts down.
+func (s *metricsServer) healthz(w http.ResponseWriter, req *http.Request) {
+	if s.r.shuttingDown.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
+
+// readyz reports whether the server can serve its dataset, which it cannot while loading it.
+func (s *metricsServer) readyz(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case s.r.shuttingDown.Load():
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case s.r.persistence.loading.Load():
+		http.Error(w, "loading", http.StatusServiceUnavailable)
+	default:
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+func (s *metricsServer) metrics(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p := promWriter{w}
+	m := s.r.metrics
+
+	p.metric("redis_uptime_in_seconds", "gauge", "Seconds since the server started.", int64(time.Since(m.start)/time.Second))
+	p.metric("redis_loading", "gauge", "1 while the dataset is being loaded.", boolToInt(s.r.persistence.loading.Load()))
+	p.metric("redis_connected_clients", "gauge", "Clients currently connected.", s.r.clients.len())
+	p.metric("redis_blocked_clients", "gauge", "Clients waiting in a blocking command.", m.blockedClients.Load())
+	p.metric("redis_connections_received_total", "counter", "Connections accepted by the server.", m.connectionsReceived.Load())
+	p.metric("redis_commands_processed_total", "counter", "Commands processed by the server.", m.commandsProcessed.Load())
+	p.metric("redis_net_input_bytes_total", "counter", "Bytes read from clients.", m.netInputBytes.Load())
+	p.metric("redis_net_output_bytes_total", "counter", "Bytes written to clients.", m.netOutputBytes.Load())
+	p.metric("redis_error_replies_total", "counter", "Error replies sent to clients.", m.errorReplies.Load())
+
+	misses := m.keyspaceMisses.Load()
+	p.metric("redis_keyspace_hits_total", "counter", "Key lookups that found the key.", max(0, m.keyspaceLookups.Load()-misses))
+	p.metric("redis_keyspace_misses_total", "counter", "Key lookups that did not find the key.", misses)
+	p.metric("redis_expired_keys_total", "counter", "Keys removed because their expiry elapsed.", m.expiredKeys.Load())
+
+	used, rss, peak, gcCycles := m.memory()
+	p.metric("redis_memory_used_bytes", "gauge", "Bytes allocated by the server.", used)
+	p.metric("redis_memory_used_rss_bytes", "gauge", "Bytes obtained from the operating system.", rss)
+	p.metric("redis_memory_used_peak_bytes", "gauge", "Most bytes ever allocated by the server.", peak)
+	p.metric("redis_gc_cycles_total", "counter", "Garbage collections run.", gcCycles)
+
+	commands, errorPrefixes := m.snapshot()
+	names := slices.Sorted(maps.Keys(commands))
+	p.family("redis_commands_total", "counter", "Calls of each command.")
+	for _, name := range names {
+		p.sample("redis_commands_total", commands[name].calls, "cmd", strings.ToLower(name))
+	}
+
+	p.family("redis_commands_duration_seconds_total", "counter", "Time spent running each command.")
+	for _, name := range names {
+		p.sample("redis_commands_duration_seconds_total", float64(commands[name].usec)/1e6, "cmd", strings.ToLower(name))
+	}
+
+	p.family("redis_commands_rejected_calls_total", "counter", "Calls of each command refused before running.")
+	for _, name := range names {
+		p.sample("redis_commands_rejected_calls_total", commands[name].rejected, "cmd", strings.ToLower(name))
+	}
+
+	p.family("redis_commands_failed_calls_total", "counter", "Calls of each command that replied with an error.")
+	for _, name := range names {
+		p.sample("redis_commands_failed_calls_total", commands[name].failed, "cmd", strings.ToLower(name))
+	}
+
+	p.family("redis_errors_total", "counter", "Error replies by prefix.")
+	for _, prefix := range slices.Sorted(maps.Keys(errorPrefixes)) {
+		p.sample("redis_errors_total", errorPrefixes[prefix], "err", prefix)
+	}
+
+	now := time.Now()
+	p.family("redis_db_keys", "gauge", "Keys in each database.")
+	expiring := make([]int, s.keyspace.Len())
+	for index := range s.keyspace.Len() {
+		keys, expires, _ := s.keyspace.size(index, now)
+		expiring[index] = expires
+		p.sample("redis_db_keys", keys, "db", fmt.Sprintf("db%d", index))
+	}
+
+	p.family("redis_db_keys_expiring", "gauge", "Keys with an expiry in each database.")
+	for index, expires := range expiring {
+		p.sample("redis_db_keys_expiring", expires, "db", fmt.Sprintf("db%d", index))
+	}
+}
+
+func (p promWriter) family(name string, typ string, help string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes one sample of a family, labelled by the given name and value pairs.
+func (p promWriter) sample(name string, value any, labels ...string) {
+	if len(labels) == 0 {
+		fmt.Fprintf(p.w, "%s %v\n", name, value)
+		return
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], promLabelEscaper.Replace(labels[i+1])))
+	}
+
+	fmt.Fprintf(p.w, "%s{%s} %v\n", name, strings.Join(pairs, ","), value)
+}
+
+// metric writes a family with a single unlabelled sample.
+func (p promWriter) metric(name string, typ string, help string, value any) {
+	p.family(name, typ, help)
+	p.sample(name, value)
+}
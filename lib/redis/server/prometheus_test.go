@@ -0,0 +1,88 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func httpGet(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	addr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithMetricsAddr(addr))
+	closed := false
+	t.Cleanup(func() {
+		if !closed {
+			cp.Close()
+		}
+	})
+	c := newTestClient(cp)
+
+	t.Run("Health checks", func(t *testing.T) {
+		for _, path := range []string{"/healthz", "/readyz"} {
+			if status, body := httpGet(t, "http://"+addr+path); status != http.StatusOK || body != "ok\n" {
+				t.Errorf("GET %s = %d %q", path, status, body)
+			}
+		}
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SET", "k", "v", "PX", "100000")
+		c.expect(t, "+OK\r\n", "SET", "other", "v")
+		c.expect(t, ":1\r\n", "RPUSH", "l", "a")
+		c.do("GET", "l")
+		c.do("NOSUCHCOMMAND")
+
+		status, body := httpGet(t, "http://"+addr+"/metrics")
+		if status != http.StatusOK {
+			t.Fatalf("GET /metrics = %d", status)
+		}
+
+		for _, line := range []string{
+			"# TYPE redis_connected_clients gauge\nredis_connected_clients 1\n",
+			"# TYPE redis_commands_total counter\n",
+			`redis_commands_total{cmd="set"} 2` + "\n",
+			`redis_commands_failed_calls_total{cmd="get"} 1` + "\n",
+			`redis_errors_total{err="NOTSUPPORTED"} 1` + "\n",
+			`redis_errors_total{err="WRONGTYPE"} 1` + "\n",
+			`redis_db_keys{db="db0"} 3` + "\n",
+			`redis_db_keys_expiring{db="db0"} 1` + "\n",
+			"redis_blocked_clients 0\n",
+			"redis_loading 0\n",
+			"# TYPE redis_memory_used_bytes gauge\n",
+		} {
+			if !strings.Contains(body, line) {
+				t.Errorf("GET /metrics is missing %q", line)
+			}
+		}
+
+		if stat := c.infoField("errorstats", "errorstat_WRONGTYPE"); stat != "count=1" {
+			t.Errorf("errorstat_WRONGTYPE = %s", stat)
+		}
+	})
+
+	closed = true
+	cp.Close()
+	if _, err := http.Get("http://" + addr + "/healthz"); err == nil {
+		t.Errorf("The metrics endpoint is still served after Close")
+	}
+}
@@ -0,0 +1,92 @@
+package redisserverlib
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+)
+
+type (
+	// propagatedCommand is a write command as it should be replayed elsewhere,
+	// together with the database it applies to.
+	propagatedCommand struct {
+		db   int
+		args []string
+	}
+
+	// propagationSink receives the effects of write commands in execution order.
+	// A batch must be applied atomically, e.g. a whole transaction.
+	propagationSink interface {
+		propagate(batch []propagatedCommand)
+	}
+
+	// propagator fans out write commands to the AOF and, later, other consumers.
+	propagator struct {
+		mu    sync.Mutex
+		sinks []propagationSink
+	}
+)
+
+func (p *propagator) addSink(s propagationSink) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sinks = append(p.sinks, s)
+}
+
+func (p *propagator) removeSink(s propagationSink) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sinks = slices.DeleteFunc(p.sinks, func(other propagationSink) bool { return other == s })
+}
+
+func (p *propagator) feed(batch []propagatedCommand) {
+	if len(batch) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.sinks {
+		s.propagate(batch)
+	}
+}
+
+// propagateAs replaces how the running write command is propagated, e.g. to make it
+// deterministic when replayed. Calling it without arguments propagates nothing.
+func propagateAs(ctx context.Context, args ...string) {
+	c := clientFromContext(ctx)
+	c.rewrite = true
+	if len(args) > 0 {
+		c.rewritten = append(c.rewritten, args)
+	}
+}
+
+// wrapInTransaction makes a batch apply atomically when replayed.
+func wrapInTransaction(batch []propagatedCommand) []propagatedCommand {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	wrapped := make([]propagatedCommand, 0, len(batch)+2)
+	wrapped = append(wrapped, propagatedCommand{db: batch[0].db, args: []string{"MULTI"}})
+	wrapped = append(wrapped, batch...)
+	return append(wrapped, propagatedCommand{db: batch[len(batch)-1].db, args: []string{"EXEC"}})
+}
+
+// appendPropagated serializes a batch, emitting SELECT whenever the database differs from *db,
+// the database last selected in the same stream.
+func appendPropagated(buf []byte, db *int, batch []propagatedCommand) []byte {
+	for _, cmd := range batch {
+		if cmd.db != *db {
+			buf = redislib.AppendCommand(buf, "SELECT", strconv.Itoa(cmd.db))
+			*db = cmd.db
+		}
+
+		buf = redislib.AppendCommand(buf, cmd.args...)
+	}
+
+	return buf
+}
@@ -0,0 +1,51 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	psubscribe struct {
+		*pubsub
+	}
+)
+
+func (c psubscribe) moniker() string {
+	return "PSUBSCRIBE"
+}
+
+func (c psubscribe) spec() commandSpec {
+	return commandSpec{
+		arity:        -2,
+		flags:        flagPubSub,
+		categories:   []string{"pubsub"},
+		channelSpecs: []keySpec{{flags: []string{"pattern"}, index: 1, lastKey: -1, keyStep: 1}},
+		group:        "pubsub",
+		summary:      "Listens for messages published to channels that match one or more patterns.",
+		since:        "2.0.0",
+		complexity:   "O(N) where N is the number of patterns to subscribe to.",
+		args:         []commandArg{{name: "pattern", typ: argPattern, multiple: true}},
+	}
+}
+
+func (c psubscribe) getUsage() string {
+	return `
+usage:
+	PSUBSCRIBE pattern [pattern ...]
+summary:
+	Subscribes the client to the given patterns.
+	Supported glob-style patterns: h?llo, h*llo, h[ae]llo, h[^e]llo and h[a-b]llo. Use \ to escape special characters.
+`
+}
+
+func (c psubscribe) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR PSUBSCRIBE requires at least one pattern! %s", c.getUsage())}
+	}
+
+	c.psubscribe(clientFromContext(ctx), channelNames(params[1:])...)
+	return resptypes.NoReply{}
+}
@@ -0,0 +1,64 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	psync struct {
+		*replication
+	}
+)
+
+func (c psync) moniker() string {
+	return "PSYNC"
+}
+
+func (c psync) spec() commandSpec {
+	return commandSpec{
+		arity: -3,
+		// The snapshot and the stream offset it corresponds to must be taken atomically
+		flags:   flagExclusive | flagAdmin,
+		group:   "server",
+		summary: "An internal command used in replication.",
+		since:   "2.8.0",
+		args:    []commandArg{{name: "replicationid", typ: argString}, {name: "offset", typ: argInteger}},
+	}
+}
+
+func (c psync) getUsage() string {
+	return `
+usage:
+	PSYNC replicationid offset
+summary:
+	Internal command used by replicas to attach to their primary.
+	The primary continues the replication stream from offset when it still holds it, and otherwise transfers a snapshot of the dataset.
+	Use ? and -1 to request a full resynchronization.
+`
+}
+
+func (c psync) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR wrong number of arguments for 'psync' command! %s", c.getUsage())}
+	}
+
+	offset, err := strconv.ParseInt(params[2].Val, 10, 64)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	cl := clientFromContext(ctx)
+	if cl.multi != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Replica can't interact with the keyspace inside MULTI")}
+	}
+
+	if err := c.psync(cl, params[1].Val, offset); err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	return resptypes.NoReply{}
+}
@@ -0,0 +1,50 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	publish struct {
+		*pubsub
+	}
+)
+
+func (c publish) moniker() string {
+	return "PUBLISH"
+}
+
+func (c publish) spec() commandSpec {
+	return commandSpec{
+		arity:        3,
+		flags:        flagFast,
+		categories:   []string{"pubsub"},
+		channelSpecs: []keySpec{{index: 1, keyStep: 1}},
+		group:        "pubsub",
+		summary:      "Posts a message to a channel.",
+		since:        "2.0.0",
+		complexity:   "O(N+M) where N is the number of clients subscribed to the receiving channel and M is the total number of subscribed patterns (by any client).",
+		args:         []commandArg{{name: "channel", typ: argString}, {name: "message", typ: argString}},
+	}
+}
+
+func (c publish) getUsage() string {
+	return `
+usage:
+	PUBLISH channel message
+summary:
+	Posts a message to the given channel.
+	Returns the number of clients that received the message.
+`
+}
+
+func (c publish) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR PUBLISH requires channel and message! %s", c.getUsage())}
+	}
+
+	return resptypes.Integer{Val: int64(c.publish(params[1].Val, params[2].Val))}
+}
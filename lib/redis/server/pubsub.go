@@ -0,0 +1,212 @@
+package redisserverlib
+
+import (
+	"sort"
+	"sync"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// pubsub tracks channel and pattern subscriptions. Confirmations are pushed while holding
+	// the lock so that a subscriber never sees a message before its subscribe confirmation.
+	pubsub struct {
+		mu       sync.RWMutex
+		channels map[string]map[*client]struct{}
+		patterns map[string]map[*client]struct{}
+	}
+)
+
+func newPubSub() *pubsub {
+	return &pubsub{
+		channels: make(map[string]map[*client]struct{}),
+		patterns: make(map[string]map[*client]struct{}),
+	}
+}
+
+func pubsubMessage(kind string, target string, count int) resptypes.RespSerializable {
+	return resptypes.Array[resptypes.RespSerializable]{
+		resptypes.NewBulkString(kind),
+		resptypes.NewBulkString(target),
+		resptypes.Integer{Val: int64(count)},
+	}
+}
+
+func (ps *pubsub) subscribe(c *client, channels ...string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, channel := range channels {
+		subscribeNoLock(ps.channels, c.channels, c, channel)
+		c.push(pubsubMessage("subscribe", channel, c.subscriptionCount()))
+	}
+}
+
+func (ps *pubsub) psubscribe(c *client, patterns ...string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, pattern := range patterns {
+		subscribeNoLock(ps.patterns, c.patterns, c, pattern)
+		c.push(pubsubMessage("psubscribe", pattern, c.subscriptionCount()))
+	}
+}
+
+// unsubscribe removes the given channels, or every channel of the client when none are given.
+func (ps *pubsub) unsubscribe(c *client, channels ...string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	unsubscribeNoLock(ps.channels, c.channels, c, "unsubscribe", channels)
+}
+
+func (ps *pubsub) punsubscribe(c *client, patterns ...string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	unsubscribeNoLock(ps.patterns, c.patterns, c, "punsubscribe", patterns)
+}
+
+// removeClient drops every subscription of a disconnecting client without sending confirmations.
+func (ps *pubsub) removeClient(c *client) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for channel := range c.channels {
+		removeSubscriberNoLock(ps.channels, c, channel)
+	}
+	for pattern := range c.patterns {
+		removeSubscriberNoLock(ps.patterns, c, pattern)
+	}
+
+	clear(c.channels)
+	clear(c.patterns)
+}
+
+func (ps *pubsub) isSubscribed(c *client) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return c.subscriptionCount() > 0
+}
+
+// publish delivers message to the subscribers of channel and of every matching pattern,
+// returning the number of clients that received it.
+func (ps *pubsub) publish(channel string, message string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	receivers := 0
+	if subscribers, exists := ps.channels[channel]; exists {
+		msg := resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("message"),
+			resptypes.NewBulkString(channel),
+			resptypes.NewBulkString(message),
+		}
+
+		for c := range subscribers {
+			if c.push(msg) {
+				receivers++
+			}
+		}
+	}
+
+	for pattern, subscribers := range ps.patterns {
+		if !redislib.GlobMatch(pattern, channel) {
+			continue
+		}
+
+		msg := resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("pmessage"),
+			resptypes.NewBulkString(pattern),
+			resptypes.NewBulkString(channel),
+			resptypes.NewBulkString(message),
+		}
+
+		for c := range subscribers {
+			if c.push(msg) {
+				receivers++
+			}
+		}
+	}
+
+	return receivers
+}
+
+// pushIfSubscribed pushes msg to c only if it is subscribed to channel.
+func (ps *pubsub) pushIfSubscribed(c *client, channel string, msg resptypes.RespSerializable) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	if _, subscribed := ps.channels[channel][c]; !subscribed {
+		return false
+	}
+
+	return c.push(msg)
+}
+
+// activeChannels returns the channels with at least one subscriber, optionally filtered by a glob pattern.
+func (ps *pubsub) activeChannels(pattern string) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	channels := make([]string, 0, len(ps.channels))
+	for channel := range ps.channels {
+		if pattern == "" || redislib.GlobMatch(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+
+	sort.Strings(channels)
+	return channels
+}
+
+func (ps *pubsub) numSub(channel string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return len(ps.channels[channel])
+}
+
+func (ps *pubsub) numPat() int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return len(ps.patterns)
+}
+
+func subscribeNoLock(index map[string]map[*client]struct{}, own map[string]struct{}, c *client, target string) {
+	own[target] = struct{}{}
+	subscribers, exists := index[target]
+	if !exists {
+		subscribers = make(map[*client]struct{})
+		index[target] = subscribers
+	}
+
+	subscribers[c] = struct{}{}
+}
+
+func unsubscribeNoLock(index map[string]map[*client]struct{}, own map[string]struct{}, c *client, kind string, targets []string) {
+	if len(targets) == 0 {
+		for target := range own {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+	}
+
+	if len(targets) == 0 {
+		c.push(resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString(kind),
+			resptypes.NullBulkString,
+			resptypes.Integer{Val: int64(c.subscriptionCount())},
+		})
+		return
+	}
+
+	for _, target := range targets {
+		delete(own, target)
+		removeSubscriberNoLock(index, c, target)
+		c.push(pubsubMessage(kind, target, c.subscriptionCount()))
+	}
+}
+
+func removeSubscriberNoLock(index map[string]map[*client]struct{}, c *client, target string) {
+	if subscribers, exists := index[target]; exists {
+		delete(subscribers, c)
+		if len(subscribers) == 0 {
+			delete(index, target)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package redisserverlib_test
+
+import (
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func (c testClient) expectPush(t *testing.T, expected string) {
+	t.Helper()
+	select {
+	case actual := <-c.out:
+		if actual != expected {
+			t.Errorf("Pushed %q; Expected: %q", actual, expected)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Timed out waiting for push; Expected: %q", expected)
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor()
+	sub := newTestClient(cp)
+	pub := newTestClient(cp)
+
+	sub.expect(t, "", "SUBSCRIBE", "news.tech")
+	sub.expectPush(t, "*3\r\n$9\r\nsubscribe\r\n$9\r\nnews.tech\r\n:1\r\n")
+	sub.expect(t, "", "PSUBSCRIBE", "news.*")
+	sub.expectPush(t, "*3\r\n$10\r\npsubscribe\r\n$6\r\nnews.*\r\n:2\r\n")
+
+	sub.expect(t, "-ERR Can't execute 'get': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context\r\n", "GET", "k")
+	sub.expect(t, "*2\r\n$4\r\npong\r\n$0\r\n\r\n", "PING")
+
+	pub.expect(t, ":2\r\n", "PUBLISH", "news.tech", "hi")
+	sub.expectPush(t, "*3\r\n$7\r\nmessage\r\n$9\r\nnews.tech\r\n$2\r\nhi\r\n")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$6\r\nnews.*\r\n$9\r\nnews.tech\r\n$2\r\nhi\r\n")
+	pub.expect(t, ":1\r\n", "PUBLISH", "news.art", "hey")
+	sub.expectPush(t, "*4\r\n$8\r\npmessage\r\n$6\r\nnews.*\r\n$8\r\nnews.art\r\n$3\r\nhey\r\n")
+	pub.expect(t, ":0\r\n", "PUBLISH", "sports", "goal")
+
+	pub.expect(t, "*1\r\n$9\r\nnews.tech\r\n", "PUBSUB", "CHANNELS", "news.*")
+	pub.expect(t, "*4\r\n$9\r\nnews.tech\r\n:1\r\n$6\r\nsports\r\n:0\r\n", "PUBSUB", "NUMSUB", "news.tech", "sports")
+	pub.expect(t, ":1\r\n", "PUBSUB", "NUMPAT")
+
+	sub.expect(t, "", "UNSUBSCRIBE")
+	sub.expectPush(t, "*3\r\n$11\r\nunsubscribe\r\n$9\r\nnews.tech\r\n:1\r\n")
+	sub.expect(t, "", "PUNSUBSCRIBE")
+	sub.expectPush(t, "*3\r\n$12\r\npunsubscribe\r\n$6\r\nnews.*\r\n:0\r\n")
+	sub.expect(t, "+PONG\r\n", "PING")
+	pub.expect(t, ":0\r\n", "PUBSUB", "NUMPAT")
+}
+
+func TestSlowSubscriberIsDisconnected(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithOutputBufferLimit(2))
+	sub := newTestClient(cp)
+	pub := newTestClient(cp)
+
+	sub.expect(t, "", "SUBSCRIBE", "c")
+	pub.expect(t, ":1\r\n", "PUBLISH", "c", "1")
+	pub.expect(t, ":0\r\n", "PUBLISH", "c", "2")
+
+	select {
+	case <-sub.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Slow subscriber was not disconnected")
+	}
+
+	cp.DisconnectClient(sub.ctx)
+	pub.expect(t, "*2\r\n$1\r\nc\r\n:0\r\n", "PUBSUB", "NUMSUB", "c")
+}
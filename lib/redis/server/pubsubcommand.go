@@ -0,0 +1,84 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	pubsubCmd struct {
+		*pubsub
+	}
+)
+
+func (c pubsubCmd) moniker() string {
+	return "PUBSUB"
+}
+
+func (c pubsubCmd) spec() commandSpec {
+	return commandSpec{
+		arity:      -2,
+		categories: []string{"pubsub"},
+		group:      "pubsub",
+		summary:    "A container for Pub/Sub commands.",
+		since:      "2.8.0",
+		subcommands: []commandSpec{
+			{
+				name: "channels", arity: -2, summary: "Returns the active channels.", since: "2.8.0",
+				complexity: "O(N) where N is the number of active channels, and assuming constant time pattern matching (relatively short channels and patterns)",
+				args:       []commandArg{{name: "pattern", typ: argPattern, optional: true}},
+			},
+			{
+				name: "numsub", arity: -2, summary: "Returns a count of subscribers to channels.", since: "2.8.0",
+				complexity: "O(N) for the NUMSUB subcommand, where N is the number of requested channels",
+				args:       []commandArg{{name: "channel", typ: argString, optional: true, multiple: true}},
+			},
+			{name: "numpat", arity: 2, summary: "Returns a count of unique pattern subscriptions.", since: "2.8.0", complexity: "O(1)"},
+		},
+	}
+}
+
+func (c pubsubCmd) getUsage() string {
+	return `
+usage:
+	PUBSUB CHANNELS [pattern]
+	PUBSUB NUMSUB [channel [channel ...]]
+	PUBSUB NUMPAT
+summary:
+	Introspects the state of the Pub/Sub subsystem.
+	CHANNELS lists the channels with at least one subscriber, optionally matching a glob-style pattern.
+	NUMSUB returns the number of subscribers (not counting pattern subscribers) for each given channel.
+	NUMPAT returns the number of unique patterns that are subscribed to.
+`
+}
+
+func (c pubsubCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR PUBSUB requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "CHANNELS" && len(params) <= 3:
+		pattern := ""
+		if len(params) == 3 {
+			pattern = params[2].Val
+		}
+
+		return resptypes.ToBulkStringArray(c.activeChannels(pattern))
+	case subcommand == "NUMSUB":
+		result := make(resptypes.Array[resptypes.RespSerializable], 0, 2*(len(params)-2))
+		for _, channel := range params[2:] {
+			result = append(result, channel, resptypes.Integer{Val: int64(c.numSub(channel.Val))})
+		}
+
+		return result
+	case subcommand == "NUMPAT" && len(params) == 2:
+		return resptypes.Integer{Val: int64(c.numPat())}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown PUBSUB subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
@@ -0,0 +1,44 @@
+package redisserverlib
+
+import (
+	"context"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	punsubscribe struct {
+		*pubsub
+	}
+)
+
+func (c punsubscribe) moniker() string {
+	return "PUNSUBSCRIBE"
+}
+
+func (c punsubscribe) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagPubSub,
+		categories: []string{"pubsub"},
+		group:      "pubsub",
+		summary:    "Stops listening to messages published to channels that match one or more patterns.",
+		since:      "2.0.0",
+		complexity: "O(N) where N is the number of patterns to unsubscribe.",
+		args:       []commandArg{{name: "pattern", typ: argPattern, optional: true, multiple: true}},
+	}
+}
+
+func (c punsubscribe) getUsage() string {
+	return `
+usage:
+	PUNSUBSCRIBE [pattern [pattern ...]]
+summary:
+	Unsubscribes the client from the given patterns, or from all of them if none is given.
+`
+}
+
+func (c punsubscribe) execute(ctx context.Context, params commandParams) commandResult {
+	c.punsubscribe(clientFromContext(ctx), channelNames(params[1:])...)
+	return resptypes.NoReply{}
+}
@@ -0,0 +1,165 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+type raftTestNode struct {
+	testClient
+	addr    string
+	dir     string
+	stopped bool
+	drop    func()
+	closeCp func()
+}
+
+// startRaftNode serves a processor in Raft mode on listener, keeping its data in dir.
+func startRaftNode(t *testing.T, listener net.Listener, dir string, members []string) *raftTestNode {
+	cp := redisserverlib.NewRedisCommandProcessor(
+		redisserverlib.WithDir(dir),
+		redisserverlib.WithPort(listenerPort(listener)),
+		redisserverlib.WithRaftMembers(members...),
+		redisserverlib.WithRaftTickInterval(10*time.Millisecond),
+		redisserverlib.WithRaftSnapshotEntries(5),
+	)
+	_, drop := serveListener(t, listener, cp)
+	n := &raftTestNode{testClient: newTestClient(cp), addr: listener.Addr().String(), dir: dir, drop: drop}
+	n.closeCp = func() {
+		listener.Close()
+		drop()
+		cp.Close()
+	}
+	t.Cleanup(n.stop)
+	return n
+}
+
+// stop shuts the node down like a crashed server, keeping its data.
+func (n *raftTestNode) stop() {
+	if !n.stopped {
+		n.stopped = true
+		n.closeCp()
+	}
+}
+
+// raftLeader waits until every node follows the same leader and returns it.
+func raftLeader(t *testing.T, nodes ...*raftTestNode) *raftTestNode {
+	t.Helper()
+	var leader *raftTestNode
+	waitFor(t, "a Raft leader", func() bool {
+		leader = nil
+		for _, n := range nodes {
+			if n.infoField("raft", "raft_role") == "leader" {
+				leader = n
+			}
+		}
+
+		for _, n := range nodes {
+			if leader == nil || n.infoField("raft", "raft_leader") != leader.addr {
+				return false
+			}
+		}
+
+		return true
+	})
+	return leader
+}
+
+func TestRaft(t *testing.T) {
+	listeners := []net.Listener{listen(t), listen(t), listen(t)}
+	var members []string
+	for _, listener := range listeners {
+		members = append(members, listener.Addr().String())
+	}
+
+	var nodes []*raftTestNode
+	for _, listener := range listeners {
+		nodes = append(nodes, startRaftNode(t, listener, t.TempDir(), members))
+	}
+
+	leader := raftLeader(t, nodes...)
+	var followers []*raftTestNode
+	for _, n := range nodes {
+		if n != leader {
+			followers = append(followers, n)
+		}
+	}
+
+	t.Run("Followers redirect to the leader", func(t *testing.T) {
+		leader.expect(t, "+OK\r\n", "SET", "k", "v")
+		leader.expect(t, "$1\r\nv\r\n", "GET", "k")
+		redirect := fmt.Sprintf("-REDIRECT %s\r\n", leader.addr)
+		followers[0].expect(t, redirect, "GET", "k")
+		followers[0].expect(t, redirect, "SET", "k", "w")
+		for _, f := range followers {
+			f.eventually(t, ":1\r\n", "DBSIZE")
+		}
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		leader.expect(t, "+OK\r\n", "MULTI")
+		leader.expect(t, "+QUEUED\r\n", "SET", "a", "1")
+		leader.expect(t, "+QUEUED\r\n", "RPUSH", "l", "x", "y")
+		leader.expect(t, "*2\r\n+OK\r\n:2\r\n", "EXEC")
+
+		leader.expect(t, "+OK\r\n", "MULTI")
+		leader.expect(t, "+QUEUED\r\n", "GET", "a")
+		leader.expect(t, "*1\r\n$1\r\n1\r\n", "EXEC")
+
+		leader.expect(t, "-ERR WATCH is not supported in Raft mode\r\n", "WATCH", "a")
+		for _, f := range followers {
+			f.eventually(t, ":3\r\n", "DBSIZE")
+		}
+	})
+
+	t.Run("Blocking commands", func(t *testing.T) {
+		popped := make(chan string)
+		blocked := newTestClient(leader.cp)
+		go func() { popped <- blocked.do("BLPOP", "q", "5") }()
+
+		time.Sleep(50 * time.Millisecond)
+		leader.expect(t, ":1\r\n", "RPUSH", "q", "e")
+		if reply := <-popped; reply != "*2\r\n$1\r\nq\r\n$1\r\ne\r\n" {
+			t.Errorf("BLPOP = %q", reply)
+		}
+
+		leader.expect(t, "*-1\r\n", "BLPOP", "q", "0.05")
+	})
+
+	// The log is compacted every few entries, so the stopped node catches up from a snapshot
+	stopped := followers[0]
+	stopped.stop()
+	for i := range 10 {
+		leader.expect(t, "+OK\r\n", "SET", fmt.Sprint("key", i), "v")
+	}
+
+	leader.stop()
+	survivor := followers[1]
+	listener, err := net.Listen("tcp", stopped.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := startRaftNode(t, listener, stopped.dir, members)
+	t.Run("Failover keeps committed writes", func(t *testing.T) {
+		newLeader := raftLeader(t, survivor, restarted)
+		newLeader.expect(t, "$1\r\nv\r\n", "GET", "key9")
+		newLeader.expect(t, "$1\r\n1\r\n", "GET", "a")
+		id := newLeader.do("XADD", "s", "*", "f", "v")
+		if !strings.HasPrefix(id, "$") {
+			t.Fatalf("XADD = %q", id)
+		}
+
+		for _, n := range []*raftTestNode{survivor, restarted} {
+			n.eventually(t, ":15\r\n", "DBSIZE")
+			if index := n.infoField("raft", "raft_snapshot_index"); index == "0" {
+				t.Errorf("%s did not compact its log", n.addr)
+			}
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package redisserverlib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/raft"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	raftCmd struct {
+		*consensus
+	}
+)
+
+func (c raftCmd) moniker() string {
+	return "RAFT"
+}
+
+func (c raftCmd) spec() commandSpec {
+	return commandSpec{
+		arity:   -2,
+		group:   "server",
+		summary: "A container for Raft consensus commands.",
+		subcommands: []commandSpec{
+			{name: "info", arity: 2, summary: "Returns the Raft state of the node."},
+			{
+				name: "message", arity: 3, flags: flagAdmin, summary: "Delivers a message from another member of the Raft cluster.",
+				args: []commandArg{{name: "payload", typ: argString}},
+			},
+		},
+	}
+}
+
+func (c raftCmd) getUsage() string {
+	return `
+usage:
+	RAFT INFO
+	RAFT MESSAGE payload
+summary:
+	Inspects the Raft state of the node, or delivers a message from another member of the Raft cluster.
+	MESSAGE is sent by the members to each other and is not meant for clients.
+`
+}
+
+func (c raftCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR RAFT requires a subcommand! %s", c.getUsage())}
+	}
+
+	if c.consensus == nil || c.node == nil {
+		return resptypes.SimpleError{Val: errRaftDisabled}
+	}
+
+	switch subcommand := strings.ToUpper(params[1].Val); {
+	case subcommand == "INFO" && len(params) == 2:
+		return resptypes.NewBulkString(strings.Join(c.info(), "\r\n") + "\r\n")
+	case subcommand == "MESSAGE" && len(params) == 3:
+		var msg raft.Message
+		if err := json.Unmarshal([]byte(params[2].Val), &msg); err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid Raft message: %w", err)}
+		}
+
+		c.node.Step(msg)
+		return resptypes.SimpleString{Val: "OK"}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown RAFT subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
@@ -0,0 +1,100 @@
+package redisserverlib_test
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestRenameCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "redis.conf")
+	writeFile(t, path, "rename-command FLUSHALL \"\"\nrename-command config cfg-7f3a\nrename-command SET store\nrename-command NOSUCH other\nrename-command GET del\n")
+	config, err := redisserverlib.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := startWithConfig(t, config)
+	count := c.do("COMMAND", "COUNT")
+
+	t.Run("Calls", func(t *testing.T) {
+		c.expect(t, "-NOTSUPPORTED Command 'FLUSHALL' is not supported!\r\n", "FLUSHALL")
+		c.expect(t, "-NOTSUPPORTED Command 'CONFIG' is not supported!\r\n", "CONFIG", "GET", "databases")
+		c.expect(t, "*2\r\n$9\r\ndatabases\r\n$2\r\n16\r\n", "CFG-7F3A", "GET", "databases")
+		c.expect(t, "-ERR wrong number of arguments for 'cfg-7f3a|get' command\r\n", "cfg-7f3a", "GET")
+		c.expect(t, "+OK\r\n", "store", "k", "v")
+		// Renaming to the name of another command is ignored
+		c.expect(t, "$1\r\nv\r\n", "GET", "k")
+		c.expect(t, "+OK\r\n", "MULTI")
+		c.expect(t, "+QUEUED\r\n", "STORE", "k", "w")
+		c.expect(t, "*1\r\n+OK\r\n", "EXEC")
+	})
+
+	t.Run("ACL rules", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "ACL", "SETUSER", "writer", "on", ">pw", "~*", "+store", "+cfg-7f3a|get")
+		if list := c.do("ACL", "LIST"); !strings.Contains(list, "user writer on #") || !strings.Contains(list, "-@all +set +config|get") {
+			t.Errorf("ACL LIST = %q", list)
+		}
+
+		writer := newTestClient(c.cp)
+		writer.expect(t, "+OK\r\n", "AUTH", "writer", "pw")
+		writer.expect(t, "+OK\r\n", "STORE", "k", "v")
+		writer.expect(t, "*2\r\n$9\r\ndatabases\r\n$2\r\n16\r\n", "CFG-7F3A", "GET", "databases")
+		if reply := writer.do("GET", "k"); !strings.HasPrefix(reply, "-NOPERM") {
+			t.Errorf("GET k = %q", reply)
+		}
+	})
+
+	t.Run("Introspection", func(t *testing.T) {
+		c.expect(t, "*1\r\n*-1\r\n", "COMMAND", "INFO", "flushall")
+		if info := c.do("COMMAND", "INFO", "cfg-7f3a"); !strings.HasPrefix(info, "*1\r\n*10\r\n$8\r\ncfg-7f3a\r\n") || !strings.Contains(info, "cfg-7f3a|get") {
+			t.Errorf("COMMAND INFO cfg-7f3a = %q", info)
+		}
+
+		c.expect(t, "*2\r\n$8\r\ncfg-7f3a\r\n$5\r\nstore\r\n", "COMMAND", "LIST", "FILTERBY", "PATTERN", "[cs][ft]*")
+		c.expect(t, "*1\r\n$1\r\nk\r\n", "COMMAND", "GETKEYS", "store", "k", "v")
+		c.expect(t, "-NOTSUPPORTED Command 'CONFIG' is not supported\r\n", "HELP", "config")
+		if help := bulkStringOf(t, c.do("HELP", "cfg-7f3a")); !strings.HasPrefix(help, "\nrenamed from:\n\tCONFIG\nusage:") {
+			t.Errorf("HELP cfg-7f3a = %q", help)
+		}
+
+		if help := bulkStringOf(t, c.do("HELP", "get")); strings.Contains(help, "renamed") {
+			t.Errorf("HELP get = %q", help)
+		}
+
+		if help := c.do("HELP", "@dangerous"); strings.Contains(help, "FLUSHALL") || !strings.Contains(help, "CFG-7F3A") {
+			t.Errorf("HELP @dangerous = %q", help)
+		}
+
+		plain := newTestClient(redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir())))
+		t.Cleanup(func() { plain.cp.Close() })
+		renamed, _ := strconv.Atoi(strings.Trim(count, ":\r\n"))
+		all, _ := strconv.Atoi(strings.Trim(plain.do("COMMAND", "COUNT"), ":\r\n"))
+		if renamed != all-1 {
+			t.Errorf("COMMAND COUNT = %d; Expected: %d", renamed, all-1)
+		}
+	})
+}
+
+func TestRenameCommandPropagation(t *testing.T) {
+	dir := t.TempDir()
+	cp := redisserverlib.NewRedisCommandProcessor(
+		redisserverlib.WithDir(dir),
+		redisserverlib.WithAppendOnly(true),
+		redisserverlib.WithAppendFsync("always"),
+		redisserverlib.WithRenameCommand("SET", "store"),
+		redisserverlib.WithRenameCommand("RPUSH", ""),
+	)
+	c := newTestClient(cp)
+	c.expect(t, "+OK\r\n", "STORE", "k", "v")
+	cp.Close()
+
+	// The AOF holds the original names, so it loads with or without the renames
+	restarted := newAofProcessor(dir)
+	t.Cleanup(func() { restarted.Close() })
+	newTestClient(restarted).expect(t, "$1\r\nv\r\n", "GET", "k")
+}
@@ -0,0 +1,78 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	replconf struct {
+		*replication
+	}
+)
+
+func (c replconf) moniker() string {
+	return "REPLCONF"
+}
+
+func (c replconf) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagAdmin,
+		group:      "server",
+		summary:    "An internal command for configuring the replication stream.",
+		since:      "3.0.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c replconf) getUsage() string {
+	return `
+usage:
+	REPLCONF option value [option value ...]
+summary:
+	Internal command used by replicas to configure the replication link.
+	listening-port announces the port the replica serves clients on, capa announces capabilities,
+	and ACK reports the replicated offset to the primary (without a reply).
+`
+}
+
+func (c replconf) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 3 || len(params)%2 == 0 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error! %s", c.getUsage())}
+	}
+
+	cl := clientFromContext(ctx)
+	for i := 1; i < len(params); i += 2 {
+		option, value := strings.ToLower(params[i].Val), params[i+1].Val
+		switch option {
+		case "listening-port":
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 0 || port > 65535 {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+			}
+
+			cl.listeningPort = port
+		case "capa":
+			// Snapshots are always sent as a bulk of known length, and PSYNC always understands replication IDs
+		case "ack":
+			offset, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return resptypes.NoReply{}
+			}
+
+			c.ack(cl, offset)
+			return resptypes.NoReply{}
+		case "getack":
+			// Only meaningful on the replication stream, which the replication link handles itself
+		default:
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Unrecognized REPLCONF option: %s", params[i].Val)}
+		}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
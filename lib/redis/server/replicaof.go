@@ -0,0 +1,72 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	replicaof struct {
+		*replication
+	}
+)
+
+func (c replicaof) moniker() string {
+	return "REPLICAOF"
+}
+
+func (c replicaof) spec() commandSpec {
+	return commandSpec{
+		arity: 3,
+		// Stops a running replication link, which must not apply anything afterwards
+		flags:      flagExclusive | flagAdmin,
+		group:      "server",
+		summary:    "Configures a server as replica of another, or promotes it to a master.",
+		since:      "5.0.0",
+		complexity: "O(1)",
+		args: []commandArg{{name: "args", typ: argOneOf, args: []commandArg{
+			{name: "host-port", typ: argBlock, args: []commandArg{{name: "host", typ: argString}, {name: "port", typ: argInteger}}},
+			{name: "no-one", typ: argBlock, args: []commandArg{
+				{name: "no", typ: argPureToken, token: "NO"},
+				{name: "one", typ: argPureToken, token: "ONE"},
+			}},
+		}}},
+	}
+}
+
+func (c replicaof) getUsage() string {
+	return `
+usage:
+	REPLICAOF host port
+	REPLICAOF NO ONE
+summary:
+	Make the server a replica of another instance, discarding its dataset in favour of the primary's.
+	REPLICAOF NO ONE stops replication and turns the replica into a primary, keeping the dataset.
+`
+}
+
+func (c replicaof) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR wrong number of arguments for 'replicaof' command! %s", c.getUsage())}
+	}
+
+	if strings.EqualFold(params[1].Val, "NO") && strings.EqualFold(params[2].Val, "ONE") {
+		c.replicaOf("", 0)
+		return resptypes.SimpleString{Val: "OK"}
+	}
+
+	port, err := strconv.Atoi(params[2].Val)
+	if err != nil || port < 0 || port > 65535 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid master port")}
+	}
+
+	if c.replicaOf(params[1].Val, port) {
+		return resptypes.SimpleString{Val: "OK Already connected to specified master"}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -0,0 +1,835 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	redisrdblib "github.com/codecrafters-io/redis-starter-go/lib/redis/rdb"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// replicaLink is a primary's view of a connected replica. Until the snapshot of a full
+	// resync has been sent, the replication stream is buffered instead of pushed.
+	replicaLink struct {
+		client *client
+		port   int
+
+		mu       sync.Mutex
+		online   bool
+		buffered []rawReply
+
+		// The offset last acknowledged with REPLCONF ACK and when, in Unix milliseconds
+		ackOffset atomic.Int64
+		lastAck   atomic.Int64
+	}
+
+	// replicationBacklog is a ring buffer holding the tail of the replication stream, so that
+	// replicas reconnecting after a short disconnect can continue instead of resyncing fully.
+	replicationBacklog struct {
+		buf     []byte
+		next    int
+		histlen int
+	}
+
+	// primaryLink is a replica's connection to its primary. It reconnects until cancelled.
+	primaryLink struct {
+		host   string
+		port   int
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		// The pseudo client applying the replication stream. It survives reconnections, so that
+		// a partial resync continues with the database that was selected when the link broke.
+		// Only used by the link's goroutine.
+		client   *client
+		applyCtx context.Context
+
+		up      atomic.Bool
+		syncing atomic.Bool
+		// Unix time in seconds of the last read from the primary
+		lastIO atomic.Int64
+		// Guards writes to the connection, shared by the stream loop and the ACK ticker
+		writeMu sync.Mutex
+	}
+
+	// replication holds the state of both sides of replication. Every server is a primary
+	// to the replicas connected to it, and may itself replicate from another primary.
+	replication struct {
+		r    *redisCommandProcessor
+		port int
+
+		mu sync.Mutex
+		// The history of the dataset is identified by replid. After a promotion, the
+		// previous history stays valid as replid2 up to secondOffset.
+		replid       string
+		replid2      string
+		offset       int64
+		secondOffset int64
+		backlog      replicationBacklog
+		// The database last selected in the stream, -1 to force a SELECT
+		streamDb int
+		replicas []*replicaLink
+		// nil unless the server is a replica
+		primary *primaryLink
+		// Closed and replaced whenever a replica acknowledges an offset
+		acked chan struct{}
+
+		readOnly atomic.Bool
+		// Writes are rejected unless at least minReplicas replicas acknowledged
+		// within the last maxLag seconds
+		minReplicas atomic.Int64
+		maxLag      atomic.Int64
+		// Announced in INFO for Sentinel to pick the replica to promote; 0 means never
+		priority       atomic.Int64
+		syncFull       atomic.Int64
+		syncPartialOk  atomic.Int64
+		syncPartialErr atomic.Int64
+
+		stop chan struct{}
+		wg   sync.WaitGroup
+	}
+
+	// rawReply is a piece of the replication stream, sent to replicas as is.
+	rawReply string
+)
+
+const (
+	// How long the replication link may stay silent before it is considered broken
+	replTimeout = 60 * time.Second
+	// How often a primary pings its replicas, so that they can detect a broken link
+	replPingPeriod = 10 * time.Second
+	replAckPeriod  = time.Second
+	// How long a replica waits before reconnecting to its primary
+	replRetryDelay = time.Second
+)
+
+var (
+	errLinkClosed    = errors.New("replication link closed")
+	errNoPrimaryLink = errors.New("NOMASTERLINK Can't SYNC while not connected with my master")
+	errReadOnly      = errors.New("READONLY You can't write against a read only replica.")
+	errNoReplicas    = errors.New("NOREPLICAS Not enough good replicas to write.")
+)
+
+func (r rawReply) ToRespString() string {
+	return string(r)
+}
+
+func newReplid() string {
+	id := make([]byte, 20)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func newReplication(r *redisCommandProcessor, options processorOptions) *replication {
+	rp := &replication{
+		r:            r,
+		port:         options.port,
+		replid:       newReplid(),
+		replid2:      strings.Repeat("0", 40),
+		secondOffset: -1,
+		backlog:      replicationBacklog{buf: make([]byte, max(1, options.replBacklogSize))},
+		streamDb:     -1,
+		acked:        make(chan struct{}),
+		stop:         make(chan struct{}),
+	}
+	rp.readOnly.Store(options.replicaReadOnly)
+	rp.minReplicas.Store(int64(options.minReplicasToWrite))
+	rp.maxLag.Store(int64(options.minReplicasMaxLag))
+	rp.priority.Store(int64(options.replicaPriority))
+	return rp
+}
+
+func (b *replicationBacklog) write(data []byte) {
+	if len(data) >= len(b.buf) {
+		copy(b.buf, data[len(data)-len(b.buf):])
+		b.next, b.histlen = 0, len(b.buf)
+		return
+	}
+
+	n := copy(b.buf[b.next:], data)
+	copy(b.buf, data[n:])
+	b.next = (b.next + len(data)) % len(b.buf)
+	b.histlen = min(len(b.buf), b.histlen+len(data))
+}
+
+// tail returns the last n bytes written, n must not exceed histlen.
+func (b *replicationBacklog) tail(n int) []byte {
+	out := make([]byte, n)
+	start := (b.next - n + len(b.buf)) % len(b.buf)
+	copied := copy(out, b.buf[start:])
+	copy(out[copied:], b.buf)
+	return out
+}
+
+func (b *replicationBacklog) reset() {
+	b.next, b.histlen = 0, 0
+}
+
+// send pushes a piece of the stream to the replica, or buffers it while the snapshot is pending.
+func (l *replicaLink) send(data rawReply) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.online {
+		l.buffered = append(l.buffered, data)
+		return
+	}
+
+	l.client.push(data)
+}
+
+// sendSnapshot transfers the dataset of a full resync, followed by the stream buffered meanwhile.
+func (l *replicaLink) sendSnapshot(snapshot []redisrdblib.Database) {
+	var buf bytes.Buffer
+	if err := redisrdblib.Write(&buf, snapshot); err != nil {
+		slog.Error("Failed to serialize the snapshot for a replica", "id", l.client.id, "error", err)
+		l.client.kill()
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Unlike a regular bulk string, the payload is not terminated by CRLF
+	l.client.push(rawReply(fmt.Sprintf("$%d\r\n%s", buf.Len(), buf.Bytes())))
+	for _, data := range l.buffered {
+		l.client.push(data)
+	}
+
+	l.buffered, l.online = nil, true
+}
+
+// propagate implements propagationSink. A replica forwards its primary's stream verbatim
+// instead, so that offsets match across the whole chain.
+func (rp *replication) propagate(batch []propagatedCommand) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.primary != nil {
+		return
+	}
+
+	rp.feedNoLock(appendPropagated(nil, &rp.streamDb, batch))
+}
+
+// feedNoLock appends data to the replication stream.
+func (rp *replication) feedNoLock(data []byte) {
+	rp.offset += int64(len(data))
+	rp.backlog.write(data)
+	for _, l := range rp.replicas {
+		l.send(rawReply(data))
+	}
+}
+
+// forward appends a command received from the primary to the replica's own stream.
+func (rp *replication) forward(l *primaryLink, data []byte) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.primary == l {
+		rp.feedNoLock(data)
+	}
+}
+
+func (rp *replication) currentOffset() int64 {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.offset
+}
+
+// canContinueNoLock reports whether the stream starting at offset, from the history identified
+// by replid, is still available in the backlog.
+func (rp *replication) canContinueNoLock(replid string, offset int64) bool {
+	if replid != rp.replid && (replid != rp.replid2 || offset > rp.secondOffset) {
+		return false
+	}
+
+	first := rp.offset - int64(rp.backlog.histlen) + 1
+	return offset >= first && offset <= rp.offset+1
+}
+
+// psync attaches c as a replica, continuing from offset when possible and otherwise sending
+// a snapshot of the dataset. The caller must hold the transaction lock exclusively.
+func (rp *replication) psync(c *client, replid string, offset int64) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.primary != nil && !rp.primary.up.Load() {
+		return errNoPrimaryLink
+	}
+
+	link := &replicaLink{client: c, port: c.listeningPort}
+	link.lastAck.Store(time.Now().UnixMilli())
+	if rp.canContinueNoLock(replid, offset) {
+		rp.syncPartialOk.Add(1)
+		c.push(resptypes.SimpleString{Val: "CONTINUE " + rp.replid})
+		if missing := rp.offset + 1 - offset; missing > 0 {
+			c.push(rawReply(rp.backlog.tail(int(missing))))
+		}
+
+		link.online = true
+		rp.replicas = append(rp.replicas, link)
+		slog.Info("Partial resynchronization accepted", "id", c.id, "offset", offset)
+		return nil
+	}
+
+	if replid != "?" {
+		rp.syncPartialErr.Add(1)
+	}
+
+	rp.syncFull.Add(1)
+	snapshot := rp.r.persistence.snapshot()
+	// The replica starts applying the stream with database 0 selected
+	rp.streamDb = -1
+	c.push(resptypes.SimpleString{Val: fmt.Sprintf("FULLRESYNC %s %d", rp.replid, rp.offset)})
+	rp.replicas = append(rp.replicas, link)
+	rp.wg.Go(func() { link.sendSnapshot(snapshot) })
+	slog.Info("Full resynchronization started", "id", c.id, "offset", rp.offset)
+	return nil
+}
+
+func (rp *replication) removeReplica(c *client) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.replicas = slices.DeleteFunc(rp.replicas, func(l *replicaLink) bool { return l.client == c })
+}
+
+func (rp *replication) ack(c *client, offset int64) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	for _, l := range rp.replicas {
+		if l.client == c {
+			l.ackOffset.Store(offset)
+			l.lastAck.Store(time.Now().UnixMilli())
+		}
+	}
+
+	close(rp.acked)
+	rp.acked = make(chan struct{})
+}
+
+// ackedNoLock counts the replicas that acknowledged the stream up to offset.
+func (rp *replication) ackedNoLock(offset int64) int {
+	count := 0
+	for _, l := range rp.replicas {
+		if l.ackOffset.Load() >= offset {
+			count++
+		}
+	}
+
+	return count
+}
+
+// goodReplicasNoLock counts the online replicas that acknowledged recently enough.
+func (rp *replication) goodReplicasNoLock() int {
+	count := 0
+	oldest := time.Now().UnixMilli() - rp.maxLag.Load()*1000
+	for _, l := range rp.replicas {
+		l.mu.Lock()
+		online := l.online
+		l.mu.Unlock()
+		if online && l.lastAck.Load() >= oldest {
+			count++
+		}
+	}
+
+	return count
+}
+
+// waitForReplicas blocks until numReplicas replicas acknowledged the stream up to offset,
+// or ctx is done. It returns how many did.
+func (rp *replication) waitForReplicas(ctx context.Context, offset int64, numReplicas int) int {
+	requested := false
+	for {
+		rp.mu.Lock()
+		count := rp.ackedNoLock(offset)
+		acked := rp.acked
+		if count < numReplicas && ctx.Err() == nil && !requested {
+			// Ask for acknowledgements now rather than waiting for the periodic ones
+			rp.feedNoLock(redislib.AppendCommand(nil, "REPLCONF", "GETACK", "*"))
+			requested = true
+		}
+		rp.mu.Unlock()
+
+		if count >= numReplicas || ctx.Err() != nil {
+			return count
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-acked:
+		}
+	}
+}
+
+// disconnectReplicasNoLock drops every replica, which have to resync with the new history.
+func (rp *replication) disconnectReplicasNoLock() {
+	for _, l := range rp.replicas {
+		l.client.kill()
+	}
+
+	rp.replicas = nil
+}
+
+// checkWrite tells why a write command of c must be rejected: the server is a read only
+// replica, or too few replicas are connected to satisfy min-replicas-to-write.
+func (rp *replication) checkWrite(c *client) error {
+	if c.internal {
+		return nil
+	}
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.primary != nil {
+		if rp.readOnly.Load() {
+			return errReadOnly
+		}
+
+		return nil
+	}
+
+	if minReplicas := rp.minReplicas.Load(); minReplicas > 0 && int64(rp.goodReplicasNoLock()) < minReplicas {
+		return errNoReplicas
+	}
+
+	return nil
+}
+
+func (rp *replication) isReplica() bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.primary != nil
+}
+
+// replicaOf makes the server replicate from host:port, or a primary when host is empty.
+// It reports whether the server already replicates from host:port.
+// The caller must hold the transaction lock exclusively.
+func (rp *replication) replicaOf(host string, port int) bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	old := rp.primary
+	if old != nil && old.host == host && old.port == port {
+		return true
+	}
+
+	if old != nil {
+		// The link's goroutine may be waiting for the transaction lock held by the caller.
+		// Commands it applies from now on are rejected, see call.
+		old.cancel()
+		rp.primary = nil
+	}
+
+	if host == "" {
+		if old != nil {
+			// Replicas of the former primary can continue with the history up to here
+			rp.replid2, rp.secondOffset = rp.replid, rp.offset+1
+			rp.replid, rp.streamDb = newReplid(), -1
+			slog.Info("Replication stopped, serving as primary", "replid", rp.replid)
+		}
+
+		return false
+	}
+
+	rp.disconnectReplicasNoLock()
+	l := &primaryLink{host: host, port: port}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	l.resetClient()
+	rp.primary = l
+	rp.wg.Go(func() { rp.runLink(l) })
+	slog.Info("Replicating", "primary", net.JoinHostPort(host, strconv.Itoa(port)))
+	return false
+}
+
+// parseReplicaOf parses a primary's address in the form "<host> <port>".
+func parseReplicaOf(address string) (string, int, bool) {
+	fields := strings.Fields(address)
+	if len(fields) != 2 {
+		return "", 0, false
+	}
+
+	port, err := strconv.Atoi(fields[1])
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, false
+	}
+
+	return fields[0], port, true
+}
+
+func (l *primaryLink) resetClient() {
+	l.client = newClient()
+	l.client.link = l
+	l.client.internal = true
+	l.applyCtx = contextWithClient(l.ctx, l.client)
+}
+
+func (rp *replication) runLink(l *primaryLink) {
+	for {
+		err := rp.syncWithPrimary(l)
+		l.up.Store(false)
+		l.syncing.Store(false)
+		if l.ctx.Err() != nil {
+			return
+		}
+
+		slog.Warn("Replication link broken, reconnecting", "primary", net.JoinHostPort(l.host, strconv.Itoa(l.port)), "error", err)
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-time.After(replRetryDelay):
+		}
+	}
+}
+
+// syncWithPrimary performs the handshake and then applies the stream until the connection breaks.
+func (rp *replication) syncWithPrimary(l *primaryLink) error {
+	dialer := net.Dialer{Timeout: replTimeout}
+	conn, err := dialer.DialContext(l.ctx, "tcp", net.JoinHostPort(l.host, strconv.Itoa(l.port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer context.AfterFunc(l.ctx, func() { conn.Close() })()
+
+	reader := bufio.NewReader(conn)
+	send := func(args ...string) error {
+		l.writeMu.Lock()
+		defer l.writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(replTimeout))
+		_, err := conn.Write(redislib.AppendCommand(nil, args...))
+		return err
+	}
+	readLine := func() (string, error) {
+		conn.SetReadDeadline(time.Now().Add(replTimeout))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+
+		l.lastIO.Store(time.Now().Unix())
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	handshake := func(args ...string) error {
+		if err := send(args...); err != nil {
+			return err
+		}
+
+		line, err := readLine()
+		if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(line, "-") {
+			return fmt.Errorf("primary replied to %s with an error: %s", args[0], line[1:])
+		}
+
+		return nil
+	}
+
+	if auth := authCommand(rp.r.options); auth != nil {
+		if err := handshake(auth...); err != nil {
+			return err
+		}
+	}
+
+	if err := handshake("PING"); err != nil {
+		return err
+	}
+
+	if err := handshake("REPLCONF", "listening-port", strconv.Itoa(rp.port)); err != nil {
+		return err
+	}
+
+	if err := handshake("REPLCONF", "capa", "eof", "capa", "psync2"); err != nil {
+		return err
+	}
+
+	rp.mu.Lock()
+	replid, offset := rp.replid, rp.offset+1
+	rp.mu.Unlock()
+	if err := send("PSYNC", replid, strconv.FormatInt(offset, 10)); err != nil {
+		return err
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 3 && fields[0] == "+FULLRESYNC":
+		offset, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid offset in %q", line)
+		}
+
+		l.syncing.Store(true)
+		conn.SetReadDeadline(time.Now().Add(replTimeout))
+		payload, err := readSnapshot(reader)
+		if err != nil {
+			return fmt.Errorf("receiving snapshot: %w", err)
+		}
+
+		if err := rp.fullSync(l, fields[1], offset, payload); err != nil {
+			return err
+		}
+
+		l.syncing.Store(false)
+	case len(fields) >= 1 && fields[0] == "+CONTINUE":
+		newReplid := ""
+		if len(fields) > 1 {
+			newReplid = fields[1]
+		}
+
+		rp.continueSync(l, newReplid)
+	default:
+		return fmt.Errorf("unexpected reply to PSYNC: %q", line)
+	}
+
+	l.up.Store(true)
+	done := make(chan struct{})
+	var acks sync.WaitGroup
+	defer acks.Wait()
+	defer close(done)
+	acks.Go(func() {
+		ticker := time.NewTicker(replAckPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				send("REPLCONF", "ACK", strconv.FormatInt(rp.currentOffset(), 10))
+			}
+		}
+	})
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(replTimeout))
+		args, _, err := redislib.ReadCommand(reader)
+		if err != nil {
+			return err
+		}
+
+		l.lastIO.Store(time.Now().Unix())
+		if len(args) >= 2 && strings.EqualFold(args[0], "REPLCONF") && strings.EqualFold(args[1], "GETACK") {
+			// The reported offset does not include the GETACK itself
+			if err := send("REPLCONF", "ACK", strconv.FormatInt(rp.currentOffset(), 10)); err != nil {
+				return err
+			}
+		} else {
+			rp.r.dispatch(l.applyCtx, resptypes.ToBulkStringArray(args))
+		}
+
+		rp.forward(l, redislib.AppendCommand(nil, args...))
+	}
+}
+
+// readSnapshot reads the RDB payload of a full resync, a bulk string without the trailing CRLF.
+func readSnapshot(r *bufio.Reader) ([]byte, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	header = strings.TrimRight(header, "\r\n")
+	if !strings.HasPrefix(header, "$") {
+		return nil, fmt.Errorf("expected a bulk string, got %q", header)
+	}
+
+	length, err := strconv.Atoi(header[1:])
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid snapshot length %q", header)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// fullSync replaces the dataset with the primary's snapshot.
+func (rp *replication) fullSync(l *primaryLink, replid string, offset int64, payload []byte) error {
+	r := rp.r
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+	if l.ctx.Err() != nil {
+		return errLinkClosed
+	}
+
+	for i := range r.dbs.Len() {
+		r.dbs.DB(i).Clear(false)
+	}
+
+	r.tracking.invalidateAll(nil)
+	r.persistence.dirty.Add(1)
+	r.persistence.loading.Store(true)
+	err := r.persistence.loadFrom(bytes.NewReader(payload), "primary snapshot")
+	r.persistence.loading.Store(false)
+
+	rp.mu.Lock()
+	// Sub-replicas hold the previous dataset
+	rp.disconnectReplicasNoLock()
+	rp.backlog.reset()
+	rp.streamDb = -1
+	rp.replid2, rp.secondOffset = strings.Repeat("0", 40), -1
+	if err != nil {
+		// Never continue a history the dataset does not match
+		rp.replid = newReplid()
+		rp.mu.Unlock()
+		return err
+	}
+
+	rp.replid, rp.offset = replid, offset
+	rp.mu.Unlock()
+
+	l.resetClient()
+	if r.aof.isEnabled() {
+		if err := r.aof.rewrite(false); err != nil {
+			slog.Error("Failed to rewrite the AOF after a full resynchronization", "error", err)
+		}
+	}
+
+	slog.Info("Full resynchronization completed", "replid", replid, "offset", offset)
+	return nil
+}
+
+// continueSync adopts the primary's replication ID after a partial resync. A new ID means the
+// primary was promoted; the previous history stays valid for our own replicas.
+func (rp *replication) continueSync(l *primaryLink, replid string) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if replid != "" && replid != rp.replid {
+		rp.replid2, rp.secondOffset = rp.replid, rp.offset+1
+		rp.replid = replid
+	}
+
+	slog.Info("Partial resynchronization completed", "replid", rp.replid, "offset", rp.offset)
+}
+
+// start pings the replicas periodically, so that they can tell a quiet primary from a dead one.
+func (rp *replication) start() {
+	rp.wg.Go(func() {
+		ticker := time.NewTicker(replPingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rp.stop:
+				return
+			case <-ticker.C:
+				rp.mu.Lock()
+				if rp.primary == nil && len(rp.replicas) > 0 {
+					rp.feedNoLock(redislib.AppendCommand(nil, "PING"))
+				}
+				rp.mu.Unlock()
+			}
+		}
+	})
+}
+
+func (rp *replication) close() {
+	rp.mu.Lock()
+	if rp.primary != nil {
+		rp.primary.cancel()
+	}
+	rp.mu.Unlock()
+	close(rp.stop)
+	rp.wg.Wait()
+}
+
+func (rp *replication) resetStats() {
+	rp.syncFull.Store(0)
+	rp.syncPartialOk.Store(0)
+	rp.syncPartialErr.Store(0)
+}
+
+func (rp *replication) infoStats() []string {
+	return []string{
+		fmt.Sprintf("sync_full:%d", rp.syncFull.Load()),
+		fmt.Sprintf("sync_partial_ok:%d", rp.syncPartialOk.Load()),
+		fmt.Sprintf("sync_partial_err:%d", rp.syncPartialErr.Load()),
+	}
+}
+
+func (rp *replication) info() []string {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	var lines []string
+	if l := rp.primary; l != nil {
+		lastIO := int64(-1)
+		if t := l.lastIO.Load(); t != 0 {
+			lastIO = time.Now().Unix() - t
+		}
+
+		status := "down"
+		if l.up.Load() {
+			status = "up"
+		}
+
+		lines = append(lines,
+			"role:slave",
+			"master_host:"+l.host,
+			fmt.Sprintf("master_port:%d", l.port),
+			"master_link_status:"+status,
+			fmt.Sprintf("master_last_io_seconds_ago:%d", lastIO),
+			fmt.Sprintf("master_sync_in_progress:%d", boolToInt(l.syncing.Load())),
+			fmt.Sprintf("slave_read_repl_offset:%d", rp.offset),
+			fmt.Sprintf("slave_repl_offset:%d", rp.offset),
+			fmt.Sprintf("slave_priority:%d", rp.priority.Load()),
+			fmt.Sprintf("slave_read_only:%d", boolToInt(rp.readOnly.Load())),
+		)
+	} else {
+		lines = append(lines, "role:master")
+	}
+
+	lines = append(lines, fmt.Sprintf("connected_slaves:%d", len(rp.replicas)))
+	if rp.minReplicas.Load() > 0 {
+		lines = append(lines, fmt.Sprintf("min_slaves_good_slaves:%d", rp.goodReplicasNoLock()))
+	}
+
+	now := time.Now().UnixMilli()
+	for i, l := range rp.replicas {
+		host, _, _ := net.SplitHostPort(l.client.addr)
+		l.mu.Lock()
+		state := "wait_bgsave"
+		if l.online {
+			state = "online"
+		}
+		l.mu.Unlock()
+		lines = append(lines, fmt.Sprintf("slave%d:ip=%s,port=%d,state=%s,offset=%d,lag=%d",
+			i, host, l.port, state, l.ackOffset.Load(), (now-l.lastAck.Load())/1000))
+	}
+
+	return append(lines,
+		"master_failover_state:no-failover",
+		"master_replid:"+rp.replid,
+		"master_replid2:"+rp.replid2,
+		fmt.Sprintf("master_repl_offset:%d", rp.offset),
+		fmt.Sprintf("second_repl_offset:%d", rp.secondOffset),
+		"repl_backlog_active:1",
+		fmt.Sprintf("repl_backlog_size:%d", len(rp.backlog.buf)),
+		fmt.Sprintf("repl_backlog_first_byte_offset:%d", rp.offset-int64(rp.backlog.histlen)+1),
+		fmt.Sprintf("repl_backlog_histlen:%d", rp.backlog.histlen),
+	)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
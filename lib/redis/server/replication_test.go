@@ -0,0 +1,316 @@
+package redisserverlib_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/logger"
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+// listen opens a listener on a free loopback port.
+func listen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return listener
+}
+
+// serve accepts connections for cp on a loopback port, like the server binary does.
+// It returns the port and a function dropping every open connection.
+func serve(t *testing.T, cp redisserverlib.CommandProcessor) (int, func()) {
+	return serveListener(t, listen(t), cp)
+}
+
+// serveListener is serve for processors that need to know their port before accepting connections.
+func serveListener(t *testing.T, listener net.Listener, cp redisserverlib.CommandProcessor) (int, func()) {
+	var (
+		mu    sync.Mutex
+		conns = make(map[net.Conn]struct{})
+		wg    sync.WaitGroup
+	)
+	dropAll := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for conn := range conns {
+			conn.Close()
+		}
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		dropAll()
+		wg.Wait()
+	})
+
+	handle := func(conn net.Conn) {
+		defer func() {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(conns, conn)
+		}()
+
+		ctx := context.WithValue(context.Background(), logger.ClientKey, conn.RemoteAddr().String())
+		ctx, out := cp.ConnectClient(ctx)
+		defer context.AfterFunc(ctx, func() { conn.Close() })()
+
+		var writer sync.WaitGroup
+		writer.Go(func() {
+			defer conn.Close()
+			for str := range out {
+				if _, err := conn.Write([]byte(str)); err != nil {
+					return
+				}
+			}
+		})
+
+		reader := bufio.NewReader(conn)
+		for {
+			args, _, err := redislib.ReadCommand(reader)
+			if err != nil {
+				break
+			}
+
+			if reply := cp.ExecuteCommand(ctx, string(redislib.AppendCommand(nil, args...))).ToRespString(); reply != "" {
+				out <- reply
+			}
+		}
+
+		cp.DisconnectClient(ctx)
+		writer.Wait()
+	}
+
+	wg.Go(func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns[conn] = struct{}{}
+			mu.Unlock()
+			wg.Go(func() { handle(conn) })
+		}
+	})
+
+	return listener.Addr().(*net.TCPAddr).Port, dropAll
+}
+
+// eventually retries a command until it returns the expected reply.
+func (c testClient) eventually(t *testing.T, expected string, args ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		actual := c.do(args...)
+		if actual == expected {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%v = %q; Expected eventually: %q", args, actual, expected)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// infoField returns a field of the INFO report.
+func (c testClient) infoField(section string, field string) string {
+	for line := range strings.SplitSeq(c.do("INFO", section), "\r\n") {
+		if value, found := strings.CutPrefix(line, field+":"); found {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func TestReplication(t *testing.T) {
+	primary := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	defer primary.Close()
+	primaryPort, dropConnections := serve(t, primary)
+
+	replica := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithPort(7777))
+	defer replica.Close()
+
+	p := newTestClient(primary)
+	r := newTestClient(replica)
+	p.expect(t, "+OK\r\n", "SET", "before", "1")
+	r.expect(t, "+OK\r\n", "SET", "stale", "x")
+
+	t.Run("Full resynchronization", func(t *testing.T) {
+		r.expect(t, "+OK\r\n", "REPLICAOF", "127.0.0.1", strconv.Itoa(primaryPort))
+		r.expect(t, "+OK Already connected to specified master\r\n", "REPLICAOF", "127.0.0.1", strconv.Itoa(primaryPort))
+		r.eventually(t, "$1\r\n1\r\n", "GET", "before")
+		r.expect(t, "$-1\r\n", "GET", "stale")
+	})
+
+	t.Run("Write commands are propagated", func(t *testing.T) {
+		p.expect(t, ":2\r\n", "RPUSH", "list", "a", "b")
+		p.expect(t, "$3\r\n1-1\r\n", "XADD", "stream", "1-1", "f", "v")
+		p.expect(t, "+OK\r\n", "SELECT", "3")
+		p.expect(t, "+OK\r\n", "MULTI")
+		p.expect(t, "+QUEUED\r\n", "SET", "a", "1")
+		p.expect(t, "+QUEUED\r\n", "SET", "b", "2")
+		p.expect(t, "*2\r\n+OK\r\n+OK\r\n", "EXEC")
+		p.expect(t, "+OK\r\n", "SELECT", "0")
+
+		r.expect(t, "+OK\r\n", "SELECT", "3")
+		r.eventually(t, "$1\r\n2\r\n", "GET", "b")
+		r.expect(t, "$1\r\n1\r\n", "GET", "a")
+		r.expect(t, "+OK\r\n", "SELECT", "0")
+		r.expect(t, "*2\r\n$1\r\na\r\n$1\r\nb\r\n", "LRANGE", "list", "0", "-1")
+		r.expect(t, "*1\r\n*2\r\n$3\r\n1-1\r\n*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "XRANGE", "stream", "-", "+")
+	})
+
+	t.Run("Replicas reject writes", func(t *testing.T) {
+		r.expect(t, "-READONLY You can't write against a read only replica.\r\n", "SET", "k", "v")
+		r.expect(t, "+OK\r\n", "MULTI")
+		r.do("SET", "k", "v")
+		r.expect(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+	})
+
+	t.Run("INFO replication", func(t *testing.T) {
+		if role := p.infoField("replication", "role"); role != "master" {
+			t.Errorf("Primary role = %q", role)
+		}
+
+		if slave := p.infoField("replication", "slave0"); !strings.HasPrefix(slave, "ip=127.0.0.1,port=7777,state=online,") {
+			t.Errorf("Primary slave0 = %q", slave)
+		}
+
+		if role := r.infoField("replication", "role"); role != "slave" {
+			t.Errorf("Replica role = %q", role)
+		}
+
+		if status := r.infoField("replication", "master_link_status"); status != "up" {
+			t.Errorf("Replica master_link_status = %q", status)
+		}
+
+		primaryOffset := p.infoField("replication", "master_repl_offset")
+		if offset := r.infoField("replication", "slave_repl_offset"); offset != primaryOffset {
+			t.Errorf("Replica offset = %s; Primary offset: %s", offset, primaryOffset)
+		}
+	})
+
+	t.Run("Partial resynchronization after a disconnect", func(t *testing.T) {
+		dropConnections()
+		p.expect(t, "+OK\r\n", "SET", "during", "disconnect")
+		r.eventually(t, "$10\r\ndisconnect\r\n", "GET", "during")
+		p.expect(t, "+OK\r\n", "SET", "after", "reconnect")
+		r.eventually(t, "$9\r\nreconnect\r\n", "GET", "after")
+
+		if full := p.infoField("stats", "sync_full"); full != "1" {
+			t.Errorf("sync_full = %s; Expected: 1", full)
+		}
+
+		if partial := p.infoField("stats", "sync_partial_ok"); partial != "1" {
+			t.Errorf("sync_partial_ok = %s; Expected: 1", partial)
+		}
+	})
+
+	t.Run("REPLICAOF NO ONE promotes the replica", func(t *testing.T) {
+		primaryReplid := p.infoField("replication", "master_replid")
+		r.expect(t, "+OK\r\n", "REPLICAOF", "NO", "ONE")
+		if role := r.infoField("replication", "role"); role != "master" {
+			t.Errorf("Promoted role = %q", role)
+		}
+
+		if replid2 := r.infoField("replication", "master_replid2"); replid2 != primaryReplid {
+			t.Errorf("Promoted master_replid2 = %q; Expected: %q", replid2, primaryReplid)
+		}
+
+		r.expect(t, "+OK\r\n", "SET", "k", "v")
+		r.expect(t, "$9\r\nreconnect\r\n", "GET", "after")
+	})
+}
+
+// startReplica creates a processor replicating from the primary at port and waits for the link.
+func startReplica(t *testing.T, primaryPort int, opts ...redisserverlib.Option) testClient {
+	replica := redisserverlib.NewRedisCommandProcessor(append(opts, redisserverlib.WithDir(t.TempDir()))...)
+	t.Cleanup(func() { replica.Close() })
+	r := newTestClient(replica)
+	r.expect(t, "+OK\r\n", "REPLICAOF", "127.0.0.1", strconv.Itoa(primaryPort))
+	deadline := time.Now().Add(5 * time.Second)
+	for r.infoField("replication", "master_link_status") != "up" {
+		if time.Now().After(deadline) {
+			t.Fatal("Replica did not connect to the primary")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return r
+}
+
+func TestWait(t *testing.T) {
+	primary := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	defer primary.Close()
+	primaryPort, _ := serve(t, primary)
+	p := newTestClient(primary)
+	first := startReplica(t, primaryPort)
+	second := startReplica(t, primaryPort)
+
+	p.expect(t, "+OK\r\n", "SET", "k", "v")
+	p.expect(t, ":2\r\n", "WAIT", "2", "0")
+	first.expect(t, "$1\r\nv\r\n", "GET", "k")
+	second.expect(t, "$1\r\nv\r\n", "GET", "k")
+
+	start := time.Now()
+	p.expect(t, ":2\r\n", "WAIT", "3", "100")
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("WAIT returned after %v; Expected it to wait for the timeout", elapsed)
+	}
+
+	first.expect(t, "-ERR WAIT cannot be used with replica instances\r\n", "WAIT", "1", "0")
+	p.expect(t, "-ERR timeout is negative\r\n", "WAIT", "1", "-1")
+
+	// Inside a transaction, WAIT does not block
+	p.expect(t, "+OK\r\n", "MULTI")
+	p.expect(t, "+QUEUED\r\n", "SET", "k", "w")
+	p.expect(t, "+QUEUED\r\n", "WAIT", "3", "0")
+	if reply := p.do("EXEC"); !strings.HasPrefix(reply, "*2\r\n+OK\r\n:") {
+		t.Errorf("EXEC = %q", reply)
+	}
+
+	p.expect(t, ":2\r\n", "WAIT", "2", "1000")
+	second.expect(t, "$1\r\nw\r\n", "GET", "k")
+}
+
+func TestMinReplicasToWrite(t *testing.T) {
+	primary := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()), redisserverlib.WithMinReplicasToWrite(2))
+	defer primary.Close()
+	primaryPort, _ := serve(t, primary)
+	p := newTestClient(primary)
+
+	p.expect(t, "-NOREPLICAS Not enough good replicas to write.\r\n", "SET", "k", "v")
+	p.expect(t, "$-1\r\n", "GET", "k")
+
+	first := startReplica(t, primaryPort)
+	p.expect(t, "-NOREPLICAS Not enough good replicas to write.\r\n", "SET", "k", "v")
+	p.expect(t, "+OK\r\n", "MULTI")
+	p.do("SET", "k", "v")
+	p.expect(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+
+	startReplica(t, primaryPort)
+	p.eventually(t, "+OK\r\n", "SET", "k", "v")
+	if good := p.infoField("replication", "min_slaves_good_slaves"); good != "2" {
+		t.Errorf("min_slaves_good_slaves = %q; Expected: 2", good)
+	}
+
+	// A replica that leaves no longer counts
+	first.expect(t, "+OK\r\n", "REPLICAOF", "NO", "ONE")
+	p.eventually(t, "-NOREPLICAS Not enough good replicas to write.\r\n", "SET", "k", "v")
+	p.expect(t, "+OK\r\n", "CONFIG", "SET", "min-replicas-to-write", "1")
+	p.expect(t, "+OK\r\n", "SET", "k", "v")
+	p.expect(t, "-ERR CONFIG SET failed (possibly related to argument 'min-replicas-max-lag') - argument must be a non-negative integer\r\n", "CONFIG", "SET", "min-replicas-max-lag", "-1")
+}
@@ -0,0 +1,145 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	redisrdblib "github.com/codecrafters-io/redis-starter-go/lib/redis/rdb"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// restore implements both RESTORE and RESTORE-ASKING, which MIGRATE sends to the target
+	// of a slot migration so that it is served before the slot is assigned to the target.
+	restore struct {
+		keyspace
+		asking bool
+	}
+)
+
+func (c restore) moniker() string {
+	if c.asking {
+		return "RESTORE-ASKING"
+	}
+
+	return "RESTORE"
+}
+
+func (c restore) spec() commandSpec {
+	spec := commandSpec{
+		arity:      -4,
+		flags:      flagWrite,
+		categories: []string{"keyspace", "dangerous"},
+		keySpecs:   firstKey(keyOW),
+		group:      "generic",
+		summary:    "Creates a key from the serialized representation of a value.",
+		since:      "2.6.0",
+		complexity: "O(1) to create the new key and additional O(N*M) to reconstruct the serialized value, where N is the number of Redis objects composing the value and M their average size.",
+		args: []commandArg{
+			{name: "key", typ: argKey},
+			{name: "ttl", typ: argInteger},
+			{name: "serialized-value", typ: argString},
+			{name: "replace", typ: argPureToken, token: "REPLACE", optional: true},
+			{name: "absttl", typ: argPureToken, token: "ABSTTL", optional: true},
+			{name: "seconds", typ: argInteger, token: "IDLETIME", optional: true},
+			{name: "frequency", typ: argInteger, token: "FREQ", optional: true},
+		},
+	}
+	if c.asking {
+		spec.flags |= flagAsking
+		spec.summary = "An internal command for migrating keys in a cluster."
+		spec.since = "3.0.0"
+	}
+
+	return spec
+}
+
+func (c restore) getUsage() string {
+	return fmt.Sprintf(`
+usage:
+	%s key ttl serialized-value [REPLACE] [ABSTTL] [IDLETIME seconds] [FREQ frequency]
+summary:
+	Create a key associated with a value that is obtained by deserializing the provided serialized value (obtained via DUMP).
+	If ttl is 0 the key is created without any expire, otherwise the specified expire time (in milliseconds) is set.
+	With ABSTTL, ttl is an absolute Unix time in milliseconds. IDLETIME and FREQ are accepted and ignored.
+	Fails with BUSYKEY if key already exists, unless REPLACE is given.
+`, c.moniker())
+}
+
+func (c restore) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 4 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %s requires key, ttl and serialized-value! %s", c.moniker(), c.getUsage())}
+	}
+
+	key := params[1].Val
+	ttlMs, err := strconv.ParseInt(params[2].Val, 10, 64)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	if ttlMs < 0 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid TTL value, must be >= 0")}
+	}
+
+	replace, absTTL := false, false
+	for i := 4; i < len(params); i++ {
+		switch option := strings.ToUpper(params[i].Val); {
+		case option == "REPLACE":
+			replace = true
+		case option == "ABSTTL":
+			absTTL = true
+		case (option == "IDLETIME" || option == "FREQ") && i+1 < len(params):
+			if _, err := strconv.ParseInt(params[i+1].Val, 10, 64); err != nil {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+			}
+
+			i++
+		default:
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+		}
+	}
+
+	if _, exists := c.db(ctx).Get(key); exists && !replace {
+		return resptypes.SimpleError{Val: fmt.Errorf("BUSYKEY Target key name already exists.")}
+	}
+
+	dsVal, err := redisrdblib.Restore([]byte(params[3].Val))
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+	}
+
+	expiresAt := time.Time{}
+	if ttlMs > 0 {
+		if absTTL {
+			expiresAt = time.UnixMilli(ttlMs)
+		} else {
+			expiresAt = commandTime(ctx).Add(time.Duration(ttlMs) * time.Millisecond)
+		}
+	}
+
+	ttl := time.Duration(0)
+	if !expiresAt.IsZero() {
+		if ttl = time.Until(expiresAt); ttl <= 0 {
+			// Already expired: the key is only removed
+			propagateAs(ctx, "DEL", key)
+			c.db(ctx).Delete(key)
+			c.modified(ctx, key)
+			return resptypes.SimpleString{Val: "OK"}
+		}
+	}
+
+	// Replays must neither fail on an existing key nor extend the expiry
+	if expiresAt.IsZero() {
+		propagateAs(ctx, "RESTORE", key, "0", params[3].Val, "REPLACE")
+	} else {
+		propagateAs(ctx, "RESTORE", key, strconv.FormatInt(expiresAt.UnixMilli(), 10), params[3].Val, "REPLACE", "ABSTTL")
+	}
+
+	c.db(ctx).Set(key, dsVal, ttl)
+	c.modified(ctx, key)
+	c.notify(ctx, notifyGeneric, "restore", key)
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -10,7 +10,7 @@ import (
 
 type (
 	rpush struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -18,6 +18,20 @@ func (c rpush) moniker() string {
 	return "RPUSH"
 }
 
+func (c rpush) spec() commandSpec {
+	return commandSpec{
+		arity:      -3,
+		flags:      flagWrite | flagFast,
+		categories: []string{"list"},
+		keySpecs:   firstKey(keyRWInsert),
+		group:      "list",
+		summary:    "Appends one or more elements to a list. Creates the key if it doesn't exist.",
+		since:      "1.0.0",
+		complexity: "O(1) for each element added, so O(N) to add N elements when the command is called with multiple arguments.",
+		args:       []commandArg{{name: "key", typ: argKey}, {name: "element", typ: argString, multiple: true}},
+	}
+}
+
 func (c rpush) getUsage() string {
 	return `
 usage:
@@ -35,12 +49,14 @@ func (c rpush) execute(ctx context.Context, params commandParams) commandResult
 	}
 
 	listName := params[1].Val
-	dsVal := c.GetOrCreate(listName, redistypes.NewList, 0)
+	dsVal := c.getOrCreate(ctx, listName, redistypes.NewList)
 
 	if dsVal.Type != redistypes.TypeList {
 		return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 	}
 
 	newLen := dsVal.List.PushBack(params[2:]...)
+	c.modified(ctx, listName)
+	c.notify(ctx, notifyList, "rpush", listName)
 	return resptypes.Integer{Val: int64(newLen)}
 }
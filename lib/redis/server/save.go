@@ -0,0 +1,54 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	save struct {
+		*persistence
+	}
+)
+
+func (c save) moniker() string {
+	return "SAVE"
+}
+
+func (c save) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagExclusive | flagAdmin,
+		group:      "server",
+		summary:    "Synchronously saves the database(s) to disk.",
+		since:      "1.0.0",
+		complexity: "O(N) where N is the total number of keys in all databases",
+	}
+}
+
+func (c save) getUsage() string {
+	return `
+usage:
+	SAVE
+summary:
+	Synchronously save the dataset to disk as an RDB snapshot. No other command runs until the snapshot is written.
+`
+}
+
+func (c save) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 1 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SAVE takes no arguments! %s", c.getUsage())}
+	}
+
+	if err := c.save(); err != nil {
+		if err == errBgsaveInProgress {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR %w", err)}
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -0,0 +1,61 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	selectCmd struct {
+		keyspace
+		cluster *cluster
+	}
+)
+
+func (c selectCmd) moniker() string {
+	return "SELECT"
+}
+
+func (c selectCmd) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagFast,
+		categories: []string{"connection"},
+		group:      "connection",
+		summary:    "Changes the selected database.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "index", typ: argInteger}},
+	}
+}
+
+func (c selectCmd) getUsage() string {
+	return `
+usage:
+	SELECT index
+summary:
+	Select the Redis logical database having the specified zero-based numeric index.
+	New connections always use the database 0.
+	The selected database is a property of the connection.
+`
+}
+
+func (c selectCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SELECT requires exactly one argument! %s", c.getUsage())}
+	}
+
+	index, err := c.parseDbIndex(params[1].Val)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	if c.cluster != nil && index != 0 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SELECT is not allowed in cluster mode")}
+	}
+
+	clientFromContext(ctx).db = index
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -0,0 +1,879 @@
+package redisserverlib
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	redislib "github.com/codecrafters-io/redis-starter-go/lib/redis/common"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// sentinelConn is a connection from a sentinel to a monitored instance or another sentinel.
+	sentinelConn struct {
+		conn   net.Conn
+		reader *bufio.Reader
+	}
+
+	// sentinelInstance is a primary or replica monitored by a sentinel. Its fields are
+	// guarded by the sentinel's lock.
+	sentinelInstance struct {
+		host string
+		port int
+
+		// Last valid reply to PING
+		lastPong time.Time
+		// As last reported by INFO replication
+		role        string
+		primaryHost string
+		primaryPort int
+		offset      int64
+		priority    int
+		// Since when the instance is configured differently from what the sentinel expects
+		mismatchSince time.Time
+		// Commands for the instance link to send, like REPLICAOF during failovers
+		commands chan []string
+	}
+
+	// peerSentinel is another sentinel monitoring the same primary, discovered through hello messages.
+	peerSentinel struct {
+		id        string
+		host      string
+		port      int
+		lastHello time.Time
+		conn      *sentinelConn
+		querying  bool
+
+		// From its last reply to SENTINEL IS-MASTER-DOWN-BY-ADDR
+		downAt      time.Time
+		leader      string
+		leaderEpoch uint64
+	}
+
+	// sentinelPrimary is a primary monitored under a name, together with its replicas and the
+	// other sentinels monitoring it.
+	sentinelPrimary struct {
+		name        string
+		quorum      int
+		primary     *sentinelInstance
+		instances   map[string]*sentinelInstance
+		peers       map[string]*peerSentinel
+		configEpoch uint64
+
+		// Subjectively down: the primary did not reply for down-after-milliseconds.
+		// Objectively down: a quorum of sentinels agrees that it is subjectively down.
+		sdown bool
+		odown bool
+
+		// The sentinel's vote for the leader of a failover in leaderEpoch
+		leader      string
+		leaderEpoch uint64
+
+		failover      failoverState
+		failoverEpoch uint64
+		failoverStart time.Time
+		// Random delay after the primary is objectively down, so the sentinels do not all
+		// become candidates in the same epoch and split the votes
+		failoverAfter time.Time
+		promoted      *sentinelInstance
+	}
+
+	failoverState int
+
+	// sentinel monitors primaries and fails them over to one of their replicas once a quorum
+	// of sentinels agrees they are down. Sentinels discover each other and the replicas through
+	// the monitored instances: replicas are listed in the primary's INFO, and every sentinel
+	// publishes hello messages on the __sentinel__:hello channel of every instance.
+	sentinel struct {
+		id              string
+		port            int
+		downAfter       time.Duration
+		failoverTimeout time.Duration
+		period          time.Duration
+
+		mu           sync.Mutex
+		currentEpoch uint64
+		primaries    map[string]*sentinelPrimary
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+)
+
+const (
+	failoverNone failoverState = iota
+	// Waiting for the other sentinels to elect this one as the leader
+	failoverWaitStart
+	failoverSelectReplica
+	// Waiting for the selected replica to report it became a primary
+	failoverWaitPromotion
+)
+
+const sentinelHelloChannel = "__sentinel__:hello"
+
+var (
+	errNoSuchPrimary = errors.New("ERR No such master with that name")
+	errNoGoodReplica = errors.New("NOGOODSLAVE No suitable replica to promote")
+	errInProgress    = errors.New("INPROG Failover already in progress")
+)
+
+func (s failoverState) String() string {
+	switch s {
+	case failoverWaitStart:
+		return "wait_start"
+	case failoverSelectReplica:
+		return "select_slave"
+	case failoverWaitPromotion:
+		return "wait_promotion"
+	default:
+		return "none"
+	}
+}
+
+func dialSentinelConn(ctx context.Context, addr string, timeout time.Duration) (*sentinelConn, error) {
+	conn, err := (&net.Dialer{Timeout: timeout}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sentinelConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// do sends a command and reads its reply. Error replies are returned as values.
+func (c *sentinelConn) do(timeout time.Duration, args ...string) (resptypes.RespSerializable, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := c.conn.Write(redislib.AppendCommand(nil, args...)); err != nil {
+		return nil, err
+	}
+
+	return redislib.ReadReply(c.reader)
+}
+
+func (c *sentinelConn) close() {
+	c.conn.Close()
+}
+
+func newSentinel(options processorOptions) *sentinel {
+	s := &sentinel{
+		id:              newNodeId(),
+		port:            options.port,
+		downAfter:       options.sentinelDownAfter,
+		failoverTimeout: options.sentinelFailoverTimeout,
+		// Instances are pinged several times within down-after-milliseconds
+		period:    min(max(options.sentinelDownAfter/10, 10*time.Millisecond), time.Second),
+		primaries: make(map[string]*sentinelPrimary),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+func (s *sentinel) close() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func addrOf(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func (i *sentinelInstance) addr() string {
+	return addrOf(i.host, i.port)
+}
+
+// monitor starts monitoring the primary at host:port under name.
+func (s *sentinel) monitor(name string, host string, port int, quorum int) error {
+	if quorum <= 0 {
+		return fmt.Errorf("ERR Quorum must be 1 or greater.")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.primaries[name]; exists {
+		return fmt.Errorf("ERR Duplicated master name.")
+	}
+
+	mp := &sentinelPrimary{
+		name:      name,
+		quorum:    quorum,
+		instances: make(map[string]*sentinelInstance),
+		peers:     make(map[string]*peerSentinel),
+	}
+	mp.primary = s.instanceNoLock(mp, host, port)
+	s.primaries[name] = mp
+	if s.ctx.Err() == nil {
+		s.wg.Go(func() { s.runPrimary(mp) })
+	}
+
+	slog.Info("Sentinel monitoring", "name", name, "addr", mp.primary.addr(), "quorum", quorum)
+	return nil
+}
+
+// instanceNoLock returns the instance at host:port, starting to monitor it if it is new.
+func (s *sentinel) instanceNoLock(mp *sentinelPrimary, host string, port int) *sentinelInstance {
+	addr := addrOf(host, port)
+	if inst, exists := mp.instances[addr]; exists {
+		return inst
+	}
+
+	// A new instance gets the full down-after period to answer
+	inst := &sentinelInstance{host: host, port: port, lastPong: time.Now(), priority: 100, commands: make(chan []string, 16)}
+	mp.instances[addr] = inst
+	if s.ctx.Err() == nil {
+		s.wg.Go(func() { s.runInstance(mp, inst) })
+		s.wg.Go(func() { s.runHello(mp, inst) })
+	}
+
+	return inst
+}
+
+// sleep waits for one period, reporting false once the sentinel is closed.
+func (s *sentinel) sleep() bool {
+	select {
+	case <-s.ctx.Done():
+		return false
+	case <-time.After(s.period):
+		return true
+	}
+}
+
+// runInstance pings the instance, refreshes its replication info and publishes hello messages
+// on it, reconnecting whenever the link breaks.
+func (s *sentinel) runInstance(mp *sentinelPrimary, inst *sentinelInstance) {
+	for s.sleep() {
+		conn, err := dialSentinelConn(s.ctx, inst.addr(), s.downAfter)
+		if err != nil {
+			continue
+		}
+
+		stop := context.AfterFunc(s.ctx, conn.close)
+		for s.refreshInstance(mp, inst, conn) == nil && s.sleep() {
+		}
+
+		stop()
+		conn.close()
+	}
+}
+
+func (s *sentinel) refreshInstance(mp *sentinelPrimary, inst *sentinelInstance, conn *sentinelConn) error {
+	for pending := true; pending; {
+		select {
+		case args := <-inst.commands:
+			reply, err := conn.do(s.downAfter, args...)
+			if err != nil {
+				return err
+			}
+
+			slog.Info("Sentinel reconfigured instance", "addr", inst.addr(), "command", strings.Join(args, " "), "reply", strings.TrimSpace(reply.ToRespString()))
+		default:
+			pending = false
+		}
+	}
+
+	reply, err := conn.do(s.downAfter, "PING")
+	if err != nil {
+		return err
+	}
+
+	if valid, _ := reply.(resptypes.SimpleString); valid.Val == "PONG" {
+		s.mu.Lock()
+		inst.lastPong = time.Now()
+		s.mu.Unlock()
+	}
+
+	reply, err = conn.do(s.downAfter, "INFO", "replication")
+	if err != nil {
+		return err
+	}
+
+	if report, ok := reply.(resptypes.BulkString); ok {
+		s.mu.Lock()
+		s.refreshInfoNoLock(mp, inst, report.Val)
+		s.mu.Unlock()
+	}
+
+	host, _, _ := net.SplitHostPort(conn.conn.LocalAddr().String())
+	s.mu.Lock()
+	hello := fmt.Sprintf("%s,%d,%s,%d,%s,%s,%d,%d", host, s.port, s.id, s.currentEpoch,
+		mp.name, mp.primary.host, mp.primary.port, mp.configEpoch)
+	s.mu.Unlock()
+	_, err = conn.do(s.downAfter, "PUBLISH", sentinelHelloChannel, hello)
+	return err
+}
+
+// refreshInfoNoLock applies an INFO replication report of inst.
+func (s *sentinel) refreshInfoNoLock(mp *sentinelPrimary, inst *sentinelInstance, report string) {
+	inst.role, inst.primaryHost, inst.primaryPort = "", "", 0
+	for line := range strings.SplitSeq(report, "\r\n") {
+		field, value, _ := strings.Cut(line, ":")
+		switch {
+		case field == "role":
+			inst.role = value
+		case field == "master_host":
+			inst.primaryHost = value
+		case field == "master_port":
+			inst.primaryPort, _ = strconv.Atoi(value)
+		case field == "slave_repl_offset":
+			inst.offset, _ = strconv.ParseInt(value, 10, 64)
+		case field == "slave_priority":
+			inst.priority, _ = strconv.Atoi(value)
+		case strings.HasPrefix(field, "slave") && inst == mp.primary:
+			// slave<n>:ip=<host>,port=<port>,...
+			var host string
+			var port int
+			for kv := range strings.SplitSeq(value, ",") {
+				k, v, _ := strings.Cut(kv, "=")
+				switch k {
+				case "ip":
+					host = v
+				case "port":
+					port, _ = strconv.Atoi(v)
+				}
+			}
+
+			if host != "" && port > 0 {
+				s.instanceNoLock(mp, host, port)
+			}
+		}
+	}
+
+	if inst == mp.primary || mp.failover != failoverNone || mp.sdown {
+		inst.mismatchSince = time.Time{}
+		return
+	}
+
+	// Replicas that are primaries themselves, like a failed primary that came back, or that
+	// replicate from elsewhere are pointed at the current primary. The delay leaves time for
+	// hello messages announcing a failover by another sentinel to arrive.
+	if inst.role == "slave" && addrOf(inst.primaryHost, inst.primaryPort) == mp.primary.addr() {
+		inst.mismatchSince = time.Time{}
+		return
+	}
+
+	now := time.Now()
+	if inst.mismatchSince.IsZero() {
+		inst.mismatchSince = now
+	} else if now.Sub(inst.mismatchSince) > 8*s.period {
+		inst.mismatchSince = now
+		s.sendNoLock(inst, "REPLICAOF", mp.primary.host, strconv.Itoa(mp.primary.port))
+	}
+}
+
+// sendNoLock queues a command for the instance link.
+func (s *sentinel) sendNoLock(inst *sentinelInstance, args ...string) {
+	select {
+	case inst.commands <- args:
+	default:
+		slog.Warn("Sentinel dropped a command for a busy instance", "addr", inst.addr(), "command", args[0])
+	}
+}
+
+// runHello subscribes to the hello messages published on the instance.
+func (s *sentinel) runHello(mp *sentinelPrimary, inst *sentinelInstance) {
+	for s.sleep() {
+		conn, err := dialSentinelConn(s.ctx, inst.addr(), s.downAfter)
+		if err != nil {
+			continue
+		}
+
+		stop := context.AfterFunc(s.ctx, conn.close)
+		if _, err := conn.do(s.downAfter, "SUBSCRIBE", sentinelHelloChannel); err == nil {
+			// Messages arrive whenever a sentinel publishes, so the read does not time out
+			conn.conn.SetDeadline(time.Time{})
+			for {
+				reply, err := redislib.ReadReply(conn.reader)
+				if err != nil {
+					break
+				}
+
+				if msg, ok := reply.(resptypes.Array[resptypes.RespSerializable]); ok && len(msg) == 3 {
+					if payload, ok := msg[2].(resptypes.BulkString); ok {
+						s.processHello(payload.Val)
+					}
+				}
+			}
+		}
+
+		stop()
+		conn.close()
+	}
+}
+
+// processHello learns about another sentinel, and about a newer configuration of the primary
+// it announces, e.g. after it failed the primary over.
+func (s *sentinel) processHello(hello string) {
+	fields := strings.Split(hello, ",")
+	if len(fields) != 8 || fields[2] == s.id {
+		return
+	}
+
+	port, err1 := strconv.Atoi(fields[1])
+	epoch, err2 := strconv.ParseUint(fields[3], 10, 64)
+	primaryPort, err3 := strconv.Atoi(fields[6])
+	configEpoch, err4 := strconv.ParseUint(fields[7], 10, 64)
+	if err := errors.Join(err1, err2, err3, err4); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mp, exists := s.primaries[fields[4]]
+	if !exists {
+		return
+	}
+
+	s.currentEpoch = max(s.currentEpoch, epoch)
+	peer, exists := mp.peers[fields[2]]
+	if !exists {
+		// A sentinel restarted with a new ID replaces its old entry
+		for id, other := range mp.peers {
+			if other.host == fields[0] && other.port == port {
+				s.removePeerNoLock(mp, id)
+			}
+		}
+
+		peer = &peerSentinel{id: fields[2]}
+		mp.peers[peer.id] = peer
+		slog.Info("Sentinel discovered", "name", mp.name, "id", peer.id, "addr", addrOf(fields[0], port))
+	}
+
+	peer.host, peer.port, peer.lastHello = fields[0], port, time.Now()
+	if configEpoch <= mp.configEpoch {
+		return
+	}
+
+	if addr := addrOf(fields[5], primaryPort); addr != mp.primary.addr() {
+		s.switchPrimaryNoLock(mp, s.instanceNoLock(mp, fields[5], primaryPort), configEpoch)
+	} else {
+		mp.configEpoch = configEpoch
+	}
+}
+
+func (s *sentinel) removePeerNoLock(mp *sentinelPrimary, id string) {
+	if peer := mp.peers[id]; peer.conn != nil {
+		peer.conn.close()
+	}
+
+	delete(mp.peers, id)
+}
+
+// runPrimary checks the primary's health and drives failovers.
+func (s *sentinel) runPrimary(mp *sentinelPrimary) {
+	for s.sleep() {
+		s.mu.Lock()
+		s.checkPrimaryNoLock(mp)
+		s.mu.Unlock()
+	}
+}
+
+func (s *sentinel) checkPrimaryNoLock(mp *sentinelPrimary) {
+	now := time.Now()
+	mp.sdown = now.Sub(mp.primary.lastPong) > s.downAfter
+	mp.odown = false
+	if mp.sdown {
+		agreed := 1
+		for _, peer := range mp.peers {
+			if now.Sub(peer.downAt) < 5*s.period {
+				agreed++
+			}
+		}
+
+		mp.odown = agreed >= mp.quorum
+	}
+
+	switch mp.failover {
+	case failoverNone:
+		if mp.sdown {
+			s.askPeersNoLock(mp, "*", s.currentEpoch)
+		}
+
+		switch {
+		case !mp.odown || now.Sub(mp.failoverStart) <= 2*s.failoverTimeout:
+			mp.failoverAfter = time.Time{}
+		case mp.failoverAfter.IsZero():
+			mp.failoverAfter = now.Add(rand.N(10 * s.period))
+		case now.After(mp.failoverAfter):
+			mp.failoverAfter = time.Time{}
+			s.startFailoverNoLock(mp, false)
+		}
+	case failoverWaitStart:
+		votes := 1
+		for _, peer := range mp.peers {
+			if peer.leader == s.id && peer.leaderEpoch == mp.failoverEpoch {
+				votes++
+			}
+		}
+
+		if votes >= max(mp.quorum, (len(mp.peers)+1)/2+1) {
+			slog.Info("Sentinel elected leader", "name", mp.name, "epoch", mp.failoverEpoch, "votes", votes)
+			mp.failover = failoverSelectReplica
+		} else {
+			s.askPeersNoLock(mp, s.id, mp.failoverEpoch)
+		}
+	case failoverSelectReplica:
+		replica := s.selectReplicaNoLock(mp)
+		if replica == nil {
+			s.abortFailoverNoLock(mp, "no suitable replica")
+			return
+		}
+
+		slog.Info("Sentinel promoting replica", "name", mp.name, "addr", replica.addr())
+		mp.promoted = replica
+		mp.failover = failoverWaitPromotion
+		s.sendNoLock(replica, "REPLICAOF", "NO", "ONE")
+	case failoverWaitPromotion:
+		if mp.promoted.role == "master" {
+			for _, inst := range mp.instances {
+				if inst != mp.promoted && inst != mp.primary {
+					s.sendNoLock(inst, "REPLICAOF", mp.promoted.host, strconv.Itoa(mp.promoted.port))
+				}
+			}
+
+			s.switchPrimaryNoLock(mp, mp.promoted, mp.failoverEpoch)
+			return
+		}
+	}
+
+	if mp.failover != failoverNone && now.Sub(mp.failoverStart) > s.failoverTimeout {
+		s.abortFailoverNoLock(mp, "timeout")
+	}
+}
+
+// startFailoverNoLock starts a failover in a new epoch. A forced failover, as with SENTINEL FAILOVER,
+// does not wait for the other sentinels to agree.
+func (s *sentinel) startFailoverNoLock(mp *sentinelPrimary, forced bool) {
+	s.currentEpoch++
+	mp.failoverEpoch, mp.failoverStart = s.currentEpoch, time.Now()
+	mp.leader, mp.leaderEpoch = s.id, s.currentEpoch
+	mp.failover = failoverWaitStart
+	if forced {
+		mp.failover = failoverSelectReplica
+	}
+
+	slog.Info("Sentinel starting failover", "name", mp.name, "epoch", mp.failoverEpoch, "forced", forced)
+}
+
+func (s *sentinel) abortFailoverNoLock(mp *sentinelPrimary, reason string) {
+	slog.Warn("Sentinel failover aborted", "name", mp.name, "epoch", mp.failoverEpoch, "reason", reason)
+	mp.failover, mp.promoted = failoverNone, nil
+}
+
+// selectReplicaNoLock picks the replica to promote: one that replies, replicates from the primary
+// and may be promoted, preferring lower priorities, then the most data, then the lowest address.
+func (s *sentinel) selectReplicaNoLock(mp *sentinelPrimary) *sentinelInstance {
+	now := time.Now()
+	candidates := []*sentinelInstance{}
+	for _, inst := range mp.instances {
+		if inst == mp.primary || inst.role != "slave" || inst.priority == 0 || now.Sub(inst.lastPong) > 5*s.period {
+			continue
+		}
+
+		if addrOf(inst.primaryHost, inst.primaryPort) == mp.primary.addr() {
+			candidates = append(candidates, inst)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return slices.MinFunc(candidates, func(a, b *sentinelInstance) int {
+		return cmp.Or(cmp.Compare(a.priority, b.priority), cmp.Compare(b.offset, a.offset), strings.Compare(a.addr(), b.addr()))
+	})
+}
+
+// switchPrimaryNoLock makes inst the primary in configEpoch. The previous primary is
+// reconfigured as a replica once it is reachable again.
+func (s *sentinel) switchPrimaryNoLock(mp *sentinelPrimary, inst *sentinelInstance, configEpoch uint64) {
+	slog.Info("Sentinel switched primary", "name", mp.name, "from", mp.primary.addr(), "to", inst.addr(), "configEpoch", configEpoch)
+	mp.primary.mismatchSince = time.Time{}
+	mp.primary, mp.configEpoch = inst, configEpoch
+	inst.lastPong = time.Now()
+	mp.sdown, mp.odown = false, false
+	mp.failover, mp.promoted = failoverNone, nil
+	for _, peer := range mp.peers {
+		peer.downAt = time.Time{}
+	}
+}
+
+// askPeersNoLock asks the other sentinels whether they consider the primary down. With the
+// sentinel's own ID instead of "*", it also asks for their vote in the current epoch.
+func (s *sentinel) askPeersNoLock(mp *sentinelPrimary, runId string, epoch uint64) {
+	args := []string{"SENTINEL", "IS-MASTER-DOWN-BY-ADDR", mp.primary.host, strconv.Itoa(mp.primary.port), strconv.FormatUint(epoch, 10), runId}
+	for _, peer := range mp.peers {
+		if peer.querying || s.ctx.Err() != nil {
+			continue
+		}
+
+		peer.querying = true
+		conn, addr := peer.conn, addrOf(peer.host, peer.port)
+		s.wg.Go(func() {
+			reply, err := s.queryPeer(&conn, addr, args)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			peer.querying, peer.conn = false, conn
+			if mp.peers[peer.id] != peer {
+				// Removed meanwhile
+				if conn != nil {
+					conn.close()
+				}
+
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			down, _ := reply[0].(resptypes.Integer)
+			leader, _ := reply[1].(resptypes.BulkString)
+			leaderEpoch, _ := reply[2].(resptypes.Integer)
+			if down.Val == 1 {
+				peer.downAt = time.Now()
+			}
+
+			if leader.Val != "*" {
+				peer.leader, peer.leaderEpoch = leader.Val, uint64(leaderEpoch.Val)
+			}
+		})
+	}
+}
+
+func (s *sentinel) queryPeer(conn **sentinelConn, addr string, args []string) (resptypes.Array[resptypes.RespSerializable], error) {
+	if *conn == nil {
+		c, err := dialSentinelConn(s.ctx, addr, s.downAfter)
+		if err != nil {
+			return nil, err
+		}
+
+		*conn = c
+	}
+
+	reply, err := (*conn).do(s.downAfter, args...)
+	if err != nil {
+		(*conn).close()
+		*conn = nil
+		return nil, err
+	}
+
+	if arr, ok := reply.(resptypes.Array[resptypes.RespSerializable]); ok && len(arr) == 3 {
+		return arr, nil
+	}
+
+	return nil, fmt.Errorf("unexpected reply %q", reply.ToRespString())
+}
+
+// isPrimaryDownByAddr answers SENTINEL IS-MASTER-DOWN-BY-ADDR. With a sentinel ID instead of "*",
+// it also votes for that sentinel as the leader of a failover in epoch, unless it already voted
+// in that epoch, and returns its vote.
+func (s *sentinel) isPrimaryDownByAddr(host string, port int, epoch uint64, runId string) (bool, string, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, mp := range s.primaries {
+		if mp.primary.addr() != addrOf(host, port) {
+			continue
+		}
+
+		if runId == "*" {
+			return mp.sdown, "*", 0
+		}
+
+		s.currentEpoch = max(s.currentEpoch, epoch)
+		if mp.leaderEpoch < epoch {
+			mp.leader, mp.leaderEpoch = runId, epoch
+			if runId != s.id {
+				// Leave the failover to the candidate
+				mp.failoverStart = time.Now()
+				slog.Info("Sentinel voted for leader", "name", mp.name, "leader", runId, "epoch", epoch)
+			}
+		}
+
+		return mp.sdown, mp.leader, mp.leaderEpoch
+	}
+
+	return false, "*", 0
+}
+
+// forceFailover starts a failover without asking the other sentinels, as with SENTINEL FAILOVER.
+func (s *sentinel) forceFailover(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mp, exists := s.primaries[name]
+	if !exists {
+		return errNoSuchPrimary
+	}
+
+	if mp.failover != failoverNone {
+		return errInProgress
+	}
+
+	if s.selectReplicaNoLock(mp) == nil {
+		return errNoGoodReplica
+	}
+
+	s.startFailoverNoLock(mp, true)
+	return nil
+}
+
+// primaryAddr returns the address of the primary monitored under name.
+func (s *sentinel) primaryAddr(name string) (string, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mp, exists := s.primaries[name]
+	if !exists {
+		return "", 0, false
+	}
+
+	return mp.primary.host, mp.primary.port, true
+}
+
+func (s *sentinel) primaryFieldsNoLock(mp *sentinelPrimary) []string {
+	flags := "master"
+	if mp.sdown {
+		flags += ",s_down"
+	}
+
+	if mp.odown {
+		flags += ",o_down"
+	}
+
+	if mp.failover != failoverNone {
+		flags += ",failover_in_progress"
+	}
+
+	return []string{
+		"name", mp.name,
+		"ip", mp.primary.host,
+		"port", strconv.Itoa(mp.primary.port),
+		"flags", flags,
+		"last-ok-ping-reply", strconv.FormatInt(time.Since(mp.primary.lastPong).Milliseconds(), 10),
+		"down-after-milliseconds", strconv.FormatInt(s.downAfter.Milliseconds(), 10),
+		"num-slaves", strconv.Itoa(len(mp.instances) - 1),
+		"num-other-sentinels", strconv.Itoa(len(mp.peers)),
+		"quorum", strconv.Itoa(mp.quorum),
+		"config-epoch", strconv.FormatUint(mp.configEpoch, 10),
+		"failover-timeout", strconv.FormatInt(s.failoverTimeout.Milliseconds(), 10),
+		"failover-state", mp.failover.String(),
+	}
+}
+
+// primaryFields returns the SENTINEL MASTERS fields of every primary, or of the one named name.
+func (s *sentinel) primaryFields(name string) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name != "" {
+		mp, exists := s.primaries[name]
+		if !exists {
+			return nil, errNoSuchPrimary
+		}
+
+		return [][]string{s.primaryFieldsNoLock(mp)}, nil
+	}
+
+	result := [][]string{}
+	for _, name := range slices.Sorted(maps.Keys(s.primaries)) {
+		result = append(result, s.primaryFieldsNoLock(s.primaries[name]))
+	}
+
+	return result, nil
+}
+
+// replicaFields returns the SENTINEL REPLICAS fields of the replicas of the primary named name.
+func (s *sentinel) replicaFields(name string) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mp, exists := s.primaries[name]
+	if !exists {
+		return nil, errNoSuchPrimary
+	}
+
+	now := time.Now()
+	result := [][]string{}
+	for _, addr := range slices.Sorted(maps.Keys(mp.instances)) {
+		inst := mp.instances[addr]
+		if inst == mp.primary {
+			continue
+		}
+
+		flags := "slave"
+		if now.Sub(inst.lastPong) > s.downAfter {
+			flags += ",s_down"
+		}
+
+		result = append(result, []string{
+			"name", addr,
+			"ip", inst.host,
+			"port", strconv.Itoa(inst.port),
+			"flags", flags,
+			"role-reported", inst.role,
+			"master-host", inst.primaryHost,
+			"master-port", strconv.Itoa(inst.primaryPort),
+			"slave-priority", strconv.Itoa(inst.priority),
+			"slave-repl-offset", strconv.FormatInt(inst.offset, 10),
+		})
+	}
+
+	return result, nil
+}
+
+// sentinelFields returns the SENTINEL SENTINELS fields of the other sentinels monitoring name.
+func (s *sentinel) sentinelFields(name string) ([][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mp, exists := s.primaries[name]
+	if !exists {
+		return nil, errNoSuchPrimary
+	}
+
+	result := [][]string{}
+	for _, id := range slices.Sorted(maps.Keys(mp.peers)) {
+		peer := mp.peers[id]
+		result = append(result, []string{
+			"name", peer.id,
+			"ip", peer.host,
+			"port", strconv.Itoa(peer.port),
+			"runid", peer.id,
+			"last-hello-message", strconv.FormatInt(time.Since(peer.lastHello).Milliseconds(), 10),
+			"voted-leader", cmp.Or(peer.leader, "?"),
+			"voted-leader-epoch", strconv.FormatUint(peer.leaderEpoch, 10),
+		})
+	}
+
+	return result, nil
+}
+
+// info returns the fields of the Sentinel section of INFO.
+func (s *sentinel) info() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lines := []string{fmt.Sprintf("sentinel_masters:%d", len(s.primaries))}
+	for i, name := range slices.Sorted(maps.Keys(s.primaries)) {
+		mp := s.primaries[name]
+		status := "ok"
+		if mp.odown {
+			status = "odown"
+		} else if mp.sdown {
+			status = "sdown"
+		}
+
+		lines = append(lines, fmt.Sprintf("master%d:name=%s,status=%s,address=%s,slaves=%d,sentinels=%d",
+			i, name, status, mp.primary.addr(), len(mp.instances)-1, len(mp.peers)+1))
+	}
+
+	return lines
+}
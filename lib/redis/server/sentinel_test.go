@@ -0,0 +1,139 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+type sentinelTestInstance struct {
+	testClient
+	listener net.Listener
+	port     int
+	drop     func()
+}
+
+// startInstance serves a data processor on a loopback port, replicating from primaryPort unless it is 0.
+func startInstance(t *testing.T, listener net.Listener, primaryPort int, opts ...redisserverlib.Option) *sentinelTestInstance {
+	port := listenerPort(listener)
+	opts = append(opts, redisserverlib.WithDir(t.TempDir()), redisserverlib.WithPort(port))
+	if primaryPort != 0 {
+		opts = append(opts, redisserverlib.WithReplicaOf(fmt.Sprintf("127.0.0.1 %d", primaryPort)))
+	}
+
+	cp := redisserverlib.NewRedisCommandProcessor(opts...)
+	t.Cleanup(func() { cp.Close() })
+	_, drop := serveListener(t, listener, cp)
+	return &sentinelTestInstance{testClient: newTestClient(cp), listener: listener, port: port, drop: drop}
+}
+
+// stop makes the instance unreachable like a crashed server.
+func (i *sentinelTestInstance) stop() {
+	i.listener.Close()
+	i.drop()
+}
+
+func startSentinel(t *testing.T, primaryPort int) testClient {
+	listener := listen(t)
+	cp := redisserverlib.NewSentinelProcessor(
+		redisserverlib.WithPort(listenerPort(listener)),
+		redisserverlib.WithSentinelMonitor("mymaster", "127.0.0.1", primaryPort, 2),
+		redisserverlib.WithSentinelDownAfter(200*time.Millisecond),
+		redisserverlib.WithSentinelFailoverTimeout(2*time.Second),
+	)
+	t.Cleanup(func() { cp.Close() })
+	serveListener(t, listener, cp)
+	return newTestClient(cp)
+}
+
+func primaryAddrReply(port int) string {
+	return fmt.Sprintf("*2\r\n$9\r\n127.0.0.1\r\n$%d\r\n%d\r\n", len(strconv.Itoa(port)), port)
+}
+
+func TestSentinel(t *testing.T) {
+	primaryListener := listen(t)
+	p := startInstance(t, primaryListener, 0)
+	r1 := startInstance(t, listen(t), p.port)
+	r2 := startInstance(t, listen(t), p.port, redisserverlib.WithReplicaPriority(0))
+	sentinels := []testClient{startSentinel(t, p.port), startSentinel(t, p.port), startSentinel(t, p.port)}
+
+	for _, s := range sentinels {
+		waitFor(t, "the sentinels to discover the replicas and each other", func() bool {
+			fields := s.do("SENTINEL", "MASTER", "mymaster")
+			return strings.Contains(fields, "$10\r\nnum-slaves\r\n$1\r\n2\r\n") &&
+				strings.Contains(fields, "$19\r\nnum-other-sentinels\r\n$1\r\n2\r\n")
+		})
+	}
+
+	t.Run("Introspection", func(t *testing.T) {
+		s := sentinels[0]
+		s.expect(t, primaryAddrReply(p.port), "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "mymaster")
+		s.expect(t, "*-1\r\n", "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "unknown")
+		s.expect(t, "-ERR No such master with that name\r\n", "SENTINEL", "REPLICAS", "unknown")
+		s.expect(t, "-ERR Duplicated master name.\r\n", "SENTINEL", "MONITOR", "mymaster", "127.0.0.1", "1", "2")
+		waitFor(t, "the replica priorities", func() bool {
+			return strings.Contains(s.do("SENTINEL", "REPLICAS", "mymaster"), "$14\r\nslave-priority\r\n$1\r\n0\r\n")
+		})
+
+		if status := s.infoField("sentinel", "master0"); status != fmt.Sprintf("name=mymaster,status=ok,address=127.0.0.1:%d,slaves=2,sentinels=3", p.port) {
+			t.Errorf("master0 = %q", status)
+		}
+
+		s.expect(t, "-NOTSUPPORTED Command 'SET' is not supported in sentinel mode!\r\n", "SET", "k", "v")
+	})
+
+	t.Run("Automatic failover", func(t *testing.T) {
+		p.expect(t, "+OK\r\n", "SET", "k", "v")
+		r1.eventually(t, "$1\r\nv\r\n", "GET", "k")
+		r2.eventually(t, "$1\r\nv\r\n", "GET", "k")
+		p.stop()
+
+		// r2 may not be promoted with priority 0
+		for _, s := range sentinels {
+			s.eventually(t, primaryAddrReply(r1.port), "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "mymaster")
+		}
+
+		waitFor(t, "r2 to replicate from r1", func() bool {
+			return r2.infoField("replication", "master_port") == strconv.Itoa(r1.port) &&
+				r2.infoField("replication", "master_link_status") == "up"
+		})
+		r1.expect(t, "+OK\r\n", "SET", "after", "failover")
+		r2.eventually(t, "$8\r\nfailover\r\n", "GET", "after")
+		r2.expect(t, "$1\r\nv\r\n", "GET", "k")
+	})
+
+	// The restarted primary has to outlive the subtest
+	listener, err := net.Listen("tcp", primaryListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p = startInstance(t, listener, 0)
+	t.Run("Failed primary rejoins as a replica", func(t *testing.T) {
+		waitFor(t, "the old primary to replicate from r1", func() bool {
+			return p.infoField("replication", "master_port") == strconv.Itoa(r1.port) &&
+				p.infoField("replication", "master_link_status") == "up"
+		})
+		p.eventually(t, "$8\r\nfailover\r\n", "GET", "after")
+	})
+
+	t.Run("SENTINEL FAILOVER", func(t *testing.T) {
+		// The only replica that may be promoted is the old primary
+		sentinels[1].eventually(t, "+OK\r\n", "SENTINEL", "FAILOVER", "mymaster")
+		for _, s := range sentinels {
+			s.eventually(t, primaryAddrReply(p.port), "SENTINEL", "GET-MASTER-ADDR-BY-NAME", "mymaster")
+		}
+
+		waitFor(t, "r1 and r2 to replicate from the new primary", func() bool {
+			return r1.infoField("replication", "master_port") == strconv.Itoa(p.port) &&
+				r2.infoField("replication", "master_port") == strconv.Itoa(p.port)
+		})
+		p.expect(t, "+OK\r\n", "SET", "k", "w")
+		r1.eventually(t, "$1\r\nw\r\n", "GET", "k")
+	})
+}
@@ -0,0 +1,188 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	sentinelCmd struct {
+		*sentinel
+	}
+)
+
+func (c sentinelCmd) moniker() string {
+	return "SENTINEL"
+}
+
+func (c sentinelCmd) spec() commandSpec {
+	return commandSpec{
+		arity:   -2,
+		flags:   flagAdmin,
+		group:   "sentinel",
+		summary: "A container for Redis Sentinel commands.",
+		since:   "2.8.4",
+		subcommands: []commandSpec{
+			{
+				name: "get-master-addr-by-name", arity: 3, summary: "Returns the port and address of a master instance.", since: "2.8.4",
+				complexity: "O(1)", args: []commandArg{{name: "master-name", typ: argString}},
+			},
+			{name: "masters", arity: 2, summary: "Returns a list of monitored masters.", since: "2.8.4", complexity: "O(N) where N is the number of masters"},
+			{
+				name: "master", arity: 3, summary: "Returns the state of a master instance.", since: "2.8.4", complexity: "O(1)",
+				args: []commandArg{{name: "master-name", typ: argString}},
+			},
+			{
+				name: "replicas", arity: 3, summary: "Returns a list of the monitored replicas.", since: "5.0.0",
+				complexity: "O(N) where N is the number of replicas", args: []commandArg{{name: "master-name", typ: argString}},
+			},
+			{
+				name: "slaves", arity: 3, summary: "Returns a list of the monitored replicas.", since: "2.8.0",
+				complexity: "O(N) where N is the number of replicas", args: []commandArg{{name: "master-name", typ: argString}},
+			},
+			{
+				name: "sentinels", arity: 3, summary: "Returns a list of Sentinel instances.", since: "2.8.4",
+				complexity: "O(N) where N is the number of Sentinels", args: []commandArg{{name: "master-name", typ: argString}},
+			},
+			{
+				name: "failover", arity: 3, summary: "Forces a Sentinel failover.", since: "2.8.4",
+				args: []commandArg{{name: "master-name", typ: argString}},
+			},
+			{
+				name: "monitor", arity: 6, summary: "Starts monitoring.", since: "2.8.4", complexity: "O(1)",
+				args: []commandArg{
+					{name: "name", typ: argString},
+					{name: "ip", typ: argString},
+					{name: "port", typ: argInteger},
+					{name: "quorum", typ: argInteger},
+				},
+			},
+			{
+				name: "is-master-down-by-addr", arity: 6, summary: "Determines whether a master instance is down.", since: "2.8.4",
+				complexity: "O(1)",
+				args: []commandArg{
+					{name: "ip", typ: argString},
+					{name: "port", typ: argInteger},
+					{name: "current-epoch", typ: argInteger},
+					{name: "runid", typ: argString},
+				},
+			},
+			{name: "myid", arity: 2, summary: "Returns the Sentinel instance ID.", since: "6.2.0", complexity: "O(1)"},
+		},
+	}
+}
+
+func (c sentinelCmd) getUsage() string {
+	return `
+usage:
+	SENTINEL GET-MASTER-ADDR-BY-NAME master-name
+	SENTINEL MASTERS
+	SENTINEL MASTER master-name
+	SENTINEL REPLICAS master-name
+	SENTINEL SENTINELS master-name
+	SENTINEL FAILOVER master-name
+	SENTINEL MONITOR master-name ip port quorum
+	SENTINEL IS-MASTER-DOWN-BY-ADDR ip port current-epoch runid
+	SENTINEL MYID
+summary:
+	Inspects and controls the primaries monitored by the sentinel.
+	GET-MASTER-ADDR-BY-NAME returns the address of the current primary, which changes after a failover.
+	MASTERS, MASTER, REPLICAS and SENTINELS describe the primaries, their replicas and the other sentinels.
+	FAILOVER promotes a replica right away, without asking the other sentinels.
+	IS-MASTER-DOWN-BY-ADDR is used between sentinels to agree that a primary is down and to elect the
+	sentinel that fails it over.
+`
+}
+
+func fieldsToArray(fields [][]string) commandResult {
+	result := make(resptypes.Array[resptypes.RespSerializable], len(fields))
+	for i, f := range fields {
+		result[i] = resptypes.ToBulkStringArray(f)
+	}
+
+	return result
+}
+
+func (c sentinelCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SENTINEL requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "GET-MASTER-ADDR-BY-NAME" && len(params) == 3:
+		host, port, exists := c.primaryAddr(params[2].Val)
+		if !exists {
+			return resptypes.NullArray
+		}
+
+		return resptypes.ToBulkStringArray([]string{host, strconv.Itoa(port)})
+	case subcommand == "MASTERS" && len(params) == 2:
+		fields, _ := c.primaryFields("")
+		return fieldsToArray(fields)
+	case subcommand == "MASTER" && len(params) == 3:
+		fields, err := c.primaryFields(params[2].Val)
+		if err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.ToBulkStringArray(fields[0])
+	case (subcommand == "REPLICAS" || subcommand == "SLAVES") && len(params) == 3:
+		fields, err := c.replicaFields(params[2].Val)
+		if err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return fieldsToArray(fields)
+	case subcommand == "SENTINELS" && len(params) == 3:
+		fields, err := c.sentinelFields(params[2].Val)
+		if err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return fieldsToArray(fields)
+	case subcommand == "FAILOVER" && len(params) == 3:
+		if err := c.forceFailover(params[2].Val); err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "MONITOR" && len(params) == 6:
+		port, err := strconv.Atoi(params[4].Val)
+		if err != nil || port <= 0 || port > 65535 {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid port number")}
+		}
+
+		quorum, err := strconv.Atoi(params[5].Val)
+		if err != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+		}
+
+		if err := c.monitor(params[2].Val, params[3].Val, port, quorum); err != nil {
+			return resptypes.SimpleError{Val: err}
+		}
+
+		return resptypes.SimpleString{Val: "OK"}
+	case subcommand == "IS-MASTER-DOWN-BY-ADDR" && len(params) == 6:
+		port, err1 := strconv.Atoi(params[3].Val)
+		epoch, err2 := strconv.ParseUint(params[4].Val, 10, 64)
+		if err1 != nil || err2 != nil {
+			return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+		}
+
+		down, leader, leaderEpoch := c.isPrimaryDownByAddr(params[2].Val, port, epoch, params[5].Val)
+		return resptypes.Array[resptypes.RespSerializable]{
+			resptypes.Integer{Val: int64(boolToInt(down))},
+			resptypes.NewBulkString(leader),
+			resptypes.Integer{Val: int64(leaderEpoch)},
+		}
+	case subcommand == "MYID" && len(params) == 2:
+		return resptypes.NewBulkString(c.id)
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown SENTINEL subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
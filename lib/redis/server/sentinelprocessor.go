@@ -0,0 +1,104 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/lib/logger"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	// sentinelProcessor serves the commands of a server running in Sentinel mode, which holds
+	// no dataset but monitors primaries and their replicas.
+	sentinelProcessor struct {
+		commands commandMap
+		clients  *clientList
+		sentinel *sentinel
+		options  processorOptions
+		*middlewareChain
+	}
+)
+
+// NewSentinelProcessor returns the processor of a server running in Sentinel mode. It monitors the
+// primaries given with WithSentinelMonitor and fails them over when they stop replying. WithPort
+// must be set to the port the server listens on, which is announced to the other sentinels.
+func NewSentinelProcessor(opts ...Option) CommandProcessor {
+	options := newProcessorOptions(opts)
+	p := &sentinelProcessor{
+		commands: make(commandMap),
+		clients:  newClientList(),
+		sentinel: newSentinel(options),
+		options:  options,
+	}
+	p.middlewareChain = newMiddlewareChain(dispatchHandler(p.dispatch))
+
+	p.commands.registerCommand(ping{})
+	p.commands.registerCommand(echo{})
+	p.commands.registerCommand(help{p.commands})
+	p.commands.registerCommand(commandCmd{p.commands})
+	p.commands.registerCommand(info{[]infoSection{
+		{title: "Server", fields: func() []string { return options.metrics.serverInfo("sentinel", options.port, options.configFile) }},
+		{title: "Clients", fields: func() []string { return options.metrics.clientsInfo(p.clients.len()) }},
+		{title: "Sentinel", fields: p.sentinel.info},
+	}})
+	p.commands.registerCommand(sentinelCmd{p.sentinel})
+
+	for _, m := range options.sentinelMonitors {
+		if err := p.sentinel.monitor(m.name, m.host, m.port, m.quorum); err != nil {
+			slog.Error("Ignoring invalid sentinel monitor", "name", m.name, "error", err)
+		}
+	}
+
+	return p
+}
+
+func (p *sentinelProcessor) ConnectClient(ctx context.Context) (context.Context, chan string) {
+	c := newClient()
+	if addr, ok := ctx.Value(logger.ClientKey).(string); ok {
+		c.addr = addr
+	}
+
+	ctx, c.kill = context.WithCancel(ctx)
+	c.out = make(chan string, max(1, p.options.outputBufferLimit))
+	p.clients.add(c)
+	p.options.metrics.connectionsReceived.Add(1)
+	return contextWithClient(ctx, c), c.out
+}
+
+func (p *sentinelProcessor) DisconnectClient(ctx context.Context) {
+	c := clientFromContext(ctx)
+	p.clients.remove(c)
+	c.close()
+}
+
+func (p *sentinelProcessor) Close() error {
+	p.sentinel.close()
+	return nil
+}
+
+func (p *sentinelProcessor) ExecuteCommand(ctx context.Context, respStr string) resptypes.RespSerializable {
+	params, errResult := parseRequest(respStr)
+	if errResult != nil {
+		slog.DebugContext(ctx, "Malformed request received", "respStr", respStr)
+		return errResult
+	}
+
+	return p.serve(ctx, newRequest(clientFromContext(ctx), "", params, p.commands))
+}
+
+func (p *sentinelProcessor) dispatch(ctx context.Context, params commandParams) commandResult {
+	commandName := strings.ToUpper(params[0].Val)
+	entry, ok := p.commands[commandName]
+	if !ok {
+		return resptypes.SimpleError{Val: fmt.Errorf("NOTSUPPORTED Command '%s' is not supported in sentinel mode!", commandName)}
+	}
+
+	if err := entry.spec().checkArity(params); err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	return entry.execute(ctx, params)
+}
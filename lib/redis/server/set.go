@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	redistypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/redis"
@@ -12,7 +13,7 @@ import (
 
 type (
 	set struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -20,15 +21,38 @@ func (c set) moniker() string {
 	return "SET"
 }
 
+func (c set) spec() commandSpec {
+	return commandSpec{
+		arity:      -3,
+		flags:      flagWrite,
+		categories: []string{"string"},
+		keySpecs:   firstKey(keyRW),
+		group:      "string",
+		summary:    "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args: []commandArg{
+			{name: "key", typ: argKey},
+			{name: "value", typ: argString},
+			{name: "expiration", typ: argOneOf, optional: true, args: []commandArg{
+				{name: "milliseconds", typ: argInteger, token: "PX"},
+				{name: "unix-time-milliseconds", typ: argUnixTime, token: "PXAT"},
+			}},
+		},
+	}
+}
+
 func (c set) getUsage() string {
 	return `
 usage:
-	set key value [PX milliseconds]
+	set key value [PX milliseconds | PXAT unix-time-milliseconds]
 
 summary:
 	Set key to hold the string value.
 	If key already holds a value, it is overwritten, regardless of its type.
 	Any previous time to live associated with the key is discarded on successful SET operation.
+	PX sets an expiry relative to now, PXAT at an absolute Unix time. A PXAT time in the past deletes the key.
+	As in Redis, the expiry must be a positive integer, and an unknown or repeated option is a syntax error.
 `
 }
 
@@ -48,29 +72,55 @@ func (c set) execute(ctx context.Context, params commandParams) commandResult {
 			return resptypes.SimpleError{Val: fmt.Errorf("ERR Value cannot be empty!")}
 		}
 
-		expiryDurationMs := 0
-		err := error(nil)
-		for i := 3; i < arrSize; i++ {
-			if tokens[i].Val == "PX" {
-				if i+1 >= arrSize {
-					return resptypes.SimpleError{Val: fmt.Errorf("ERR No expiration specified!")}
-				}
-
-				expiry := tokens[i+1].Val
-				expiryDurationMs, err = strconv.Atoi(expiry)
-				if err != nil {
-					return resptypes.SimpleError{Val: fmt.Errorf("ERR Could not convert %s to an int for expiry! Err: %w", expiry, err)}
-				}
-
-				break
+		expiresAt := time.Time{}
+		for i := 3; i < arrSize; i += 2 {
+			option := strings.ToUpper(tokens[i].Val)
+			if option != "PX" && option != "PXAT" || !expiresAt.IsZero() || i+1 >= arrSize {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR syntax error")}
+			}
+
+			expiryMs, err := strconv.ParseInt(tokens[i+1].Val, 10, 64)
+			if err != nil {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+			}
+
+			if expiryMs <= 0 {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR invalid expire time in 'set' command")}
 			}
+
+			if option == "PXAT" {
+				expiresAt = time.UnixMilli(expiryMs)
+			} else {
+				expiresAt = commandTime(ctx).Add(time.Duration(expiryMs) * time.Millisecond)
+			}
+		}
+
+		if expiresAt.IsZero() {
+			propagateAs(ctx, "SET", key, value)
+		} else {
+			// Replays must not extend the expiry
+			propagateAs(ctx, "SET", key, value, "PXAT", strconv.FormatInt(expiresAt.UnixMilli(), 10))
 		}
 
-		dsVal := c.GetOrCreate(key, redistypes.NewString(value), time.Duration(expiryDurationMs)*time.Millisecond)
-		if dsVal.Type != redistypes.TypeString {
-			return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
+		ttl := time.Duration(0)
+		if !expiresAt.IsZero() {
+			// Measured against the same clock as PX, so that every node applying the command agrees
+			if ttl = expiresAt.Sub(commandTime(ctx)); ttl <= 0 {
+				c.db(ctx).Delete(key)
+				c.modified(ctx, key)
+				return resptypes.SimpleString{Val: "OK"}
+			}
 		}
 
+		if replaced := c.db(ctx).Set(key, redistypes.NewString(value)(), ttl); !replaced {
+			c.notify(ctx, notifyNew, "new", key)
+		}
+
+		c.modified(ctx, key)
+		c.notify(ctx, notifyString, "set", key)
+		if ttl > 0 {
+			c.notify(ctx, notifyGeneric, "expire", key)
+		}
 		return resptypes.SimpleString{Val: "OK"}
 	case arrSize == 2:
 		return resptypes.SimpleError{Val: fmt.Errorf("ERR No value given for key %s!", tokens[1].Val)}
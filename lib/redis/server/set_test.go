@@ -0,0 +1,46 @@
+package redisserverlib_test
+
+import (
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestSet(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("Overwrites keys of any type", func(t *testing.T) {
+		c.expect(t, ":2\r\n", "RPUSH", "list", "a", "b")
+		c.expect(t, "+OK\r\n", "SET", "list", "v")
+		c.expect(t, "+string\r\n", "TYPE", "list")
+		c.expect(t, "$1\r\nv\r\n", "GET", "list")
+		c.expect(t, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n", "LLEN", "list")
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"SET", "k", "v", "NX"},
+			{"SET", "k", "v", "PXX", "100"},
+			{"SET", "k", "v", "PX"},
+			{"SET", "k", "v", "PX", "100", "PXAT", "99999999999999"},
+			{"SET", "k", "v", "px", "100", "PX", "100"},
+		} {
+			c.expect(t, "-ERR syntax error\r\n", args...)
+		}
+
+		c.expect(t, "-ERR invalid expire time in 'set' command\r\n", "SET", "k", "v", "PX", "0")
+		c.expect(t, "-ERR invalid expire time in 'set' command\r\n", "SET", "k", "v", "PX", "-5")
+		c.expect(t, "-ERR invalid expire time in 'set' command\r\n", "SET", "k", "v", "PXAT", "0")
+		c.expect(t, "-ERR value is not an integer or out of range\r\n", "SET", "k", "v", "PX", "soon")
+		c.expect(t, "$-1\r\n", "GET", "k")
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "SET", "k", "v", "px", "100000")
+		c.expect(t, "$1\r\nv\r\n", "GET", "k")
+		c.expect(t, "+OK\r\n", "SET", "k", "v", "PXAT", "1")
+		c.expect(t, "$-1\r\n", "GET", "k")
+	})
+}
@@ -0,0 +1,115 @@
+package redisserverlib
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// slowLog remembers the latest commands that ran for longer than a threshold, for SLOWLOG.
+	slowLog struct {
+		// In microseconds. Commands are logged when they run for longer, never when it is negative.
+		slowerThan atomic.Int64
+		maxLen     atomic.Int64
+
+		mu     sync.Mutex
+		lastId int64
+		// Oldest first
+		entries []slowLogEntry
+	}
+
+	slowLogEntry struct {
+		id        int64
+		timestamp int64
+		duration  time.Duration
+		args      []string
+		addr      string
+		name      string
+	}
+)
+
+const (
+	// Arguments beyond the first ones are summarized, like in Redis
+	slowLogMaxArgs = 32
+	// Longer arguments are truncated
+	slowLogMaxArgLen = 128
+)
+
+func newSlowLog(options processorOptions) *slowLog {
+	l := &slowLog{}
+	l.slowerThan.Store(options.slowlogLogSlowerThan.Microseconds())
+	l.maxLen.Store(int64(options.slowlogMaxLen))
+	return l
+}
+
+// record logs a command c ran for d, if it is slow enough.
+func (l *slowLog) record(c *client, params commandParams, d time.Duration) {
+	threshold := l.slowerThan.Load()
+	if threshold < 0 || d.Microseconds() < threshold {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, slowLogEntry{
+		id:        l.lastId,
+		timestamp: time.Now().Unix(),
+		duration:  d,
+		args:      truncateSlowLogArgs(redactArgs(argsOf(params))),
+		addr:      c.addr,
+		name:      c.name,
+	})
+	l.lastId++
+	if excess := len(l.entries) - int(max(0, l.maxLen.Load())); excess > 0 {
+		l.entries = append([]slowLogEntry(nil), l.entries[excess:]...)
+	}
+}
+
+// latest returns up to count entries, newest first. A negative count returns every entry.
+func (l *slowLog) latest(count int) []slowLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+
+	latest := make([]slowLogEntry, count)
+	for i := range latest {
+		latest[i] = l.entries[len(l.entries)-1-i]
+	}
+
+	return latest
+}
+
+func (l *slowLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// reset drops the entries. IDs keep increasing.
+func (l *slowLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+func truncateSlowLogArgs(args []string) []string {
+	truncated := make([]string, 0, min(len(args), slowLogMaxArgs))
+	for i, arg := range args {
+		if i == slowLogMaxArgs-1 && len(args) > slowLogMaxArgs {
+			truncated = append(truncated, fmt.Sprintf("... (%d more arguments)", len(args)-i))
+			break
+		}
+
+		if len(arg) > slowLogMaxArgLen {
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:slowLogMaxArgLen], len(arg)-slowLogMaxArgLen)
+		}
+
+		truncated = append(truncated, arg)
+	}
+
+	return truncated
+}
@@ -0,0 +1,79 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func TestSlowLog(t *testing.T) {
+	cp := redisserverlib.NewRedisCommandProcessor(redisserverlib.WithDir(t.TempDir()),
+		redisserverlib.WithSlowlogLogSlowerThan(0), redisserverlib.WithSlowlogMaxLen(3))
+	t.Cleanup(func() { cp.Close() })
+	c := newTestClient(cp)
+
+	t.Run("Every command is logged with a zero threshold", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CLIENT", "SETNAME", "worker-1")
+		c.expect(t, "$8\r\nworker-1\r\n", "CLIENT", "GETNAME")
+		c.expect(t, "+OK\r\n", "SET", "k", "v")
+
+		entries := c.do("SLOWLOG", "GET", "1")
+		if !strings.HasPrefix(entries, "*1\r\n*6\r\n:2\r\n:") || !strings.HasSuffix(entries, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n$0\r\n\r\n$8\r\nworker-1\r\n") {
+			t.Errorf("SLOWLOG GET 1 = %q", entries)
+		}
+
+		c.expect(t, ":3\r\n", "SLOWLOG", "LEN")
+	})
+
+	t.Run("The oldest entries are dropped", func(t *testing.T) {
+		c.do("PING")
+		if entries := c.do("SLOWLOG", "GET", "-1"); !strings.HasPrefix(entries, "*3\r\n*6\r\n:5\r\n") {
+			t.Errorf("SLOWLOG GET -1 = %q", entries)
+		}
+	})
+
+	t.Run("Arguments are truncated", func(t *testing.T) {
+		args := []string{"RPUSH", "l", strings.Repeat("x", 130)}
+		for i := range 40 {
+			args = append(args, fmt.Sprint(i))
+		}
+
+		c.do(args...)
+		entries := c.do("SLOWLOG", "GET", "1")
+		if !strings.Contains(entries, "*32\r\n$5\r\nRPUSH\r\n$1\r\nl\r\n$146\r\n"+strings.Repeat("x", 128)+"... (2 more bytes)\r\n") ||
+			!strings.Contains(entries, "$23\r\n... (12 more arguments)\r\n") {
+			t.Errorf("SLOWLOG GET 1 = %q", entries)
+		}
+	})
+
+	t.Run("Credentials are redacted", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "hunter2", "maxclients", "100")
+		entries := c.do("SLOWLOG", "GET", "1")
+		if !strings.Contains(entries, "*6\r\n$6\r\nCONFIG\r\n$3\r\nSET\r\n$11\r\nrequirepass\r\n$10\r\n(redacted)\r\n$10\r\nmaxclients\r\n$3\r\n100\r\n") ||
+			strings.Contains(entries, "hunter2") {
+			t.Errorf("SLOWLOG GET 1 = %q", entries)
+		}
+
+		// Refused calls are logged too
+		c.do("CONFIG", "SET", "masterauth", "primary-secret")
+		if entries := c.do("SLOWLOG", "GET", "1"); !strings.Contains(entries, "$10\r\nmasterauth\r\n$10\r\n(redacted)\r\n") {
+			t.Errorf("SLOWLOG GET 1 = %q", entries)
+		}
+
+		c.expect(t, "+OK\r\n", "AUTH", "hunter2")
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "requirepass", "")
+	})
+
+	t.Run("RESET and the threshold", func(t *testing.T) {
+		c.expect(t, "+OK\r\n", "CONFIG", "SET", "slowlog-log-slower-than", "-1")
+		c.expect(t, "+OK\r\n", "SLOWLOG", "RESET")
+		c.do("PING")
+		c.expect(t, ":0\r\n", "SLOWLOG", "LEN")
+		c.expect(t, "*0\r\n", "SLOWLOG", "GET")
+		c.expect(t, "-ERR count should be greater than or equal to -1\r\n", "SLOWLOG", "GET", "-2")
+	})
+
+	c.expect(t, "-ERR Client names cannot contain spaces, newlines or special characters.\r\n", "CLIENT", "SETNAME", "a b")
+}
@@ -0,0 +1,110 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	slowlogCmd struct {
+		*slowLog
+	}
+)
+
+const (
+	// Entries SLOWLOG GET returns without a count
+	slowLogDefaultCount = 10
+)
+
+func (c slowlogCmd) moniker() string {
+	return "SLOWLOG"
+}
+
+func (c slowlogCmd) spec() commandSpec {
+	return commandSpec{
+		arity:   -2,
+		flags:   flagAdmin,
+		group:   "server",
+		summary: "A container for slow log commands.",
+		since:   "2.2.12",
+		subcommands: []commandSpec{
+			{
+				name: "get", arity: -2, summary: "Returns the slow log's entries.", since: "2.2.12",
+				complexity: "O(N) where N is the number of entries returned",
+				args:       []commandArg{{name: "count", typ: argInteger, optional: true}},
+			},
+			{name: "len", arity: 2, summary: "Returns the number of entries in the slow log.", since: "2.2.12", complexity: "O(1)"},
+			{
+				name: "reset", arity: 2, summary: "Clears all entries from the slow log.", since: "2.2.12",
+				complexity: "O(N) where N is the number of entries in the slowlog",
+			},
+		},
+	}
+}
+
+func (c slowlogCmd) getUsage() string {
+	return `
+usage:
+	SLOWLOG GET [count]
+	SLOWLOG LEN
+	SLOWLOG RESET
+summary:
+	GET returns the latest entries of the slow log, 10 unless a count is given, or all of them for -1.
+	Each entry holds its ID, the UNIX time it was logged at, the duration of the command in microseconds,
+	its arguments with credentials redacted, and the address and name of the client that sent it.
+	Commands are logged when they run for longer than slowlog-log-slower-than microseconds, and the
+	log keeps the latest slowlog-max-len entries.
+	LEN returns the number of entries in the slow log.
+	RESET empties the slow log.
+`
+}
+
+func (c slowlogCmd) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SLOWLOG requires a subcommand! %s", c.getUsage())}
+	}
+
+	subcommand := strings.ToUpper(params[1].Val)
+	switch {
+	case subcommand == "GET" && len(params) <= 3:
+		count := slowLogDefaultCount
+		if len(params) == 3 {
+			n, err := strconv.Atoi(params[2].Val)
+			if err != nil || n < -1 {
+				return resptypes.SimpleError{Val: fmt.Errorf("ERR count should be greater than or equal to -1")}
+			}
+
+			count = n
+		}
+
+		return c.executeGet(count)
+	case subcommand == "LEN" && len(params) == 2:
+		return resptypes.Integer{Val: int64(c.len())}
+	case subcommand == "RESET" && len(params) == 2:
+		c.reset()
+		return resptypes.SimpleString{Val: "OK"}
+	default:
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR Unknown SLOWLOG subcommand or wrong number of arguments for '%s'! %s", params[1].Val, c.getUsage())}
+	}
+}
+
+func (c slowlogCmd) executeGet(count int) commandResult {
+	entries := c.latest(count)
+	result := make(resptypes.Array[resptypes.RespSerializable], len(entries))
+	for i, entry := range entries {
+		result[i] = resptypes.Array[resptypes.RespSerializable]{
+			resptypes.Integer{Val: entry.id},
+			resptypes.Integer{Val: entry.timestamp},
+			resptypes.Integer{Val: entry.duration.Microseconds()},
+			resptypes.ToBulkStringArray(entry.args),
+			resptypes.NewBulkString(entry.addr),
+			resptypes.NewBulkString(entry.name),
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,61 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	subscribe struct {
+		*pubsub
+	}
+)
+
+func (c subscribe) moniker() string {
+	return "SUBSCRIBE"
+}
+
+func (c subscribe) spec() commandSpec {
+	return commandSpec{
+		arity:        -2,
+		flags:        flagPubSub,
+		categories:   []string{"pubsub"},
+		channelSpecs: []keySpec{{index: 1, lastKey: -1, keyStep: 1}},
+		group:        "pubsub",
+		summary:      "Listens for messages published to channels.",
+		since:        "2.0.0",
+		complexity:   "O(N) where N is the number of channels to subscribe to.",
+		args:         []commandArg{{name: "channel", typ: argString, multiple: true}},
+	}
+}
+
+func (c subscribe) getUsage() string {
+	return `
+usage:
+	SUBSCRIBE channel [channel ...]
+summary:
+	Subscribes the client to the specified channels.
+	Once the client enters the subscribed state it is not supposed to issue any other commands,
+	except for additional SUBSCRIBE, PSUBSCRIBE, UNSUBSCRIBE, PUNSUBSCRIBE and PING commands.
+`
+}
+
+func (c subscribe) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SUBSCRIBE requires at least one channel! %s", c.getUsage())}
+	}
+
+	c.subscribe(clientFromContext(ctx), channelNames(params[1:])...)
+	return resptypes.NoReply{}
+}
+
+func channelNames(params commandParams) []string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.Val
+	}
+
+	return names
+}
@@ -0,0 +1,61 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	swapdb struct {
+		keyspace
+	}
+)
+
+func (c swapdb) moniker() string {
+	return "SWAPDB"
+}
+
+func (c swapdb) spec() commandSpec {
+	return commandSpec{
+		arity:      3,
+		flags:      flagWrite | flagFast,
+		categories: []string{"keyspace", "dangerous"},
+		group:      "server",
+		summary:    "Swaps two Redis databases.",
+		since:      "4.0.0",
+		complexity: "O(N) where N is the count of clients watching or blocking on keys from both databases.",
+		args:       []commandArg{{name: "index1", typ: argInteger}, {name: "index2", typ: argInteger}},
+	}
+}
+
+func (c swapdb) getUsage() string {
+	return `
+usage:
+	SWAPDB index1 index2
+summary:
+	This command swaps two Redis databases, so that immediately all the clients connected to a given database
+	will see the data of the other database, and the other way around.
+`
+}
+
+func (c swapdb) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR SWAPDB requires exactly two arguments! %s", c.getUsage())}
+	}
+
+	a, err := c.parseDbIndex(params[1].Val)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	b, err := c.parseDbIndex(params[2].Val)
+	if err != nil {
+		return resptypes.SimpleError{Val: err}
+	}
+
+	c.Swap(a, b)
+	c.dirty.Add(1)
+	return resptypes.SimpleString{Val: "OK"}
+}
@@ -0,0 +1,166 @@
+package redisserverlib
+
+import (
+	"strings"
+	"sync"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	trackingOptions struct {
+		redirect int64
+		bcast    bool
+		prefixes []string
+		optIn    bool
+		optOut   bool
+		noLoop   bool
+	}
+
+	// tracking implements server-assisted client-side caching. By default it remembers which
+	// clients read which keys and sends each of them one invalidation when the key changes.
+	// In broadcasting mode it instead invalidates every changed key matching a client's prefixes.
+	tracking struct {
+		mu      sync.Mutex
+		options map[int64]trackingOptions
+		keys    map[string]map[int64]struct{}
+		clients *clientList
+		pubsub  *pubsub
+	}
+)
+
+const (
+	// Clients using REDIRECT receive invalidations as Pub/Sub messages on this channel.
+	invalidateChannel = "__redis__:invalidate"
+)
+
+func newTracking(clients *clientList, ps *pubsub) *tracking {
+	return &tracking{
+		options: make(map[int64]trackingOptions),
+		keys:    make(map[string]map[int64]struct{}),
+		clients: clients,
+		pubsub:  ps,
+	}
+}
+
+func (t *tracking) enable(c *client, opts trackingOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.options[c.id] = opts
+}
+
+// disable turns tracking off for c. Keys it read are left in the table and pruned on invalidation.
+func (t *tracking) disable(c *client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.options, c.id)
+}
+
+func (t *tracking) optionsOf(c *client) (trackingOptions, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	opts, enabled := t.options[c.id]
+	return opts, enabled
+}
+
+// remember records that c read key, honouring OPTIN/OPTOUT and CLIENT CACHING.
+func (t *tracking) remember(c *client, key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	opts, enabled := t.options[c.id]
+	if !enabled || opts.bcast {
+		return
+	}
+
+	if opts.optIn && (c.caching == nil || !*c.caching) {
+		return
+	}
+
+	if opts.optOut && c.caching != nil && !*c.caching {
+		return
+	}
+
+	ids, exists := t.keys[key]
+	if !exists {
+		ids = make(map[int64]struct{})
+		t.keys[key] = ids
+	}
+
+	ids[c.id] = struct{}{}
+}
+
+// invalidate notifies the clients caching key. origin is the client that modified the key,
+// or nil when it expired.
+func (t *tracking) invalidate(key string, origin *client) {
+	t.mu.Lock()
+	targets := make(map[int64]trackingOptions)
+	for id := range t.keys[key] {
+		if opts, enabled := t.options[id]; enabled && !opts.bcast {
+			targets[id] = opts
+		}
+	}
+	delete(t.keys, key)
+
+	for id, opts := range t.options {
+		if opts.bcast && hasAnyPrefix(key, opts.prefixes) {
+			targets[id] = opts
+		}
+	}
+	t.mu.Unlock()
+
+	keys := resptypes.ToBulkStringArray([]string{key})
+	for id, opts := range targets {
+		if opts.noLoop && origin != nil && origin.id == id {
+			continue
+		}
+
+		t.send(id, opts, keys)
+	}
+}
+
+// invalidateAll tells every tracking client to drop its whole cache, as after FLUSHALL.
+func (t *tracking) invalidateAll(origin *client) {
+	t.mu.Lock()
+	targets := make(map[int64]trackingOptions, len(t.options))
+	for id, opts := range t.options {
+		targets[id] = opts
+	}
+	t.keys = make(map[string]map[int64]struct{})
+	t.mu.Unlock()
+
+	for id, opts := range targets {
+		t.send(id, opts, resptypes.NullArray)
+	}
+}
+
+func (t *tracking) send(id int64, opts trackingOptions, keys resptypes.RespSerializable) {
+	if opts.redirect == 0 {
+		if c, exists := t.clients.get(id); exists {
+			c.push(resptypes.Push{resptypes.NewBulkString("invalidate"), keys})
+		}
+		return
+	}
+
+	if target, exists := t.clients.get(opts.redirect); exists {
+		t.pubsub.pushIfSubscribed(target, invalidateChannel, resptypes.Array[resptypes.RespSerializable]{
+			resptypes.NewBulkString("message"),
+			resptypes.NewBulkString(invalidateChannel),
+			keys,
+		})
+	}
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package redisserverlib_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+)
+
+func (c testClient) expectNoPush(t *testing.T) {
+	t.Helper()
+	select {
+	case actual := <-c.out:
+		t.Errorf("Unexpected push %q", actual)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestClientTracking(t *testing.T) {
+	const invalidateK = ">2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\nk\r\n"
+
+	tcs := []struct {
+		name  string
+		steps func(t *testing.T, reader testClient, writer testClient)
+	}{
+		{
+			name: "Keys read are invalidated once",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON")
+				reader.expect(t, "$-1\r\n", "GET", "k")
+				writer.expect(t, "+OK\r\n", "SET", "k", "1")
+				reader.expectPush(t, invalidateK)
+				writer.expect(t, "+OK\r\n", "SET", "k", "2")
+				reader.expectNoPush(t)
+			},
+		},
+		{
+			name: "Expiry invalidates",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				writer.expect(t, "+OK\r\n", "SET", "k", "1", "PX", "20")
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON")
+				reader.expect(t, "$1\r\n1\r\n", "GET", "k")
+				reader.expectPush(t, invalidateK)
+			},
+		},
+		{
+			name: "NOLOOP skips own writes",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON", "NOLOOP")
+				reader.expect(t, "$-1\r\n", "GET", "k")
+				reader.expect(t, "+OK\r\n", "SET", "k", "1")
+				reader.expectNoPush(t)
+			},
+		},
+		{
+			name: "OPTIN only tracks after CLIENT CACHING YES",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON", "OPTIN")
+				reader.expect(t, "$-1\r\n", "GET", "k")
+				writer.expect(t, "+OK\r\n", "SET", "k", "1")
+				reader.expectNoPush(t)
+				reader.expect(t, "+OK\r\n", "CLIENT", "CACHING", "YES")
+				reader.expect(t, "$1\r\n1\r\n", "GET", "k")
+				writer.expect(t, "+OK\r\n", "SET", "k", "2")
+				reader.expectPush(t, invalidateK)
+			},
+		},
+		{
+			name: "BCAST invalidates matching prefixes",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "user:")
+				writer.expect(t, "+OK\r\n", "SET", "order:1", "x")
+				reader.expectNoPush(t)
+				writer.expect(t, ":1\r\n", "RPUSH", "user:1", "x")
+				reader.expectPush(t, ">2\r\n$10\r\ninvalidate\r\n*1\r\n$6\r\nuser:1\r\n")
+			},
+		},
+		{
+			name: "FLUSHALL invalidates everything",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON")
+				writer.expect(t, "+OK\r\n", "FLUSHALL")
+				reader.expectPush(t, ">2\r\n$10\r\ninvalidate\r\n*-1\r\n")
+			},
+		},
+		{
+			name: "REDIRECT publishes to the invalidation channel",
+			steps: func(t *testing.T, reader testClient, writer testClient) {
+				id := writer.do("CLIENT", "ID")[1:]
+				id = id[:len(id)-2]
+				writer.expect(t, "", "SUBSCRIBE", "__redis__:invalidate")
+				writer.expectPush(t, "*3\r\n$9\r\nsubscribe\r\n$20\r\n__redis__:invalidate\r\n:1\r\n")
+
+				reader.expect(t, "+OK\r\n", "CLIENT", "TRACKING", "ON", "REDIRECT", id)
+				reader.expect(t, fmt.Sprintf(":%s\r\n", id), "CLIENT", "GETREDIR")
+				reader.expect(t, "$-1\r\n", "GET", "k")
+				reader.expect(t, "+OK\r\n", "SET", "k", "1")
+				writer.expectPush(t, "*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$1\r\nk\r\n")
+				reader.expectNoPush(t)
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cp := redisserverlib.NewRedisCommandProcessor()
+			tc.steps(t, newTestClient(cp), newTestClient(cp))
+		})
+	}
+}
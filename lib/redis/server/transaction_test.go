@@ -0,0 +1,129 @@
+package redisserverlib_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	redisserverlib "github.com/codecrafters-io/redis-starter-go/lib/redis/server"
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	testClient struct {
+		ctx context.Context
+		cp  redisserverlib.CommandProcessor
+		out chan string
+	}
+)
+
+func newTestClient(cp redisserverlib.CommandProcessor) testClient {
+	ctx, out := cp.ConnectClient(context.Background())
+	return testClient{ctx: ctx, cp: cp, out: out}
+}
+
+func (c testClient) do(args ...string) string {
+	return c.cp.ExecuteCommand(c.ctx, resptypes.ToBulkStringArray(args).ToRespString()).ToRespString()
+}
+
+func (c testClient) expect(t *testing.T, expected string, args ...string) {
+	t.Helper()
+	if actual := c.do(args...); actual != expected {
+		t.Errorf("%v = %q; Expected: %q", args, actual, expected)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	tcs := []struct {
+		name  string
+		steps func(t *testing.T, a testClient, b testClient)
+	}{
+		{
+			name: "EXEC runs queued commands",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "+QUEUED\r\n", "SET", "k", "v")
+				a.expect(t, "+QUEUED\r\n", "GET", "k")
+				b.expect(t, "$-1\r\n", "GET", "k")
+				a.expect(t, "*2\r\n+OK\r\n$1\r\nv\r\n", "EXEC")
+			},
+		},
+		{
+			name: "Runtime errors are reported per command",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, ":1\r\n", "RPUSH", "list", "a")
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "+QUEUED\r\n", "GET", "list")
+				a.expect(t, "+QUEUED\r\n", "LLEN", "list")
+				a.expect(t, "*2\r\n-WRONGTYPE Operation against a key holding the wrong kind of value\r\n:1\r\n", "EXEC")
+			},
+		},
+		{
+			name: "Queue time errors abort EXEC",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.do("NOTACOMMAND")
+				a.expect(t, "+QUEUED\r\n", "SET", "k", "v")
+				a.expect(t, "-EXECABORT Transaction discarded because of previous errors.\r\n", "EXEC")
+				a.expect(t, "$-1\r\n", "GET", "k")
+			},
+		},
+		{
+			name: "Modified watched key aborts EXEC",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "WATCH", "k")
+				b.expect(t, "+OK\r\n", "SET", "k", "other")
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "+QUEUED\r\n", "SET", "k", "mine")
+				a.expect(t, "*-1\r\n", "EXEC")
+				a.expect(t, "$5\r\nother\r\n", "GET", "k")
+			},
+		},
+		{
+			name: "Expired watched key aborts EXEC",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "SET", "k", "v", "PX", "20")
+				a.expect(t, "+OK\r\n", "WATCH", "k")
+				time.Sleep(50 * time.Millisecond)
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "+QUEUED\r\n", "GET", "k")
+				a.expect(t, "*-1\r\n", "EXEC")
+			},
+		},
+		{
+			name: "Flushed watched key aborts EXEC",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "SET", "k", "v")
+				a.expect(t, "+OK\r\n", "WATCH", "k")
+				b.expect(t, "+OK\r\n", "FLUSHDB")
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "*-1\r\n", "EXEC")
+			},
+		},
+		{
+			name: "Unmodified watched key allows EXEC",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "WATCH", "k")
+				b.expect(t, "+OK\r\n", "SET", "unrelated", "v")
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "+QUEUED\r\n", "SET", "k", "v")
+				a.expect(t, "*1\r\n+OK\r\n", "EXEC")
+			},
+		},
+		{
+			name: "Blocking commands do not block inside EXEC",
+			steps: func(t *testing.T, a testClient, b testClient) {
+				a.expect(t, "+OK\r\n", "MULTI")
+				a.expect(t, "+QUEUED\r\n", "BLPOP", "empty", "0")
+				a.expect(t, "*1\r\n*-1\r\n", "EXEC")
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cp := redisserverlib.NewRedisCommandProcessor()
+			tc.steps(t, newTestClient(cp), newTestClient(cp))
+		})
+	}
+}
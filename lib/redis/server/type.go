@@ -10,7 +10,7 @@ import (
 
 type (
 	typeCmd struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -18,6 +18,20 @@ func (c typeCmd) moniker() string {
 	return "TYPE"
 }
 
+func (c typeCmd) spec() commandSpec {
+	return commandSpec{
+		arity:      2,
+		flags:      flagReadonly | flagFast,
+		categories: []string{"keyspace"},
+		keySpecs:   firstKey(keyRO),
+		group:      "generic",
+		summary:    "Determines the type of value stored at a key.",
+		since:      "1.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "key", typ: argKey}},
+	}
+}
+
 func (c typeCmd) getUsage() string {
 	return `
 usage:
@@ -35,7 +49,8 @@ func (c typeCmd) execute(ctx context.Context, params commandParams) commandResul
 
 	key := params[1].Val
 	typeString := "none"
-	if dsVal, exists := c.Get(key); exists {
+	c.read(ctx, key)
+	if dsVal, exists := c.db(ctx).Get(key); exists {
 		switch dsVal.Type {
 		case redistypes.TypeString:
 			typeString = "string"
@@ -45,7 +60,13 @@ func (c typeCmd) execute(ctx context.Context, params commandParams) commandResul
 			typeString = "stream"
 		case redistypes.TypeUnknown:
 			typeString = "unknown"
+		default:
+			if t, custom := redistypes.CustomTypeOf(dsVal.Type); custom {
+				typeString = t.Name
+			}
 		}
+	} else {
+		c.missed(ctx, key)
 	}
 
 	return resptypes.SimpleString{Val: typeString}
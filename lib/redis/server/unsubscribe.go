@@ -0,0 +1,45 @@
+package redisserverlib
+
+import (
+	"context"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	unsubscribe struct {
+		*pubsub
+	}
+)
+
+func (c unsubscribe) moniker() string {
+	return "UNSUBSCRIBE"
+}
+
+func (c unsubscribe) spec() commandSpec {
+	return commandSpec{
+		arity:      -1,
+		flags:      flagPubSub,
+		categories: []string{"pubsub"},
+		group:      "pubsub",
+		summary:    "Stops listening to messages posted to channels.",
+		since:      "2.0.0",
+		complexity: "O(N) where N is the number of channels to unsubscribe.",
+		args:       []commandArg{{name: "channel", typ: argString, optional: true, multiple: true}},
+	}
+}
+
+func (c unsubscribe) getUsage() string {
+	return `
+usage:
+	UNSUBSCRIBE [channel [channel ...]]
+summary:
+	Unsubscribes the client from the given channels, or from all of them if none is given.
+	When no channels are specified, the client is unsubscribed from all the previously subscribed channels.
+`
+}
+
+func (c unsubscribe) execute(ctx context.Context, params commandParams) commandResult {
+	c.unsubscribe(clientFromContext(ctx), channelNames(params[1:])...)
+	return resptypes.NoReply{}
+}
@@ -0,0 +1,42 @@
+package redisserverlib
+
+import (
+	"context"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	unwatch struct{}
+)
+
+func (c unwatch) moniker() string {
+	return "UNWATCH"
+}
+
+func (c unwatch) spec() commandSpec {
+	return commandSpec{
+		arity:      1,
+		flags:      flagFast,
+		categories: []string{"transaction"},
+		group:      "transactions",
+		summary:    "Forgets about watched keys of a transaction.",
+		since:      "2.2.0",
+		complexity: "O(1)",
+	}
+}
+
+func (c unwatch) getUsage() string {
+	return `
+usage:
+	UNWATCH
+summary:
+	Flushes all the previously watched keys for a transaction.
+	If you call EXEC or DISCARD, there's no need to manually call UNWATCH.
+`
+}
+
+func (c unwatch) execute(ctx context.Context, params commandParams) commandResult {
+	clientFromContext(ctx).unwatchAll()
+	return resptypes.SimpleString{Val: "OK"}
+}
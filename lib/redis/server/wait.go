@@ -0,0 +1,77 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	wait struct {
+		*replication
+	}
+)
+
+func (c wait) moniker() string {
+	return "WAIT"
+}
+
+func (c wait) spec() commandSpec {
+	return commandSpec{
+		arity:      3,
+		flags:      flagBlocking,
+		categories: []string{"connection"},
+		group:      "generic",
+		summary:    "Blocks until the asynchronous replication of all preceding write commands sent by the connection is completed.",
+		since:      "3.0.0",
+		complexity: "O(1)",
+		args:       []commandArg{{name: "numreplicas", typ: argInteger}, {name: "timeout", typ: argInteger}},
+	}
+}
+
+func (c wait) getUsage() string {
+	return `
+usage:
+	WAIT numreplicas timeout
+summary:
+	Block until all previous write commands of the connection were acknowledged by at least numreplicas replicas,
+	or until timeout milliseconds elapsed. A timeout of 0 blocks forever.
+	Returns the number of replicas that acknowledged the writes, which may be lower than numreplicas on timeout.
+`
+}
+
+func (c wait) execute(ctx context.Context, params commandParams) commandResult {
+	if len(params) != 3 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR wrong number of arguments for 'wait' command! %s", c.getUsage())}
+	}
+
+	numReplicas, err := strconv.Atoi(params[1].Val)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR value is not an integer or out of range")}
+	}
+
+	timeoutMillis, err := strconv.ParseInt(params[2].Val, 10, 64)
+	if err != nil {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR timeout is not an integer or out of range")}
+	}
+
+	if timeoutMillis < 0 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR timeout is negative")}
+	}
+
+	if c.isReplica() {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR WAIT cannot be used with replica instances")}
+	}
+
+	if timeoutMillis > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutMillis)*time.Millisecond)
+		defer cancel()
+	}
+
+	count := c.waitForReplicas(ctx, clientFromContext(ctx).replOffset, numReplicas)
+	return resptypes.Integer{Val: int64(count)}
+}
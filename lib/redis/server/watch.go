@@ -0,0 +1,61 @@
+package redisserverlib
+
+import (
+	"context"
+	"fmt"
+
+	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
+)
+
+type (
+	watch struct {
+		keyspace
+	}
+)
+
+func (c watch) moniker() string {
+	return "WATCH"
+}
+
+func (c watch) spec() commandSpec {
+	return commandSpec{
+		arity:      -2,
+		flags:      flagTransaction | flagFast,
+		categories: []string{"transaction"},
+		keySpecs:   []keySpec{{flags: keyRO, index: 1, lastKey: -1, keyStep: 1}},
+		group:      "transactions",
+		summary:    "Monitors changes to keys to determine the execution of a transaction.",
+		since:      "2.2.0",
+		complexity: "O(1) for every key.",
+		args:       []commandArg{{name: "key", typ: argKey, multiple: true}},
+	}
+}
+
+func (c watch) getUsage() string {
+	return `
+usage:
+	WATCH key [key ...]
+summary:
+	Marks the given keys to be watched for conditional execution of a transaction.
+	If any watched key is modified, expires or is deleted before EXEC, the transaction is aborted and EXEC returns a null reply.
+`
+}
+
+func (c watch) execute(ctx context.Context, params commandParams) commandResult {
+	client := clientFromContext(ctx)
+	if client.multi != nil {
+		client.multi.aborted = true
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR WATCH inside MULTI is not allowed")}
+	}
+
+	if len(params) < 2 {
+		return resptypes.SimpleError{Val: fmt.Errorf("ERR WATCH requires at least one key! %s", c.getUsage())}
+	}
+
+	ds := c.db(ctx)
+	for _, key := range params[1:] {
+		client.watch(ds, key.Val)
+	}
+
+	return resptypes.SimpleString{Val: "OK"}
+}
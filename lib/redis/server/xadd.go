@@ -13,7 +13,7 @@ import (
 
 type (
 	xadd struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -25,6 +25,24 @@ func (c xadd) moniker() string {
 	return "XADD"
 }
 
+func (c xadd) spec() commandSpec {
+	return commandSpec{
+		arity:      -5,
+		flags:      flagWrite | flagFast,
+		categories: []string{"stream"},
+		keySpecs:   firstKey(keyRWInsert),
+		group:      "stream",
+		summary:    "Appends a new message to a stream. Creates the key if it doesn't exist.",
+		since:      "5.0.0",
+		complexity: "O(1)",
+		args: []commandArg{
+			{name: "key", typ: argKey},
+			{name: "id", typ: argString},
+			{name: "data", typ: argBlock, multiple: true, args: []commandArg{{name: "field", typ: argString}, {name: "value", typ: argString}}},
+		},
+	}
+}
+
 func (c xadd) getUsage() string {
 	return `
 usage:
@@ -63,7 +81,7 @@ func (c xadd) execute(ctx context.Context, params commandParams) commandResult {
 			return resptypes.SimpleError{Val: fmt.Errorf("ERR Invalid stream ID! Cannot use '-' when ms is generated.")}
 		}
 
-		streamEntryId.GenMs = true
+		streamEntryId.Ms = uint64(commandTime(ctx).UnixMilli())
 		streamEntryId.GenSeq = true
 	} else {
 		var err error
@@ -82,10 +100,23 @@ func (c xadd) execute(ctx context.Context, params commandParams) commandResult {
 		}
 	}
 
-	dsVal := c.GetOrCreate(key, redistypes.NewStream, 0)
+	dsVal := c.getOrCreate(ctx, key, redistypes.NewStream)
 	if dsVal.Type != redistypes.TypeStream {
 		return resptypes.SimpleError{Val: fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")}
 	}
 
-	return dsVal.Stream.AddEntry(streamEntryId, params[3:])
+	result := dsVal.Stream.AddEntry(streamEntryId, params[3:])
+	if _, failed := result.(resptypes.SimpleError); !failed {
+		c.modified(ctx, key)
+		c.notify(ctx, notifyStream, "xadd", key)
+
+		// Generated IDs must not be generated again when replayed
+		args := []string{"XADD", key, result.(*resptypes.BulkString).Val}
+		for _, param := range params[3:] {
+			args = append(args, param.Val)
+		}
+		propagateAs(ctx, args...)
+	}
+
+	return result
 }
@@ -12,7 +12,7 @@ import (
 
 type (
 	xrange struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -20,6 +20,25 @@ func (c xrange) moniker() string {
 	return "XRANGE"
 }
 
+func (c xrange) spec() commandSpec {
+	return commandSpec{
+		arity:      -4,
+		flags:      flagReadonly,
+		categories: []string{"stream"},
+		keySpecs:   firstKey(keyRO),
+		group:      "stream",
+		summary:    "Returns the messages from a stream within a range of IDs.",
+		since:      "5.0.0",
+		complexity: "O(N) with N being the number of elements being returned. If N is constant (e.g. always asking for the first 10 elements with COUNT), you can consider it O(1).",
+		args: []commandArg{
+			{name: "key", typ: argKey},
+			{name: "start", typ: argString},
+			{name: "end", typ: argString},
+			{name: "count", typ: argInteger, token: "COUNT", optional: true},
+		},
+	}
+}
+
 func (c xrange) getUsage() string {
 	return `
 usage:
@@ -37,8 +56,10 @@ func (c xrange) execute(ctx context.Context, params commandParams) commandResult
 	}
 
 	streamKey := params[1].Val
-	dsVal, exists := c.Get(streamKey)
+	c.read(ctx, streamKey)
+	dsVal, exists := c.db(ctx).Get(streamKey)
 	if !exists {
+		c.missed(ctx, streamKey)
 		return resptypes.Array[resptypes.RespSerializable]{}
 	}
 
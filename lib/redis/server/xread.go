@@ -12,7 +12,7 @@ import (
 
 type (
 	xread struct {
-		redistypes.DataStore
+		keyspace
 	}
 )
 
@@ -20,6 +20,23 @@ func (c xread) moniker() string {
 	return "XREAD"
 }
 
+func (c xread) spec() commandSpec {
+	return commandSpec{
+		arity:      -4,
+		flags:      flagReadonly,
+		categories: []string{"stream"},
+		// XREAD STREAMS key [key ...] id [id ...]
+		keySpecs: []keySpec{{flags: keyRO, keyword: "STREAMS", startFrom: 1, lastKey: -1, keyStep: 1, limit: 2}},
+		group:    "stream",
+		summary:  "Returns messages from multiple streams with IDs greater than the ones requested.",
+		since:    "5.0.0",
+		args: []commandArg{{name: "streams", typ: argBlock, token: "STREAMS", args: []commandArg{
+			{name: "key", typ: argKey, multiple: true},
+			{name: "id", typ: argString, multiple: true},
+		}}},
+	}
+}
+
 func (c xread) getUsage() string {
 	return `
 usage:
@@ -79,8 +96,10 @@ func (c xread) execute(ctx context.Context, params commandParams) commandResult
 	for i, pairs := range streamIdPairs {
 		streamKey := pairs.First
 		streamKeyStr := streamKey.Val
-		dsVal, exists := c.Get(streamKeyStr)
+		c.read(ctx, streamKeyStr)
+		dsVal, exists := c.db(ctx).Get(streamKeyStr)
 		if !exists {
+			c.missed(ctx, streamKeyStr)
 			return resptypes.Array[resptypes.RespSerializable]{}
 		}
 
@@ -0,0 +1,112 @@
+package redistypes
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type (
+	// CustomValue is the value of a key holding a custom type. Write commands run exclusively,
+	// so a value only modified by commands needs no locking of its own.
+	CustomValue interface {
+		// Clone returns a copy that is not affected by later modifications of the value.
+		// Snapshots are written from it.
+		Clone() CustomValue
+	}
+
+	// CustomType describes a value type added by an extension, like the data types of Redis modules.
+	CustomType struct {
+		// Reported by TYPE, and written to RDB files and DUMP payloads to find the type again when
+		// loading them. Like the names of Redis module types, it is 9 characters among A-Z, a-z,
+		// 0-9, - and _.
+		Name string
+		// The version of the encoding returned by Encode, from 0 to 1023. Decode is given the
+		// version a value was encoded with, so that it can read what older versions wrote.
+		EncodingVersion int
+		Encode          func(v CustomValue) []byte
+		Decode          func(data []byte, encodingVersion int) (CustomValue, error)
+
+		// Set by RegisterType
+		valueType StoreValueType
+	}
+
+	customTypeRegistry struct {
+		mu     sync.RWMutex
+		byType map[StoreValueType]*CustomType
+		byName map[string]*CustomType
+		next   StoreValueType
+	}
+)
+
+const (
+	// The characters allowed in the names of custom types, in the order RDB files number them
+	CustomTypeNameChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	CustomTypeNameLen   = 9
+	MaxEncodingVersion  = 1<<10 - 1
+)
+
+var (
+	// Custom types are shared by every processor, since values are decoded from RDB files and
+	// DUMP payloads without knowing which processor they are for.
+	customTypes = customTypeRegistry{
+		byType: make(map[StoreValueType]*CustomType),
+		byName: make(map[string]*CustomType),
+		next:   firstCustomType,
+	}
+)
+
+// RegisterType registers a custom type and returns the StoreValueType of its values. Registering
+// the same type again returns the same StoreValueType, but another type may not take its name.
+func RegisterType(t *CustomType) (StoreValueType, error) {
+	if len(t.Name) != CustomTypeNameLen || strings.Trim(t.Name, CustomTypeNameChars) != "" {
+		return TypeUnknown, fmt.Errorf("invalid type name %q: must be %d characters among %s", t.Name, CustomTypeNameLen, CustomTypeNameChars)
+	}
+
+	if t.EncodingVersion < 0 || t.EncodingVersion > MaxEncodingVersion {
+		return TypeUnknown, fmt.Errorf("invalid encoding version %d of type %s: must be between 0 and %d", t.EncodingVersion, t.Name, MaxEncodingVersion)
+	}
+
+	if t.Encode == nil || t.Decode == nil {
+		return TypeUnknown, fmt.Errorf("type %s must be able to encode and decode its values", t.Name)
+	}
+
+	customTypes.mu.Lock()
+	defer customTypes.mu.Unlock()
+	if existing, exists := customTypes.byName[t.Name]; exists {
+		if existing != t {
+			return TypeUnknown, fmt.Errorf("type name %s is already registered", t.Name)
+		}
+
+		return t.valueType, nil
+	}
+
+	t.valueType = customTypes.next
+	customTypes.next++
+	customTypes.byType[t.valueType] = t
+	customTypes.byName[t.Name] = t
+	return t.valueType, nil
+}
+
+// CustomTypeOf returns the custom type registered as valueType.
+func CustomTypeOf(valueType StoreValueType) (*CustomType, bool) {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	t, exists := customTypes.byType[valueType]
+	return t, exists
+}
+
+// CustomTypeNamed returns the custom type registered with name.
+func CustomTypeNamed(name string) (*CustomType, bool) {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	t, exists := customTypes.byName[name]
+	return t, exists
+}
+
+// NewCustomValue returns a store value holding v, of the custom type t. The type must be registered.
+func NewCustomValue(t *CustomType, v CustomValue) StoreValue {
+	customTypes.mu.RLock()
+	defer customTypes.mu.RUnlock()
+	return StoreValue{Type: t.valueType, Custom: v}
+}
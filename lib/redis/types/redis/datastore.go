@@ -1,6 +1,11 @@
 package redistypes
 
 import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/codecrafters-io/redis-starter-go/lib/concurrent"
 )
 
@@ -12,10 +17,54 @@ type (
 		String String
 		List   List
 		Stream ConcurrentStream
+		// Set for the custom types registered with RegisterType
+		Custom CustomValue
 	}
 
 	DataStore interface {
 		concurrent.ConcurrentMap[StoreKey, StoreValue]
+
+		// Touch records a modification of key. Commands call it after changing a value in place.
+		// Delete, Clear and expiry touch the affected keys themselves.
+		Touch(key StoreKey)
+
+		// Watch starts tracking modifications of key and returns its current version.
+		// Every Watch must be paired with an Unwatch.
+		Watch(key StoreKey) uint64
+		Unwatch(key StoreKey)
+
+		// Version returns the current version of a watched key. Versions only move forward,
+		// so comparing against the value returned by Watch tells whether the key was touched since.
+		Version(key StoreKey) uint64
+	}
+
+	// dataStore only keeps versions for watched keys, so untouched and unwatched keys cost nothing.
+	dataStore struct {
+		concurrent.ConcurrentMap[StoreKey, StoreValue]
+		mu      sync.Mutex
+		watched map[StoreKey]*keyVersion
+	}
+
+	keyVersion struct {
+		refs    int
+		version uint64
+	}
+
+	// Databases is the fixed set of logical databases addressed by index, as with SELECT.
+	Databases interface {
+		DB(index int) DataStore
+		Len() int
+		Swap(a int, b int)
+
+		// OnExpire registers a callback invoked with the database's current index
+		// after a key was removed because its expiry elapsed.
+		OnExpire(f func(index int, key StoreKey))
+	}
+
+	databases struct {
+		mu       sync.RWMutex
+		dbs      []DataStore
+		onExpire func(index int, key StoreKey)
 	}
 )
 
@@ -24,8 +73,172 @@ const (
 	TypeString
 	TypeList
 	TypeStream
+
+	// Custom types registered with RegisterType are numbered from here
+	firstCustomType StoreValueType = 1 << 8
+)
+
+const (
+	DefaultDatabaseCount = 16
+)
+
+var (
+	// Shared by all data stores so that versions stay comparable across SWAPDB.
+	lastVersion atomic.Uint64
 )
 
 func NewRedisDataStore() DataStore {
-	return concurrent.NewConcurrentMap[StoreKey, StoreValue]()
+	ds := &dataStore{
+		ConcurrentMap: concurrent.NewConcurrentMap[StoreKey, StoreValue](),
+		watched:       make(map[StoreKey]*keyVersion),
+	}
+	ds.OnExpire(ds.Touch)
+	return ds
+}
+
+// Clone returns a copy of v that is not affected by later modifications of v.
+// Strings are replaced rather than modified in place, so they are shared.
+func (v StoreValue) Clone() StoreValue {
+	switch v.Type {
+	case TypeList:
+		list := NewList()
+		list.List.PushBack(v.List.GetRange(0, -1)...)
+		return list
+	case TypeStream:
+		return StoreValue{Type: TypeStream, Stream: v.Stream.Clone()}
+	default:
+		if v.Custom != nil {
+			return StoreValue{Type: v.Type, Custom: v.Custom.Clone()}
+		}
+
+		return v
+	}
+}
+
+func (d *dataStore) Touch(key StoreKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if kv, exists := d.watched[key]; exists {
+		kv.version = lastVersion.Add(1)
+	}
+}
+
+func (d *dataStore) touchAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, kv := range d.watched {
+		kv.version = lastVersion.Add(1)
+	}
+}
+
+func (d *dataStore) Watch(key StoreKey) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kv, exists := d.watched[key]
+	if !exists {
+		kv = &keyVersion{}
+		d.watched[key] = kv
+	}
+
+	kv.refs++
+	return kv.version
+}
+
+func (d *dataStore) Unwatch(key StoreKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if kv, exists := d.watched[key]; exists {
+		kv.refs--
+		if kv.refs <= 0 {
+			delete(d.watched, key)
+		}
+	}
+}
+
+func (d *dataStore) Version(key StoreKey) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if kv, exists := d.watched[key]; exists {
+		return kv.version
+	}
+
+	return 0
+}
+
+func (d *dataStore) Delete(key StoreKey) {
+	d.ConcurrentMap.Delete(key)
+	d.Touch(key)
+}
+
+func (d *dataStore) Set(key StoreKey, value StoreValue, expiryDuration time.Duration) bool {
+	replaced := d.ConcurrentMap.Set(key, value, expiryDuration)
+	d.Touch(key)
+	return replaced
+}
+
+func (d *dataStore) Clear(async bool) {
+	d.ConcurrentMap.Clear(async)
+	d.touchAll()
+}
+
+func NewRedisDatabases(count int) Databases {
+	d := &databases{
+		dbs: make([]DataStore, count),
+	}
+
+	for i := range d.dbs {
+		ds := NewRedisDataStore()
+		ds.OnExpire(func(key StoreKey) {
+			ds.Touch(key)
+			d.expired(ds, key)
+		})
+		d.dbs[i] = ds
+	}
+
+	return d
+}
+
+// DB returns the database at index. Callers are expected to validate the index against Len.
+func (d *databases) DB(index int) DataStore {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.dbs[index]
+}
+
+func (d *databases) OnExpire(f func(index int, key StoreKey)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onExpire = f
+}
+
+// expired forwards an expiry to the registered callback, resolving the index the
+// database currently lives at since SWAPDB may have moved it.
+func (d *databases) expired(ds DataStore, key StoreKey) {
+	d.mu.RLock()
+	onExpire := d.onExpire
+	index := slices.Index(d.dbs, ds)
+	d.mu.RUnlock()
+
+	if onExpire != nil && index >= 0 {
+		onExpire(index, key)
+	}
+}
+
+func (d *databases) Len() int {
+	return len(d.dbs)
+}
+
+// Swap exchanges the contents of two databases. Clients keep their selected index,
+// so they immediately see the other database's data.
+func (d *databases) Swap(a int, b int) {
+	d.mu.Lock()
+	d.dbs[a], d.dbs[b] = d.dbs[b], d.dbs[a]
+	d.mu.Unlock()
+
+	// Watchers of either database now see different data
+	for _, db := range []DataStore{d.DB(a), d.DB(b)} {
+		if ds, ok := db.(*dataStore); ok {
+			ds.touchAll()
+		}
+	}
 }
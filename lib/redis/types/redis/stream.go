@@ -3,8 +3,8 @@ package redistypes
 import (
 	"fmt"
 	"math"
+	"slices"
 	"sync"
-	"time"
 
 	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
 )
@@ -34,13 +34,16 @@ type (
 
 	AddStreamEntryId struct {
 		StreamEntryId
-		GenMs  bool
 		GenSeq bool
 	}
 
 	ConcurrentStream interface {
 		AddEntry(id AddStreamEntryId, entry resptypes.Array[resptypes.BulkString]) resptypes.RespSerializable
 		GetEntries(start StreamEntryId, end StreamEntryId) resptypes.RespSerializable
+		// ForEach calls f for every entry in ID order. fields alternates field names and values.
+		ForEach(f func(id StreamEntryId, fields resptypes.Array[resptypes.BulkString]))
+		LastId() StreamEntryId
+		Clone() ConcurrentStream
 	}
 )
 
@@ -67,10 +70,6 @@ func (s streamEntry) ToRespString() string {
 }
 
 func (s *stream) AddEntry(id AddStreamEntryId, entry resptypes.Array[resptypes.BulkString]) resptypes.RespSerializable {
-	if id.GenMs {
-		id.Ms = uint64(time.Now().UnixMilli())
-	}
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if id.GenSeq {
@@ -128,3 +127,29 @@ func (s *stream) GetEntries(start StreamEntryId, end StreamEntryId) resptypes.Re
 
 	return entries
 }
+
+func (s *stream) ForEach(f func(id StreamEntryId, fields resptypes.Array[resptypes.BulkString])) {
+	s.mu.RLock()
+	entries := s.entries
+	s.mu.RUnlock()
+
+	// Entries are only ever appended, so the captured slice stays valid without the lock
+	for _, entry := range entries {
+		f(entry.StreamEntryId, entry.Array)
+	}
+}
+
+func (s *stream) LastId() StreamEntryId {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastStreamEntryId
+}
+
+func (s *stream) Clone() ConcurrentStream {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &stream{
+		lastStreamEntryId: s.lastStreamEntryId,
+		entries:           slices.Clone(s.entries),
+	}
+}
@@ -67,7 +67,7 @@ func ParseRespString(respStr string) (RespSerializable, int) {
 		}
 
 		return BulkString{Length: length, Val: parts[1]}, nextSeparatorIndex + len(parts[1]) + 4
-	case '*':
+	case '*', '>':
 		if respStr[:nextSeparatorIndex] == "*-1" {
 			return NullArray, nextSeparatorIndex + 2
 		}
@@ -77,6 +77,7 @@ func ParseRespString(respStr string) (RespSerializable, int) {
 			return parseErr(fmt.Errorf("Invalid length value in RESP array! Err: %v", err))
 		}
 
+		isPush := respStr[0] == '>'
 		size := 0
 		totalNumBytes := nextSeparatorIndex + 2
 		elements := make(Array[RespSerializable], expectedLength)
@@ -94,7 +95,7 @@ func ParseRespString(respStr string) (RespSerializable, int) {
 			}
 
 			switch respStr[0] {
-			case '$', '*':
+			case '$', '*', '>':
 				respStr = respStr[numBytes-2:]
 				nextSeparatorIndex = strings.Index(respStr, "\r\n")
 			}
@@ -108,6 +109,10 @@ func ParseRespString(respStr string) (RespSerializable, int) {
 			return parseErr(fmt.Errorf("Array length values do not match! Got: %v, expected: %v", len(elements), expectedLength))
 		}
 
+		if isPush {
+			return Push(elements), totalNumBytes
+		}
+
 		return Array[RespSerializable](elements), totalNumBytes
 	default:
 		return parseErr(fmt.Errorf("Invalid RESP type prefix! Got: %v", respStr))
@@ -0,0 +1,11 @@
+package resptypes
+
+type (
+	// NoReply is returned by commands that already delivered their replies out of band,
+	// such as SUBSCRIBE. It serializes to nothing, so nothing is written for it.
+	NoReply struct{}
+)
+
+func (r NoReply) ToRespString() string {
+	return ""
+}
@@ -0,0 +1,22 @@
+package resptypes
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// Push is the RESP3 out-of-band push type, used for messages the server sends
+	// without a matching request (e.g. client-side caching invalidations).
+	Push []RespSerializable
+)
+
+func (r Push) ToRespString() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ">%d\r\n", len(r))
+	for _, respType := range r {
+		fmt.Fprint(&sb, respType.ToRespString())
+	}
+
+	return sb.String()
+}
@@ -28,6 +28,8 @@ func TestParsingCompleteStrings(t *testing.T) {
 		{"*2\r\n*3\r\n:1\r\n:2\r\n:3\r\n*2\r\n+Hello\r\n-World\r\n"},
 		{"*2\r\n*2\r\n$15\r\n1526985054069-0\r\n*4\r\n$11\r\ntemperature\r\n$2\r\n36\r\n$8\r\nhumidity\r\n$2\r\n95\r\n*2\r\n$15\r\n1526985054079-0\r\n*4\r\n$11\r\ntemperature\r\n$2\r\n37\r\n$8\r\nhumidity\r\n$2\r\n94\r\n"},
 		{"*5\r\n$-1\r\n$0\r\n\r\n*-1\r\n*0\r\n*4\r\n$-1\r\n$0\r\n\r\n*-1\r\n*0\r\n"},
+		{">2\r\n$10\r\ninvalidate\r\n*1\r\n$3\r\nkey\r\n"},
+		{"*2\r\n>2\r\n$10\r\ninvalidate\r\n*-1\r\n:1\r\n"},
 	}
 
 	for _, tc := range tcs {
@@ -3,9 +3,12 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -15,12 +18,13 @@ import (
 	resptypes "github.com/codecrafters-io/redis-starter-go/lib/redis/types/resp"
 )
 
-func ReadWorker(ctx context.Context, conn net.Conn, c chan string, commandProcessor redisserverlib.CommandProcessor) {
+func ReadWorker(ctx context.Context, conn net.Conn, c chan<- string, commandProcessor redisserverlib.CommandProcessor, metrics *redisserverlib.Metrics) {
 	ctx, cancel := context.WithCancel(ctx)
-	defer close(c)
+	// Closes c once no more replies will be sent
+	defer commandProcessor.DisconnectClient(ctx)
 	slog.DebugContext(ctx, "ReadWorker started")
 
-	in := rediscommon.CreateScannerChannel(ctx, cancel, conn, rediscommon.ScanResp)
+	in := rediscommon.CreateScannerChannel(ctx, cancel, conn, rediscommon.ScanCommand)
 	requestId := 0
 	for {
 		ctx := context.WithValue(ctx, logger.RequestIdKey, requestId)
@@ -36,6 +40,7 @@ func ReadWorker(ctx context.Context, conn net.Conn, c chan string, commandProces
 				return
 			}
 
+			metrics.AddNetInput(len(text))
 			result := commandProcessor.ExecuteCommand(ctx, text)
 			switch result.(type) {
 			case resptypes.Null:
@@ -43,12 +48,23 @@ func ReadWorker(ctx context.Context, conn net.Conn, c chan string, commandProces
 				return
 			}
 
-			c <- result.ToRespString()
+			str := result.ToRespString()
+			if str == "" {
+				// The reply was already pushed out of band
+				continue
+			}
+
+			select {
+			case c <- str:
+			case <-ctx.Done():
+				slog.DebugContext(ctx, "ReadWorker context cancelled while sending reply")
+				return
+			}
 		}
 	}
 }
 
-func WriteWorker(ctx context.Context, conn net.Conn, in <-chan string) {
+func WriteWorker(ctx context.Context, conn net.Conn, in <-chan string, metrics *redisserverlib.Metrics) {
 	defer conn.Close()
 	slog.DebugContext(ctx, "WriteWorker started")
 	writer := bufio.NewWriter(conn)
@@ -63,7 +79,8 @@ func WriteWorker(ctx context.Context, conn net.Conn, in <-chan string) {
 				return // Channel closed by ReadWorker
 			}
 
-			_, err := writer.WriteString(str)
+			n, err := writer.WriteString(str)
+			metrics.AddNetOutput(n)
 			if strings.HasPrefix(str, "-ERRTERM") {
 				slog.DebugContext(ctx, "WriteWorker exiting - terminating error sent")
 				return
@@ -80,14 +97,13 @@ func WriteWorker(ctx context.Context, conn net.Conn, in <-chan string) {
 	}
 }
 
-func ListenConn(ctx context.Context) {
+func ListenConn(ctx context.Context, port int, newProcessor func(...redisserverlib.Option) redisserverlib.CommandProcessor, opts ...redisserverlib.Option) {
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
 	network := "tcp"
 	address := "localhost"
-	port := "6379"
-	endpoint := fmt.Sprintf("%s:%s", address, port)
+	endpoint := fmt.Sprintf("%s:%d", address, port)
 
 	slog.InfoContext(ctx, "Attempting to start listening", "endpoint", endpoint)
 	listener, err := net.Listen(network, endpoint)
@@ -98,7 +114,13 @@ func ListenConn(ctx context.Context) {
 
 	ctx, cancel := context.WithCancel(ctx)
 
-	commandProcessor := redisserverlib.NewRedisCommandProcessor()
+	metrics := redisserverlib.NewMetrics()
+	commandProcessor := newProcessor(append(opts, redisserverlib.WithPort(port), redisserverlib.WithMetrics(metrics))...)
+	defer func() {
+		if err := commandProcessor.Close(); err != nil {
+			slog.ErrorContext(ctx, "Error saving the final snapshot", "error", err)
+		}
+	}()
 
 	defer listener.Close()
 	in := make(chan net.Conn)
@@ -129,28 +151,125 @@ func ListenConn(ctx context.Context) {
 		case conn := <-in:
 			remoteAddr := conn.RemoteAddr()
 			ctx := context.WithValue(ctx, logger.ClientKey, remoteAddr.String())
-			c := make(chan string)
+			ctx, c := commandProcessor.ConnectClient(ctx)
 			slog.InfoContext(ctx, "Client connected")
 			wg.Go(func() {
-				ReadWorker(ctx, conn, c, commandProcessor)
+				ReadWorker(ctx, conn, c, commandProcessor, metrics)
 				slog.DebugContext(ctx, "ReadWorker done")
 			})
 			wg.Go(func() {
-				WriteWorker(ctx, conn, c)
+				WriteWorker(ctx, conn, c, metrics)
 				slog.DebugContext(ctx, "WriteWorker done")
 			})
 		}
 	}
 }
 
+// defaultSave are the save points used unless the config file or the command line sets some.
+const defaultSave = "3600 1 300 100 60 10000"
+
 func main() {
-	slog.SetDefault(slog.New(logger.NewHandler(slog.LevelDebug)))
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options] [/path/to/redis.conf]\n\nOptions override the directives of the config file:\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Int("port", 6379, "port to listen on")
+	flag.String("replicaof", "", `primary to replicate from as "<host> <port>"`)
+	flag.Int("min-replicas-to-write", 0, "reject writes unless this many replicas are connected, 0 to disable")
+	flag.Int("min-replicas-max-lag", 10, "seconds since its last acknowledgement for a replica to count towards min-replicas-to-write")
+	flag.Int("databases", 16, "number of logical databases")
+	flag.String("notify-keyspace-events", "", "keyspace event classes to publish, e.g. KEA")
+	flag.String("dir", ".", "directory of the RDB file")
+	flag.String("dbfilename", "dump.rdb", "name of the RDB file")
+	flag.String("save", defaultSave, `save points as "<seconds> <changes> ...", empty to disable`)
+	flag.String("appendonly", "no", "log every write command to the append only file, yes or no")
+	flag.String("appendfsync", "everysec", "when to fsync the append only file: always, everysec or no")
+	flag.String("appendfilename", "appendonly.aof", "base name of the append only files")
+	flag.String("appenddirname", "appendonlydir", "directory of the append only files, relative to dir")
+	flag.String("cluster-enabled", "no", "run as a node of a Redis Cluster, yes or no")
+	flag.Int("cluster-port", 0, "port of the cluster bus, 0 for port + 10000")
+	flag.String("cluster-config-file", "nodes.conf", "file the cluster configuration is saved to, relative to dir")
+	flag.String("raft-members", "", `comma separated "<host>:<port>" of every Raft member including this server, empty to disable Raft mode`)
+	flag.String("raft-addr", "", `"<host>:<port>" identifying this server among the Raft members, defaults to 127.0.0.1:<port>`)
+	flag.Int("raft-snapshot-entries", 10000, "entries applied before the Raft log is compacted into a snapshot")
+	flag.String("active-active-peers", "", `comma separated "<host>:<port>" of the other instances accepting writes, empty to disable active-active mode`)
+	flag.String("loglevel", "debug", "verbosity of the log: debug, verbose, notice, warning or nothing")
+	flag.Int("timeout", 0, "seconds a client may stay idle before it is disconnected, 0 to disable")
+	flag.Int("maxclients", 10000, "maximum number of connected clients")
+	flag.String("metrics-addr", "", `"<host>:<port>" to serve Prometheus metrics and health checks on over HTTP, empty to disable`)
+	flag.String("requirepass", "", "password of the default user, empty to let clients in without AUTH")
+	flag.String("aclfile", "", "file the ACL users are loaded from and saved to, empty to disable")
+	flag.String("protected-mode", "yes", "refuse clients from other hosts while the default user has no password, yes or no")
+	flag.String("masterauth", "", "password to authenticate to the primary and to the Raft or active-active peers with")
+	var renamedCommands []string
+	flag.Func("rename-command", `command to rename as "<command> <new-name>", or to disable as "<command>", may be repeated`, func(value string) error {
+		renamedCommands = append(renamedCommands, value)
+		return nil
+	})
+	sentinel := flag.Bool("sentinel", false, "run as a sentinel monitoring the primaries given with --sentinel-monitor")
+	var sentinelMonitors []string
+	flag.Func("sentinel-monitor", `primary to monitor as "<name> <host> <port> <quorum>", may be repeated`, func(value string) error {
+		sentinelMonitors = append(sentinelMonitors, value)
+		return nil
+	})
+	flag.Int("sentinel-down-after-milliseconds", 30000, "milliseconds a monitored instance may not reply before it is considered down")
+	flag.Int("sentinel-failover-timeout", 180000, "milliseconds a failover may take before it is aborted")
+	flag.Parse()
+
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(slog.LevelDebug)
+	slog.SetDefault(slog.New(logger.NewHandler(levelVar)))
+
+	config := redisserverlib.NewConfig()
+	if flag.NArg() > 0 {
+		var err error
+		if config, err = redisserverlib.LoadConfig(flag.Arg(0)); err != nil {
+			slog.Error("Failed to load the config file", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Only the options given explicitly override the config file
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "sentinel" && f.Name != "sentinel-monitor" && f.Name != "rename-command" {
+			config.Set(f.Name, f.Value.String())
+		}
+	})
+	for _, monitor := range sentinelMonitors {
+		config.Set("sentinel-monitor", monitor)
+	}
+
+	for _, rename := range renamedCommands {
+		config.Set("rename-command", rename)
+	}
+
+	configOpts, err := config.Options()
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	port := 6379
+	if args, ok := config.Get("port"); ok {
+		port, _ = strconv.Atoi(args[0])
+	}
+
+	newProcessor := redisserverlib.NewRedisCommandProcessor
+	if *sentinel {
+		newProcessor = redisserverlib.NewSentinelProcessor
+	}
+
+	opts := append([]redisserverlib.Option{
+		redisserverlib.WithLogLevel(levelVar),
+		redisserverlib.WithSave(defaultSave),
+	}, configOpts...)
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	var wg sync.WaitGroup
 	rediscommon.ListenStdin(ctx, cancel)
 	wg.Go(func() {
-		ListenConn(ctx)
+		ListenConn(ctx, port, newProcessor, opts...)
 		slog.DebugContext(ctx, "ListenConn done")
 		cancel()
 	})